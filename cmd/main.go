@@ -3,9 +3,15 @@ package main
 import (
 	"context"
 	"eth-parser/internal/api"
+	"eth-parser/internal/audit"
+	"eth-parser/internal/auth"
 	"eth-parser/internal/config"
 	"eth-parser/internal/ethereum"
+	"eth-parser/internal/ratelimit"
+	"eth-parser/internal/rpc"
 	"eth-parser/internal/storage"
+	"eth-parser/pkg/fourbyte"
+	"eth-parser/pkg/models"
 	"log"
 	"net/http"
 	"os"
@@ -16,35 +22,105 @@ import (
 
 func main() {
 
-	// Initialize config
-	cfg := config.Load()
-
 	// Initialize logger
 	logger := log.New(os.Stdout, "", log.LstdFlags)
 
-	// Initialize storage
+	// Initialize config
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize storage, shared by all chains with chain-scoped keys
 	memoryStorage := storage.NewMemoryStorage()
-	// Initialize parser
-	parser := ethereum.NewEthParser(memoryStorage, logger)
+
+	// Initialize the signature database used to label calls and logs
+	signatures := fourbyte.Default()
+	for _, path := range cfg.SignatureFiles {
+		added, err := signatures.LoadFile(path)
+		if err != nil {
+			logger.Fatalf("Failed to load signatures: %v", err)
+		}
+		logger.Printf("Loaded %d signatures from %s", added, path)
+	}
+
+	// Initialize one parser per chain
+	var parsers []*ethereum.EthParser
+	var chains []api.Chain
+	for _, chain := range cfg.Chains {
+		chainLogger := log.New(os.Stdout, "["+chain.Name+"] ", log.LstdFlags)
+
+		// Fallback endpoints are used when a block from the current one
+		// fails verification, so every one of them has to serve the chain
+		client := rpc.NewClient(chain.RPCURLs...)
+		for _, url := range chain.RPCURLs {
+			reported, err := rpc.NewClient(url).ChainID()
+			if err != nil {
+				logger.Fatalf("Chain %s: node at %s: %v", chain.Name, url, err)
+			}
+			if reported != chain.ChainID {
+				logger.Fatalf("Chain %s: node at %s reports chain id %d, configured %d", chain.Name, url, reported, chain.ChainID)
+			}
+		}
+
+		parser := ethereum.NewEthParser(memoryStorage.ForChain(chain.ChainID), chainLogger)
+		parser.SetClient(client)
+		parser.SetBlockTime(chain.BlockTime)
+		parser.SetConfirmations(chain.Confirmations)
+		if cfg.FetchMode == "selective" {
+			parser.SetFetchMode(ethereum.FetchSelective, cfg.FullBlockThreshold)
+		}
+		parser.SetVerifyBlocks(cfg.VerifyBlocks)
+		parser.SetVerifySenders(cfg.VerifySenders)
+		parser.SetSignatures(signatures)
+		parser.SetLedger(cfg.Ledger, cfg.LedgerTraceInternal)
+		parser.SetTenantQuotas(cfg.TenantMaxSubscriptions, cfg.TenantQuotas)
+		var snapshotTokens []models.Address
+		for _, token := range chain.SnapshotTokens {
+			address, _ := models.ParseAddress(token) // validated by config
+			snapshotTokens = append(snapshotTokens, address)
+		}
+		parser.SetSnapshots(chain.SnapshotInterval, snapshotTokens)
+		if len(chain.BlobSchedule) > 0 {
+			var schedule ethereum.BlobSchedule
+			for _, fork := range chain.BlobSchedule {
+				schedule = append(schedule, ethereum.BlobFork(fork))
+			}
+			parser.SetBlobSchedule(schedule)
+		}
+
+		parsers = append(parsers, parser)
+		chains = append(chains, api.Chain{Name: chain.Name, ID: chain.ChainID, Parser: parser})
+	}
+
 	// Initialize API handler
-	handler := api.NewHandler(parser, logger)
+	handler := api.NewMultiChainHandler(chains, logger)
+	if cfg.Auth {
+		authenticator := auth.NewAuthenticator(memoryStorage, logger)
+		if err := authenticator.Bootstrap(cfg.AdminAPIKey); err != nil {
+			logger.Fatalf("Failed to register ADMIN_API_KEY: %v", err)
+		}
+		handler.SetAuthenticator(authenticator)
+	}
+	handler.SetAuditLog(audit.NewLog(memoryStorage, logger))
+	handler.SetRateLimits(
+		ratelimit.Limit{Rate: cfg.RateLimit, Burst: cfg.RateLimitBurst},
+		ratelimit.Limit{Rate: cfg.ExpensiveRateLimit, Burst: cfg.ExpensiveRateLimitBurst},
+	)
 
 	// Set up HTTP server
 	mux := http.NewServeMux()
-	mux.HandleFunc("/health", handler.HealthCheckHandler)
-	mux.HandleFunc("/current-block", handler.GetCurrentBlockHandler)
-	mux.HandleFunc("/subscribe-list", handler.GetSubscribeListHandler)
-	mux.HandleFunc("/subscribe", handler.SubscribeHandler)
-	mux.HandleFunc("/unsubscribe", handler.UnsubscribeHandler)
-	mux.HandleFunc("/transactions", handler.GetTransactionsHandler)
+	handler.Register(mux)
 
 	server := &http.Server{
 		Addr:    cfg.ServerAddress,
 		Handler: mux,
 	}
 
-	// Start the background task
-	parser.Start()
+	// Start the background tasks
+	for _, parser := range parsers {
+		parser.Start()
+	}
 
 	// Start the server
 	go func() {
@@ -59,8 +135,10 @@ func main() {
 	<-quit
 	logger.Println("Shutting down server...")
 
-	// Stop the parser
-	parser.Stop()
+	// Stop the parsers
+	for _, parser := range parsers {
+		parser.Stop()
+	}
 
 	// Shutdown the server
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
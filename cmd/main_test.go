@@ -10,7 +10,6 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
-	"strings"
 	"testing"
 )
 
@@ -87,7 +86,6 @@ func TestMainIntegration(t *testing.T) {
 			}
 
 			for _, addr := range expectedAddresses {
-				addr = strings.ToLower(addr)
 				found := false
 				for _, subAddr := range result["subscribedAddresses"] {
 					if addr == subAddr {
@@ -1,10 +1,18 @@
 package common
 
 const (
-	CloudFlareRpcUrl           = "https://cloudflare-eth.com"
-	JsonRpcVersion             = "2.0"
-	HeaderContentTypeKey       = "Content-Type"
-	ApplicationJsonContentType = "application/json"
-	EthBlockNumber             = "eth_blockNumber"
-	EthGetBlockByNumber        = "eth_getBlockByNumber"
+	CloudFlareRpcUrl                = "https://cloudflare-eth.com"
+	JsonRpcVersion                  = "2.0"
+	HeaderContentTypeKey            = "Content-Type"
+	ApplicationJsonContentType      = "application/json"
+	TextCsvContentType              = "text/csv"
+	ApplicationJsonLinesContentType = "application/jsonl"
+	EthBlockNumber                  = "eth_blockNumber"
+	EthGetBlockByNumber             = "eth_getBlockByNumber"
+	EthChainId                      = "eth_chainId"
+	EthGetTransactionReceipt        = "eth_getTransactionReceipt"
+	EthGetLogs                      = "eth_getLogs"
+	EthCall                         = "eth_call"
+	EthGetBalance                   = "eth_getBalance"
+	DebugTraceBlockByNumber         = "debug_traceBlockByNumber"
 )
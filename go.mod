@@ -1 +1,3 @@
 module eth-parser
+
+go 1.27
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"eth-parser/pkg/models"
+	"net/http"
+)
+
+const errCodeInvalidABI = "invalid_abi"
+
+// RegisterABIHandler stores the ABI of a contract so that calls to it are
+// returned with their decoded input.
+type registerABIRequest struct {
+	Address string          `json:"address"`
+	ABI     json.RawMessage `json:"abi"`
+}
+
+func (h *Handler) RegisterABIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Printf("Register ABI: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Register ABI")
+	if !ok {
+		return
+	}
+
+	var req registerABIRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Printf("Register ABI: Error decoding request: %v", err)
+		h.fail(w, r, http.StatusBadRequest, "Bad request")
+		return
+	}
+
+	address, err := models.ParseAddress(req.Address)
+	if err != nil {
+		h.logger.Printf("Register ABI: Invalid address %q: %v", req.Address, err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
+		return
+	}
+	if err := parser.RegisterABI(address.String(), req.ABI); err != nil {
+		h.logger.Printf("Register ABI: Invalid ABI for %s: %v", address, err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidABI, err.Error())
+		return
+	}
+	h.auditChain(r, models.AuditRegisterABI, address.Checksum(), nil)
+
+	response := changeResponse(r, "address", address.Checksum(), "registered", true)
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
+		h.logger.Printf("Register ABI: Error encoding response: %v", err)
+		return
+	}
+	h.logger.Printf("Register ABI: Address %s", address)
+}
+
+func (h *Handler) GetABIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get ABI: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Get ABI")
+	if !ok {
+		return
+	}
+
+	rawAddress := r.URL.Query().Get("address")
+	address, err := models.ParseAddress(rawAddress)
+	if err != nil {
+		h.logger.Printf("Get ABI: Invalid address %q: %v", rawAddress, err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
+		return
+	}
+	abiJSON, ok := parser.GetABI(address.String())
+	if !ok {
+		h.logger.Printf("Get ABI: No ABI registered for %s", address)
+		h.fail(w, r, http.StatusNotFound, "No ABI registered for address")
+		return
+	}
+
+	response := map[string]interface{}{"address": address.Checksum(), "abi": json.RawMessage(abiJSON)}
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
+		h.logger.Printf("Get ABI: Error encoding response: %v", err)
+		return
+	}
+}
+
+func (h *Handler) UnregisterABIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Printf("Unregister ABI: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Unregister ABI")
+	if !ok {
+		return
+	}
+
+	var req addressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Printf("Unregister ABI: Error decoding request: %v", err)
+		h.fail(w, r, http.StatusBadRequest, "Bad request")
+		return
+	}
+
+	address, err := models.ParseAddress(req.Address)
+	if err != nil {
+		h.logger.Printf("Unregister ABI: Invalid address %q: %v", req.Address, err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
+		return
+	}
+
+	success := parser.UnregisterABI(address.String())
+	if success {
+		h.auditChain(r, models.AuditUnregisterABI, address.Checksum(), nil)
+	}
+	response := changeResponse(r, "address", address.Checksum(), "unregistered", success)
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
+		h.logger.Printf("Unregister ABI: Error encoding response: %v", err)
+		return
+	}
+	h.logger.Printf("Unregister ABI: Address %s, Success: %v", address, success)
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"eth-parser/common"
+	"eth-parser/internal/audit"
+	"eth-parser/pkg/models"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequestIDHeader carries the ID of a request. A client may set it to
+// correlate its own logs; otherwise one is generated. Either way it is
+// returned in the response.
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+type requestIDContextKey struct{}
+
+// withRequestID returns r carrying its request ID and sets the ID on the
+// response. A request that already has one keeps it.
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	if requestID(r) != "" {
+		return r
+	}
+	id := r.Header.Get(RequestIDHeader)
+	if id == "" || len(id) > maxRequestIDLength || strings.ContainsFunc(id, func(c rune) bool { return c <= ' ' || c > '~' }) {
+		var random [16]byte
+		rand.Read(random[:])
+		id = hex.EncodeToString(random[:])
+	}
+	w.Header().Set(RequestIDHeader, id)
+	return r.WithContext(context.WithValue(r.Context(), requestIDContextKey{}, id))
+}
+
+// requestID returns the ID of a request that went through Require or V1.
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// remoteHost returns the address a request came from, without its port.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// SetAuditLog makes the handlers record every change they make in log.
+func (h *Handler) SetAuditLog(log *audit.Log) {
+	h.auditLog = log
+}
+
+// audit records a change made by r, attributed to its API key or, without
+// one, to anonymous.
+func (h *Handler) audit(r *http.Request, record models.AuditRecord) {
+	if h.auditLog == nil {
+		return
+	}
+	record.Actor = "anonymous"
+	if key, ok := requestKey(r); ok {
+		record.Actor, record.ActorName = key.ID, key.Name
+	}
+	record.IP = remoteHost(r)
+	record.RequestID = requestID(r)
+	h.auditLog.Record(record)
+}
+
+// auditChain records a change made by r to its chain, and to its tenant's
+// subscriptions.
+func (h *Handler) auditChain(r *http.Request, action models.AuditAction, target string, details map[string]string) {
+	record := models.AuditRecord{Action: action, Target: target, Details: details}
+	if chain, err := h.resolveChain(r); err == nil {
+		record.ChainID = chain.ID
+	}
+	record.Tenant, _ = resolveTenant(r)
+	h.audit(r, record)
+}
+
+// subscriptionDetails returns the metadata of sub worth recording.
+func subscriptionDetails(sub models.Subscription) map[string]string {
+	details := map[string]string{}
+	for name, value := range map[string]string{
+		"label":     sub.Label,
+		"tags":      strings.Join(sub.Tags, " "),
+		"ownerTeam": sub.OwnerTeam,
+		"notes":     sub.Notes,
+	} {
+		if value != "" {
+			details[name] = value
+		}
+	}
+	if sub.StartBlock != 0 {
+		details["startBlock"] = strconv.FormatInt(sub.StartBlock, 10)
+	}
+	return details
+}
+
+// auditEnabled writes an error response when there is no audit log.
+func (h *Handler) auditEnabled(w http.ResponseWriter, r *http.Request, op string) bool {
+	if h.auditLog == nil {
+		h.logger.Printf("%s: Audit log is disabled", op)
+		h.writeError(w, r, http.StatusConflict, errCodeAuditDisabled, "audit log is disabled")
+		return false
+	}
+	return true
+}
+
+const (
+	errCodeAuditDisabled = "audit_disabled"
+	errCodeInvalidQuery  = "invalid_query"
+)
+
+// GetAuditRecordsHandler lists audit records, optionally only those of the
+// actor, action, target, tenant and chain query parameters, made between
+// since and until (RFC 3339). With limit, only the newest records are
+// returned.
+func (h *Handler) GetAuditRecordsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get audit records: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.auditEnabled(w, r, "Get audit records") {
+		return
+	}
+
+	query := r.URL.Query()
+	var since, until time.Time
+	var limit int
+	var err error
+	if raw := query.Get("since"); raw != "" {
+		since, err = time.Parse(time.RFC3339, raw)
+	}
+	if raw := query.Get("until"); raw != "" && err == nil {
+		until, err = time.Parse(time.RFC3339, raw)
+	}
+	if raw := query.Get("limit"); raw != "" && err == nil {
+		if limit, err = strconv.Atoi(raw); err == nil && limit <= 0 {
+			err = errors.New("limit must be positive")
+		}
+	}
+	if err != nil {
+		h.logger.Printf("Get audit records: Invalid query: %v", err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidQuery, err.Error())
+		return
+	}
+	actor, action, tenant, chain := query.Get("actor"), models.AuditAction(query.Get("action")), query.Get("tenant"), query.Get("chain")
+	target := query.Get("target")
+
+	records := []models.AuditRecord{}
+	for _, record := range h.auditLog.Records(0) {
+		if actor != "" && record.Actor != actor ||
+			action != "" && record.Action != action ||
+			target != "" && !strings.EqualFold(record.Target, target) ||
+			tenant != "" && record.Tenant != tenant ||
+			chain != "" && strconv.FormatUint(record.ChainID, 10) != chain ||
+			!since.IsZero() && record.Time.Before(since) ||
+			!until.IsZero() && !record.Time.Before(until) {
+			continue
+		}
+		records = append(records, record)
+	}
+	if limit > 0 && len(records) > limit {
+		records = records[len(records)-limit:]
+	}
+	if err := h.writeJSON(w, r, http.StatusOK, map[string][]models.AuditRecord{"records": records}); err != nil {
+		h.logger.Printf("Get audit records: Error encoding response: %v", err)
+		return
+	}
+}
+
+// ExportAuditRecordsHandler writes the audit trail as JSON Lines, one record
+// per line, from the record after sequence number after on. An export from
+// the start can be verified on its own; a later one links to the hash of
+// the record before it.
+func (h *Handler) ExportAuditRecordsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Export audit records: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.auditEnabled(w, r, "Export audit records") {
+		return
+	}
+
+	var after int64
+	if raw := r.URL.Query().Get("after"); raw != "" {
+		var err error
+		if after, err = strconv.ParseInt(raw, 10, 64); err != nil || after < 0 {
+			h.logger.Printf("Export audit records: Invalid sequence number %q", raw)
+			h.writeError(w, r, http.StatusBadRequest, errCodeInvalidQuery, "after must be a sequence number")
+			return
+		}
+	}
+	records := h.auditLog.Records(after)
+	w.Header().Set(common.HeaderContentTypeKey, common.ApplicationJsonLinesContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.jsonl"`)
+	encoder := json.NewEncoder(w)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			h.logger.Printf("Export audit records: Error writing record %d: %v", record.Sequence, err)
+			return
+		}
+	}
+	h.logger.Printf("Export audit records: Returned %d records", len(records))
+}
+
+type auditVerification struct {
+	Valid    bool   `json:"valid"`
+	Records  int64  `json:"records"`
+	HeadHash string `json:"headHash,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// VerifyAuditLogHandler checks the hash chain of the whole audit trail.
+func (h *Handler) VerifyAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Verify audit log: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.auditEnabled(w, r, "Verify audit log") {
+		return
+	}
+
+	response := auditVerification{Valid: true}
+	head, err := h.auditLog.Verify()
+	if err != nil {
+		h.logger.Printf("Verify audit log: %v", err)
+		response = auditVerification{Error: err.Error()}
+	} else {
+		response.Records, response.HeadHash = head.Sequence, head.Hash
+	}
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
+		h.logger.Printf("Verify audit log: Error encoding response: %v", err)
+		return
+	}
+}
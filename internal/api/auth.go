@@ -0,0 +1,243 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"eth-parser/internal/auth"
+	"eth-parser/pkg/models"
+	"net/http"
+	"strings"
+)
+
+const (
+	errCodeUnauthorized = "unauthorized"
+	errCodeForbidden    = "forbidden"
+	errCodeAuthDisabled = "auth_disabled"
+	errCodeInvalidKey   = "invalid_key"
+	errCodeUnknownKey   = "unknown_key"
+)
+
+// APIKeyHeader carries the API key of clients that do not send it as a
+// bearer token.
+const APIKeyHeader = "X-API-Key"
+
+type apiKeyContextKey struct{}
+
+// requestKey returns the API key a request was authenticated with.
+func requestKey(r *http.Request) (models.APIKey, bool) {
+	key, ok := r.Context().Value(apiKeyContextKey{}).(models.APIKey)
+	return key, ok
+}
+
+// SetAuthenticator turns on API key authentication for the handlers wrapped
+// with Require. Without it every request is let through.
+func (h *Handler) SetAuthenticator(a *auth.Authenticator) {
+	h.auth = a
+}
+
+// statusRecorder remembers the status written through it for the audit log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Require wraps next so that it is only served to requests carrying an API
+// key whose role includes role, within the rate limit of the key. Every
+// authenticated request is logged with the key that made it. Requests
+// without a valid key are limited by client address, as are all requests
+// without an authenticator.
+func (h *Handler) Require(role models.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r = withRequestID(w, r)
+		if h.auth == nil {
+			if h.allow(w, r, h.limiter) {
+				next(w, r)
+			}
+			return
+		}
+		key, err := h.auth.Authenticate(apiKeyOf(r))
+		if err != nil {
+			// Only failed authentications spend from the address, so
+			// that keys behind one proxy keep their own limits.
+			if !h.allow(w, r, h.limiter) {
+				return
+			}
+			h.logger.Printf("Auth: %s %s from %s: %v", r.Method, r.URL.Path, r.RemoteAddr, err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="eth-parser"`)
+			h.writeError(w, r, http.StatusUnauthorized, errCodeUnauthorized, err.Error())
+			return
+		}
+		if !key.Role.Includes(role) {
+			h.logger.Printf("Auth: %s %s by key %s (%s): role %s, %s required", r.Method, r.URL.Path, key.ID, key.Name, key.Role, role)
+			h.writeError(w, r, http.StatusForbidden, errCodeForbidden, "this endpoint requires the "+string(role)+" role")
+			return
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key))
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		if h.allow(recorder, r, h.limiter) {
+			next(recorder, r)
+		}
+		h.logger.Printf("Audit: %s %s by key %s (%s), role: %s, tenant: %s, from %s, request %s: %d",
+			r.Method, r.URL.RequestURI(), key.ID, key.Name, key.Role, key.Tenant, r.RemoteAddr, requestID(r), recorder.status)
+	}
+}
+
+// apiKeyOf returns the bearer token of a request, or else its X-API-Key
+// header.
+func apiKeyOf(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return r.Header.Get(APIKeyHeader)
+}
+
+// authEnabled writes an error response when API keys are not in use.
+func (h *Handler) authEnabled(w http.ResponseWriter, r *http.Request, op string) bool {
+	if h.auth == nil {
+		h.logger.Printf("%s: Authentication is disabled", op)
+		h.writeError(w, r, http.StatusConflict, errCodeAuthDisabled, "API key authentication is disabled")
+		return false
+	}
+	return true
+}
+
+// GetAPIKeysHandler lists the keys of the admin's tenant, or every key for
+// global admins.
+func (h *Handler) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get API keys: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.authEnabled(w, r, "Get API keys") {
+		return
+	}
+
+	keys := h.auth.GetKeys()
+	if tenant, ok := keyTenant(r); ok {
+		keys = keys[:0]
+		for _, key := range h.auth.GetKeys() {
+			if key.Tenant == tenant {
+				keys = append(keys, key)
+			}
+		}
+	}
+	if err := h.writeJSON(w, r, http.StatusOK, map[string][]models.APIKey{"keys": keys}); err != nil {
+		h.logger.Printf("Get API keys: Error encoding response: %v", err)
+		return
+	}
+}
+
+// createdKey is an API key with its secret, which is only ever returned
+// here.
+type createKeyRequest struct {
+	Name   string      `json:"name"`
+	Role   models.Role `json:"role"`
+	Tenant string      `json:"tenant"`
+}
+
+type createdKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKeyHandler issues a key. Admins bound to a tenant issue keys for
+// their tenant only, and no global admin keys.
+func (h *Handler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Printf("Create API key: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.authEnabled(w, r, "Create API key") {
+		return
+	}
+
+	var req createKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Printf("Create API key: Error decoding request: %v", err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidKey, err.Error())
+		return
+	}
+	if tenant, ok := keyTenant(r); ok {
+		if req.Tenant == "" {
+			req.Tenant = tenant
+		}
+		if req.Tenant != tenant || req.Role.Includes(models.RoleGlobalAdmin) {
+			h.logger.Printf("Create API key: %s key for tenant %s requested by an admin of tenant %s", req.Role, req.Tenant, tenant)
+			h.writeError(w, r, http.StatusForbidden, errCodeForbidden, "admins may only create keys for their own tenant, and no global admin keys")
+			return
+		}
+	}
+	key, secret, err := h.auth.CreateKey(req.Name, req.Role, req.Tenant)
+	switch {
+	case errors.Is(err, models.ErrInvalidRole), errors.Is(err, models.ErrInvalidTenant):
+		h.logger.Printf("Create API key: %v", err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidKey, err.Error())
+		return
+	case err != nil:
+		h.logger.Printf("Create API key: %v", err)
+		h.fail(w, r, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	h.audit(r, models.AuditRecord{Action: models.AuditCreateKey, Target: key.ID, Details: map[string]string{"name": key.Name, "role": string(key.Role), "tenant": key.Tenant}})
+
+	if err := h.writeJSON(w, r, createdStatus(r), createdKey{APIKey: key, Key: secret}); err != nil {
+		h.logger.Printf("Create API key: Error encoding response: %v", err)
+		return
+	}
+}
+
+// RevokeAPIKeyHandler revokes a key. Admins bound to a tenant revoke keys of
+// their tenant only, and no global admin keys.
+func (h *Handler) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Printf("Revoke API key: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.authEnabled(w, r, "Revoke API key") {
+		return
+	}
+
+	var req idRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Printf("Revoke API key: Error decoding request: %v", err)
+		h.fail(w, r, http.StatusBadRequest, "Bad request")
+		return
+	}
+	if tenant, ok := keyTenant(r); ok {
+		// Keys of other tenants are reported as unknown, so that their
+		// IDs cannot be probed.
+		key, found := h.auth.GetKey(req.ID)
+		if !found || key.Tenant != tenant {
+			h.logger.Printf("Revoke API key: Key %s is not one of tenant %s", req.ID, tenant)
+			h.writeError(w, r, http.StatusNotFound, errCodeUnknownKey, auth.ErrUnknownKey.Error()+": "+req.ID)
+			return
+		}
+		if key.Role.Includes(models.RoleGlobalAdmin) {
+			h.logger.Printf("Revoke API key: An admin of tenant %s may not revoke global admin key %s", tenant, req.ID)
+			h.writeError(w, r, http.StatusForbidden, errCodeForbidden, "only global admins may revoke global admin keys")
+			return
+		}
+	}
+	key, err := h.auth.RevokeKey(req.ID)
+	if err != nil {
+		h.logger.Printf("Revoke API key: %v", err)
+		h.writeError(w, r, http.StatusNotFound, errCodeUnknownKey, err.Error())
+		return
+	}
+	h.audit(r, models.AuditRecord{Action: models.AuditRevokeKey, Target: key.ID, Details: map[string]string{"name": key.Name}})
+
+	if err := h.writeJSON(w, r, http.StatusOK, key); err != nil {
+		h.logger.Printf("Revoke API key: Error encoding response: %v", err)
+		return
+	}
+}
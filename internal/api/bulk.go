@@ -0,0 +1,357 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"eth-parser/common"
+	"eth-parser/pkg/models"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	errCodeInvalidBulkRequest = "invalid_bulk_request"
+	errCodeInvalidRows        = "invalid_rows"
+)
+
+const (
+	maxBulkRows      = 10000
+	maxBulkBodyBytes = 16 << 20
+)
+
+// csvColumns are the columns of exported subscriptions. Imports accept any
+// subset in any order as long as address is present; createdAt is ignored.
+var csvColumns = []string{"address", "label", "tags", "ownerTeam", "notes", "startBlock", "createdAt"}
+
+// bulkRow is one row of a bulk request. err is set for rows that could not
+// be read, which are reported as invalid without reaching the parser.
+type bulkRow struct {
+	sub models.Subscription
+	err error
+}
+
+// bulkResponse reports a bulk request row by row. Applied is false when an
+// atomic request was rejected, in which case Error and Message say why.
+type bulkResponse struct {
+	Error   string                    `json:"error,omitempty"`
+	Message string                    `json:"message,omitempty"`
+	Atomic  bool                      `json:"atomic"`
+	Applied bool                      `json:"applied"`
+	Summary map[models.BulkStatus]int `json:"summary"`
+	Results []models.BulkResult       `json:"results"`
+}
+
+// BulkSubscribeHandler subscribes the rows of a JSON array or a CSV upload,
+// with atomic=true all or nothing.
+func (h *Handler) BulkSubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Printf("Bulk subscribe: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Bulk subscribe")
+	if !ok {
+		return
+	}
+
+	rows, err := readBulkRows(w, r)
+	if err != nil {
+		h.logger.Printf("Bulk subscribe: Invalid request: %v", err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidBulkRequest, err.Error())
+		return
+	}
+	atomic := r.URL.Query().Get("atomic") == "true"
+	results := applyBulkRows(rows, atomic, func(rows []bulkRow) []models.BulkResult {
+		subs := make([]models.Subscription, len(rows))
+		for i, row := range rows {
+			subs[i] = row.sub
+		}
+		return parser.SubscribeBulk(subs, atomic)
+	})
+	for i, result := range results {
+		if result.Status == models.BulkAdded {
+			details := subscriptionDetails(rows[i].sub)
+			details["bulk"] = "true"
+			h.auditChain(r, models.AuditSubscribe, result.Address, details)
+		}
+	}
+	h.writeBulkResponse(w, r, "Bulk subscribe", atomic, results)
+}
+
+// BulkUnsubscribeHandler unsubscribes the addresses of a JSON array or a
+// CSV upload, with atomic=true all or nothing.
+func (h *Handler) BulkUnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Printf("Bulk unsubscribe: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Bulk unsubscribe")
+	if !ok {
+		return
+	}
+
+	rows, err := readBulkRows(w, r)
+	if err != nil {
+		h.logger.Printf("Bulk unsubscribe: Invalid request: %v", err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidBulkRequest, err.Error())
+		return
+	}
+	atomic := r.URL.Query().Get("atomic") == "true"
+	results := applyBulkRows(rows, atomic, func(rows []bulkRow) []models.BulkResult {
+		addresses := make([]string, len(rows))
+		for i, row := range rows {
+			addresses[i] = row.sub.Address
+		}
+		return parser.UnsubscribeBulk(addresses, atomic)
+	})
+	for _, result := range results {
+		if result.Status == models.BulkRemoved {
+			h.auditChain(r, models.AuditUnsubscribe, result.Address, map[string]string{"bulk": "true"})
+		}
+	}
+	h.writeBulkResponse(w, r, "Bulk unsubscribe", atomic, results)
+}
+
+// applyBulkRows passes the readable rows to apply and merges its results
+// with those of the unreadable ones. An atomic request with unreadable rows
+// is not applied at all.
+func applyBulkRows(rows []bulkRow, atomic bool, apply func([]bulkRow) []models.BulkResult) []models.BulkResult {
+	results := make([]models.BulkResult, len(rows))
+	var readable []bulkRow
+	var index []int
+	for i, row := range rows {
+		if row.err != nil {
+			results[i] = models.BulkResult{Row: i + 1, Address: row.sub.Address, Status: models.BulkInvalid, Error: row.err.Error()}
+			continue
+		}
+		readable = append(readable, row)
+		index = append(index, i)
+	}
+	if atomic && len(readable) < len(rows) {
+		for _, i := range index {
+			results[i] = models.BulkResult{Row: i + 1, Address: rows[i].sub.Address, Status: models.BulkSkipped}
+		}
+		return results
+	}
+	if len(readable) == 0 {
+		return results
+	}
+	for j, result := range apply(readable) {
+		result.Row = index[j] + 1
+		results[index[j]] = result
+	}
+	return results
+}
+
+func (h *Handler) writeBulkResponse(w http.ResponseWriter, r *http.Request, op string, atomic bool, results []models.BulkResult) {
+	response := bulkResponse{
+		Atomic:  atomic,
+		Applied: true,
+		Summary: make(map[models.BulkStatus]int),
+		Results: results,
+	}
+	for _, result := range results {
+		response.Summary[result.Status]++
+	}
+	status := http.StatusOK
+	if atomic && response.Summary[models.BulkInvalid] > 0 {
+		status = http.StatusBadRequest
+		response.Applied = false
+		response.Error = errCodeInvalidRows
+		response.Message = fmt.Sprintf("%d invalid rows, nothing applied", response.Summary[models.BulkInvalid])
+	}
+	if status != http.StatusOK && isV1(r) {
+		// On /v1 the rejected request is an error like any other, with the
+		// rows in its details.
+		message := response.Message
+		response.Error, response.Message = "", ""
+		h.logger.Printf("%s: %d rows, %s", op, len(results), message)
+		h.writeErrorDetails(w, r, status, errCodeInvalidRows, message, response)
+		return
+	}
+
+	if err := h.writeJSON(w, r, status, response); err != nil {
+		h.logger.Printf("%s: Error encoding response: %v", op, err)
+		return
+	}
+	h.logger.Printf("%s: %d rows, applied: %v, summary: %v", op, len(results), response.Applied, response.Summary)
+}
+
+// readBulkRows reads a CSV upload when the content type is text/csv and a
+// JSON array otherwise. Errors are about the request as a whole; problems
+// with single rows are left to the row.
+func readBulkRows(w http.ResponseWriter, r *http.Request) ([]bulkRow, error) {
+	body := http.MaxBytesReader(w, r.Body, maxBulkBodyBytes)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get(common.HeaderContentTypeKey))
+	var rows []bulkRow
+	var err error
+	if mediaType == common.TextCsvContentType {
+		rows, err = readCSVRows(body)
+	} else {
+		rows, err = readJSONRows(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("no rows")
+	}
+	if len(rows) > maxBulkRows {
+		return nil, fmt.Errorf("%d rows, at most %d per request", len(rows), maxBulkRows)
+	}
+	return rows, nil
+}
+
+// readJSONRows reads an array whose elements are subscriptions, as accepted
+// by /subscribe, or bare addresses.
+func readJSONRows(body io.Reader) ([]bulkRow, error) {
+	var items []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&items); err != nil {
+		return nil, fmt.Errorf("expected a JSON array: %w", err)
+	}
+	rows := make([]bulkRow, len(items))
+	for i, item := range items {
+		if len(item) > 0 && item[0] == '"' {
+			rows[i].err = json.Unmarshal(item, &rows[i].sub.Address)
+		} else {
+			rows[i].err = json.Unmarshal(item, &rows[i].sub)
+		}
+	}
+	return rows, nil
+}
+
+// readCSVRows reads a CSV file with a header row naming its columns, see
+// csvColumns. Tags are separated by spaces.
+func readCSVRows(body io.Reader) ([]bulkRow, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("no rows")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		known := false
+		for _, column := range csvColumns {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				columns[column], known = i, true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown column %q, expected %s", name, strings.Join(csvColumns, ", "))
+		}
+	}
+	if _, ok := columns["address"]; !ok {
+		return nil, errors.New("missing address column")
+	}
+
+	var rows []bulkRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row := bulkRow{sub: models.Subscription{
+			Address:   field("address"),
+			Label:     field("label"),
+			Tags:      strings.Fields(field("tags")),
+			OwnerTeam: field("ownerTeam"),
+			Notes:     field("notes"),
+		}}
+		if raw := field("startBlock"); raw != "" {
+			if row.sub.StartBlock, err = strconv.ParseInt(raw, 10, 64); err != nil {
+				row.err = fmt.Errorf("start block %q is not a block number", raw)
+			}
+		}
+		if len(record) > len(header) {
+			row.err = fmt.Errorf("%d fields, the header has %d", len(record), len(header))
+		}
+		rows = append(rows, row)
+	}
+}
+
+// ExportSubscriptionsHandler writes the subscription set as a JSON array or,
+// with format=csv, as CSV. Either can be posted back to the bulk endpoints.
+func (h *Handler) ExportSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Export subscriptions: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Export subscriptions")
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		h.logger.Printf("Export subscriptions: Unknown format %s", format)
+		h.fail(w, r, http.StatusBadRequest, "Unknown format, expected json or csv")
+		return
+	}
+	tag := r.URL.Query().Get("tag")
+	subs := []models.Subscription{}
+	for _, sub := range parser.GetSubscriptions() {
+		if tag == "" || sub.HasTag(tag) {
+			sub.Address = checksum(sub.Address)
+			subs = append(subs, sub)
+		}
+	}
+
+	// Exports are files meant to be posted back, so they are never wrapped
+	// in the /v1 envelope.
+	if format != "csv" {
+		w.Header().Set(common.HeaderContentTypeKey, common.ApplicationJsonContentType)
+		if err := json.NewEncoder(w).Encode(subs); err != nil {
+			h.logger.Printf("Export subscriptions: Error encoding response: %v", err)
+			h.fail(w, r, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+		h.logger.Printf("Export subscriptions: Returned %d subscriptions", len(subs))
+		return
+	}
+
+	w.Header().Set(common.HeaderContentTypeKey, common.TextCsvContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="subscriptions.csv"`)
+	writer := csv.NewWriter(w)
+	records := [][]string{csvColumns}
+	for _, sub := range subs {
+		records = append(records, []string{
+			sub.Address,
+			sub.Label,
+			strings.Join(sub.Tags, " "),
+			sub.OwnerTeam,
+			sub.Notes,
+			strconv.FormatInt(sub.StartBlock, 10),
+			sub.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	if err := writer.WriteAll(records); err != nil {
+		h.logger.Printf("Export subscriptions: Error writing CSV: %v", err)
+		return
+	}
+	h.logger.Printf("Export subscriptions: Returned %d subscriptions as CSV", len(subs))
+}
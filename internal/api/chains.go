@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"eth-parser/internal/ethereum"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const errCodeUnknownChain = "unknown_chain"
+
+// Chain is the parser of one watched chain.
+type Chain struct {
+	Name   string
+	ID     uint64
+	Parser ethereum.Parser
+}
+
+// NewMultiChainHandler serves one parser per chain. Requests select a chain
+// with the chain query parameter, by name or chain ID; the first chain is
+// used when it is absent.
+func NewMultiChainHandler(chains []Chain, logger *log.Logger) *Handler {
+	return &Handler{chains: chains, logger: logger}
+}
+
+// resolveChain finds the chain named by the chain query parameter.
+func (h *Handler) resolveChain(r *http.Request) (Chain, error) {
+	param := r.URL.Query().Get("chain")
+	if param == "" {
+		return h.chains[0], nil
+	}
+	id, idErr := strconv.ParseUint(param, 10, 64)
+	for _, chain := range h.chains {
+		if strings.EqualFold(chain.Name, param) || (idErr == nil && chain.ID == id) {
+			return chain, nil
+		}
+	}
+	return Chain{}, fmt.Errorf("unknown chain %q", param)
+}
+
+// chainParser resolves the requested chain and returns its parser scoped to
+// the requesting tenant, writing an error response when there is no such
+// chain or the tenant is invalid.
+func (h *Handler) chainParser(w http.ResponseWriter, r *http.Request, operation string) (ethereum.Parser, bool) {
+	chain, err := h.resolveChain(r)
+	if err != nil {
+		h.logger.Printf("%s: %v", operation, err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeUnknownChain, err.Error())
+		return nil, false
+	}
+	tenant, err := resolveTenant(r)
+	if errors.Is(err, errTenantForbidden) {
+		h.logger.Printf("%s: %v", operation, err)
+		h.writeError(w, r, http.StatusForbidden, errCodeForbidden, err.Error())
+		return nil, false
+	}
+	if err != nil {
+		h.logger.Printf("%s: %v", operation, err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidTenant, err.Error())
+		return nil, false
+	}
+	return chain.Parser.ForTenant(tenant), true
+}
+
+type chainResponse struct {
+	Name         string `json:"name"`
+	ChainID      uint64 `json:"chainId"`
+	CurrentBlock int64  `json:"currentBlock"`
+}
+
+func (h *Handler) GetChainsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get chains: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	chains := make([]chainResponse, 0, len(h.chains))
+	for _, chain := range h.chains {
+		chains = append(chains, chainResponse{
+			Name:         chain.Name,
+			ChainID:      chain.ID,
+			CurrentBlock: chain.Parser.GetCurrentBlock(),
+		})
+	}
+	if err := h.writeJSON(w, r, http.StatusOK, map[string][]chainResponse{"chains": chains}); err != nil {
+		h.logger.Printf("Get chains: Error encoding response: %v", err)
+		return
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"eth-parser/common"
+	"net/http"
+	"strings"
+)
+
+const (
+	errCodeInvalidAddress      = "invalid_address"
+	errCodeInvalidSubscription = "invalid_subscription"
+	errCodeNotSubscribed       = "not_subscribed"
+	errCodeNotFound            = "not_found"
+)
+
+// errorResponse is the JSON body written for rejected requests on the
+// legacy routes.
+type errorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// errorEnvelope is the body of every /v1 error.
+type errorEnvelope struct {
+	Error apiError `json:"error"`
+}
+
+// apiError describes a rejected /v1 request. Code is stable and meant for
+// programs, Message for people; Details carries whatever else the error has
+// to say, such as the rows of a rejected bulk request.
+type apiError struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"requestId,omitempty"`
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	h.writeErrorDetails(w, r, status, code, message, nil)
+}
+
+// writeErrorDetails writes an error response. The details only show in the
+// /v1 envelope; the legacy body has no room for them.
+func (h *Handler) writeErrorDetails(w http.ResponseWriter, r *http.Request, status int, code, message string, details interface{}) {
+	var body interface{} = errorResponse{Error: code, Message: message}
+	if isV1(r) {
+		body = errorEnvelope{Error: apiError{Code: code, Message: message, Details: details, RequestID: requestID(r)}}
+	}
+	w.Header().Set(common.HeaderContentTypeKey, common.ApplicationJsonContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.logger.Printf("Error encoding error response: %v", err)
+	}
+}
+
+// fail rejects a request that has no more specific error code. The legacy
+// routes keep their plain-text bodies; /v1 answers with the envelope and a
+// code named after the status, such as bad_request.
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if !isV1(r) {
+		http.Error(w, message, status)
+		return
+	}
+	h.writeError(w, r, status, statusCode(status), message)
+}
+
+// statusCode turns an HTTP status into an error code: 405 becomes
+// method_not_allowed.
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
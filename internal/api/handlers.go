@@ -2,34 +2,45 @@ package api
 
 import (
 	"encoding/json"
-	"eth-parser/common"
+	"errors"
+	"eth-parser/internal/audit"
+	"eth-parser/internal/auth"
 	"eth-parser/internal/ethereum"
+	"eth-parser/internal/ratelimit"
+	"eth-parser/pkg/models"
 	"log"
 	"net/http"
 	"strings"
 )
 
+// UndecodableHeader lists, on the legacy /transactions route, the hashes of
+// the transactions left out of its decimal form.
+const UndecodableHeader = "X-Undecodable-Transactions"
+
 type Handler struct {
-	parser ethereum.Parser
-	logger *log.Logger
+	chains           []Chain
+	auth             *auth.Authenticator
+	limiter          *ratelimit.Limiter
+	expensiveLimiter *ratelimit.Limiter
+	auditLog         *audit.Log
+	logger           *log.Logger
 }
 
+// NewHandler serves a single Ethereum mainnet parser.
 func NewHandler(parser ethereum.Parser, logger *log.Logger) *Handler {
-	return &Handler{parser: parser, logger: logger}
+	return NewMultiChainHandler([]Chain{{Name: "ethereum", ID: 1, Parser: parser}}, logger)
 }
 
 func (h *Handler) HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		h.logger.Printf("Health check: Method not allowed: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
 	response := map[string]string{"status": "OK"}
-	w.Header().Set(common.HeaderContentTypeKey, common.ApplicationJsonContentType)
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
 		h.logger.Printf("Health check: Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 	h.logger.Println("Health check: OK")
@@ -38,15 +49,18 @@ func (h *Handler) HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) GetCurrentBlockHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		h.logger.Printf("Get current block: Method not allowed: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	currentBlock := h.parser.GetCurrentBlock()
-	err := json.NewEncoder(w).Encode(map[string]int64{"currentBlock": currentBlock})
-	if err != nil {
+	parser, ok := h.chainParser(w, r, "Get current block")
+	if !ok {
+		return
+	}
+
+	currentBlock := parser.GetCurrentBlock()
+	if err := h.writeJSON(w, r, http.StatusOK, map[string]int64{"currentBlock": currentBlock}); err != nil {
 		h.logger.Printf("Get current block: Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 }
@@ -54,97 +68,313 @@ func (h *Handler) GetCurrentBlockHandler(w http.ResponseWriter, r *http.Request)
 func (h *Handler) GetSubscribeListHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		h.logger.Printf("Get subscribe list: Method not allowed: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	list := h.parser.GetSubscribeList()
-	response := map[string][]string{"subscribedAddresses": list}
-	w.Header().Set(common.HeaderContentTypeKey, common.ApplicationJsonContentType)
+	parser, ok := h.chainParser(w, r, "Get subscribe list")
+	if !ok {
+		return
+	}
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	// The bare address list stays the default so that existing clients can
+	// keep decoding it; metadata=true adds the subscriptions themselves.
+	query := r.URL.Query()
+	tag := query.Get("tag")
+	subs := []models.Subscription{}
+	addresses := []string{}
+	for _, sub := range parser.GetSubscriptions() {
+		if tag != "" && !sub.HasTag(tag) {
+			continue
+		}
+		sub.Address = checksum(sub.Address)
+		subs = append(subs, sub)
+		addresses = append(addresses, sub.Address)
+	}
+	response := map[string]interface{}{"subscribedAddresses": addresses}
+	if query.Get("metadata") == "true" {
+		response["subscriptions"] = subs
+	}
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
 		h.logger.Printf("Get subscribe list: Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	h.logger.Printf("Get subscribe list: Returned %d addresses", len(list))
+	h.logger.Printf("Get subscribe list: Returned %d addresses", len(addresses))
 
 }
 
 func (h *Handler) SubscribeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		h.logger.Printf("Subscribe: Method not allowed: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	var req struct {
-		Address string `json:"address"`
+	parser, ok := h.chainParser(w, r, "Subscribe")
+	if !ok {
+		return
 	}
+
+	var req models.Subscription
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Printf("Subscribe: Error decoding request: %v", err)
-		http.Error(w, "Bad request", http.StatusBadRequest)
+		h.fail(w, r, http.StatusBadRequest, "Bad request")
 		return
 	}
 
-	address := strings.ToLower(req.Address)
-	success := h.parser.Subscribe(address)
-	w.Header().Set(common.HeaderContentTypeKey, common.ApplicationJsonContentType)
+	address, err := models.ParseAddress(req.Address)
+	if err != nil {
+		h.logger.Printf("Subscribe: Invalid address %q: %v", req.Address, err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
+		return
+	}
 
-	if err := json.NewEncoder(w).Encode(map[string]bool{"subscribed": success}); err != nil {
+	req.Address = address.String()
+	success, err := parser.AddSubscription(req)
+	if err != nil {
+		h.writeSubscriptionError(w, r, "Subscribe", err)
+		return
+	}
+	if success {
+		h.auditChain(r, models.AuditSubscribe, address.Checksum(), subscriptionDetails(req))
+	}
+	status := http.StatusOK
+	if success {
+		status = createdStatus(r)
+	}
+	response := changeResponse(r, "address", address.Checksum(), "subscribed", success)
+	if err := h.writeJSON(w, r, status, response); err != nil {
 		h.logger.Printf("Subscribe: Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 	h.logger.Printf("Subscribe: Address %s, Success: %v", address, success)
 }
 
+// UpdateSubscriptionHandler replaces the metadata of a subscribed address.
+func (h *Handler) UpdateSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Printf("Update subscription: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Update subscription")
+	if !ok {
+		return
+	}
+
+	var req models.Subscription
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Printf("Update subscription: Error decoding request: %v", err)
+		h.fail(w, r, http.StatusBadRequest, "Bad request")
+		return
+	}
+
+	address, err := models.ParseAddress(req.Address)
+	if err != nil {
+		h.logger.Printf("Update subscription: Invalid address %q: %v", req.Address, err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
+		return
+	}
+
+	req.Address = address.String()
+	sub, err := parser.UpdateSubscription(req)
+	if err != nil {
+		h.writeSubscriptionError(w, r, "Update subscription", err)
+		return
+	}
+	sub.Address = address.Checksum()
+	h.auditChain(r, models.AuditUpdateSubscription, sub.Address, subscriptionDetails(sub))
+	if err := h.writeJSON(w, r, http.StatusOK, sub); err != nil {
+		h.logger.Printf("Update subscription: Error encoding response: %v", err)
+		return
+	}
+	h.logger.Printf("Update subscription: Address %s", address)
+}
+
+func (h *Handler) writeSubscriptionError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	h.logger.Printf("%s: %v", op, err)
+	switch {
+	case errors.Is(err, models.ErrInvalidTag), errors.Is(err, ethereum.ErrStartBlockProcessed):
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidSubscription, err.Error())
+	case errors.Is(err, ethereum.ErrNotSubscribed):
+		h.writeError(w, r, http.StatusNotFound, errCodeNotSubscribed, err.Error())
+	case errors.Is(err, ethereum.ErrQuotaExceeded):
+		h.writeError(w, r, http.StatusForbidden, errCodeQuotaExceeded, err.Error())
+	default:
+		h.fail(w, r, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+// addressRequest is the body of the requests that name just an address.
+type addressRequest struct {
+	Address string `json:"address"`
+}
+
+// idRequest is the body of the requests that name just an ID.
+type idRequest struct {
+	ID string `json:"id"`
+}
+
 func (h *Handler) UnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		h.logger.Printf("Unsubscribe: Method not allowed: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	var req struct {
-		Address string `json:"address"`
+	parser, ok := h.chainParser(w, r, "Unsubscribe")
+	if !ok {
+		return
 	}
+
+	var req addressRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.logger.Printf("Unsubscribe: Error decoding request: %v", err)
-		http.Error(w, "Bad request", http.StatusBadRequest)
+		h.fail(w, r, http.StatusBadRequest, "Bad request")
+		return
+	}
+
+	address, err := models.ParseAddress(req.Address)
+	if err != nil {
+		h.logger.Printf("Unsubscribe: Invalid address %q: %v", req.Address, err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
 		return
 	}
 
-	w.Header().Set(common.HeaderContentTypeKey, common.ApplicationJsonContentType)
-	success := h.parser.Unsubscribe(req.Address)
-	if err := json.NewEncoder(w).Encode(map[string]bool{"unsubscribed": success}); err != nil {
+	success := parser.Unsubscribe(address.String())
+	if success {
+		h.auditChain(r, models.AuditUnsubscribe, address.Checksum(), nil)
+	}
+	response := changeResponse(r, "address", address.Checksum(), "unsubscribed", success)
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
 		h.logger.Printf("Unsubscribe: Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	h.logger.Printf("Unsubscribe: Address %s, Success: %v", req.Address, success)
+	h.logger.Printf("Unsubscribe: Address %s, Success: %v", address, success)
 }
 
 func (h *Handler) GetTransactionsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		h.logger.Printf("Get transactions: Method not allowed: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Get transactions")
+	if !ok {
 		return
 	}
 
-	address := r.URL.Query().Get("address")
-	if address == "" {
+	rawAddress := r.URL.Query().Get("address")
+	tag := r.URL.Query().Get("tag")
+	if rawAddress == "" && tag == "" {
 		h.logger.Println("Get transactions: No address provided")
-		http.Error(w, "No address provided", http.StatusBadRequest)
+		h.fail(w, r, http.StatusBadRequest, "No address provided")
+		return
+	}
+	if rawAddress != "" && tag != "" {
+		h.logger.Println("Get transactions: Both address and tag provided")
+		h.fail(w, r, http.StatusBadRequest, "Address and tag are mutually exclusive")
 		return
 	}
+	var address string
+	if rawAddress != "" {
+		parsed, err := models.ParseAddress(rawAddress)
+		if err != nil {
+			h.logger.Printf("Get transactions: Invalid address %q: %v", rawAddress, err)
+			h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
+			return
+		}
+		address = parsed.String()
+	}
 
-	w.Header().Set(common.HeaderContentTypeKey, common.ApplicationJsonContentType)
-	transactions := h.parser.GetTransactions(address)
-	if err := json.NewEncoder(w).Encode(transactions); err != nil {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "hex" && format != "decimal" {
+		h.logger.Printf("Get transactions: Unknown format %s", format)
+		h.fail(w, r, http.StatusBadRequest, "Unknown format, expected hex or decimal")
+		return
+	}
+
+	var transactions []models.Transaction
+	if tag != "" {
+		transactions = parser.GetTransactionsByTag(tag)
+	} else {
+		transactions = parser.GetTransactions(address)
+	}
+	var response interface{} = transactions
+	// Transactions the node returned with fields that do not parse are left
+	// out of the decimal form rather than failing the whole list, and named
+	// in the response.
+	var undecodable []string
+	if format == "decimal" {
+		decoded := make([]models.DecodedTransaction, 0, len(transactions))
+		for _, tx := range transactions {
+			d, err := tx.Decode()
+			if err != nil {
+				h.logger.Printf("Get transactions: Skipping transaction %s: %v", tx.Hash, err)
+				undecodable = append(undecodable, tx.Hash)
+				continue
+			}
+			decoded = append(decoded, d)
+		}
+		response = decoded
+	}
+	// The legacy route answers with a bare array; /v1 names it like every
+	// other list.
+	if isV1(r) {
+		if transactions == nil {
+			response = []models.Transaction{}
+		}
+		body := map[string]interface{}{"transactions": response}
+		if len(undecodable) > 0 {
+			body["undecodable"] = undecodable
+		}
+		response = body
+	} else if len(undecodable) > 0 {
+		w.Header().Set(UndecodableHeader, strings.Join(undecodable, ", "))
+	}
+
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
 		h.logger.Printf("Get transactions: Error encoding response: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if tag != "" {
+		h.logger.Printf("Get transactions: Returned %d transactions for tag %s", len(transactions), tag)
 		return
 	}
 	h.logger.Printf("Get transactions: Returned %d transactions for address %s", len(transactions), address)
 }
+
+func (h *Handler) GetRejectedBlocksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get rejected blocks: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Get rejected blocks")
+	if !ok {
+		return
+	}
+
+	rejected := parser.GetRejectedBlocks()
+	if rejected == nil {
+		rejected = []models.RejectedBlock{}
+	}
+	if err := h.writeJSON(w, r, http.StatusOK, map[string][]models.RejectedBlock{"rejectedBlocks": rejected}); err != nil {
+		h.logger.Printf("Get rejected blocks: Error encoding response: %v", err)
+		return
+	}
+	h.logger.Printf("Get rejected blocks: Returned %d blocks", len(rejected))
+}
+
+// checksum returns the EIP-55 form of a stored address. Entries that do not
+// parse as an address are returned unchanged.
+func checksum(address string) string {
+	parsed, err := models.HexToAddress(address)
+	if err != nil {
+		return address
+	}
+	return parsed.Checksum()
+}
@@ -0,0 +1,1319 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"eth-parser/internal/audit"
+	"eth-parser/internal/auth"
+	"eth-parser/internal/ethereum"
+	"eth-parser/internal/ratelimit"
+	"eth-parser/internal/storage"
+	"eth-parser/pkg/abi"
+	"eth-parser/pkg/models"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubParser is an in-memory ethereum.Parser without a background task.
+type stubParser struct {
+	subscribed map[string]bool
+	subs       map[string]models.Subscription
+	txs        map[string][]models.Transaction
+	abis       map[string][]byte
+	logSubs    []models.LogSubscription
+	tokens     map[string]models.Token
+	ledger     []models.LedgerEntry
+	snapshots  []models.BalanceSnapshot
+	drifts     []models.BalanceDrift
+	rules      []models.AlertRule
+	alerts     []models.Alert
+	// tenants holds the views of tenants other than the default one, which
+	// share quota, the subscription limit of every tenant.
+	tenants map[string]*stubParser
+	quota   int
+}
+
+func newStubParser() *stubParser {
+	return &stubParser{subscribed: map[string]bool{}, subs: map[string]models.Subscription{}, txs: map[string][]models.Transaction{}, abis: map[string][]byte{}, tokens: map[string]models.Token{}}
+}
+
+func (p *stubParser) GetCurrentBlock() int64 { return 0 }
+
+func (p *stubParser) ForTenant(tenant string) ethereum.Parser {
+	if tenant == storage.DefaultTenant {
+		return p
+	}
+	if p.tenants == nil {
+		p.tenants = map[string]*stubParser{}
+	}
+	if _, ok := p.tenants[tenant]; !ok {
+		view := newStubParser()
+		view.quota = p.quota
+		p.tenants[tenant] = view
+	}
+	return p.tenants[tenant]
+}
+
+func (p *stubParser) Subscribe(address string) bool {
+	success, _ := p.AddSubscription(models.Subscription{Address: address})
+	return success
+}
+
+func (p *stubParser) AddSubscription(sub models.Subscription) (bool, error) {
+	tags, err := models.NormalizeTags(sub.Tags)
+	if err != nil {
+		return false, err
+	}
+	if p.subscribed[sub.Address] {
+		return false, nil
+	}
+	if p.quota > 0 && len(p.subscribed) >= p.quota {
+		return false, ethereum.ErrQuotaExceeded
+	}
+	sub.Tags = tags
+	p.subscribed[sub.Address] = true
+	p.subs[sub.Address] = sub
+	return true, nil
+}
+
+func (p *stubParser) UpdateSubscription(sub models.Subscription) (models.Subscription, error) {
+	if !p.subscribed[sub.Address] {
+		return models.Subscription{}, ethereum.ErrNotSubscribed
+	}
+	tags, err := models.NormalizeTags(sub.Tags)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+	sub.Tags = tags
+	p.subs[sub.Address] = sub
+	return sub, nil
+}
+
+func (p *stubParser) GetSubscriptions() []models.Subscription {
+	var subs []models.Subscription
+	for _, address := range p.GetSubscribeList() {
+		sub := p.subs[address]
+		sub.Address = address
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Address < subs[j].Address })
+	return subs
+}
+
+func (p *stubParser) SubscribeBulk(subs []models.Subscription, atomic bool) []models.BulkResult {
+	results := make([]models.BulkResult, len(subs))
+	for i, sub := range subs {
+		results[i] = models.BulkResult{Row: i + 1, Address: sub.Address, Status: models.BulkAdded}
+		address, err := models.ParseAddress(sub.Address)
+		if err != nil {
+			results[i].Status = models.BulkInvalid
+			continue
+		}
+		subs[i].Address = address.String()
+		if p.subscribed[subs[i].Address] {
+			results[i].Status = models.BulkExisting
+		}
+	}
+	for i := range results {
+		if results[i].Status == models.BulkInvalid && atomic {
+			for j := range results {
+				if results[j].Status != models.BulkInvalid {
+					results[j].Status = models.BulkSkipped
+				}
+			}
+			return results
+		}
+	}
+	for i, sub := range subs {
+		if results[i].Status == models.BulkAdded {
+			p.AddSubscription(sub)
+		}
+	}
+	return results
+}
+
+func (p *stubParser) UnsubscribeBulk(addresses []string, atomic bool) []models.BulkResult {
+	results := make([]models.BulkResult, len(addresses))
+	for i, address := range addresses {
+		results[i] = models.BulkResult{Row: i + 1, Address: address, Status: models.BulkNotSubscribed}
+		if p.Unsubscribe(address) {
+			results[i].Status = models.BulkRemoved
+		}
+	}
+	return results
+}
+
+func (p *stubParser) GetTransactionsByTag(tag string) []models.Transaction {
+	var txs []models.Transaction
+	for _, sub := range p.GetSubscriptions() {
+		if sub.HasTag(tag) {
+			txs = append(txs, p.txs[sub.Address]...)
+		}
+	}
+	return txs
+}
+
+func (p *stubParser) GetTransactions(address string) []models.Transaction {
+	return p.txs[address]
+}
+
+func (p *stubParser) GetSubscribeList() []string {
+	var list []string
+	for address := range p.subscribed {
+		list = append(list, address)
+	}
+	return list
+}
+
+func (p *stubParser) Unsubscribe(address string) bool {
+	existed := p.subscribed[address]
+	delete(p.subscribed, address)
+	return existed
+}
+
+func (p *stubParser) GetRejectedBlocks() []models.RejectedBlock { return nil }
+
+func (p *stubParser) RegisterABI(address string, abiJSON []byte) error {
+	if _, err := abi.Parse(abiJSON); err != nil {
+		return err
+	}
+	p.abis[address] = abiJSON
+	return nil
+}
+
+func (p *stubParser) GetABI(address string) ([]byte, bool) {
+	abiJSON, ok := p.abis[address]
+	return abiJSON, ok
+}
+
+func (p *stubParser) UnregisterABI(address string) bool {
+	_, ok := p.abis[address]
+	delete(p.abis, address)
+	return ok
+}
+
+func (p *stubParser) SubscribeLogs(filter models.LogFilter) (models.LogSubscription, error) {
+	if len(filter.Addresses) == 0 && len(filter.Topics) == 0 {
+		return models.LogSubscription{}, ethereum.ErrEmptyLogFilter
+	}
+	sub := models.LogSubscription{ID: fmt.Sprintf("sub%d", len(p.logSubs)), Filter: filter}
+	p.logSubs = append(p.logSubs, sub)
+	return sub, nil
+}
+
+func (p *stubParser) UnsubscribeLogs(id string) bool { return false }
+
+func (p *stubParser) GetLogSubscriptions() []models.LogSubscription { return p.logSubs }
+
+func (p *stubParser) GetSubscriptionLogs(id string) ([]models.Log, bool) {
+	for _, sub := range p.logSubs {
+		if sub.ID == id {
+			return nil, true
+		}
+	}
+	return nil, false
+}
+
+func (p *stubParser) GetToken(address string) (models.Token, error) {
+	token, ok := p.tokens[address]
+	if !ok {
+		return models.Token{}, fmt.Errorf("failed to resolve token %s: node unreachable", address)
+	}
+	return token, nil
+}
+
+func (p *stubParser) GetTokens() []models.Token {
+	var tokens []models.Token
+	for _, token := range p.tokens {
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func (p *stubParser) GetBalanceHistory(address string) []models.LedgerEntry {
+	return append([]models.LedgerEntry(nil), p.ledger...)
+}
+
+func (p *stubParser) ReconcileBalance(address string, block int64) (models.Reconciliation, error) {
+	if p.ledger == nil {
+		return models.Reconciliation{}, ethereum.ErrLedgerDisabled
+	}
+	return models.Reconciliation{Address: address, BlockNumber: block, Computed: "5", OnChain: "5", Difference: "0", Reconciled: true}, nil
+}
+
+func (p *stubParser) GetBalanceSnapshots(address, token string) []models.BalanceSnapshot {
+	var series []models.BalanceSnapshot
+	for _, snapshot := range p.snapshots {
+		if strings.EqualFold(snapshot.Address, address) && strings.EqualFold(snapshot.Token, token) {
+			series = append(series, snapshot)
+		}
+	}
+	return series
+}
+
+func (p *stubParser) GetBalanceDrifts() []models.BalanceDrift { return p.drifts }
+
+func (p *stubParser) CreateAlertRule(rule models.AlertRule) (models.AlertRule, error) {
+	if err := rule.Validate(); err != nil {
+		return models.AlertRule{}, err
+	}
+	rule.ID = fmt.Sprintf("rule%d", len(p.rules))
+	p.rules = append(p.rules, rule)
+	return rule, nil
+}
+
+func (p *stubParser) UpdateAlertRule(id string, rule models.AlertRule) (models.AlertRule, error) {
+	for i := range p.rules {
+		if p.rules[i].ID == id {
+			if err := rule.Validate(); err != nil {
+				return models.AlertRule{}, err
+			}
+			rule.ID = id
+			p.rules[i] = rule
+			return rule, nil
+		}
+	}
+	return models.AlertRule{}, ethereum.ErrUnknownRule
+}
+
+func (p *stubParser) DeleteAlertRule(id string) bool {
+	for i := range p.rules {
+		if p.rules[i].ID == id {
+			p.rules = append(p.rules[:i], p.rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (p *stubParser) GetAlertRule(id string) (models.AlertRule, bool) {
+	for _, rule := range p.rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return models.AlertRule{}, false
+}
+
+func (p *stubParser) GetAlertRules() []models.AlertRule { return p.rules }
+
+func (p *stubParser) GetAlerts() []models.Alert { return p.alerts }
+
+func (p *stubParser) Start() {}
+func (p *stubParser) Stop()  {}
+
+func newTestHandler() (*Handler, *stubParser) {
+	parser := newStubParser()
+	return NewHandler(parser, log.New(io.Discard, "", 0)), parser
+}
+
+func TestSubscribeAddressValidation(t *testing.T) {
+	handler, parser := newTestHandler()
+
+	testCases := []struct {
+		address string
+		status  int
+	}{
+		{"0xdac17f958d2ee523a2206206994597c13d831ec7", http.StatusOK},
+		{"0xdAC17F958D2ee523a2206206994597C13D831ec7", http.StatusOK},
+		{"0xDAC17F958D2ee523a2206206994597C13D831ec7", http.StatusBadRequest},
+		{"0x1234", http.StatusBadRequest},
+		{"garbage", http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"address":"` + tc.address + `"}`)
+		handler.SubscribeHandler(rec, httptest.NewRequest(http.MethodPost, "/subscribe", body))
+		if rec.Code != tc.status {
+			t.Errorf("Subscribe(%s) status = %d, want %d", tc.address, rec.Code, tc.status)
+			continue
+		}
+
+		if tc.status == http.StatusBadRequest {
+			var resp errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Error != errCodeInvalidAddress {
+				t.Errorf("Subscribe(%s) error body = %+v, %v", tc.address, resp, err)
+			}
+			continue
+		}
+
+		var resp struct {
+			Address string `json:"address"`
+		}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if resp.Address != "0xdAC17F958D2ee523a2206206994597C13D831ec7" {
+			t.Errorf("Subscribe(%s) returned address %s, want checksummed form", tc.address, resp.Address)
+		}
+	}
+
+	if len(parser.subscribed) != 1 || !parser.subscribed["0xdac17f958d2ee523a2206206994597c13d831ec7"] {
+		t.Errorf("parser subscriptions = %v, want the lowercase address once", parser.subscribed)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.GetSubscribeListHandler(rec, httptest.NewRequest(http.MethodGet, "/subscribe-list", nil))
+	if !strings.Contains(rec.Body.String(), "0xdAC17F958D2ee523a2206206994597C13D831ec7") {
+		t.Errorf("subscribe list %s is not checksummed", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	body := strings.NewReader(`{"address":"0xDAC17F958D2EE523A2206206994597C13D831EC7"}`)
+	handler.UnsubscribeHandler(rec, httptest.NewRequest(http.MethodPost, "/unsubscribe", body))
+	if rec.Code != http.StatusOK || len(parser.subscribed) != 0 {
+		t.Errorf("Unsubscribe with uppercase address: status %d, remaining %v", rec.Code, parser.subscribed)
+	}
+}
+
+func TestGetTransactionsAddressValidation(t *testing.T) {
+	handler, _ := newTestHandler()
+
+	rec := httptest.NewRecorder()
+	handler.GetTransactionsHandler(rec, httptest.NewRequest(http.MethodGet, "/transactions?address=0xnothex", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGetTransactionsDecimal(t *testing.T) {
+	handler, parser := newTestHandler()
+	mux := http.NewServeMux()
+	handler.Register(mux)
+	const address = "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	good := models.Transaction{
+		Hash:        "0x" + strings.Repeat("11", 32),
+		BlockHash:   "0x" + strings.Repeat("22", 32),
+		BlockNumber: "0x10",
+		From:        address,
+		Value:       "0xde0b6b3a7640000",
+	}
+	bad := good
+	bad.Hash = "0x" + strings.Repeat("33", 32)
+	bad.Value = "0xzz"
+	parser.txs[address] = []models.Transaction{good, bad}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/transactions?format=decimal&address="+address, nil))
+	var resp struct {
+		Data struct {
+			Transactions []map[string]interface{} `json:"transactions"`
+			Undecodable  []string                 `json:"undecodable"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("status %d, error %v", rec.Code, err)
+	}
+	if len(resp.Data.Transactions) != 1 || resp.Data.Transactions[0]["value"] != "1000000000000000000" || resp.Data.Transactions[0]["blockNumber"] != float64(16) {
+		t.Errorf("transactions = %v", resp.Data.Transactions)
+	}
+	if !reflect.DeepEqual(resp.Data.Undecodable, []string{bad.Hash}) {
+		t.Errorf("undecodable = %v, want %s", resp.Data.Undecodable, bad.Hash)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/transactions?format=decimal&address="+address, nil))
+	var legacy []map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&legacy); err != nil || rec.Code != http.StatusOK || len(legacy) != 1 {
+		t.Errorf("legacy: status %d, %d transactions, error %v", rec.Code, len(legacy), err)
+	}
+	if got := rec.Header().Get(UndecodableHeader); got != bad.Hash {
+		t.Errorf("legacy: %s = %q, want %s", UndecodableHeader, got, bad.Hash)
+	}
+}
+
+func TestChainParameter(t *testing.T) {
+	mainnet, base := newStubParser(), newStubParser()
+	handler := NewMultiChainHandler([]Chain{
+		{Name: "ethereum", ID: 1, Parser: mainnet},
+		{Name: "base", ID: 8453, Parser: base},
+	}, log.New(io.Discard, "", 0))
+	address := "0xdac17f958d2ee523a2206206994597c13d831ec7"
+
+	for _, tc := range []struct {
+		query  string
+		status int
+		want   *stubParser
+	}{
+		{"", http.StatusOK, mainnet},
+		{"?chain=base", http.StatusOK, base},
+		{"?chain=BASE", http.StatusOK, base},
+		{"?chain=8453", http.StatusOK, base},
+		{"?chain=1", http.StatusOK, mainnet},
+		{"?chain=polygon", http.StatusBadRequest, nil},
+	} {
+		mainnet.subscribed, base.subscribed = map[string]bool{}, map[string]bool{}
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"address":"` + address + `"}`)
+		handler.SubscribeHandler(rec, httptest.NewRequest(http.MethodPost, "/subscribe"+tc.query, body))
+		if rec.Code != tc.status {
+			t.Errorf("Subscribe%s status = %d, want %d", tc.query, rec.Code, tc.status)
+			continue
+		}
+		if tc.want == nil {
+			var resp errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Error != errCodeUnknownChain {
+				t.Errorf("Subscribe%s error body = %+v, %v", tc.query, resp, err)
+			}
+			continue
+		}
+		if !tc.want.subscribed[address] || len(mainnet.subscribed)+len(base.subscribed) != 1 {
+			t.Errorf("Subscribe%s subscribed on the wrong chain", tc.query)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.GetChainsHandler(rec, httptest.NewRequest(http.MethodGet, "/chains", nil))
+	if !strings.Contains(rec.Body.String(), `{"name":"base","chainId":8453,"currentBlock":0}`) {
+		t.Errorf("chains response %s does not list base", rec.Body.String())
+	}
+}
+
+func TestABIEndpoints(t *testing.T) {
+	handler, parser := newTestHandler()
+	address := "0xdac17f958d2ee523a2206206994597c13d831ec7"
+
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(`{"address":"` + address + `","abi":[{"type":"function","name":"f","inputs":[{"type":"uint7"}]}]}`)
+	handler.RegisterABIHandler(rec, httptest.NewRequest(http.MethodPost, "/abi/register", body))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), errCodeInvalidABI) {
+		t.Errorf("invalid ABI: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	body = strings.NewReader(`{"address":"` + address + `","abi":[{"type":"function","name":"f","inputs":[]}]}`)
+	handler.RegisterABIHandler(rec, httptest.NewRequest(http.MethodPost, "/abi/register", body))
+	if rec.Code != http.StatusOK || parser.abis[address] == nil {
+		t.Fatalf("register: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.GetABIHandler(rec, httptest.NewRequest(http.MethodGet, "/abi?address="+address, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"abi":[{"type":"function","name":"f","inputs":[]}]`) {
+		t.Errorf("get: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.UnregisterABIHandler(rec, httptest.NewRequest(http.MethodPost, "/abi/unregister", strings.NewReader(`{"address":"`+address+`"}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"unregistered":true`) {
+		t.Errorf("unregister: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.GetABIHandler(rec, httptest.NewRequest(http.MethodGet, "/abi?address="+address, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("get after unregister: status = %d", rec.Code)
+	}
+}
+
+func TestLogSubscriptionEndpoints(t *testing.T) {
+	handler, parser := newTestHandler()
+
+	for _, body := range []string{`{"address":"0x1234"}`, `{}`} {
+		rec := httptest.NewRecorder()
+		handler.SubscribeLogsHandler(rec, httptest.NewRequest(http.MethodPost, "/logs/subscribe", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), errCodeInvalidFilter) {
+			t.Errorf("filter %s: status = %d, body %s", body, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	body := `{"address":"0xdac17f958d2ee523a2206206994597c13d831ec7","topics":["0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"]}`
+	handler.SubscribeLogsHandler(rec, httptest.NewRequest(http.MethodPost, "/logs/subscribe", strings.NewReader(body)))
+	if rec.Code != http.StatusOK || len(parser.logSubs) != 1 {
+		t.Fatalf("subscribe: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var sub models.LogSubscription
+	if err := json.NewDecoder(rec.Body).Decode(&sub); err != nil || sub.ID != "sub0" {
+		t.Errorf("subscription response %+v, %v", sub, err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.GetLogsHandler(rec, httptest.NewRequest(http.MethodGet, "/logs?subscription=sub0", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"logs":[]`) {
+		t.Errorf("get logs: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.GetLogsHandler(rec, httptest.NewRequest(http.MethodGet, "/logs?subscription=nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("get logs of an unknown subscription: status = %d", rec.Code)
+	}
+}
+
+func TestTokenEndpoints(t *testing.T) {
+	handler, parser := newTestHandler()
+	decimals := uint8(6)
+	parser.tokens["0xdac17f958d2ee523a2206206994597c13d831ec7"] = models.Token{
+		Address: "0xdAC17F958D2ee523a2206206994597C13D831ec7", Name: "Tether USD", Symbol: "USDT", Decimals: &decimals,
+	}
+
+	rec := httptest.NewRecorder()
+	handler.GetTokenHandler(rec, httptest.NewRequest(http.MethodGet, "/token?address=0xdAC17F958D2ee523a2206206994597C13D831ec7", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"symbol":"USDT","decimals":6`) {
+		t.Errorf("get: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.GetTokenHandler(rec, httptest.NewRequest(http.MethodGet, "/token?address=0x6b175474e89094c44da98b954eedeac495271d0f", nil))
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), errCodeNodeUnavailable) {
+		t.Errorf("unresolvable: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.GetTokenHandler(rec, httptest.NewRequest(http.MethodGet, "/token?address=0x1234", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid address: status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.GetTokensHandler(rec, httptest.NewRequest(http.MethodGet, "/tokens", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"name":"Tether USD"`) {
+		t.Errorf("list: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestBalanceHistoryEndpoints(t *testing.T) {
+	handler, parser := newTestHandler()
+	address := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/addresses/{addr}/balance-history", handler.GetBalanceHistoryHandler)
+	mux.HandleFunc("/addresses/{addr}/reconcile", handler.ReconcileBalanceHandler)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/addresses/"+address+"/reconcile?block=1", nil))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), errCodeLedgerDisabled) {
+		t.Errorf("reconcile without ledger: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	parser.ledger = []models.LedgerEntry{
+		{Address: address, BlockNumber: 1, Kind: models.LedgerOpening, Direction: models.LedgerCredit, Amount: "7", Balance: "7"},
+		{Address: address, BlockNumber: 2, Kind: models.LedgerFee, Direction: models.LedgerDebit, Amount: "2", Balance: "5"},
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/addresses/"+address+"/balance-history", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"balance":"5","entries":[{"address":"0x742d35Cc6634C0532925a3b844Bc454e4438f44e"`) {
+		t.Errorf("history: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/addresses/0x1234/balance-history", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid address: status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/addresses/"+address+"/reconcile?block=x", nil))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), errCodeInvalidBlock) {
+		t.Errorf("invalid block: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/addresses/"+address+"/reconcile?block=2", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"reconciled":true`) {
+		t.Errorf("reconcile: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestBalanceSnapshotEndpoints(t *testing.T) {
+	handler, parser := newTestHandler()
+	address := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	token := "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+	parser.snapshots = []models.BalanceSnapshot{
+		{Address: address, BlockNumber: 10, Balance: "5"},
+		{Address: address, Token: token, BlockNumber: 10, Balance: "1000"},
+	}
+	parser.drifts = []models.BalanceDrift{
+		{Address: address, FromBlock: 10, ToBlock: 20, PreviousBalance: "5", Balance: "7", Change: "2"},
+		{Address: "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", FromBlock: 10, ToBlock: 20, PreviousBalance: "1", Balance: "0", Change: "-1"},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/addresses/{addr}/snapshots", handler.GetBalanceSnapshotsHandler)
+	mux.HandleFunc("/drifts", handler.GetBalanceDriftsHandler)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/addresses/"+address+"/snapshots?token="+token, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"balance":"1000"`) || strings.Contains(rec.Body.String(), `"balance":"5"`) {
+		t.Errorf("token snapshots: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/drifts?address=0x742d35Cc6634C0532925a3b844Bc454e4438f44e", nil))
+	var resp struct {
+		Drifts []models.BalanceDrift `json:"drifts"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || len(resp.Drifts) != 1 || resp.Drifts[0].Change != "2" {
+		t.Errorf("drifts of one address = %+v, %v", resp, err)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/addresses/"+address+"/snapshots?token=0x1234", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid token: status = %d", rec.Code)
+	}
+}
+
+func TestAlertRuleEndpoints(t *testing.T) {
+	handler, parser := newTestHandler()
+	now := time.Now()
+	parser.alerts = []models.Alert{
+		{RuleID: "rule0", Severity: models.SeverityCritical, Address: "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", DedupKey: "rule0:0x01"},
+		{RuleID: "rule0", Severity: models.SeverityCritical, Address: "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", DedupKey: "rule0:0x02", ResolvedAt: &now},
+		{RuleID: "rule1", Severity: models.SeverityInfo, Address: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", DedupKey: "rule1:0x03"},
+	}
+	post := func(h http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(handler.CreateAlertRuleHandler, `{"name":"big outflow","type":"outbound_value","severity":"critical","threshold":"10000000000000000000"}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"id":"rule0"`) {
+		t.Errorf("create: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	for name, body := range map[string]string{
+		"no threshold":       `{"type":"outbound_value"}`,
+		"unknown type":       `{"type":"gas_spike"}`,
+		"bad checksum":       `{"type":"counterparty_not_allowlisted","allowlist":["0x742d35Cc6634C0532925a3b844Bc454e4438F44e"]}`,
+		"threshold on fails": `{"type":"failed_transaction","threshold":"1"}`,
+	} {
+		if rec := post(handler.CreateAlertRuleHandler, body); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), errCodeInvalidRule) {
+			t.Errorf("create with %s: status = %d, body %s", name, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = post(handler.UpdateAlertRuleHandler, `{"id":"rule0","type":"balance_below","threshold":"1"}`)
+	if rec.Code != http.StatusOK || parser.rules[0].Type != models.RuleBalanceBelow {
+		t.Errorf("update: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(handler.UpdateAlertRuleHandler, `{"id":"missing","type":"failed_transaction"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("update of a missing rule: status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.GetAlertRulesHandler(rec, httptest.NewRequest(http.MethodGet, "/rules?id=rule0", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"threshold":"1"`) {
+		t.Errorf("get rule: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	handler.GetAlertRulesHandler(rec, httptest.NewRequest(http.MethodGet, "/rules?id=missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("get missing rule: status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.GetAlertsHandler(rec, httptest.NewRequest(http.MethodGet, "/alerts?severity=critical&active=true&address=0x742d35cc6634c0532925a3b844bc454e4438f44e", nil))
+	var resp struct {
+		Alerts []models.Alert `json:"alerts"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || len(resp.Alerts) != 1 || resp.Alerts[0].DedupKey != "rule0:0x01" {
+		t.Errorf("filtered alerts = %+v, %v", resp, err)
+	}
+
+	if rec := post(handler.DeleteAlertRuleHandler, `{"id":"rule0"}`); !strings.Contains(rec.Body.String(), `"deleted":true`) || len(parser.rules) != 0 {
+		t.Errorf("delete: body %s", rec.Body.String())
+	}
+}
+
+func TestSubscriptionMetadata(t *testing.T) {
+	handler, parser := newTestHandler()
+	treasury := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	other := "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	parser.txs[treasury] = []models.Transaction{{Hash: "0x01", BlockNumber: "0x1"}}
+	parser.txs[other] = []models.Transaction{{Hash: "0x02", BlockNumber: "0x1"}}
+	post := func(h http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(handler.SubscribeHandler, `{"address":"`+treasury+`","label":"Cold wallet","tags":["Treasury"," ops"],"ownerTeam":"finance","notes":"multisig"}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"subscribed":true`) {
+		t.Fatalf("subscribe with metadata: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	post(handler.SubscribeHandler, `{"address":"`+other+`"}`)
+	if rec := post(handler.SubscribeHandler, `{"address":"`+other+`","tags":["hot wallet"]}`); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), errCodeInvalidSubscription) {
+		t.Errorf("tag with a space: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.GetSubscribeListHandler(rec, httptest.NewRequest(http.MethodGet, "/subscribe-list?tag=treasury&metadata=true", nil))
+	var list struct {
+		Addresses     []string              `json:"subscribedAddresses"`
+		Subscriptions []models.Subscription `json:"subscriptions"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Subscriptions) != 1 || len(list.Addresses) != 1 {
+		t.Fatalf("tagged subscribe list = %+v", list)
+	}
+	if sub := list.Subscriptions[0]; sub.Label != "Cold wallet" || sub.OwnerTeam != "finance" || !reflect.DeepEqual(sub.Tags, []string{"ops", "treasury"}) {
+		t.Errorf("subscription = %+v", sub)
+	}
+
+	rec = post(handler.UpdateSubscriptionHandler, `{"address":"`+other+`","tags":["treasury"]}`)
+	if rec.Code != http.StatusOK {
+		t.Errorf("update: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(handler.UpdateSubscriptionHandler, `{"address":"0xdac17f958d2ee523a2206206994597c13d831ec7"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("update of an unsubscribed address: status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.GetTransactionsHandler(rec, httptest.NewRequest(http.MethodGet, "/transactions?tag=treasury", nil))
+	var txs []models.Transaction
+	if err := json.NewDecoder(rec.Body).Decode(&txs); err != nil || len(txs) != 2 {
+		t.Errorf("transactions by tag = %+v, %v", txs, err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.GetTransactionsHandler(rec, httptest.NewRequest(http.MethodGet, "/transactions?tag=treasury&address="+treasury, nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("address and tag: status = %d", rec.Code)
+	}
+}
+
+func TestBulkSubscriptionEndpoints(t *testing.T) {
+	handler, parser := newTestHandler()
+	post := func(h http.HandlerFunc, target, contentType, body string) (*httptest.ResponseRecorder, bulkResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		h(rec, req)
+		var resp bulkResponse
+		if err := json.NewDecoder(bytes.NewReader(rec.Body.Bytes())).Decode(&resp); err != nil {
+			t.Fatalf("%s: %v, body %s", target, err, rec.Body.String())
+		}
+		return rec, resp
+	}
+
+	csvBody := "address,label,tags,startBlock\n" +
+		"0x742d35Cc6634C0532925a3b844Bc454e4438f44e,Deposit 1,deposits customer,\n" +
+		"0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48,Deposit 2,deposits,\n" +
+		"0x742d35Cc6634C0532925a3b844Bc454e4438F44e,bad checksum,,\n" +
+		"0xdac17f958d2ee523a2206206994597c13d831ec7,,,soon\n"
+
+	rec, resp := post(handler.BulkSubscribeHandler, "/subscribe/bulk?atomic=true", "text/csv", csvBody)
+	if rec.Code != http.StatusBadRequest || resp.Applied || resp.Error != errCodeInvalidRows || len(parser.subscribed) != 0 {
+		t.Errorf("atomic with invalid rows: status %d, response %+v", rec.Code, resp)
+	}
+	if resp.Summary[models.BulkInvalid] != 1 || resp.Summary[models.BulkSkipped] != 3 || resp.Results[3].Status != models.BulkInvalid {
+		t.Errorf("atomic summary = %+v, results %+v", resp.Summary, resp.Results)
+	}
+
+	rec, resp = post(handler.BulkSubscribeHandler, "/subscribe/bulk", "text/csv; charset=utf-8", csvBody)
+	if rec.Code != http.StatusOK || !resp.Applied {
+		t.Fatalf("partial import: status %d, response %+v", rec.Code, resp)
+	}
+	want := []models.BulkStatus{models.BulkAdded, models.BulkAdded, models.BulkInvalid, models.BulkInvalid}
+	for i, result := range resp.Results {
+		if result.Row != i+1 || result.Status != want[i] {
+			t.Errorf("row %d: %+v, want %s", i+1, result, want[i])
+		}
+	}
+	if sub := parser.subs["0x742d35cc6634c0532925a3b844bc454e4438f44e"]; sub.Label != "Deposit 1" || !reflect.DeepEqual(sub.Tags, []string{"customer", "deposits"}) {
+		t.Errorf("imported subscription = %+v", sub)
+	}
+
+	_, resp = post(handler.BulkSubscribeHandler, "/subscribe/bulk", "application/json",
+		`["0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", {"address": "0xdac17f958d2ee523a2206206994597c13d831ec7", "tags": ["exchange"]}, {"address": 5}]`)
+	if resp.Summary[models.BulkExisting] != 1 || resp.Summary[models.BulkAdded] != 1 || resp.Summary[models.BulkInvalid] != 1 {
+		t.Errorf("JSON import summary = %+v", resp.Summary)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ExportSubscriptionsHandler(rec, httptest.NewRequest(http.MethodGet, "/subscriptions/export?format=csv&tag=deposits", nil))
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil || len(records) != 3 || records[0][0] != "address" || records[1][2] != "customer deposits" {
+		t.Errorf("CSV export = %v, %v", records, err)
+	}
+
+	_, resp = post(handler.BulkUnsubscribeHandler, "/unsubscribe/bulk", "application/json",
+		`["0x742d35cc6634c0532925a3b844bc454e4438f44e", "0x9f8f72aa9304c8b593d555f12ef6589cc3a579a2"]`)
+	if resp.Summary[models.BulkRemoved] != 1 || resp.Summary[models.BulkNotSubscribed] != 1 {
+		t.Errorf("bulk unsubscribe summary = %+v", resp.Summary)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/subscribe/bulk", strings.NewReader("addr,label\n0x1,x\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	handler.BulkSubscribeHandler(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), errCodeInvalidBulkRequest) {
+		t.Errorf("unknown column: status %d, body %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTenantScoping(t *testing.T) {
+	handler, parser := newTestHandler()
+	parser.quota = 1
+	address := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	other := "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	do := func(h http.HandlerFunc, method, target, tenant, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if tenant != "" {
+			req.Header.Set(TenantHeader, tenant)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	if rec := do(handler.SubscribeHandler, http.MethodPost, "/subscribe", "team-a", `{"address":"`+address+`"}`); rec.Code != http.StatusOK {
+		t.Fatalf("subscribe: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(handler.SubscribeHandler, http.MethodPost, "/subscribe?tenant=team-b", "", `{"address":"`+address+`"}`); rec.Code != http.StatusOK {
+		t.Fatalf("subscribe with the tenant parameter: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if !parser.tenants["team-a"].subscribed[address] || !parser.tenants["team-b"].subscribed[address] || len(parser.subscribed) != 0 {
+		t.Error("subscriptions should go to the requesting tenant")
+	}
+
+	rec := do(handler.SubscribeHandler, http.MethodPost, "/subscribe", "team-a", `{"address":"`+other+`"}`)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), errCodeQuotaExceeded) {
+		t.Errorf("subscribe over quota: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = do(handler.GetSubscribeListHandler, http.MethodGet, "/subscribe-list", "", "")
+	var list map[string][]string
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list["subscribedAddresses"]) != 0 {
+		t.Errorf("default tenant sees %v", list)
+	}
+
+	for _, tenant := range []string{"Team-A", "team a", strings.Repeat("t", 65)} {
+		rec := do(handler.GetSubscribeListHandler, http.MethodGet, "/subscribe-list", tenant, "")
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), errCodeInvalidTenant) {
+			t.Errorf("tenant %q: status = %d, body %s", tenant, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	handler, parser := newTestHandler()
+	authenticator := auth.NewAuthenticator(storage.NewMemoryStorage(), log.New(io.Discard, "", 0))
+	admin := strings.Repeat("a", 32)
+	if err := authenticator.Bootstrap(admin); err != nil {
+		t.Fatal(err)
+	}
+	handler.SetAuthenticator(authenticator)
+	address := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	do := func(role models.Role, h http.HandlerFunc, method, key, tenant, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		if tenant != "" {
+			req.Header.Set(TenantHeader, tenant)
+		}
+		rec := httptest.NewRecorder()
+		handler.Require(role, h)(rec, req)
+		return rec
+	}
+
+	rec := do(models.RoleAdmin, handler.CreateAPIKeyHandler, http.MethodPost, admin, "", `{"name":"team-a reader","role":"read-only","tenant":"team-a"}`)
+	var reader struct {
+		ID  string `json:"id"`
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&reader); err != nil || reader.Key == "" {
+		t.Fatalf("create key: status = %d, %v", rec.Code, err)
+	}
+	rec = do(models.RoleAdmin, handler.CreateAPIKeyHandler, http.MethodPost, admin, "", `{"role":"subscriber","tenant":"team-a"}`)
+	var subscriber struct {
+		Key string `json:"key"`
+	}
+	json.NewDecoder(rec.Body).Decode(&subscriber)
+	if rec := do(models.RoleAdmin, handler.CreateAPIKeyHandler, http.MethodPost, admin, "", `{"role":"root"}`); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), errCodeInvalidKey) {
+		t.Errorf("create key with an unknown role: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	body := `{"address":"` + address + `"}`
+	if rec := do(models.RoleSubscriber, handler.SubscribeHandler, http.MethodPost, "", "", body); rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("subscribe without a key: status = %d", rec.Code)
+	}
+	if rec := do(models.RoleSubscriber, handler.SubscribeHandler, http.MethodPost, reader.Key, "", body); rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), errCodeForbidden) {
+		t.Errorf("subscribe with a read-only key: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(models.RoleSubscriber, handler.SubscribeHandler, http.MethodPost, subscriber.Key, "", body); rec.Code != http.StatusOK {
+		t.Errorf("subscribe with a subscriber key: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if !parser.tenants["team-a"].subscribed[address] {
+		t.Error("a key should act for its tenant")
+	}
+	if rec := do(models.RoleReadOnly, handler.GetSubscribeListHandler, http.MethodGet, reader.Key, "team-b", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("naming another tenant with a read-only key: status = %d", rec.Code)
+	}
+	if rec := do(models.RoleReadOnly, handler.GetSubscribeListHandler, http.MethodGet, admin, "team-a", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "742d35") {
+		t.Errorf("admin acting for team-a: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := do(models.RoleAdmin, handler.RevokeAPIKeyHandler, http.MethodPost, admin, "", `{"id":"`+reader.ID+`"}`); rec.Code != http.StatusOK {
+		t.Errorf("revoke: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(models.RoleReadOnly, handler.GetSubscribeListHandler, http.MethodGet, reader.Key, "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key: status = %d", rec.Code)
+	}
+	rec = do(models.RoleAdmin, handler.GetAPIKeysHandler, http.MethodGet, admin, "", "")
+	if strings.Contains(rec.Body.String(), subscriber.Key) || strings.Count(rec.Body.String(), `"role"`) != 3 {
+		t.Errorf("key list = %s", rec.Body.String())
+	}
+
+	// Admins of a tenant manage that tenant only.
+	rec = do(models.RoleAdmin, handler.CreateAPIKeyHandler, http.MethodPost, admin, "", `{"role":"admin","tenant":"team-a"}`)
+	var tenantAdmin struct {
+		Key string `json:"key"`
+	}
+	json.NewDecoder(rec.Body).Decode(&tenantAdmin)
+	if rec := do(models.RoleReadOnly, handler.GetSubscribeListHandler, http.MethodGet, tenantAdmin.Key, "team-b", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("tenant admin acting for team-b: status = %d", rec.Code)
+	}
+	for _, body := range []string{`{"role":"read-only","tenant":"team-b"}`, `{"role":"global-admin","tenant":"team-a"}`} {
+		if rec := do(models.RoleAdmin, handler.CreateAPIKeyHandler, http.MethodPost, tenantAdmin.Key, "", body); rec.Code != http.StatusForbidden {
+			t.Errorf("tenant admin creating %s: status = %d", body, rec.Code)
+		}
+	}
+	if rec := do(models.RoleAdmin, handler.CreateAPIKeyHandler, http.MethodPost, tenantAdmin.Key, "", `{"role":"read-only"}`); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"tenant":"team-a"`) {
+		t.Errorf("tenant admin creating a key: status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	rec = do(models.RoleAdmin, handler.GetAPIKeysHandler, http.MethodGet, tenantAdmin.Key, "", "")
+	if strings.Contains(rec.Body.String(), "bootstrap") || strings.Count(rec.Body.String(), `"tenant":"team-a"`) != 4 {
+		t.Errorf("tenant admin key list = %s", rec.Body.String())
+	}
+	bootstrap := authenticator.GetKeys()[0]
+	if rec := do(models.RoleAdmin, handler.RevokeAPIKeyHandler, http.MethodPost, tenantAdmin.Key, "", `{"id":"`+bootstrap.ID+`"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("tenant admin revoking the bootstrap key: status = %d", rec.Code)
+	}
+	if rec := do(models.RoleGlobalAdmin, handler.GetAuditRecordsHandler, http.MethodGet, tenantAdmin.Key, "", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("tenant admin reading the audit log: status = %d", rec.Code)
+	}
+	// ABIs are shared by every tenant of a chain.
+	for _, route := range handler.Routes() {
+		if strings.HasPrefix(route.Path, "/abi/") && route.Role != models.RoleGlobalAdmin {
+			t.Errorf("%s %s needs role %s", route.Method, route.Path, route.Role)
+		}
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	handler, _ := newTestHandler()
+	handler.SetRateLimits(ratelimit.Limit{Rate: 0.01, Burst: 3}, ratelimit.Limit{Rate: 0.01, Burst: 1})
+	transactions := handler.Require(models.RoleReadOnly, handler.Expensive(handler.GetTransactionsHandler))
+	subscribeList := handler.Require(models.RoleReadOnly, handler.GetSubscribeListHandler)
+	get := func(h http.HandlerFunc, target, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+	target := "/transactions?address=0x742d35cc6634c0532925a3b844bc454e4438f44e"
+
+	rec := get(transactions, target, "192.0.2.1:1000")
+	if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "1" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("first request: status = %d, headers %v", rec.Code, rec.Header())
+	}
+	rec = get(transactions, target, "192.0.2.1:1001")
+	if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), errCodeRateLimited) || rec.Header().Get("Retry-After") != "100" {
+		t.Errorf("over the expensive limit: status = %d, headers %v", rec.Code, rec.Header())
+	}
+	rec = get(subscribeList, "/subscribe-list", "192.0.2.1:1002")
+	if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "3" || rec.Header().Get("RateLimit-Remaining") != "0" || rec.Header().Get("RateLimit-Policy") != "3;w=300" {
+		t.Errorf("other endpoints should have the general limit: status = %d, headers %v", rec.Code, rec.Header())
+	}
+	if rec := get(subscribeList, "/subscribe-list", "192.0.2.1:1003"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("over the general limit: status = %d", rec.Code)
+	}
+	if rec := get(transactions, target, "192.0.2.2:1000"); rec.Code != http.StatusOK {
+		t.Errorf("another client: status = %d", rec.Code)
+	}
+
+	// Requests without a valid key are limited by client address, as are
+	// the routes that need no key. Keys behind one address keep their own
+	// limits.
+	authenticator := auth.NewAuthenticator(storage.NewMemoryStorage(), log.New(io.Discard, "", 0))
+	handler.SetAuthenticator(authenticator)
+	handler.SetRateLimits(ratelimit.Limit{Rate: 0.01, Burst: 1}, ratelimit.Limit{})
+	for i := 0; i < 2; i++ {
+		_, secret, _ := authenticator.CreateKey("", models.RoleReadOnly, "")
+		req := httptest.NewRequest(http.MethodGet, "/subscribe-list", nil)
+		req.RemoteAddr = "192.0.2.3:1000"
+		req.Header.Set("Authorization", "Bearer "+secret)
+		rec := httptest.NewRecorder()
+		subscribeList(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("key %d behind a shared address: status = %d", i, rec.Code)
+		}
+	}
+	if rec := get(subscribeList, "/subscribe-list", "192.0.2.3:1000"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("request without a key: status = %d", rec.Code)
+	}
+	if rec := get(subscribeList, "/subscribe-list", "192.0.2.3:1001"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("requests without a key should be limited: status = %d", rec.Code)
+	}
+	mux := http.NewServeMux()
+	handler.Register(mux)
+	for i, path := range []string{"/health", "/v1/openapi.json"} {
+		remoteAddr := fmt.Sprintf("192.0.2.%d:1000", 4+i)
+		if rec := get(mux.ServeHTTP, path, remoteAddr); rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d", path, rec.Code)
+		}
+		if rec := get(mux.ServeHTTP, path, remoteAddr); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("%s should be limited: status = %d", path, rec.Code)
+		}
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	handler, _ := newTestHandler()
+	store := storage.NewMemoryStorage()
+	handler.SetAuditLog(audit.NewLog(store, log.New(io.Discard, "", 0)))
+	address := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	do := func(h http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.1:1000"
+		req.Header.Set(RequestIDHeader, "req-1")
+		req.Header.Set(TenantHeader, "team-a")
+		rec := httptest.NewRecorder()
+		handler.Require(models.RoleReadOnly, h)(rec, req)
+		return rec
+	}
+
+	rec := do(handler.SubscribeHandler, http.MethodPost, "/subscribe", `{"address":"`+address+`","label":"Vault"}`)
+	if rec.Header().Get(RequestIDHeader) != "req-1" {
+		t.Errorf("request ID = %q", rec.Header().Get(RequestIDHeader))
+	}
+	do(handler.SubscribeHandler, http.MethodPost, "/subscribe", `{"address":"`+address+`"}`)
+	do(handler.UnsubscribeHandler, http.MethodPost, "/unsubscribe", `{"address":"`+address+`"}`)
+	do(handler.BulkSubscribeHandler, http.MethodPost, "/subscribe/bulk", `["`+address+`", "0xinvalid"]`)
+
+	rec = do(handler.GetAuditRecordsHandler, http.MethodGet, "/audit?target="+address+"&action=subscription.create", "")
+	var list struct {
+		Records []models.AuditRecord `json:"records"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Records) != 2 {
+		t.Fatalf("subscribe records = %+v", list.Records)
+	}
+	first := list.Records[0]
+	if first.Actor != "anonymous" || first.IP != "192.0.2.1" || first.RequestID != "req-1" || first.Tenant != "team-a" || first.ChainID != 1 ||
+		first.Target != "0x742d35Cc6634C0532925a3b844Bc454e4438f44e" || first.Details["label"] != "Vault" {
+		t.Errorf("record = %+v", first)
+	}
+	if list.Records[1].Details["bulk"] != "true" {
+		t.Errorf("bulk record = %+v", list.Records[1])
+	}
+
+	rec = do(handler.ExportAuditRecordsHandler, http.MethodGet, "/audit/export", "")
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if rec.Header().Get("Content-Type") != "application/jsonl" || len(lines) != 3 {
+		t.Fatalf("export: %s", rec.Body.String())
+	}
+	var exported []models.AuditRecord
+	for _, line := range lines {
+		var record models.AuditRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		exported = append(exported, record)
+	}
+	if err := models.VerifyAuditChain(exported, models.AuditGenesisHash); err != nil {
+		t.Errorf("exported records should verify: %v", err)
+	}
+
+	rec = do(handler.VerifyAuditLogHandler, http.MethodGet, "/audit/verify", "")
+	if !strings.Contains(rec.Body.String(), `"valid":true`) || !strings.Contains(rec.Body.String(), exported[2].Hash) {
+		t.Errorf("verify: %s", rec.Body.String())
+	}
+}
+
+func TestV1API(t *testing.T) {
+	handler, parser := newTestHandler()
+	mux := http.NewServeMux()
+	handler.Register(mux)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder, v interface{}) {
+		t.Helper()
+		if err := json.NewDecoder(bytes.NewReader(rec.Body.Bytes())).Decode(v); err != nil {
+			t.Fatalf("%v, body %s", err, rec.Body.String())
+		}
+	}
+	const address = "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+
+	rec := do(http.MethodPost, "/v1/subscribe", `{"address": "`+address+`"}`)
+	var subscribed struct {
+		Data      map[string]interface{} `json:"data"`
+		RequestID string                 `json:"requestId"`
+	}
+	decode(rec, &subscribed)
+	if rec.Code != http.StatusCreated || subscribed.Data["changed"] != true || subscribed.Data["address"] != address {
+		t.Errorf("subscribe: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if subscribed.RequestID == "" || subscribed.RequestID != rec.Header().Get(RequestIDHeader) {
+		t.Errorf("request ID %q, header %q", subscribed.RequestID, rec.Header().Get(RequestIDHeader))
+	}
+	if rec.Header().Get("Deprecation") != "" {
+		t.Errorf("/v1 response is deprecated")
+	}
+	rec = do(http.MethodPost, "/v1/subscribe", `{"address": "`+address+`"}`)
+	decode(rec, &subscribed)
+	if rec.Code != http.StatusOK || subscribed.Data["changed"] != false {
+		t.Errorf("subscribe again: status %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	// The legacy path keeps its old shape and points at its successor.
+	rec = do(http.MethodPost, "/subscribe", `{"address": "`+address+`"}`)
+	var legacy map[string]interface{}
+	decode(rec, &legacy)
+	if rec.Code != http.StatusOK || legacy["subscribed"] != false || legacy["data"] != nil {
+		t.Errorf("legacy subscribe: status %d, body %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Deprecation") != "true" || rec.Header().Get("Link") != `</v1/subscribe>; rel="successor-version"` {
+		t.Errorf("legacy headers = %v", rec.Header())
+	}
+
+	parser.txs[strings.ToLower(address)] = []models.Transaction{{Hash: "0x1"}}
+	rec = do(http.MethodGet, "/v1/transactions?address="+address, "")
+	var transactions struct {
+		Data struct {
+			Transactions []models.Transaction `json:"transactions"`
+		} `json:"data"`
+	}
+	decode(rec, &transactions)
+	if len(transactions.Data.Transactions) != 1 {
+		t.Errorf("transactions: body %s", rec.Body.String())
+	}
+	rec = do(http.MethodGet, "/transactions?address="+address, "")
+	var bare []models.Transaction
+	decode(rec, &bare)
+	if len(bare) != 1 {
+		t.Errorf("legacy transactions: body %s", rec.Body.String())
+	}
+
+	for _, tc := range []struct {
+		method, target, body string
+		status               int
+		code                 string
+	}{
+		{http.MethodPost, "/v1/subscribe", "{", http.StatusBadRequest, "bad_request"},
+		{http.MethodPost, "/v1/subscribe", `{"address": "0x742d35Cc6634C0532925a3b844Bc454e4438F44e"}`, http.StatusBadRequest, errCodeInvalidAddress},
+		{http.MethodGet, "/v1/subscribe", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{http.MethodGet, "/v1/transactions", "", http.StatusBadRequest, "bad_request"},
+		{http.MethodGet, "/v1/nothing", "", http.StatusNotFound, errCodeNotFound},
+		{http.MethodPost, "/v1/subscribe/bulk?atomic=true", `["` + address + `", "0x1"]`, http.StatusBadRequest, errCodeInvalidRows},
+	} {
+		rec := do(tc.method, tc.target, tc.body)
+		var resp errorEnvelope
+		decode(rec, &resp)
+		if rec.Code != tc.status || resp.Error.Code != tc.code || resp.Error.Message == "" || resp.Error.RequestID == "" {
+			t.Errorf("%s %s: status %d, body %s", tc.method, tc.target, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = do(http.MethodPost, "/v1/subscribe/bulk?atomic=true", `["0x9f8f72aa9304c8b593d555f12ef6589cc3a579a2", "0x1"]`)
+	var rejected struct {
+		Error struct {
+			Details bulkResponse `json:"details"`
+		} `json:"error"`
+	}
+	decode(rec, &rejected)
+	if details := rejected.Error.Details; details.Applied || details.Summary[models.BulkSkipped] != 1 || len(details.Results) != 2 {
+		t.Errorf("atomic bulk details = %+v", details)
+	}
+
+	rec = do(http.MethodPost, "/subscribe", "{")
+	if rec.Code != http.StatusBadRequest || strings.TrimSpace(rec.Body.String()) != "Bad request" {
+		t.Errorf("legacy bad request: status %d, body %s", rec.Code, rec.Body.String())
+	}
+
+	rec = do(http.MethodGet, "/v1/openapi.json", "")
+	var document map[string]interface{}
+	decode(rec, &document)
+	if document["openapi"] != OpenAPIVersion {
+		t.Errorf("OpenAPI document: body %.200s", rec.Body.String())
+	}
+}
+
+var update = flag.Bool("update", false, "rewrite docs/openapi.json")
+
+// TestOpenAPIDocument keeps docs/openapi.json and the endpoints listed in
+// docs/api.md in line with the route table.
+func TestOpenAPIDocument(t *testing.T) {
+	handler, _ := newTestHandler()
+	got, err := json.MarshalIndent(handler.OpenAPI(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+	const golden = "../../docs/openapi.json"
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("docs/openapi.json is out of date, regenerate it with go test ./internal/api -run TestOpenAPIDocument -update")
+	}
+
+	// Every endpoint section of docs/api.md starts with the request line of
+	// its route, and every route has a section.
+	doc, err := os.ReadFile("../../docs/api.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	documented := map[string]string{}
+	var heading string
+	for _, line := range strings.Split(string(doc), "\n") {
+		if title, ok := strings.CutPrefix(line, "### "); ok {
+			heading = title
+			continue
+		}
+		if heading == "" || !(strings.HasPrefix(line, "- GET /") || strings.HasPrefix(line, "- POST /")) {
+			continue
+		}
+		documented[heading] = strings.TrimPrefix(line, "- ")
+		heading = ""
+	}
+	param := regexp.MustCompile(`\\\{\w+\\\}`)
+	routes := handler.Routes()
+	for _, route := range routes {
+		line, ok := documented[route.Summary]
+		if !ok {
+			t.Errorf("%s %s: no section %q in docs/api.md", route.Method, route.Path, route.Summary)
+			continue
+		}
+		pattern := "^" + route.Method + " " + V1Prefix + param.ReplaceAllString(regexp.QuoteMeta(route.Path), "[^/?]+") + `(\?|$)`
+		if !regexp.MustCompile(pattern).MatchString(line) {
+			t.Errorf("section %q documents %s, want %s %s", route.Summary, line, route.Method, V1Prefix+route.Path)
+		}
+		delete(documented, route.Summary)
+	}
+	for summary, line := range documented {
+		t.Errorf("section %q documents %s, which is no route", summary, line)
+	}
+}
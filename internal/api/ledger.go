@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"eth-parser/internal/ethereum"
+	"eth-parser/pkg/models"
+	"net/http"
+	"strconv"
+)
+
+const (
+	errCodeLedgerDisabled    = "ledger_disabled"
+	errCodeInvalidBlock      = "invalid_block"
+	errCodeBlockNotProcessed = "block_not_processed"
+)
+
+// GetBalanceHistoryHandler returns the ledger of the address in the path
+// with the running balance after each entry.
+func (h *Handler) GetBalanceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get balance history: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Get balance history")
+	if !ok {
+		return
+	}
+
+	rawAddress := r.PathValue("addr")
+	address, err := models.ParseAddress(rawAddress)
+	if err != nil {
+		h.logger.Printf("Get balance history: Invalid address %q: %v", rawAddress, err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
+		return
+	}
+
+	entries := parser.GetBalanceHistory(address.String())
+	balance := "0"
+	for i := range entries {
+		entries[i].Address = checksum(entries[i].Address)
+		balance = entries[i].Balance
+	}
+	if entries == nil {
+		entries = []models.LedgerEntry{}
+	}
+	response := map[string]interface{}{"address": address.Checksum(), "balance": balance, "entries": entries}
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
+		h.logger.Printf("Get balance history: Error encoding response: %v", err)
+		return
+	}
+}
+
+// ReconcileBalanceHandler compares the ledger balance of the address in the
+// path with eth_getBalance at the block query parameter, by default the last
+// processed block.
+func (h *Handler) ReconcileBalanceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Reconcile balance: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Reconcile balance")
+	if !ok {
+		return
+	}
+
+	rawAddress := r.PathValue("addr")
+	address, err := models.ParseAddress(rawAddress)
+	if err != nil {
+		h.logger.Printf("Reconcile balance: Invalid address %q: %v", rawAddress, err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
+		return
+	}
+	block := parser.GetCurrentBlock() - 1
+	if raw := r.URL.Query().Get("block"); raw != "" {
+		if block, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			h.logger.Printf("Reconcile balance: Invalid block %q", raw)
+			h.writeError(w, r, http.StatusBadRequest, errCodeInvalidBlock, "block must be a decimal block number")
+			return
+		}
+	}
+
+	result, err := parser.ReconcileBalance(address.String(), block)
+	switch {
+	case errors.Is(err, ethereum.ErrLedgerDisabled):
+		h.writeError(w, r, http.StatusConflict, errCodeLedgerDisabled, err.Error())
+		return
+	case errors.Is(err, ethereum.ErrBlockNotProcessed):
+		h.writeError(w, r, http.StatusBadRequest, errCodeBlockNotProcessed, err.Error())
+		return
+	case errors.Is(err, ethereum.ErrNotSubscribed):
+		h.writeError(w, r, http.StatusNotFound, errCodeNotSubscribed, err.Error())
+		return
+	case err != nil:
+		h.logger.Printf("Reconcile balance: Error reconciling %s: %v", address, err)
+		h.writeError(w, r, http.StatusBadGateway, errCodeNodeUnavailable, err.Error())
+		return
+	}
+	result.Address = address.Checksum()
+
+	if err := h.writeJSON(w, r, http.StatusOK, result); err != nil {
+		h.logger.Printf("Reconcile balance: Error encoding response: %v", err)
+		return
+	}
+	h.logger.Printf("Reconcile balance: Address %s, Block %d, Reconciled: %v", address, block, result.Reconciled)
+}
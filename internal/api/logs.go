@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"eth-parser/internal/ethereum"
+	"eth-parser/pkg/models"
+	"net/http"
+)
+
+const (
+	errCodeInvalidFilter       = "invalid_filter"
+	errCodeUnknownSubscription = "unknown_subscription"
+)
+
+// SubscribeLogsHandler creates a log subscription from an eth_getLogs style
+// filter object.
+func (h *Handler) SubscribeLogsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Printf("Subscribe logs: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Subscribe logs")
+	if !ok {
+		return
+	}
+
+	var filter models.LogFilter
+	if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+		h.logger.Printf("Subscribe logs: Invalid filter: %v", err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidFilter, err.Error())
+		return
+	}
+	sub, err := parser.SubscribeLogs(filter)
+	if errors.Is(err, ethereum.ErrEmptyLogFilter) {
+		h.logger.Printf("Subscribe logs: %v", err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidFilter, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Printf("Subscribe logs: %v", err)
+		h.fail(w, r, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	h.auditChain(r, models.AuditSubscribeLogs, sub.ID, nil)
+
+	if err := h.writeJSON(w, r, createdStatus(r), sub); err != nil {
+		h.logger.Printf("Subscribe logs: Error encoding response: %v", err)
+		return
+	}
+	h.logger.Printf("Subscribe logs: Subscription %s", sub.ID)
+}
+
+func (h *Handler) UnsubscribeLogsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Printf("Unsubscribe logs: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Unsubscribe logs")
+	if !ok {
+		return
+	}
+
+	var req idRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Printf("Unsubscribe logs: Error decoding request: %v", err)
+		h.fail(w, r, http.StatusBadRequest, "Bad request")
+		return
+	}
+
+	success := parser.UnsubscribeLogs(req.ID)
+	if success {
+		h.auditChain(r, models.AuditUnsubscribeLogs, req.ID, nil)
+	}
+	response := changeResponse(r, "id", req.ID, "unsubscribed", success)
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
+		h.logger.Printf("Unsubscribe logs: Error encoding response: %v", err)
+		return
+	}
+	h.logger.Printf("Unsubscribe logs: Subscription %s, Success: %v", req.ID, success)
+}
+
+func (h *Handler) GetLogSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get log subscriptions: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Get log subscriptions")
+	if !ok {
+		return
+	}
+
+	subs := parser.GetLogSubscriptions()
+	if subs == nil {
+		subs = []models.LogSubscription{}
+	}
+	if err := h.writeJSON(w, r, http.StatusOK, map[string][]models.LogSubscription{"subscriptions": subs}); err != nil {
+		h.logger.Printf("Get log subscriptions: Error encoding response: %v", err)
+		return
+	}
+	h.logger.Printf("Get log subscriptions: Returned %d subscriptions", len(subs))
+}
+
+func (h *Handler) GetLogsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get logs: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Get logs")
+	if !ok {
+		return
+	}
+
+	id := r.URL.Query().Get("subscription")
+	logs, ok := parser.GetSubscriptionLogs(id)
+	if !ok {
+		h.logger.Printf("Get logs: Unknown subscription %q", id)
+		h.writeError(w, r, http.StatusNotFound, errCodeUnknownSubscription, "unknown subscription "+id)
+		return
+	}
+	if logs == nil {
+		logs = []models.Log{}
+	}
+
+	response := map[string]interface{}{"subscription": id, "logs": logs}
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
+		h.logger.Printf("Get logs: Error encoding response: %v", err)
+		return
+	}
+	h.logger.Printf("Get logs: Returned %d logs for subscription %s", len(logs), id)
+}
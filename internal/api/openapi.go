@@ -0,0 +1,298 @@
+package api
+
+import (
+	"encoding"
+	"encoding/json"
+	"eth-parser/common"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification the document
+// follows.
+const OpenAPIVersion = "3.0.3"
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// OpenAPI returns the OpenAPI document of /v1, generated from Routes. The
+// types of the request and response values are described by reflection,
+// following their JSON encoding; named structs become components.
+func (h *Handler) OpenAPI() map[string]interface{} {
+	s := &schemas{components: map[string]interface{}{}, types: map[string]reflect.Type{}}
+	paths := map[string]interface{}{}
+	for _, route := range h.Routes() {
+		item, ok := paths[route.Path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[route.Path] = item
+		}
+		item[strings.ToLower(route.Method)] = s.operation(route)
+	}
+
+	return map[string]interface{}{
+		"openapi": OpenAPIVersion,
+		"info": map[string]interface{}{
+			"title":   "Ethereum Parser API",
+			"version": "1",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": V1Prefix}},
+		"paths":   paths,
+		"security": []interface{}{
+			map[string]interface{}{"apiKey": []string{}},
+			map[string]interface{}{"bearer": []string{}},
+		},
+		"components": map[string]interface{}{
+			"schemas": s.components,
+			"responses": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"description": "The request was rejected",
+					"content":     jsonContent(s.schema(errorEnvelope{})),
+				},
+			},
+			"securitySchemes": map[string]interface{}{
+				"apiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": APIKeyHeader},
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document of /v1. It is a document in
+// its own right, so it is not wrapped in the envelope.
+func (h *Handler) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get OpenAPI document: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	w.Header().Set(common.HeaderContentTypeKey, common.ApplicationJsonContentType)
+	if err := json.NewEncoder(w).Encode(h.OpenAPI()); err != nil {
+		h.logger.Printf("Get OpenAPI document: Error encoding response: %v", err)
+		h.fail(w, r, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+}
+
+// schemas collects the components of a document as its operations are
+// described.
+type schemas struct {
+	components map[string]interface{}
+	types      map[string]reflect.Type
+}
+
+func (s *schemas) operation(route Route) map[string]interface{} {
+	params := []interface{}{}
+	for _, param := range route.Params {
+		params = append(params, parameter(param))
+	}
+	if route.Scoped {
+		params = append(params,
+			parameter(query("chain", "Chain name or ID, the first configured chain by default")),
+			parameter(query("tenant", "Tenant, the default one or that of the API key by default")),
+			parameter(Param{Name: TenantHeader, In: "header", Description: "Tenant, instead of the query parameter"}),
+		)
+	}
+
+	status, description := "200", "OK"
+	if route.Created {
+		status, description = "201", "Created, or 200 when nothing changed"
+	}
+	content := jsonContent(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"data":      s.schema(route.Response),
+			"requestId": map[string]interface{}{"type": "string"},
+		},
+		"required": []string{"data", "requestId"},
+	})
+	if len(route.Files) > 0 {
+		content = map[string]interface{}{}
+		for _, contentType := range route.Files {
+			schema := map[string]interface{}{"type": "string"}
+			if contentType == common.ApplicationJsonContentType {
+				schema = s.schema(route.Response)
+			}
+			content[contentType] = map[string]interface{}{"schema": schema}
+		}
+	}
+
+	operation := map[string]interface{}{
+		"operationId": operationID(route.Summary),
+		"summary":     route.Summary,
+		"parameters":  params,
+		"responses": map[string]interface{}{
+			status:    map[string]interface{}{"description": description, "content": content},
+			"default": map[string]interface{}{"$ref": "#/components/responses/Error"},
+		},
+		"x-role": route.Role,
+	}
+	if route.Expensive {
+		operation["x-expensive"] = true
+	}
+	if route.Request != nil {
+		body := jsonContent(s.schema(route.Request))
+		for _, contentType := range route.Uploads {
+			body[contentType] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+		}
+		operation["requestBody"] = map[string]interface{}{"required": true, "content": body}
+	}
+	return operation
+}
+
+func parameter(param Param) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        param.Name,
+		"in":          param.In,
+		"description": param.Description,
+		"required":    param.Required,
+		"schema":      map[string]interface{}{"type": "string"},
+	}
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{common.ApplicationJsonContentType: map[string]interface{}{"schema": schema}}
+}
+
+// operationID turns a summary like "Get Chains" into getChains.
+func operationID(summary string) string {
+	var b strings.Builder
+	for i, word := range strings.Fields(summary) {
+		if i == 0 {
+			b.WriteString(strings.ToLower(word))
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + strings.ToLower(word[1:]))
+	}
+	return b.String()
+}
+
+// schema describes v, which is an object or a oneOf, or otherwise stands
+// for its type.
+func (s *schemas) schema(v interface{}) map[string]interface{} {
+	switch v := v.(type) {
+	case object:
+		properties := map[string]interface{}{}
+		for name, property := range v {
+			properties[name] = s.schema(property)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	case oneOf:
+		alternatives := make([]interface{}, len(v))
+		for i, alternative := range v {
+			alternatives[i] = s.schema(alternative)
+		}
+		return map[string]interface{}{"oneOf": alternatives}
+	}
+	return s.typeSchema(reflect.TypeOf(v))
+}
+
+func (s *schemas) typeSchema(t reflect.Type) map[string]interface{} {
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]interface{}{}
+	case t.Implements(textMarshalerType) && !t.Implements(jsonMarshalerType):
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": s.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.typeSchema(t.Elem())}
+	case reflect.Pointer:
+		schema := s.typeSchema(t.Elem())
+		if _, ref := schema["$ref"]; ref {
+			return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		return s.component(t)
+	}
+	return map[string]interface{}{}
+}
+
+// component describes a named struct once, in the components of the
+// document, and refers to it.
+func (s *schemas) component(t reflect.Type) map[string]interface{} {
+	name := componentName(t)
+	if known, ok := s.types[name]; ok && known != t {
+		name = componentName(t) + "_" + t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+	}
+	ref := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	if _, ok := s.types[name]; ok {
+		return ref
+	}
+	s.types[name] = t
+	if t.Implements(jsonMarshalerType) {
+		// Its encoding is custom; all that can be said is that it is an
+		// object. docs/api.md describes its fields.
+		s.components[name] = map[string]interface{}{"type": "object"}
+		return ref
+	}
+	s.components[name] = s.structSchema(t)
+	return ref
+}
+
+func componentName(t reflect.Type) string {
+	name := []rune(t.Name())
+	name[0] = unicode.ToUpper(name[0])
+	return string(name)
+}
+
+// structSchema describes the fields of t as encoding/json writes them,
+// embedded structs included.
+func (s *schemas) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	s.addFields(properties, t)
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+func (s *schemas) addFields(properties map[string]interface{}, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.addFields(properties, field.Type)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = s.typeSchema(field.Type)
+	}
+}
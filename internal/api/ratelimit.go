@@ -0,0 +1,85 @@
+package api
+
+import (
+	"eth-parser/internal/ratelimit"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const errCodeRateLimited = "rate_limited"
+
+// SetRateLimits limits the requests of every API key, and of every client
+// address for requests without a valid one. Handlers wrapped with Expensive are
+// limited by expensive as well. Limits that are not enabled are not
+// enforced.
+func (h *Handler) SetRateLimits(limit, expensive ratelimit.Limit) {
+	h.limiter, h.expensiveLimiter = nil, nil
+	if limit.Enabled() {
+		h.limiter = ratelimit.NewLimiter(limit)
+	}
+	if expensive.Enabled() {
+		h.expensiveLimiter = ratelimit.NewLimiter(expensive)
+	}
+}
+
+// Limited wraps a handler that needs no API key, holding it to the limit
+// of the client address.
+func (h *Handler) Limited(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.allow(w, r, h.limiter) {
+			next(w, r)
+		}
+	}
+}
+
+// Expensive wraps a handler that is costly to serve, holding it to the
+// expensive limit on top of the one Require enforces. It goes inside
+// Require, which identifies the client.
+func (h *Handler) Expensive(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.allow(w, r, h.expensiveLimiter) {
+			next(w, r)
+		}
+	}
+}
+
+// allow takes a token for the client of r, sets the RateLimit headers and
+// writes an error response when the client has none left.
+func (h *Handler) allow(w http.ResponseWriter, r *http.Request, limiter *ratelimit.Limiter) bool {
+	if limiter == nil {
+		return true
+	}
+	client := clientOf(r)
+	result := limiter.Allow(client, time.Now())
+	limit := limiter.Limit()
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Burst, seconds(limit.Window())))
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+	if result.Allowed {
+		return true
+	}
+
+	h.logger.Printf("Rate limit: %s %s by %s, retry after %v", r.Method, r.URL.Path, client, result.RetryAfter)
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, seconds(result.RetryAfter))))
+	h.writeError(w, r, http.StatusTooManyRequests, errCodeRateLimited, "too many requests, retry after "+result.RetryAfter.Round(time.Millisecond).String())
+	return false
+}
+
+// clientOf names who is limited for a request: its API key once it is
+// authenticated, or else the address it came from. Proxies in front of the
+// service make every request come from the proxy.
+func clientOf(r *http.Request) string {
+	if key, ok := requestKey(r); ok {
+		return "key:" + key.ID
+	}
+	return "ip:" + remoteHost(r)
+}
+
+// seconds rounds d up to whole seconds, as the headers carry them.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"eth-parser/common"
+	"net/http"
+)
+
+// V1Prefix is the path prefix of the versioned API.
+const V1Prefix = "/v1"
+
+type versionContextKey struct{}
+
+// envelope is the body of every successful /v1 response.
+type envelope struct {
+	Data      interface{} `json:"data"`
+	RequestID string      `json:"requestId,omitempty"`
+}
+
+// V1 wraps next so that it answers in the /v1 format: successes in an
+// envelope with the request ID, errors with the error envelope.
+func (h *Handler) V1(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r = withRequestID(w, r)
+		next(w, r.WithContext(context.WithValue(r.Context(), versionContextKey{}, true)))
+	}
+}
+
+// isV1 reports whether r came in through the versioned API.
+func isV1(r *http.Request) bool {
+	v1, _ := r.Context().Value(versionContextKey{}).(bool)
+	return v1
+}
+
+// Deprecated wraps next, served at a legacy path, to point clients at its
+// /v1 successor. The legacy body is left as it was.
+func (h *Handler) Deprecated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+V1Prefix+r.URL.Path+`>; rel="successor-version"`)
+		next(w, r)
+	}
+}
+
+// writeJSON writes a successful response: v as it is on the legacy routes,
+// in the envelope on /v1.
+func (h *Handler) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	if isV1(r) {
+		v = envelope{Data: v, RequestID: requestID(r)}
+	}
+	w.Header().Set(common.HeaderContentTypeKey, common.ApplicationJsonContentType)
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
+
+// createdStatus is the status of a response to a request that created
+// something: 201 on /v1. The legacy routes always answered 200.
+func createdStatus(r *http.Request) int {
+	if isV1(r) {
+		return http.StatusCreated
+	}
+	return http.StatusOK
+}
+
+// changeResponse reports whether a request changed the thing named by key
+// and id. The legacy routes name the flag after the action, such as
+// subscribed; on /v1 it is always changed.
+func changeResponse(r *http.Request, key, id, action string, changed bool) map[string]interface{} {
+	if isV1(r) {
+		action = "changed"
+	}
+	return map[string]interface{}{key: id, action: changed}
+}
+
+// NotFoundHandler answers /v1 paths that match no route.
+func (h *Handler) NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Printf("Not found: %s %s", r.Method, r.URL.Path)
+	h.writeError(w, r, http.StatusNotFound, errCodeNotFound, "no such endpoint: "+r.URL.Path)
+}
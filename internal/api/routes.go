@@ -0,0 +1,303 @@
+package api
+
+import (
+	"encoding/json"
+	"eth-parser/common"
+	"eth-parser/pkg/models"
+	"net/http"
+)
+
+// Route is an endpoint of the API. It is served under /v1 and, deprecated,
+// at its legacy path without the prefix. The OpenAPI document is generated
+// from the same table, so it describes exactly what is served.
+type Route struct {
+	Method  string
+	Path    string
+	Summary string
+	// Role is the least role allowed to call the route.
+	Role models.Role
+	// Expensive routes are held to the expensive rate limit as well.
+	Expensive bool
+	// Scoped routes act on the chain and tenant of the request.
+	Scoped bool
+	Params []Param
+	// Request is a value of the type of the request body, nil without one.
+	Request interface{}
+	// Uploads lists the content types a request body may have besides JSON.
+	Uploads []string
+	// Response is a value of the type of the data in the response.
+	Response interface{}
+	// Created is set when a successful request creates something, which /v1
+	// answers with 201.
+	Created bool
+	// Files lists the content types of a route that answers with a file
+	// rather than an envelope.
+	Files   []string
+	Handler http.HandlerFunc
+}
+
+// Param is a path or query parameter of a route.
+type Param struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+}
+
+func query(name, description string) Param {
+	return Param{Name: name, In: "query", Description: description}
+}
+
+var addressPath = Param{Name: "addr", In: "path", Description: "Address of the account", Required: true}
+
+// object describes a JSON object by example: each value stands for the type
+// of the property of its name.
+type object map[string]interface{}
+
+// oneOf describes data that takes one of several shapes.
+type oneOf []interface{}
+
+// Routes returns every endpoint of the API, apart from the health check,
+// which is not versioned.
+func (h *Handler) Routes() []Route {
+	changed := func(key string) object {
+		return object{key: "", "changed": false}
+	}
+	bulk := bulkResponse{}
+	return []Route{
+		{
+			Method: http.MethodGet, Path: "/chains", Summary: "Get Chains", Role: models.RoleReadOnly,
+			Response: object{"chains": []chainResponse{}},
+			Handler:  h.GetChainsHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/current-block", Summary: "Get Current Block", Role: models.RoleReadOnly, Scoped: true,
+			Response: object{"currentBlock": int64(0)},
+			Handler:  h.GetCurrentBlockHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/subscribe-list", Summary: "Get Subscribe List", Role: models.RoleReadOnly, Scoped: true,
+			Params: []Param{
+				query("tag", "Only addresses with this tag"),
+				query("metadata", "true to add the subscriptions themselves"),
+			},
+			Response: object{"subscribedAddresses": []string{}, "subscriptions": []models.Subscription{}},
+			Handler:  h.GetSubscribeListHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/subscribe", Summary: "Subscribe Address", Role: models.RoleSubscriber, Scoped: true,
+			Request: models.Subscription{}, Response: changed("address"), Created: true,
+			Handler: h.SubscribeHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/subscriptions/update", Summary: "Update Subscription", Role: models.RoleSubscriber, Scoped: true,
+			Request: models.Subscription{}, Response: models.Subscription{},
+			Handler: h.UpdateSubscriptionHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/unsubscribe", Summary: "Unsubscribe Address", Role: models.RoleSubscriber, Scoped: true,
+			Request: addressRequest{}, Response: changed("address"),
+			Handler: h.UnsubscribeHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/subscribe/bulk", Summary: "Bulk Subscribe", Role: models.RoleSubscriber, Expensive: true, Scoped: true,
+			Params:  []Param{query("atomic", "true to apply all rows or none")},
+			Request: oneOf{[]models.Subscription{}, []string{}}, Uploads: []string{common.TextCsvContentType}, Response: bulk,
+			Handler: h.BulkSubscribeHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/unsubscribe/bulk", Summary: "Bulk Unsubscribe", Role: models.RoleSubscriber, Expensive: true, Scoped: true,
+			Params:  []Param{query("atomic", "true to apply all rows or none")},
+			Request: oneOf{[]addressRequest{}, []string{}}, Uploads: []string{common.TextCsvContentType}, Response: bulk,
+			Handler: h.BulkUnsubscribeHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/subscriptions/export", Summary: "Export Subscriptions", Role: models.RoleReadOnly, Expensive: true, Scoped: true,
+			Params: []Param{
+				query("format", "json (the default) or csv"),
+				query("tag", "Only subscriptions with this tag"),
+			},
+			Response: []models.Subscription{}, Files: []string{common.ApplicationJsonContentType, common.TextCsvContentType},
+			Handler: h.ExportSubscriptionsHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/transactions", Summary: "Get Transactions", Role: models.RoleReadOnly, Expensive: true, Scoped: true,
+			Params: []Param{
+				query("address", "Subscribed address"),
+				query("tag", "Subscription tag, instead of an address"),
+				query("format", "hex (the default) or decimal"),
+			},
+			Response: object{"transactions": oneOf{[]models.Transaction{}, []models.DecodedTransaction{}}, "undecodable": []string{}},
+			Handler:  h.GetTransactionsHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/abi/register", Summary: "Register Contract ABI", Role: models.RoleGlobalAdmin, Scoped: true,
+			Request: registerABIRequest{}, Response: changed("address"),
+			Handler: h.RegisterABIHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/abi", Summary: "Get Contract ABI", Role: models.RoleReadOnly, Scoped: true,
+			Params:   []Param{{Name: "address", In: "query", Description: "Contract address", Required: true}},
+			Response: object{"address": "", "abi": json.RawMessage{}},
+			Handler:  h.GetABIHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/abi/unregister", Summary: "Unregister Contract ABI", Role: models.RoleGlobalAdmin, Scoped: true,
+			Request: addressRequest{}, Response: changed("address"),
+			Handler: h.UnregisterABIHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/logs/subscribe", Summary: "Subscribe to Logs", Role: models.RoleSubscriber, Scoped: true,
+			Request: models.LogFilter{}, Response: models.LogSubscription{}, Created: true,
+			Handler: h.SubscribeLogsHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/logs/unsubscribe", Summary: "Unsubscribe from Logs", Role: models.RoleSubscriber, Scoped: true,
+			Request: idRequest{}, Response: changed("id"),
+			Handler: h.UnsubscribeLogsHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/logs/subscriptions", Summary: "Get Log Subscriptions", Role: models.RoleReadOnly, Scoped: true,
+			Response: object{"subscriptions": []models.LogSubscription{}},
+			Handler:  h.GetLogSubscriptionsHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/logs", Summary: "Get Logs", Role: models.RoleReadOnly, Scoped: true,
+			Params:   []Param{{Name: "subscription", In: "query", Description: "Log subscription ID", Required: true}},
+			Response: object{"subscription": "", "logs": []models.Log{}},
+			Handler:  h.GetLogsHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/token", Summary: "Get Token", Role: models.RoleReadOnly, Scoped: true,
+			Params:   []Param{{Name: "address", In: "query", Description: "Token contract address", Required: true}},
+			Response: models.Token{},
+			Handler:  h.GetTokenHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/tokens", Summary: "Get Tokens", Role: models.RoleReadOnly, Scoped: true,
+			Response: object{"tokens": []models.Token{}},
+			Handler:  h.GetTokensHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/addresses/{addr}/balance-history", Summary: "Get Balance History", Role: models.RoleReadOnly, Scoped: true,
+			Params:   []Param{addressPath},
+			Response: object{"address": "", "balance": "", "entries": []models.LedgerEntry{}},
+			Handler:  h.GetBalanceHistoryHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/addresses/{addr}/reconcile", Summary: "Reconcile Balance", Role: models.RoleReadOnly, Expensive: true, Scoped: true,
+			Params:   []Param{addressPath, query("block", "Block to reconcile at, the last processed one by default")},
+			Response: models.Reconciliation{},
+			Handler:  h.ReconcileBalanceHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/addresses/{addr}/snapshots", Summary: "Get Balance Snapshots", Role: models.RoleReadOnly, Scoped: true,
+			Params:   []Param{addressPath, query("token", "Only snapshots of this token")},
+			Response: object{"address": "", "token": "", "snapshots": []models.BalanceSnapshot{}},
+			Handler:  h.GetBalanceSnapshotsHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/drifts", Summary: "Get Balance Drifts", Role: models.RoleReadOnly, Scoped: true,
+			Params:   []Param{query("address", "Only drifts of this address")},
+			Response: object{"drifts": []models.BalanceDrift{}},
+			Handler:  h.GetBalanceDriftsHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/rules/create", Summary: "Create Alert Rule", Role: models.RoleAdmin, Scoped: true,
+			Request: models.AlertRule{}, Response: models.AlertRule{}, Created: true,
+			Handler: h.CreateAlertRuleHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/rules/update", Summary: "Update Alert Rule", Role: models.RoleAdmin, Scoped: true,
+			Request: models.AlertRule{}, Response: models.AlertRule{},
+			Handler: h.UpdateAlertRuleHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/rules/delete", Summary: "Delete Alert Rule", Role: models.RoleAdmin, Scoped: true,
+			Request: idRequest{}, Response: changed("id"),
+			Handler: h.DeleteAlertRuleHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/rules", Summary: "Get Alert Rules", Role: models.RoleReadOnly, Scoped: true,
+			Params:   []Param{query("id", "Return just this rule")},
+			Response: oneOf{object{"rules": []models.AlertRule{}}, models.AlertRule{}},
+			Handler:  h.GetAlertRulesHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/alerts", Summary: "Get Alerts", Role: models.RoleReadOnly, Scoped: true,
+			Params: []Param{
+				query("rule", "Only alerts of this rule"),
+				query("severity", "Only alerts of this severity"),
+				query("address", "Only alerts about this address"),
+				query("active", "true for the alerts still active only"),
+			},
+			Response: object{"alerts": []models.Alert{}},
+			Handler:  h.GetAlertsHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/keys/create", Summary: "Create API Key", Role: models.RoleAdmin,
+			Request: createKeyRequest{}, Response: createdKey{}, Created: true,
+			Handler: h.CreateAPIKeyHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/keys", Summary: "Get API Keys", Role: models.RoleAdmin,
+			Response: object{"keys": []models.APIKey{}},
+			Handler:  h.GetAPIKeysHandler,
+		},
+		{
+			Method: http.MethodPost, Path: "/keys/revoke", Summary: "Revoke API Key", Role: models.RoleAdmin,
+			Request: idRequest{}, Response: models.APIKey{},
+			Handler: h.RevokeAPIKeyHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/audit", Summary: "Get Audit Records", Role: models.RoleGlobalAdmin,
+			Params: []Param{
+				query("actor", "Only records of this API key ID"),
+				query("action", "Only records of this action"),
+				query("target", "Only records about this target"),
+				query("tenant", "Only records of this tenant"),
+				query("chain", "Only records of this chain ID"),
+				query("since", "Only records made at or after this time (RFC 3339)"),
+				query("until", "Only records made before this time (RFC 3339)"),
+				query("limit", "Return only this many of the newest records"),
+			},
+			Response: object{"records": []models.AuditRecord{}},
+			Handler:  h.GetAuditRecordsHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/audit/export", Summary: "Export Audit Records", Role: models.RoleGlobalAdmin, Expensive: true,
+			Params:   []Param{query("after", "Only records after this sequence number")},
+			Response: models.AuditRecord{}, Files: []string{common.ApplicationJsonLinesContentType},
+			Handler: h.ExportAuditRecordsHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/audit/verify", Summary: "Verify Audit Log", Role: models.RoleGlobalAdmin,
+			Response: auditVerification{},
+			Handler:  h.VerifyAuditLogHandler,
+		},
+		{
+			Method: http.MethodGet, Path: "/rejected-blocks", Summary: "Get Rejected Blocks", Role: models.RoleReadOnly, Scoped: true,
+			Response: object{"rejectedBlocks": []models.RejectedBlock{}},
+			Handler:  h.GetRejectedBlocksHandler,
+		},
+	}
+}
+
+// Register serves the API on mux: the health check, every route under /v1
+// and at its deprecated legacy path, and the OpenAPI document of /v1.
+// Methods are checked by the handlers rather than the patterns, so that a
+// wrong one is answered in the format of the version.
+func (h *Handler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/health", h.Limited(h.HealthCheckHandler))
+	for _, route := range h.Routes() {
+		handler := route.Handler
+		if route.Expensive {
+			handler = h.Expensive(handler)
+		}
+		handler = h.Require(route.Role, handler)
+		mux.HandleFunc(V1Prefix+route.Path, h.V1(handler))
+		mux.HandleFunc(route.Path, h.Deprecated(handler))
+	}
+	mux.HandleFunc(V1Prefix+"/openapi.json", h.V1(h.Limited(h.OpenAPIHandler)))
+	mux.HandleFunc(V1Prefix+"/", h.V1(h.Limited(h.NotFoundHandler)))
+}
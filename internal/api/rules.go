@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"eth-parser/internal/ethereum"
+	"eth-parser/pkg/models"
+	"net/http"
+)
+
+const (
+	errCodeInvalidRule = "invalid_rule"
+	errCodeUnknownRule = "unknown_rule"
+)
+
+// GetAlertRulesHandler lists the alert rules, or returns the one named by
+// the id query parameter.
+func (h *Handler) GetAlertRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get alert rules: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Get alert rules")
+	if !ok {
+		return
+	}
+
+	var response interface{}
+	if id := r.URL.Query().Get("id"); id != "" {
+		rule, ok := parser.GetAlertRule(id)
+		if !ok {
+			h.logger.Printf("Get alert rules: Unknown rule %q", id)
+			h.writeError(w, r, http.StatusNotFound, errCodeUnknownRule, "unknown alert rule "+id)
+			return
+		}
+		response = rule
+	} else {
+		rules := parser.GetAlertRules()
+		if rules == nil {
+			rules = []models.AlertRule{}
+		}
+		response = map[string][]models.AlertRule{"rules": rules}
+	}
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
+		h.logger.Printf("Get alert rules: Error encoding response: %v", err)
+		return
+	}
+}
+
+func (h *Handler) CreateAlertRuleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Printf("Create alert rule: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Create alert rule")
+	if !ok {
+		return
+	}
+
+	var rule models.AlertRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		h.logger.Printf("Create alert rule: Invalid rule: %v", err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidRule, err.Error())
+		return
+	}
+	rule, err := parser.CreateAlertRule(rule)
+	if err != nil {
+		h.writeRuleError(w, r, "Create alert rule", err)
+		return
+	}
+	h.auditChain(r, models.AuditCreateRule, rule.ID, map[string]string{"name": rule.Name, "type": string(rule.Type)})
+
+	if err := h.writeJSON(w, r, createdStatus(r), rule); err != nil {
+		h.logger.Printf("Create alert rule: Error encoding response: %v", err)
+		return
+	}
+	h.logger.Printf("Create alert rule: Rule %s", rule.ID)
+}
+
+// UpdateAlertRuleHandler replaces the rule whose ID is in the request body.
+func (h *Handler) UpdateAlertRuleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Printf("Update alert rule: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Update alert rule")
+	if !ok {
+		return
+	}
+
+	var rule models.AlertRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		h.logger.Printf("Update alert rule: Invalid rule: %v", err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidRule, err.Error())
+		return
+	}
+	rule, err := parser.UpdateAlertRule(rule.ID, rule)
+	if err != nil {
+		h.writeRuleError(w, r, "Update alert rule", err)
+		return
+	}
+	h.auditChain(r, models.AuditUpdateRule, rule.ID, map[string]string{"name": rule.Name, "type": string(rule.Type)})
+
+	if err := h.writeJSON(w, r, http.StatusOK, rule); err != nil {
+		h.logger.Printf("Update alert rule: Error encoding response: %v", err)
+		return
+	}
+	h.logger.Printf("Update alert rule: Rule %s", rule.ID)
+}
+
+func (h *Handler) writeRuleError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	h.logger.Printf("%s: %v", op, err)
+	switch {
+	case errors.Is(err, models.ErrInvalidRule):
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidRule, err.Error())
+	case errors.Is(err, ethereum.ErrUnknownRule):
+		h.writeError(w, r, http.StatusNotFound, errCodeUnknownRule, err.Error())
+	default:
+		h.fail(w, r, http.StatusInternalServerError, "Internal server error")
+	}
+}
+
+func (h *Handler) DeleteAlertRuleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.logger.Printf("Delete alert rule: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Delete alert rule")
+	if !ok {
+		return
+	}
+
+	var req idRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Printf("Delete alert rule: Error decoding request: %v", err)
+		h.fail(w, r, http.StatusBadRequest, "Bad request")
+		return
+	}
+
+	success := parser.DeleteAlertRule(req.ID)
+	if success {
+		h.auditChain(r, models.AuditDeleteRule, req.ID, nil)
+	}
+	response := changeResponse(r, "id", req.ID, "deleted", success)
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
+		h.logger.Printf("Delete alert rule: Error encoding response: %v", err)
+		return
+	}
+	h.logger.Printf("Delete alert rule: Rule %s, Success: %v", req.ID, success)
+}
+
+// GetAlertsHandler lists raised alerts, optionally only those of the rule,
+// severity and address query parameters, and with active=true only those
+// not resolved.
+func (h *Handler) GetAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get alerts: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Get alerts")
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	var address *models.Address
+	if rawAddress := query.Get("address"); rawAddress != "" {
+		parsed, err := models.ParseAddress(rawAddress)
+		if err != nil {
+			h.logger.Printf("Get alerts: Invalid address %q: %v", rawAddress, err)
+			h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
+			return
+		}
+		address = &parsed
+	}
+	ruleID := query.Get("rule")
+	severity := models.AlertSeverity(query.Get("severity"))
+	activeOnly := query.Get("active") == "true"
+
+	alerts := []models.Alert{}
+	for _, alert := range parser.GetAlerts() {
+		if ruleID != "" && alert.RuleID != ruleID ||
+			severity != "" && alert.Severity != severity ||
+			activeOnly && alert.ResolvedAt != nil {
+			continue
+		}
+		if address != nil {
+			if parsed, err := models.HexToAddress(alert.Address); err != nil || parsed != *address {
+				continue
+			}
+		}
+		alerts = append(alerts, alert)
+	}
+	if err := h.writeJSON(w, r, http.StatusOK, map[string][]models.Alert{"alerts": alerts}); err != nil {
+		h.logger.Printf("Get alerts: Error encoding response: %v", err)
+		return
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"eth-parser/pkg/models"
+	"net/http"
+)
+
+// GetBalanceSnapshotsHandler returns the balance series of the address in
+// the path, in ETH or in the token given by the token query parameter.
+func (h *Handler) GetBalanceSnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get balance snapshots: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Get balance snapshots")
+	if !ok {
+		return
+	}
+
+	rawAddress := r.PathValue("addr")
+	address, err := models.ParseAddress(rawAddress)
+	if err != nil {
+		h.logger.Printf("Get balance snapshots: Invalid address %q: %v", rawAddress, err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
+		return
+	}
+	token := ""
+	if rawToken := r.URL.Query().Get("token"); rawToken != "" {
+		parsed, err := models.ParseAddress(rawToken)
+		if err != nil {
+			h.logger.Printf("Get balance snapshots: Invalid token %q: %v", rawToken, err)
+			h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
+			return
+		}
+		token = parsed.Checksum()
+	}
+
+	snapshots := parser.GetBalanceSnapshots(address.String(), token)
+	for i := range snapshots {
+		snapshots[i].Address = address.Checksum()
+	}
+	if snapshots == nil {
+		snapshots = []models.BalanceSnapshot{}
+	}
+	response := map[string]interface{}{"address": address.Checksum(), "snapshots": snapshots}
+	if token != "" {
+		response["token"] = token
+	}
+	if err := h.writeJSON(w, r, http.StatusOK, response); err != nil {
+		h.logger.Printf("Get balance snapshots: Error encoding response: %v", err)
+		return
+	}
+}
+
+// GetBalanceDriftsHandler lists balance changes that no recorded transaction
+// explains, optionally only those of the address query parameter.
+func (h *Handler) GetBalanceDriftsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get balance drifts: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Get balance drifts")
+	if !ok {
+		return
+	}
+
+	var filter *models.Address
+	if rawAddress := r.URL.Query().Get("address"); rawAddress != "" {
+		address, err := models.ParseAddress(rawAddress)
+		if err != nil {
+			h.logger.Printf("Get balance drifts: Invalid address %q: %v", rawAddress, err)
+			h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
+			return
+		}
+		filter = &address
+	}
+
+	drifts := []models.BalanceDrift{}
+	for _, drift := range parser.GetBalanceDrifts() {
+		address, err := models.HexToAddress(drift.Address)
+		if err != nil || filter != nil && address != *filter {
+			continue
+		}
+		drift.Address = address.Checksum()
+		drifts = append(drifts, drift)
+	}
+	if err := h.writeJSON(w, r, http.StatusOK, map[string][]models.BalanceDrift{"drifts": drifts}); err != nil {
+		h.logger.Printf("Get balance drifts: Error encoding response: %v", err)
+		return
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"eth-parser/internal/storage"
+	"eth-parser/pkg/models"
+	"fmt"
+	"net/http"
+)
+
+const (
+	errCodeInvalidTenant = "invalid_tenant"
+	errCodeQuotaExceeded = "quota_exceeded"
+)
+
+// TenantHeader names the tenant a request acts for. The tenant query
+// parameter does the same for clients that cannot set headers.
+const TenantHeader = "X-Tenant"
+
+// errTenantForbidden rejects requests naming a tenant their API key is not
+// bound to.
+var errTenantForbidden = errors.New("tenant not allowed for this API key")
+
+// resolveTenant returns the tenant of the request, the default tenant when
+// none is named. Requests made with an API key act for the key's tenant;
+// only global admin keys may name another.
+func resolveTenant(r *http.Request) (string, error) {
+	tenant := r.Header.Get(TenantHeader)
+	if tenant == "" {
+		tenant = r.URL.Query().Get("tenant")
+	}
+	if key, ok := requestKey(r); ok {
+		if tenant == "" {
+			return key.Tenant, nil
+		}
+		if tenant != key.Tenant && !key.Role.Includes(models.RoleGlobalAdmin) {
+			return "", fmt.Errorf("%w: key %s is bound to tenant %s", errTenantForbidden, key.ID, key.Tenant)
+		}
+	}
+	if tenant == "" {
+		return storage.DefaultTenant, nil
+	}
+	if err := models.ValidateTenant(tenant); err != nil {
+		return "", err
+	}
+	return tenant, nil
+}
+
+// keyTenant returns the tenant whose API keys the request may manage, and
+// false for global admins and when authentication is off, who may manage
+// the keys of every tenant.
+func keyTenant(r *http.Request) (string, bool) {
+	key, ok := requestKey(r)
+	if !ok || key.Role.Includes(models.RoleGlobalAdmin) {
+		return "", false
+	}
+	return key.Tenant, true
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"eth-parser/pkg/models"
+	"net/http"
+)
+
+const errCodeNodeUnavailable = "node_unavailable"
+
+// GetTokenHandler returns the metadata of an ERC-20 token, reading it from
+// the contract if the token has not been seen before.
+func (h *Handler) GetTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get token: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Get token")
+	if !ok {
+		return
+	}
+
+	rawAddress := r.URL.Query().Get("address")
+	address, err := models.ParseAddress(rawAddress)
+	if err != nil {
+		h.logger.Printf("Get token: Invalid address %q: %v", rawAddress, err)
+		h.writeError(w, r, http.StatusBadRequest, errCodeInvalidAddress, err.Error())
+		return
+	}
+	token, err := parser.GetToken(address.String())
+	if err != nil {
+		h.logger.Printf("Get token: Error resolving %s: %v", address, err)
+		h.writeError(w, r, http.StatusBadGateway, errCodeNodeUnavailable, err.Error())
+		return
+	}
+
+	if err := h.writeJSON(w, r, http.StatusOK, token); err != nil {
+		h.logger.Printf("Get token: Error encoding response: %v", err)
+		return
+	}
+}
+
+// GetTokensHandler lists the tokens whose metadata has been resolved.
+func (h *Handler) GetTokensHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.logger.Printf("Get tokens: Method not allowed: %s", r.Method)
+		h.fail(w, r, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	parser, ok := h.chainParser(w, r, "Get tokens")
+	if !ok {
+		return
+	}
+
+	tokens := parser.GetTokens()
+	if tokens == nil {
+		tokens = []models.Token{}
+	}
+	if err := h.writeJSON(w, r, http.StatusOK, map[string][]models.Token{"tokens": tokens}); err != nil {
+		h.logger.Printf("Get tokens: Error encoding response: %v", err)
+		return
+	}
+}
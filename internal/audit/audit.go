@@ -0,0 +1,47 @@
+// Package audit keeps the append-only trail of changes made through the API.
+package audit
+
+import (
+	"eth-parser/internal/storage"
+	"eth-parser/pkg/models"
+	"log"
+	"time"
+)
+
+// Log appends audit records to storage, where each is chained to the one
+// before it by hash.
+type Log struct {
+	storage storage.Storage
+	logger  *log.Logger
+}
+
+func NewLog(storage storage.Storage, logger *log.Logger) *Log {
+	return &Log{storage: storage, logger: logger}
+}
+
+// Record appends record, stamped with the current time, and returns it as
+// stored.
+func (l *Log) Record(record models.AuditRecord) models.AuditRecord {
+	record.Time = time.Now().UTC()
+	record = l.storage.AppendAuditRecord(record)
+	l.logger.Printf("Audit record %d: %s %s by %s from %s, request %s", record.Sequence, record.Action, record.Target, record.Actor, record.IP, record.RequestID)
+	return record
+}
+
+// Records returns the records after sequence number after, oldest first.
+func (l *Log) Records(after int64) []models.AuditRecord {
+	return l.storage.GetAuditRecords(after)
+}
+
+// Verify checks the whole chain and returns its newest record, which is
+// zero for an empty trail.
+func (l *Log) Verify() (models.AuditRecord, error) {
+	records := l.storage.GetAuditRecords(0)
+	if err := models.VerifyAuditChain(records, models.AuditGenesisHash); err != nil {
+		return models.AuditRecord{}, err
+	}
+	if len(records) == 0 {
+		return models.AuditRecord{}, nil
+	}
+	return records[len(records)-1], nil
+}
@@ -0,0 +1,156 @@
+// Package auth issues API keys and authenticates requests with them.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"eth-parser/internal/storage"
+	"eth-parser/pkg/models"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid API key")
+	ErrRevokedKey = errors.New("API key revoked")
+	ErrUnknownKey = errors.New("unknown API key")
+)
+
+// secretPrefix starts every issued secret so that leaked keys are easy to
+// recognize, e.g. by secret scanners.
+const secretPrefix = "ethp_"
+
+// prefixLength is how much of a secret is kept in the clear to tell keys
+// apart.
+const prefixLength = len(secretPrefix) + 8
+
+// minBootstrapLength is the shortest secret accepted for the bootstrap key.
+const minBootstrapLength = 32
+
+// Authenticator issues, checks and revokes API keys. Keys are stored by the
+// SHA-256 hash of their secret, which is only returned when the key is
+// created. Secrets are random, so a fast hash is enough.
+type Authenticator struct {
+	storage storage.Storage
+	logger  *log.Logger
+}
+
+func NewAuthenticator(storage storage.Storage, logger *log.Logger) *Authenticator {
+	return &Authenticator{storage: storage, logger: logger}
+}
+
+// Bootstrap registers secret as a global admin key named bootstrap, so that
+// the first keys can be created. Registering the same secret again does
+// nothing.
+func (a *Authenticator) Bootstrap(secret string) error {
+	if len(secret) < minBootstrapLength {
+		return fmt.Errorf("%w: bootstrap key must be at least %d characters", ErrInvalidKey, minBootstrapLength)
+	}
+	if _, ok := a.storage.GetAPIKeyByHash(hashSecret(secret)); ok {
+		return nil
+	}
+	_, err := a.addKey("bootstrap", models.RoleGlobalAdmin, storage.DefaultTenant, secret)
+	return err
+}
+
+// CreateKey issues a key with role for tenant and returns it with its
+// secret.
+func (a *Authenticator) CreateKey(name string, role models.Role, tenant string) (models.APIKey, string, error) {
+	if _, err := models.ParseRole(string(role)); err != nil {
+		return models.APIKey{}, "", err
+	}
+	if tenant == "" {
+		tenant = storage.DefaultTenant
+	}
+	if err := models.ValidateTenant(tenant); err != nil {
+		return models.APIKey{}, "", err
+	}
+	random, err := randomHex(24)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	secret := secretPrefix + random
+	key, err := a.addKey(name, role, tenant, secret)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	return key, secret, nil
+}
+
+func (a *Authenticator) addKey(name string, role models.Role, tenant, secret string) (models.APIKey, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	if name == "" {
+		name = id
+	}
+	key := models.APIKey{
+		ID:        id,
+		Name:      name,
+		Role:      role,
+		Tenant:    tenant,
+		Prefix:    secret[:min(prefixLength, len(secret))],
+		Hash:      hashSecret(secret),
+		CreatedAt: time.Now().UTC(),
+	}
+	a.storage.AddAPIKey(key)
+	a.logger.Printf("Created API key %s (%s), role: %s, tenant: %s", key.ID, key.Name, key.Role, key.Tenant)
+	return key, nil
+}
+
+// Authenticate returns the key whose secret is secret and records its use.
+func (a *Authenticator) Authenticate(secret string) (models.APIKey, error) {
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return models.APIKey{}, fmt.Errorf("%w: no key given", ErrInvalidKey)
+	}
+	key, ok := a.storage.GetAPIKeyByHash(hashSecret(secret))
+	if !ok {
+		return models.APIKey{}, ErrInvalidKey
+	}
+	if key.RevokedAt != nil {
+		return models.APIKey{}, fmt.Errorf("%w: %s", ErrRevokedKey, key.ID)
+	}
+	now := time.Now().UTC()
+	key.LastUsedAt = &now
+	a.storage.TouchAPIKey(key.ID, now)
+	return key, nil
+}
+
+// GetKey returns the key with id.
+func (a *Authenticator) GetKey(id string) (models.APIKey, bool) {
+	return a.storage.GetAPIKey(id)
+}
+
+// RevokeKey revokes a key for good. Revoked keys stay listed.
+func (a *Authenticator) RevokeKey(id string) (models.APIKey, error) {
+	key, ok := a.storage.RevokeAPIKey(id, time.Now().UTC())
+	if !ok {
+		return models.APIKey{}, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	a.logger.Printf("Revoked API key %s (%s)", key.ID, key.Name)
+	return key, nil
+}
+
+// GetKeys returns every key, oldest first.
+func (a *Authenticator) GetKeys() []models.APIKey {
+	return a.storage.GetAPIKeys()
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
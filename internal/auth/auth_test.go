@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"errors"
+	"eth-parser/internal/storage"
+	"eth-parser/pkg/models"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestAuthenticator(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	a := NewAuthenticator(store, log.New(io.Discard, "", 0))
+
+	if err := a.Bootstrap("short"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("short bootstrap key: %v", err)
+	}
+	bootstrap := strings.Repeat("b", minBootstrapLength)
+	if err := a.Bootstrap(bootstrap); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Bootstrap(bootstrap); err != nil || len(a.GetKeys()) != 1 {
+		t.Errorf("bootstrapping twice should keep one key, got %d, %v", len(a.GetKeys()), err)
+	}
+	if key, err := a.Authenticate(bootstrap); err != nil || key.Role != models.RoleGlobalAdmin {
+		t.Errorf("bootstrap key = %+v, %v", key, err)
+	}
+
+	key, secret, err := a.CreateKey("ci", models.RoleReadOnly, "team-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, secretPrefix) || !strings.HasPrefix(secret, key.Prefix) {
+		t.Errorf("secret %q, prefix %q", secret, key.Prefix)
+	}
+	for _, stored := range store.GetAPIKeys() {
+		if stored.Hash == secret || strings.Contains(stored.Hash, secret) {
+			t.Error("the secret should not be stored")
+		}
+	}
+	authenticated, err := a.Authenticate(secret)
+	if err != nil || authenticated.ID != key.ID || authenticated.Tenant != "team-a" || authenticated.LastUsedAt == nil {
+		t.Errorf("Authenticate = %+v, %v", authenticated, err)
+	}
+	if _, err := a.Authenticate(secret + "x"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("wrong secret: %v", err)
+	}
+
+	if _, _, err := a.CreateKey("", "owner", ""); !errors.Is(err, models.ErrInvalidRole) {
+		t.Errorf("unknown role: %v", err)
+	}
+	if _, _, err := a.CreateKey("", models.RoleSubscriber, "Team A"); !errors.Is(err, models.ErrInvalidTenant) {
+		t.Errorf("invalid tenant: %v", err)
+	}
+
+	if _, err := a.RevokeKey(key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authenticate(secret); !errors.Is(err, ErrRevokedKey) {
+		t.Errorf("revoked key: %v", err)
+	}
+	if _, err := a.RevokeKey("missing"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("revoke of a missing key: %v", err)
+	}
+}
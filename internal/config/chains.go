@@ -0,0 +1,137 @@
+package config
+
+import (
+	"encoding/json"
+	"eth-parser/pkg/models"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ChainConfig describes one chain the service watches.
+type ChainConfig struct {
+	// Name identifies the chain in the API, e.g. "ethereum" or "base".
+	Name    string
+	ChainID uint64
+	// RPCURLs lists the primary JSON-RPC endpoint followed by fallbacks.
+	RPCURLs   []string
+	BlockTime time.Duration
+	// Confirmations is how many blocks the parser stays behind the head.
+	Confirmations int64
+	// SnapshotInterval is how many blocks apart the balances of subscribed
+	// addresses are snapshotted, 0 to disable; SnapshotTokens are the ERC-20
+	// contracts whose balances are snapshotted next to ETH.
+	SnapshotInterval int64
+	SnapshotTokens   []string
+	// BlobSchedule lists the blob forks of the chain in activation order,
+	// used to price blob gas when receipts do not report it. Ethereum
+	// mainnet's schedule is used when it is empty.
+	BlobSchedule []BlobFork
+}
+
+// BlobFork is a fork from whose Timestamp on the blob base fee reacts to
+// excess blob gas with UpdateFraction.
+type BlobFork struct {
+	Name           string `json:"name"`
+	Timestamp      uint64 `json:"timestamp"`
+	UpdateFraction uint64 `json:"updateFraction"`
+}
+
+// chainFile is the JSON layout of CHAINS_FILE:
+//
+//	{"chains": [{"name": "base", "chainId": 8453, "rpcUrls": ["https://mainnet.base.org"],
+//	             "blockTime": "2s", "confirmations": 10,
+//	             "snapshotInterval": 300, "snapshotTokens": ["0x8335..."],
+//	             "blobSchedule": [{"name": "cancun", "timestamp": 1710338135, "updateFraction": 3338477}]}]}
+type chainFile struct {
+	Chains []struct {
+		Name             string     `json:"name"`
+		ChainID          uint64     `json:"chainId"`
+		RPCURLs          []string   `json:"rpcUrls"`
+		BlockTime        string     `json:"blockTime"`
+		Confirmations    int64      `json:"confirmations"`
+		SnapshotInterval int64      `json:"snapshotInterval"`
+		SnapshotTokens   []string   `json:"snapshotTokens"`
+		BlobSchedule     []BlobFork `json:"blobSchedule"`
+	} `json:"chains"`
+}
+
+// LoadChains reads chain definitions from a JSON file.
+func LoadChains(path string) ([]ChainConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chains file: %w", err)
+	}
+	var file chainFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse chains file %s: %w", path, err)
+	}
+
+	chains := make([]ChainConfig, 0, len(file.Chains))
+	for _, c := range file.Chains {
+		chain := ChainConfig{
+			Name:             strings.ToLower(c.Name),
+			ChainID:          c.ChainID,
+			RPCURLs:          c.RPCURLs,
+			Confirmations:    c.Confirmations,
+			SnapshotInterval: c.SnapshotInterval,
+			SnapshotTokens:   c.SnapshotTokens,
+			BlobSchedule:     c.BlobSchedule,
+		}
+		if c.BlockTime != "" {
+			if chain.BlockTime, err = time.ParseDuration(c.BlockTime); err != nil {
+				return nil, fmt.Errorf("chain %s: invalid block time %q: %w", c.Name, c.BlockTime, err)
+			}
+		}
+		chains = append(chains, chain)
+	}
+	if err := validateChains(chains); err != nil {
+		return nil, fmt.Errorf("chains file %s: %w", path, err)
+	}
+	return chains, nil
+}
+
+func validateChains(chains []ChainConfig) error {
+	if len(chains) == 0 {
+		return fmt.Errorf("no chains defined")
+	}
+	names := make(map[string]bool)
+	ids := make(map[uint64]bool)
+	for _, chain := range chains {
+		switch {
+		case chain.Name == "":
+			return fmt.Errorf("chain %d has no name", chain.ChainID)
+		case chain.ChainID == 0:
+			return fmt.Errorf("chain %s has no chain id", chain.Name)
+		case len(chain.RPCURLs) == 0:
+			return fmt.Errorf("chain %s has no rpc urls", chain.Name)
+		case chain.BlockTime <= 0:
+			return fmt.Errorf("chain %s has no block time", chain.Name)
+		case chain.Confirmations < 0:
+			return fmt.Errorf("chain %s has negative confirmations", chain.Name)
+		case names[chain.Name]:
+			return fmt.Errorf("duplicate chain name %s", chain.Name)
+		case ids[chain.ChainID]:
+			return fmt.Errorf("duplicate chain id %d", chain.ChainID)
+		case chain.SnapshotInterval < 0:
+			return fmt.Errorf("chain %s has a negative snapshot interval", chain.Name)
+		}
+		for i, fork := range chain.BlobSchedule {
+			if fork.UpdateFraction == 0 {
+				return fmt.Errorf("chain %s: blob fork %s has no update fraction", chain.Name, fork.Name)
+			}
+			if i > 0 && fork.Timestamp <= chain.BlobSchedule[i-1].Timestamp {
+				return fmt.Errorf("chain %s: blob fork %s does not activate after %s", chain.Name, fork.Name, chain.BlobSchedule[i-1].Name)
+			}
+		}
+		for _, token := range chain.SnapshotTokens {
+			if _, err := models.ParseAddress(token); err != nil {
+				return fmt.Errorf("chain %s: snapshot token %q: %w", chain.Name, token, err)
+			}
+		}
+		names[chain.Name] = true
+		ids[chain.ChainID] = true
+	}
+	return nil
+}
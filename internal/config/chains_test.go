@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeChainsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chains.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadChains(t *testing.T) {
+	path := writeChainsFile(t, `{"chains": [
+		{"name": "Ethereum", "chainId": 1, "rpcUrls": ["https://a.example", "https://b.example"], "blockTime": "12s", "confirmations": 12},
+		{"name": "base", "chainId": 8453, "rpcUrls": ["https://base.example"], "blockTime": "2s",
+		 "snapshotInterval": 300, "snapshotTokens": ["0x833589fcd6edb6e08f4c7c32d4f71b54bda02913"],
+		 "blobSchedule": [{"name": "ecotone", "timestamp": 1710374401, "updateFraction": 3338477}]}
+	]}`)
+	chains, err := LoadChains(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chains) != 2 {
+		t.Fatalf("expected 2 chains, got %d", len(chains))
+	}
+	if c := chains[0]; c.Name != "ethereum" || c.ChainID != 1 || len(c.RPCURLs) != 2 || c.BlockTime != 12*time.Second || c.Confirmations != 12 {
+		t.Errorf("unexpected chain %+v", c)
+	}
+	if c := chains[1]; c.Name != "base" || c.BlockTime != 2*time.Second || c.Confirmations != 0 || c.SnapshotInterval != 300 || len(c.SnapshotTokens) != 1 || len(c.BlobSchedule) != 1 || c.BlobSchedule[0].UpdateFraction != 3338477 {
+		t.Errorf("unexpected chain %+v", c)
+	}
+}
+
+func TestLoadChainsInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		content string
+		want    string
+	}{
+		{`{"chains": []}`, "no chains"},
+		{`{"chains": [{"name": "a", "chainId": 1, "rpcUrls": ["x"]}]}`, "no block time"},
+		{`{"chains": [{"name": "a", "chainId": 1, "blockTime": "1s"}]}`, "no rpc urls"},
+		{`{"chains": [{"name": "a", "rpcUrls": ["x"], "blockTime": "1s"}]}`, "no chain id"},
+		{`{"chains": [{"name": "a", "chainId": 1, "rpcUrls": ["x"], "blockTime": "soon"}]}`, "invalid block time"},
+		{`{"chains": [{"name": "a", "chainId": 1, "rpcUrls": ["x"], "blockTime": "1s"},
+		              {"name": "b", "chainId": 1, "rpcUrls": ["y"], "blockTime": "1s"}]}`, "duplicate chain id"},
+		{`{"chains": [{"name": "a", "chainId": 1, "rpcUrls": ["x"], "blockTime": "1s", "snapshotTokens": ["0x1234"]}]}`, "snapshot token"},
+		{`{"chains": [{"name": "a", "chainId": 1, "rpcUrls": ["x"], "blockTime": "1s", "blobSchedule": [{"name": "cancun", "timestamp": 1}]}]}`, "no update fraction"},
+		{`{"chains": [{"name": "a", "chainId": 1, "rpcUrls": ["x"], "blockTime": "1s",
+		              "blobSchedule": [{"name": "cancun", "timestamp": 2, "updateFraction": 1}, {"name": "prague", "timestamp": 2, "updateFraction": 2}]}]}`, "does not activate after"},
+	} {
+		_, err := LoadChains(writeChainsFile(t, tc.content))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("LoadChains(%s) = %v, want an error containing %q", tc.content, err, tc.want)
+		}
+	}
+}
@@ -2,19 +2,129 @@ package config
 
 import (
 	"eth-parser/common"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	ServerAddress string
 	EthNodeURL    string
+	// FallbackNodeURLs are tried in order when a block fails verification.
+	FallbackNodeURLs []string
+	// FetchMode is either "full" or "selective", see ethereum.FetchMode.
+	FetchMode          string
+	FullBlockThreshold int
+	// VerifyBlocks enables header hash, parent linkage and transactions root
+	// verification.
+	VerifyBlocks bool
+	// VerifySenders enables sender recovery and transaction hash checks.
+	VerifySenders bool
+	// Ledger enables the ETH balance ledger of subscribed addresses;
+	// LedgerTraceInternal adds internal transfers from block traces.
+	Ledger              bool
+	LedgerTraceInternal bool
+	// SignatureFiles are extra function and event signature lists added to
+	// the embedded signature database, see fourbyte.DB.Load.
+	SignatureFiles []string
+	// TenantMaxSubscriptions bounds the subscriptions of every tenant on
+	// each chain, zero for no bound; TenantQuotas overrides it by tenant.
+	TenantMaxSubscriptions int
+	TenantQuotas           map[string]int
+	// Auth requires an API key on every endpoint but /health. AdminAPIKey
+	// is registered as a global admin key to create the others with.
+	Auth        bool
+	AdminAPIKey string
+	// RateLimit is the requests per second each API key, or client address
+	// without keys, may make with bursts of RateLimitBurst; zero disables
+	// it. The expensive limit applies on top to costly endpoints.
+	RateLimit               float64
+	RateLimitBurst          int
+	ExpensiveRateLimit      float64
+	ExpensiveRateLimitBurst int
+	// Chains are the chains to watch, from CHAINS_FILE when set and
+	// otherwise a single chain built from ETH_NODE_URL and CHAIN_ID.
+	Chains []ChainConfig
 }
 
-func Load() *Config {
-	return &Config{
-		ServerAddress: getEnv("SERVER_ADDRESS", ":8080"),
-		EthNodeURL:    getEnv("ETH_NODE_URL", common.CloudFlareRpcUrl),
+func Load() (*Config, error) {
+	cfg := &Config{
+		ServerAddress:       getEnv("SERVER_ADDRESS", ":8080"),
+		EthNodeURL:          getEnv("ETH_NODE_URL", common.CloudFlareRpcUrl),
+		FallbackNodeURLs:    getEnvList("ETH_NODE_FALLBACK_URLS"),
+		FetchMode:           getEnv("FETCH_MODE", "full"),
+		FullBlockThreshold:  getEnvInt("FULL_BLOCK_THRESHOLD", 1000),
+		VerifyBlocks:        getEnvBool("VERIFY_BLOCKS", false),
+		VerifySenders:       getEnvBool("VERIFY_SENDERS", false),
+		SignatureFiles:      getEnvList("SIGNATURE_FILES"),
+		Ledger:              getEnvBool("LEDGER", false),
+		LedgerTraceInternal: getEnvBool("LEDGER_TRACE_INTERNAL", false),
+
+		TenantMaxSubscriptions: getEnvInt("TENANT_MAX_SUBSCRIPTIONS", 0),
+
+		Auth:        getEnvBool("AUTH", false),
+		AdminAPIKey: getEnv("ADMIN_API_KEY", ""),
+
+		RateLimit:               getEnvFloat("RATE_LIMIT", 0),
+		RateLimitBurst:          getEnvInt("RATE_LIMIT_BURST", 20),
+		ExpensiveRateLimit:      getEnvFloat("EXPENSIVE_RATE_LIMIT", 0),
+		ExpensiveRateLimitBurst: getEnvInt("EXPENSIVE_RATE_LIMIT_BURST", 5),
+	}
+	if cfg.Auth && cfg.AdminAPIKey == "" {
+		return nil, fmt.Errorf("AUTH needs ADMIN_API_KEY")
+	}
+	quotas, err := parseQuotas(getEnvList("TENANT_QUOTAS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TENANT_QUOTAS: %w", err)
+	}
+	cfg.TenantQuotas = quotas
+
+	if path := getEnv("CHAINS_FILE", ""); path != "" {
+		chains, err := LoadChains(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Chains = chains
+		return cfg, nil
+	}
+
+	chainID, err := strconv.ParseUint(getEnv("CHAIN_ID", "1"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CHAIN_ID: %w", err)
+	}
+	blockTime, err := time.ParseDuration(getEnv("BLOCK_TIME", "12s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BLOCK_TIME: %w", err)
+	}
+	cfg.Chains = []ChainConfig{{
+		Name:             strings.ToLower(getEnv("CHAIN_NAME", "ethereum")),
+		ChainID:          chainID,
+		RPCURLs:          append([]string{cfg.EthNodeURL}, cfg.FallbackNodeURLs...),
+		BlockTime:        blockTime,
+		Confirmations:    int64(getEnvInt("CONFIRMATIONS", 0)),
+		SnapshotInterval: int64(getEnvInt("SNAPSHOT_INTERVAL", 0)),
+		SnapshotTokens:   getEnvList("SNAPSHOT_TOKENS"),
+	}}
+	if err := validateChains(cfg.Chains); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseQuotas reads tenant=limit entries.
+func parseQuotas(entries []string) (map[string]int, error) {
+	quotas := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		tenant, raw, ok := strings.Cut(entry, "=")
+		limit, err := strconv.Atoi(strings.TrimSpace(raw))
+		if !ok || strings.TrimSpace(tenant) == "" || err != nil || limit < 0 {
+			return nil, fmt.Errorf("expected tenant=limit, got %q", entry)
+		}
+		quotas[strings.TrimSpace(tenant)] = limit
 	}
+	return quotas, nil
 }
 
 func getEnv(key, fallback string) string {
@@ -23,3 +133,41 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvList splits a comma-separated variable, dropping empty entries.
+func getEnvList(key string) []string {
+	var list []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+func getEnvInt(key string, fallback int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
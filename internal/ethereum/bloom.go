@@ -0,0 +1,98 @@
+package ethereum
+
+import (
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
+	"fmt"
+)
+
+const bloomByteLength = 256
+
+// Bloom is the 2048 bit logs bloom filter carried in every block header.
+type Bloom [bloomByteLength]byte
+
+func ParseBloom(s string) (Bloom, error) {
+	var b Bloom
+	if err := utils.DecodeFixedBytes(s, b[:]); err != nil {
+		return Bloom{}, fmt.Errorf("invalid bloom: %w", err)
+	}
+	return b, nil
+}
+
+// Test reports whether data may have been added to the bloom. False positives
+// are possible, false negatives are not.
+func (b *Bloom) Test(data []byte) bool {
+	h := utils.Keccak256(data)
+	for i := 0; i < 6; i += 2 {
+		bit := (uint(h[i])<<8 | uint(h[i+1])) & 2047
+		if b[bloomByteLength-1-bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Add sets the three bits for data.
+func (b *Bloom) Add(data []byte) {
+	h := utils.Keccak256(data)
+	for i := 0; i < 6; i += 2 {
+		bit := (uint(h[i])<<8 | uint(h[i+1])) & 2047
+		b[bloomByteLength-1-bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// IsEmpty reports whether no bits are set, i.e. the block emitted no logs.
+func (b *Bloom) IsEmpty() bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MayContainAddress reports whether the bloom may contain the address either
+// as a log emitter or as an indexed topic (left-padded to 32 bytes), which is
+// how ERC-20 and ERC-721 transfers reference the sender and recipient.
+func (b *Bloom) MayContainAddress(address models.Address) bool {
+	if b.Test(address[:]) {
+		return true
+	}
+	var topic [32]byte
+	copy(topic[12:], address[:])
+	return b.Test(topic[:])
+}
+
+// MayMatch reports whether a block with this bloom may contain a log passing
+// filter: one of its addresses, if any, and one topic of every constrained
+// position must be in the bloom.
+func (b *Bloom) MayMatch(filter models.LogFilter) bool {
+	if len(filter.Addresses) > 0 {
+		found := false
+		for _, address := range filter.Addresses {
+			if b.Test(address[:]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, position := range filter.Topics {
+		if len(position) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range position {
+			if b.Test(topic[:]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"eth-parser/pkg/models"
+	"testing"
+)
+
+func TestBloom(t *testing.T) {
+	emitter, _ := models.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7")
+	holder, _ := models.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48")
+	other, _ := models.HexToAddress("0x742d35Cc6634C0532925a3b844Bc454e4438f44e")
+
+	var b Bloom
+	if !b.IsEmpty() {
+		t.Fatal("zero bloom should be empty")
+	}
+	if b.MayContainAddress(emitter) {
+		t.Error("empty bloom should not contain any address")
+	}
+
+	b.Add(emitter[:])
+	var topic [32]byte
+	copy(topic[12:], holder[:])
+	b.Add(topic[:])
+
+	if !b.MayContainAddress(emitter) {
+		t.Error("bloom should contain the log emitter")
+	}
+	if !b.MayContainAddress(holder) {
+		t.Error("bloom should contain the address used as an indexed topic")
+	}
+	if b.MayContainAddress(other) {
+		t.Error("bloom unexpectedly matched an address that was never added")
+	}
+
+	parsed, err := ParseBloom("0x" + hex.EncodeToString(b[:]))
+	if err != nil {
+		t.Fatalf("ParseBloom: %v", err)
+	}
+	if parsed != b {
+		t.Error("ParseBloom did not round trip")
+	}
+	if _, err := ParseBloom("0x1234"); err == nil {
+		t.Error("ParseBloom accepted a short bloom")
+	}
+}
@@ -0,0 +1,126 @@
+package ethereum
+
+import (
+	"eth-parser/pkg/models"
+)
+
+// SubscribeBulk subscribes every row of subs and returns one result per
+// row. Addresses are parsed strictly, as user input. Addresses already
+// subscribed are reported as existing whatever their start block, and start
+// blocks already processed are moved to the next block, so exports can be
+// posted back as they are. Rows that fail are reported as invalid and the
+// others applied, unless atomic is set: then nothing is applied when any row
+// is invalid, and rows already applied are unsubscribed again should one fail
+// while applying. That frees their quota, but like any unsubscription it
+// leaves the opening balances their ledgers were given.
+func (ep *EthParser) SubscribeBulk(subs []models.Subscription, atomic bool) []models.BulkResult {
+	results := make([]models.BulkResult, len(subs))
+	prepared := make([]models.Subscription, len(subs))
+	valid := true
+	for i, sub := range subs {
+		results[i] = models.BulkResult{Row: i + 1, Address: sub.Address}
+		address, err := models.ParseAddress(sub.Address)
+		if err == nil {
+			sub.Address = address.String()
+			if _, ok := ep.storage.GetSubscription(sub.Address); ok {
+				results[i].Address, results[i].Status = address.Checksum(), models.BulkExisting
+				continue
+			}
+			if sub.StartBlock < ep.storage.GetCurrentBlock() {
+				sub.StartBlock = 0
+			}
+			// Defaulted start blocks stay zero so that AddSubscription
+			// takes the next block when the row is applied.
+			_, err = ep.prepareSubscription(sub)
+			prepared[i] = sub
+		}
+		if err != nil {
+			results[i].Status, results[i].Error = models.BulkInvalid, err.Error()
+			valid = false
+			continue
+		}
+		results[i].Address = address.Checksum()
+	}
+	if atomic && !valid {
+		skipValid(results)
+		return results
+	}
+
+	var added []string
+	for i, sub := range prepared {
+		if results[i].Status != "" {
+			continue
+		}
+		ok, err := ep.AddSubscription(sub)
+		switch {
+		case err != nil:
+			results[i].Status, results[i].Error = models.BulkInvalid, err.Error()
+			if atomic {
+				// The chain moved past a start block since the rows
+				// were checked, or the tenant ran out of quota.
+				for _, address := range added {
+					ep.Unsubscribe(address)
+				}
+				skipValid(results)
+				return results
+			}
+		case ok:
+			results[i].Status = models.BulkAdded
+			if stored, ok := ep.storage.GetSubscription(sub.Address); ok {
+				results[i].StartBlock = stored.StartBlock
+			}
+			added = append(added, sub.Address)
+		default:
+			results[i].Status = models.BulkExisting
+		}
+	}
+	ep.logger.Printf("Bulk subscribe: %d rows, %d added", len(subs), len(added))
+	return results
+}
+
+// UnsubscribeBulk unsubscribes every address and returns one result per
+// row, with the same atomic semantics as SubscribeBulk.
+func (ep *EthParser) UnsubscribeBulk(addresses []string, atomic bool) []models.BulkResult {
+	results := make([]models.BulkResult, len(addresses))
+	parsed := make([]models.Address, len(addresses))
+	valid := true
+	for i, raw := range addresses {
+		results[i] = models.BulkResult{Row: i + 1, Address: raw}
+		address, err := models.ParseAddress(raw)
+		if err != nil {
+			results[i].Status, results[i].Error = models.BulkInvalid, err.Error()
+			valid = false
+			continue
+		}
+		parsed[i] = address
+		results[i].Address = address.Checksum()
+	}
+	if atomic && !valid {
+		skipValid(results)
+		return results
+	}
+
+	removed := 0
+	for i, address := range parsed {
+		if results[i].Status == models.BulkInvalid {
+			continue
+		}
+		if ep.Unsubscribe(address.String()) {
+			results[i].Status = models.BulkRemoved
+			removed++
+		} else {
+			results[i].Status = models.BulkNotSubscribed
+		}
+	}
+	ep.logger.Printf("Bulk unsubscribe: %d rows, %d removed", len(addresses), removed)
+	return results
+}
+
+// skipValid marks every row that is not invalid as skipped.
+func skipValid(results []models.BulkResult) {
+	for i := range results {
+		if results[i].Status != models.BulkInvalid {
+			results[i].Status = models.BulkSkipped
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package ethereum
+
+import (
+	"eth-parser/pkg/models"
+	"testing"
+)
+
+func TestParserBulkSubscriptions(t *testing.T) {
+	parser, closeServer := newTestParser(newFakeChain(1, 0))
+	defer closeServer()
+	parser.SetCurrentBlock(100)
+	parser.Subscribe("0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48")
+
+	subs := []models.Subscription{
+		{Address: "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", Tags: []string{"deposits"}},
+		{Address: "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"},
+		{Address: "0xdac17f958d2ee523a2206206994597c13d831ec7", StartBlock: 50},
+		{Address: "0x742d35Cc6634C0532925a3b844Bc454e4438F44e"},
+	}
+	statuses := func(results []models.BulkResult) []models.BulkStatus {
+		var s []models.BulkStatus
+		for _, result := range results {
+			s = append(s, result.Status)
+		}
+		return s
+	}
+
+	results := parser.SubscribeBulk(subs, true)
+	if got := statuses(results); got[0] != models.BulkSkipped || got[1] != models.BulkSkipped || got[2] != models.BulkSkipped || got[3] != models.BulkInvalid {
+		t.Errorf("atomic statuses = %v", got)
+	}
+	if len(parser.GetSubscribeList()) != 1 {
+		t.Errorf("atomic request with invalid rows applied: %v", parser.GetSubscribeList())
+	}
+
+	results = parser.SubscribeBulk(subs, false)
+	if got := statuses(results); got[0] != models.BulkAdded || got[1] != models.BulkExisting || got[2] != models.BulkAdded || got[3] != models.BulkInvalid {
+		t.Errorf("statuses = %v", got)
+	}
+	if results[0].Address != "0x742d35Cc6634C0532925a3b844Bc454e4438f44e" || results[3].Error == "" || results[2].StartBlock != 100 {
+		t.Errorf("results = %+v", results)
+	}
+	if sub, ok := parser.storage.GetSubscription(subs[0].Address); !ok || sub.StartBlock != 100 || !sub.HasTag("deposits") {
+		t.Errorf("subscription = %+v, %v", sub, ok)
+	}
+	// A start block already processed is moved to the next block.
+	if sub, ok := parser.storage.GetSubscription(subs[2].Address); !ok || sub.StartBlock != 100 {
+		t.Errorf("subscription = %+v, %v", sub, ok)
+	}
+
+	// An export posted back is reported as existing, even once the chain has
+	// moved past its start blocks.
+	parser.SetCurrentBlock(200)
+	results = parser.SubscribeBulk(parser.GetSubscriptions(), true)
+	if got := statuses(results); len(got) != 3 || got[0] != models.BulkExisting || got[1] != models.BulkExisting || got[2] != models.BulkExisting {
+		t.Errorf("reimport statuses = %v", got)
+	}
+
+	results = parser.UnsubscribeBulk([]string{"0x742d35cc6634c0532925a3b844bc454e4438f44e", "0x0000000000000000000000000000000000000001", "0x1234"}, false)
+	if got := statuses(results); got[0] != models.BulkRemoved || got[1] != models.BulkNotSubscribed || got[2] != models.BulkInvalid {
+		t.Errorf("unsubscribe statuses = %v", got)
+	}
+}
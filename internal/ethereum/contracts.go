@@ -0,0 +1,101 @@
+package ethereum
+
+import (
+	"eth-parser/pkg/abi"
+	"eth-parser/pkg/fourbyte"
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
+	"strings"
+)
+
+// RegisterABI validates and stores the ABI JSON of the contract at address.
+// Calls to it in later blocks carry their decoded input.
+func (ep *EthParser) RegisterABI(address string, abiJSON []byte) error {
+	parsed, err := abi.Parse(abiJSON)
+	if err != nil {
+		return err
+	}
+	ep.storage.SetContractABI(address, abiJSON)
+	ep.abis.Store(strings.ToLower(address), parsed)
+	ep.logger.Printf("Registered ABI for %s: %d methods, %d events", address, len(parsed.Methods), len(parsed.Events))
+	return nil
+}
+
+// GetABI returns the ABI JSON registered for address.
+func (ep *EthParser) GetABI(address string) ([]byte, bool) {
+	return ep.storage.GetContractABI(address)
+}
+
+func (ep *EthParser) UnregisterABI(address string) bool {
+	ep.abis.Delete(strings.ToLower(address))
+	success := ep.storage.DeleteContractABI(address)
+	ep.logger.Printf("Unregistered ABI for %s, success: %v", address, success)
+	return success
+}
+
+// contractABI returns the parsed ABI of address, parsing what storage holds
+// on first use.
+func (ep *EthParser) contractABI(address string) (*abi.ABI, bool) {
+	key := strings.ToLower(address)
+	if cached, ok := ep.abis.Load(key); ok {
+		return cached.(*abi.ABI), true
+	}
+	raw, ok := ep.storage.GetContractABI(address)
+	if !ok {
+		return nil, false
+	}
+	parsed, err := abi.Parse(raw)
+	if err != nil {
+		ep.logger.Printf("Stored ABI for %s is invalid: %v", address, err)
+		return nil, false
+	}
+	ep.abis.Store(key, parsed)
+	return parsed, true
+}
+
+// SetSignatures replaces the signature database used to label calls and
+// logs.
+func (ep *EthParser) SetSignatures(db *fourbyte.DB) {
+	ep.signatures = db
+}
+
+// decodeInput labels a call with the signature of the function it calls, and
+// for contracts with a registered ABI decodes its arguments. Calls that do
+// not match the ABI are stored undecoded.
+func (ep *EthParser) decodeInput(tx *models.Transaction) {
+	if tx.To == "" {
+		return
+	}
+	input, err := utils.DecodeBytes(tx.Input)
+	if err != nil || len(input) < 4 {
+		return
+	}
+	if signature, ok := ep.signatures.Function(input); ok {
+		tx.Method = signature
+	}
+	contract, ok := ep.contractABI(tx.To)
+	if !ok {
+		return
+	}
+	decoded, err := contract.DecodeCall(input)
+	if err != nil {
+		ep.logger.Printf("Input of transaction %s not decoded: %v", tx.Hash, err)
+		return
+	}
+	tx.DecodedInput = &decoded
+	tx.Method = decoded.Signature
+}
+
+// labelLog sets the event signature of a log from its first topic.
+func (ep *EthParser) labelLog(log *models.Log) {
+	if len(log.Topics) == 0 {
+		return
+	}
+	topic, err := models.HexToHash(log.Topics[0])
+	if err != nil {
+		return
+	}
+	if signature, ok := ep.signatures.Event(topic); ok {
+		log.Event = signature
+	}
+}
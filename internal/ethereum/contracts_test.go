@@ -0,0 +1,91 @@
+package ethereum
+
+import (
+	"eth-parser/pkg/models"
+	"testing"
+)
+
+const tokenABI = `[{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]}]`
+
+func TestParserDecodesInput(t *testing.T) {
+	node := newFakeChain(3, 0)
+	subscribed := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	token := "0xdac17f958d2ee523a2206206994597c13d831ec7"
+	block := node.blocks[1]
+	block.Transactions = append(block.Transactions,
+		models.Transaction{BlockNumber: block.Number, Hash: "0x01", From: subscribed, To: token,
+			Input: "0xa9059cbb000000000000000000000000a0b86991c6218b36c1d19d4a2e9eb0ce3606eb4800000000000000000000000000000000000000000000000000000000000f4240"},
+		// Unknown selector: stored without decoded input.
+		models.Transaction{BlockNumber: block.Number, Hash: "0x02", From: subscribed, To: token, Input: "0xdeadbeef"},
+	)
+	node.blocks[1] = block
+
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	parser.Subscribe(subscribed)
+	if err := parser.RegisterABI(token, []byte(`[{"type":"function","inputs":[{"type":"uint7"}]}]`)); err == nil {
+		t.Error("RegisterABI accepted an invalid ABI")
+	}
+	if err := parser.RegisterABI(token, []byte(tokenABI)); err != nil {
+		t.Fatal(err)
+	}
+	if err := parser.processBatch(0, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	txs := parser.GetTransactions(subscribed)
+	if len(txs) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(txs))
+	}
+	input := txs[0].DecodedInput
+	if input == nil || input.Method != "transfer" || len(input.Params) != 2 ||
+		input.Params[0].Value != "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48" || input.Params[1].Value != "1000000" {
+		t.Errorf("decoded input = %+v", input)
+	}
+	if txs[0].Method != "transfer(address,uint256)" {
+		t.Errorf("decoded call labelled %q", txs[0].Method)
+	}
+	if txs[1].DecodedInput != nil {
+		t.Errorf("call with an unknown selector decoded to %+v", txs[1].DecodedInput)
+	}
+
+	if !parser.UnregisterABI(token) {
+		t.Error("UnregisterABI of a registered ABI failed")
+	}
+	if _, ok := parser.GetABI(token); ok {
+		t.Error("ABI still registered")
+	}
+}
+
+func TestParserLabelsCalls(t *testing.T) {
+	node := newFakeChain(3, 0)
+	subscribed := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	block := node.blocks[1]
+	block.Transactions = append(block.Transactions,
+		models.Transaction{BlockNumber: block.Number, Hash: "0x01", From: subscribed, To: "0xdac17f958d2ee523a2206206994597c13d831ec7",
+			Input: "0x095ea7b3000000000000000000000000a0b86991c6218b36c1d19d4a2e9eb0ce3606eb48ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		models.Transaction{BlockNumber: block.Number, Hash: "0x02", From: subscribed, To: "0xdac17f958d2ee523a2206206994597c13d831ec7", Input: "0x12345678"},
+		models.Transaction{BlockNumber: block.Number, Hash: "0x03", From: subscribed, To: "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", Input: "0x"},
+	)
+	node.blocks[1] = block
+
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	parser.Subscribe(subscribed)
+	if err := parser.processBatch(0, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	txs := parser.GetTransactions(subscribed)
+	if len(txs) != 3 {
+		t.Fatalf("expected 3 transactions, got %d", len(txs))
+	}
+	for i, want := range []string{"approve(address,uint256)", "", ""} {
+		if txs[i].Method != want {
+			t.Errorf("transaction %s labelled %q, want %q", txs[i].Hash, txs[i].Method, want)
+		}
+		if txs[i].DecodedInput != nil {
+			t.Errorf("transaction %s decoded without an ABI", txs[i].Hash)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package ethereum
+
+import (
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// GasPerBlob is the blob gas consumed by each blob (EIP-4844).
+const GasPerBlob = 1 << 17
+
+// minBlobBaseFee is MIN_BASE_FEE_PER_BLOB_GAS in wei.
+const minBlobBaseFee = 1
+
+// BlobFork is a fork that changed how fast the blob base fee reacts to
+// excess blob gas.
+type BlobFork struct {
+	Name           string
+	Timestamp      uint64
+	UpdateFraction uint64
+}
+
+// BlobSchedule lists blob forks in activation order.
+type BlobSchedule []BlobFork
+
+// MainnetBlobSchedule is the blob base fee update fraction of Ethereum
+// mainnet from Cancun (EIP-4844) through the blob parameter only forks.
+var MainnetBlobSchedule = BlobSchedule{
+	{Name: "cancun", Timestamp: 1710338135, UpdateFraction: 3338477},
+	{Name: "prague", Timestamp: 1746612311, UpdateFraction: 5007716},
+	{Name: "bpo1", Timestamp: 1765290071, UpdateFraction: 8346193},
+	{Name: "bpo2", Timestamp: 1767747671, UpdateFraction: 11684671},
+}
+
+// UpdateFraction returns the update fraction in force at timestamp, or 0
+// before the first blob fork.
+func (s BlobSchedule) UpdateFraction(timestamp uint64) uint64 {
+	i := sort.Search(len(s), func(i int) bool { return s[i].Timestamp > timestamp })
+	if i == 0 {
+		return 0
+	}
+	return s[i-1].UpdateFraction
+}
+
+// BlobBaseFee returns the price of one unit of blob gas given the excess blob
+// gas of the block and the update fraction of its fork.
+func BlobBaseFee(excessBlobGas, updateFraction uint64) *big.Int {
+	return fakeExponential(
+		big.NewInt(minBlobBaseFee),
+		new(big.Int).SetUint64(excessBlobGas),
+		new(big.Int).SetUint64(updateFraction),
+	)
+}
+
+// fakeExponential approximates factor * e^(numerator/denominator) with the
+// integer Taylor expansion from EIP-4844.
+func fakeExponential(factor, numerator, denominator *big.Int) *big.Int {
+	output := new(big.Int)
+	accum := new(big.Int).Mul(factor, denominator)
+	divisor := new(big.Int)
+	for i := int64(1); accum.Sign() > 0; i++ {
+		output.Add(output, accum)
+		accum.Mul(accum, numerator)
+		accum.Div(accum, divisor.Mul(denominator, big.NewInt(i)))
+	}
+	return output.Div(output, denominator)
+}
+
+// TransactionFees computes what a mined transaction paid: the execution fee
+// from the gas used and effective gas price in its receipt, and for blob
+// transactions the blob fee at the blob gas price in the receipt or, when the
+// node does not report one, at the blob base fee derived from the block's
+// excess blob gas.
+func TransactionFees(tx models.Transaction, header models.Header, receipt models.Receipt, schedule BlobSchedule) (models.Fees, error) {
+	gasUsed, err := utils.DecodeBig(receipt.GasUsed)
+	if err != nil {
+		return models.Fees{}, fmt.Errorf("receipt %s: field gasUsed: %w", tx.Hash, err)
+	}
+	priceHex := receipt.EffectiveGasPrice
+	if priceHex == "" {
+		priceHex = tx.GasPrice
+	}
+	price, err := utils.DecodeBig(priceHex)
+	if err != nil {
+		return models.Fees{}, fmt.Errorf("receipt %s: field effectiveGasPrice: %w", tx.Hash, err)
+	}
+	executionFee := new(big.Int).Mul(gasUsed, price)
+	fees := models.Fees{
+		GasUsed:           utils.EncodeBig(gasUsed),
+		EffectiveGasPrice: utils.EncodeBig(price),
+		ExecutionFee:      utils.EncodeBig(executionFee),
+		TotalFee:          utils.EncodeBig(executionFee),
+	}
+	if len(tx.BlobVersionedHashes) == 0 {
+		return fees, nil
+	}
+
+	var blobBaseFee *big.Int
+	if receipt.BlobGasPrice != "" {
+		if blobBaseFee, err = utils.DecodeBig(receipt.BlobGasPrice); err != nil {
+			return models.Fees{}, fmt.Errorf("receipt %s: field blobGasPrice: %w", tx.Hash, err)
+		}
+	} else if blobBaseFee, err = HeaderBlobBaseFee(header, schedule); err != nil {
+		return models.Fees{}, fmt.Errorf("blob transaction %s: %w", tx.Hash, err)
+	}
+	blobGasUsed := new(big.Int).SetUint64(uint64(len(tx.BlobVersionedHashes)) * GasPerBlob)
+	blobFee := new(big.Int).Mul(blobGasUsed, blobBaseFee)
+	fees.BlobGasUsed = utils.EncodeBig(blobGasUsed)
+	fees.BlobBaseFee = utils.EncodeBig(blobBaseFee)
+	fees.BlobFee = utils.EncodeBig(blobFee)
+	fees.TotalFee = utils.EncodeBig(new(big.Int).Add(executionFee, blobFee))
+
+	return fees, nil
+}
+
+// HeaderBlobBaseFee derives the blob base fee of a block from its excess
+// blob gas and the fork in force at its timestamp.
+func HeaderBlobBaseFee(header models.Header, schedule BlobSchedule) (*big.Int, error) {
+	if header.ExcessBlobGas == "" {
+		return nil, fmt.Errorf("block %s has no excessBlobGas", header.Number)
+	}
+	excessBlobGas, err := utils.DecodeUint64(header.ExcessBlobGas)
+	if err != nil {
+		return nil, fmt.Errorf("block %s: field excessBlobGas: %w", header.Number, err)
+	}
+	timestamp, err := utils.DecodeUint64(header.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("block %s: field timestamp: %w", header.Number, err)
+	}
+	updateFraction := schedule.UpdateFraction(timestamp)
+	if updateFraction == 0 {
+		return nil, fmt.Errorf("block %s: no blob fork active at %d", header.Number, timestamp)
+	}
+	return BlobBaseFee(excessBlobGas, updateFraction), nil
+}
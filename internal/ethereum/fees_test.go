@@ -0,0 +1,153 @@
+package ethereum
+
+import (
+	"eth-parser/pkg/models"
+	"math/big"
+	"testing"
+)
+
+func TestFakeExponential(t *testing.T) {
+	// Vectors from the EIP-4844 test suite.
+	for _, tc := range []struct {
+		factor, numerator, denominator, want int64
+	}{
+		{1, 0, 1, 1},
+		{38493, 0, 1000, 38493},
+		{0, 1234, 2345, 0},
+		{1, 2, 1, 6},
+		{1, 4, 2, 6},
+		{1, 3, 1, 16},
+		{1, 6, 2, 18},
+		{1, 4, 1, 49},
+		{1, 8, 2, 50},
+		{10, 8, 2, 542},
+		{11, 8, 2, 596},
+		{1, 5, 1, 136},
+		{1, 5, 2, 11},
+		{2, 5, 2, 23},
+		{1, 50000000, 2225652, 5709098764},
+	} {
+		got := fakeExponential(big.NewInt(tc.factor), big.NewInt(tc.numerator), big.NewInt(tc.denominator))
+		if got.Int64() != tc.want {
+			t.Errorf("fakeExponential(%d, %d, %d) = %s, want %d", tc.factor, tc.numerator, tc.denominator, got, tc.want)
+		}
+	}
+}
+
+func TestBlobSchedule(t *testing.T) {
+	for _, tc := range []struct {
+		timestamp uint64
+		want      uint64
+	}{
+		{1710338134, 0},
+		{1710338135, 3338477},
+		{1746612310, 3338477},
+		{1746612311, 5007716},
+		{1765290071, 8346193},
+		{1800000000, 11684671},
+	} {
+		if got := MainnetBlobSchedule.UpdateFraction(tc.timestamp); got != tc.want {
+			t.Errorf("UpdateFraction(%d) = %d, want %d", tc.timestamp, got, tc.want)
+		}
+	}
+
+	// The same excess costs less once Prague raised the update fraction.
+	excess := uint64(608 * GasPerBlob)
+	if got := BlobBaseFee(excess, 3338477); got.String() != "23276216517" {
+		t.Errorf("Cancun blob base fee = %s", got)
+	}
+	if got := BlobBaseFee(excess, 5007716); got.String() != "8152188" {
+		t.Errorf("Prague blob base fee = %s", got)
+	}
+}
+
+func TestTransactionFees(t *testing.T) {
+	var header models.Header
+	header.Number = "0x1"
+	header.Timestamp = "0x681b3057" // Prague activation
+	header.ExcessBlobGas = "0x4c00000"
+	receipt := models.Receipt{GasUsed: "0x5208", EffectiveGasPrice: "0x3b9aca00"}
+
+	transfer := models.Transaction{Type: "0x2"}
+	fees, err := TransactionFees(transfer, header, receipt, MainnetBlobSchedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fees.ExecutionFee != "0x1319718a5000" || fees.TotalFee != fees.ExecutionFee || fees.BlobFee != "" {
+		t.Errorf("fees of a transfer = %+v", fees)
+	}
+
+	blob := models.Transaction{Type: "0x3", BlobVersionedHashes: []string{
+		"0x01b0a4cdd5f55589f5c5b4d46c76704bb6ce95c0a8c09f77f197a57808dded28",
+		"0x01b0a4cdd5f55589f5c5b4d46c76704bb6ce95c0a8c09f77f197a57808dded29",
+	}}
+	fees, err = TransactionFees(blob, header, receipt, MainnetBlobSchedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.Fees{
+		GasUsed:           "0x5208",
+		EffectiveGasPrice: "0x3b9aca00",
+		ExecutionFee:      "0x1319718a5000",
+		BlobGasUsed:       "0x40000",
+		BlobBaseFee:       "0x7c647c",
+		BlobFee:           "0x1f191f00000",
+		TotalFee:          "0x150b037a5000",
+	}
+	if fees != want {
+		t.Errorf("fees of a blob transaction:\n got %+v\nwant %+v", fees, want)
+	}
+
+	header.ExcessBlobGas = ""
+	if _, err := TransactionFees(blob, header, receipt, MainnetBlobSchedule); err == nil {
+		t.Error("expected an error for a blob transaction in a block without excessBlobGas")
+	}
+
+	// The blob gas price the node reports wins over the schedule, which may
+	// not be the chain's.
+	receipt.BlobGasPrice = "0x2"
+	fees, err = TransactionFees(blob, header, receipt, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fees.BlobBaseFee != "0x2" || fees.BlobFee != "0x80000" {
+		t.Errorf("fees at the reported blob gas price = %+v", fees)
+	}
+}
+
+func TestParserBlobFees(t *testing.T) {
+	node := newFakeChain(3, 0)
+	subscribed := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	block := node.blocks[1]
+	block.Timestamp = "0x681b3057"
+	block.ExcessBlobGas = "0x4c00000"
+	block.Transactions = append(block.Transactions,
+		models.Transaction{
+			BlockNumber: block.Number, Hash: "0x01", From: subscribed, Type: "0x3",
+			BlobVersionedHashes: []string{"0x01b0a4cdd5f55589f5c5b4d46c76704bb6ce95c0a8c09f77f197a57808dded28"},
+		},
+		models.Transaction{BlockNumber: block.Number, Hash: "0x02", From: subscribed, Type: "0x2"},
+	)
+	node.blocks[1] = block
+	node.receipts = map[string]models.Receipt{
+		"0x01": {GasUsed: "0x5208", EffectiveGasPrice: "0x3b9aca00", BlobGasPrice: "0x7c647c"},
+	}
+
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	parser.Subscribe(subscribed)
+	if err := parser.processBatch(0, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	txs := parser.GetTransactions(subscribed)
+	if len(txs) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(txs))
+	}
+	if fees := txs[0].Fees; fees == nil || fees.BlobFee != "0xf8c8f80000" || fees.TotalFee != "0x14123a825000" {
+		t.Errorf("fees of the blob transaction = %+v", fees)
+	}
+	if txs[1].Fees != nil {
+		t.Errorf("fees of a transaction without blobs were fetched: %+v", txs[1].Fees)
+	}
+}
@@ -0,0 +1,294 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"eth-parser/internal/rpc"
+	"eth-parser/internal/storage"
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeNode serves eth_chainId, eth_blockNumber, eth_getBlockByNumber,
+// eth_getTransactionReceipt, eth_getLogs by block hash, eth_call,
+// eth_getBalance and debug_traceBlockByNumber from an in-memory chain and
+// counts the bytes it sends back.
+type fakeNode struct {
+	chainID     uint64
+	blocks      map[int64]models.Block
+	receipts    map[string]models.Receipt
+	logs        map[string][]models.Log // by block hash
+	calls       map[string]string       // eth_call results by "address:data:block" or "address:selector"
+	balances    map[string]string       // by "address:block"
+	traces      map[int64][]models.TransactionTrace
+	logFetches  atomic.Int64
+	callCount   atomic.Int64
+	bytesSent   atomic.Int64
+	fullFetches atomic.Int64
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req models.JSONRPCRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var result, rpcError interface{}
+	switch req.Method {
+	case "eth_chainId":
+		result = fmt.Sprintf("0x%x", n.chainID)
+	case "eth_blockNumber":
+		var latest int64
+		for number := range n.blocks {
+			if number > latest {
+				latest = number
+			}
+		}
+		result = fmt.Sprintf("0x%x", latest)
+	case "eth_getBlockByNumber":
+		var number int64
+		fmt.Sscanf(req.Params[0].(string), "0x%x", &number)
+		block := n.blocks[number]
+		if req.Params[1].(bool) {
+			n.fullFetches.Add(1)
+			result = block
+		} else {
+			header := models.BlockHeader{Header: block.Header, TransactionHashes: []string{}}
+			for _, tx := range block.Transactions {
+				header.TransactionHashes = append(header.TransactionHashes, tx.Hash)
+			}
+			result = header
+		}
+	case "eth_getTransactionReceipt":
+		if receipt, ok := n.receipts[req.Params[0].(string)]; ok {
+			result = receipt
+		}
+	case "eth_getLogs":
+		n.logFetches.Add(1)
+		filter := req.Params[0].(map[string]interface{})
+		logs := n.logs[filter["blockHash"].(string)]
+		if logs == nil {
+			logs = []models.Log{}
+		}
+		result = logs
+	case "eth_call":
+		n.callCount.Add(1)
+		call := req.Params[0].(map[string]interface{})
+		data := call["data"].(string)
+		if returned, ok := n.calls[call["to"].(string)+":"+data+":"+req.Params[1].(string)]; ok {
+			result = returned
+		} else if returned, ok := n.calls[call["to"].(string)+":"+data[:10]]; ok {
+			result = returned
+		} else {
+			rpcError = map[string]interface{}{"code": 3, "message": "execution reverted"}
+		}
+	case "eth_getBalance":
+		if balance, ok := n.balances[req.Params[0].(string)+":"+req.Params[1].(string)]; ok {
+			result = balance
+		} else {
+			rpcError = map[string]interface{}{"code": -32000, "message": "missing trie node"}
+		}
+	case "debug_traceBlockByNumber":
+		var number int64
+		fmt.Sscanf(req.Params[0].(string), "0x%x", &number)
+		traces := n.traces[number]
+		if traces == nil {
+			traces = []models.TransactionTrace{}
+		}
+		result = traces
+	}
+
+	body, _ := json.Marshal(models.JSONRPCResponse{JsonRPC: "2.0", Result: result, Error: rpcError, ID: req.ID})
+	n.bytesSent.Add(int64(len(body)))
+	w.Write(body)
+}
+
+func randomHex(r *rand.Rand, n int) string {
+	b := make([]byte, n)
+	r.Read(b)
+	return "0x" + hex.EncodeToString(b)
+}
+
+// randomQuantity returns a random 256-bit QUANTITY without leading zeros.
+func randomQuantity(r *rand.Rand) string {
+	b := make([]byte, 32)
+	r.Read(b)
+	return utils.EncodeBig(new(big.Int).SetBytes(b))
+}
+
+// newFakeChain builds blocks 0..count-1. Every third block is empty; the rest
+// carry txPerBlock random transfers with a few random log emitters in the
+// bloom.
+func newFakeChain(count int64, txPerBlock int) *fakeNode {
+	r := rand.New(rand.NewSource(1))
+	node := &fakeNode{chainID: 1, blocks: make(map[int64]models.Block)}
+	for number := int64(0); number < count; number++ {
+		var bloom Bloom
+		block := models.Block{Transactions: []models.Transaction{}}
+		block.Number = fmt.Sprintf("0x%x", number)
+		block.Hash = randomHex(r, 32)
+		if number%3 != 0 {
+			for i := 0; i < txPerBlock; i++ {
+				block.Transactions = append(block.Transactions, models.Transaction{
+					BlockNumber: block.Number,
+					Hash:        randomHex(r, 32),
+					From:        randomHex(r, 20),
+					To:          randomHex(r, 20),
+					Nonce:       fmt.Sprintf("0x%x", i),
+					GasPrice:    "0x4a817c800",
+					Gas:         "0x5208",
+					Value:       "0xde0b6b3a7640000",
+					Input:       randomHex(r, 68),
+					V:           "0x25",
+					R:           randomQuantity(r),
+					S:           randomQuantity(r),
+				})
+				if i%10 == 0 {
+					emitter, _ := hex.DecodeString(randomHex(r, 20)[2:])
+					bloom.Add(emitter)
+				}
+			}
+		}
+		block.LogsBloom = "0x" + hex.EncodeToString(bloom[:])
+		node.blocks[number] = block
+	}
+	return node
+}
+
+func newTestParser(node *fakeNode) (*EthParser, func()) {
+	server := httptest.NewServer(node)
+	rpc.SetEndpoint(server.URL)
+	parser := NewEthParser(storage.NewMemoryStorage(), log.New(io.Discard, "", 0))
+	return parser, server.Close
+}
+
+func TestSelectiveFetch(t *testing.T) {
+	node := newFakeChain(9, 20)
+
+	// Block 4 carries a token transfer by the subscribed address: it shows up
+	// in the bloom as an indexed topic.
+	subscribed := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	block := node.blocks[4]
+	bloom, _ := ParseBloom(block.LogsBloom)
+	raw, _ := hex.DecodeString(subscribed[2:])
+	var topic [32]byte
+	copy(topic[12:], raw)
+	bloom.Add(topic[:])
+	block.LogsBloom = "0x" + hex.EncodeToString(bloom[:])
+	block.Transactions[0].From = subscribed
+	node.blocks[4] = block
+
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	parser.SetFetchMode(FetchSelective, 10)
+	parser.Subscribe(subscribed)
+
+	if err := parser.processBatch(0, 9); err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+	if got := node.fullFetches.Load(); got != 1 {
+		t.Errorf("expected 1 full block fetch, got %d", got)
+	}
+	if txs := parser.GetTransactions(subscribed); len(txs) != 1 {
+		t.Errorf("expected 1 matched transaction, got %d", len(txs))
+	}
+
+	// Past the threshold every non-empty block is fetched in full.
+	node.fullFetches.Store(0)
+	for i := 0; i < 10; i++ {
+		parser.Subscribe(randomHex(rand.New(rand.NewSource(int64(i))), 20))
+	}
+	if err := parser.processBatch(0, 9); err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+	if got := node.fullFetches.Load(); got != 6 {
+		t.Errorf("expected 6 full block fetches above the threshold, got %d", got)
+	}
+}
+
+// BenchmarkFetchBandwidth compares the bytes received from the node per block
+// in full and selective mode for a chain with 1/3 empty blocks and a small
+// subscription set.
+func TestParserPerChain(t *testing.T) {
+	subscribed := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	mainnet := newFakeChain(8, 5)
+	base := newFakeChain(8, 5)
+	base.chainID = 8453
+	block := base.blocks[4]
+	block.Transactions[1].To = subscribed
+	base.blocks[4] = block
+
+	store := storage.NewMemoryStorage()
+	var parsers []*EthParser
+	for _, node := range []*fakeNode{mainnet, base} {
+		server := httptest.NewServer(node)
+		defer server.Close()
+		client := rpc.NewClient(server.URL)
+		chainID, err := client.ChainID()
+		if err != nil || chainID != node.chainID {
+			t.Fatalf("ChainID = %d, %v; want %d", chainID, err, node.chainID)
+		}
+
+		parser := NewEthParser(store.ForChain(chainID), log.New(io.Discard, "", 0))
+		parser.SetClient(client)
+		parser.SetConfirmations(2)
+		parser.Subscribe(subscribed)
+		parsers = append(parsers, parser)
+	}
+	// Nothing may go through the default client.
+	rpc.SetEndpoint("http://127.0.0.1:0")
+
+	for _, parser := range parsers {
+		if err := parser.updateAndParseBlocks(); err != nil {
+			t.Fatalf("updateAndParseBlocks: %v", err)
+		}
+		// The head is block 7; with 2 confirmations block 5 is the last one
+		// processed.
+		if got := parser.GetCurrentBlock(); got != 6 {
+			t.Errorf("current block = %d, want 6", got)
+		}
+	}
+	if txs := parsers[0].GetTransactions(subscribed); len(txs) != 0 {
+		t.Errorf("mainnet view has %d transactions of another chain", len(txs))
+	}
+	if txs := parsers[1].GetTransactions(subscribed); len(txs) != 1 {
+		t.Errorf("expected 1 transaction on base, got %d", len(txs))
+	}
+}
+
+func BenchmarkFetchBandwidth(b *testing.B) {
+	const blocks = 30
+	for _, mode := range []struct {
+		name string
+		mode FetchMode
+	}{
+		{"Full", FetchFull},
+		{"Selective", FetchSelective},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			node := newFakeChain(blocks, 150)
+			parser, closeServer := newTestParser(node)
+			defer closeServer()
+			parser.SetFetchMode(mode.mode, defaultFullBlockThreshold)
+			parser.Subscribe("0x742d35cc6634c0532925a3b844bc454e4438f44e")
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := parser.processBatch(0, blocks); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(node.bytesSent.Load())/float64(b.N*blocks), "bytes/block")
+		})
+	}
+}
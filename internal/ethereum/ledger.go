@@ -0,0 +1,271 @@
+package ethereum
+
+import (
+	"errors"
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrLedgerDisabled    = errors.New("ledger is disabled")
+	ErrBlockNotProcessed = errors.New("block not processed yet")
+)
+
+// gwei is the unit of withdrawal amounts.
+var gwei = big.NewInt(1_000_000_000)
+
+// SetLedger enables the balance ledger of subscribed addresses. The receipt
+// of every matched transaction is then fetched to record the fee and whether
+// the value moved. With traceInternal, every processed block is also traced
+// with debug_traceBlockByNumber to record value moved by contract calls,
+// which needs a node with the debug namespace.
+func (ep *EthParser) SetLedger(enabled, traceInternal bool) {
+	ep.ledger = enabled
+	ep.traceInternal = enabled && traceInternal
+}
+
+// openLedger records the balance of a newly subscribed address at the last
+// processed block, which later entries build on.
+func (ep *EthParser) openLedger(address string) {
+	block := ep.storage.GetCurrentBlock() - 1
+	if block < 0 {
+		return
+	}
+	balance, err := ep.client.GetBalance(address, utils.EncodeUint64(uint64(block)))
+	if err != nil {
+		ep.logger.Printf("Ledger of %s opened without a balance: %v", address, err)
+		return
+	}
+	ep.storage.AddLedgerEntry(models.LedgerEntry{
+		Address:     address,
+		BlockNumber: block,
+		Kind:        models.LedgerOpening,
+		Direction:   models.LedgerCredit,
+		Amount:      balance.String(),
+	})
+}
+
+// recordTransaction turns a matched transaction into ledger entries of b for
+// the subscribed addresses it involves. receipt is nil when it could not be
+// fetched, in which case the fee is unknown and the transaction is assumed
+// to have succeeded.
+func (ep *EthParser) recordTransaction(b *fetchedBlock, tx models.Transaction, receipt *models.Receipt) {
+	blockNumber, err := utils.HexToInt(tx.BlockNumber)
+	if err != nil {
+		ep.logger.Printf("Ledger: transaction %s has an invalid block number %q", tx.Hash, tx.BlockNumber)
+		return
+	}
+	entry := func(address string, kind models.LedgerKind, direction models.LedgerDirection, amount *big.Int) {
+		if address == "" || amount.Sign() == 0 || !ep.storage.IsWatched(address) {
+			return
+		}
+		b.addLedgerEntry(models.LedgerEntry{
+			Address:         address,
+			BlockNumber:     blockNumber,
+			TransactionHash: tx.Hash,
+			Kind:            kind,
+			Direction:       direction,
+			Amount:          amount.String(),
+		})
+	}
+
+	// Deposits and Arbitrum system transactions are not paid for by
+	// their sender.
+	paysFee := !tx.IsDeposit() && !tx.IsArbitrumSystemTx()
+	if receipt == nil {
+		ep.logger.Printf("Ledger: no receipt for %s, fee not recorded", tx.Hash)
+	} else if paysFee && tx.Fees != nil {
+		if fee, err := utils.DecodeBig(tx.Fees.TotalFee); err == nil {
+			if l1Fee, err := utils.DecodeBig(receipt.L1Fee); err == nil {
+				fee.Add(fee, l1Fee)
+			}
+			entry(tx.From, models.LedgerFee, models.LedgerDebit, fee)
+		}
+	}
+	if tx.IsDeposit() {
+		if mint, err := utils.DecodeBig(tx.Mint); err == nil {
+			entry(tx.From, models.LedgerMint, models.LedgerCredit, mint)
+		}
+	}
+
+	if receipt != nil && receipt.Status == "0x0" {
+		return
+	}
+	value, err := utils.DecodeBig(tx.Value)
+	if err != nil {
+		return
+	}
+	entry(tx.From, models.LedgerTransfer, models.LedgerDebit, value)
+	entry(tx.To, models.LedgerTransfer, models.LedgerCredit, value)
+}
+
+// recordBlock records the withdrawals of b and, with internal tracing, the
+// value moved by contract calls, for subscribed addresses.
+func (ep *EthParser) recordBlock(b *fetchedBlock) {
+	blockNum := b.number
+	for _, w := range b.header.Withdrawals {
+		if !ep.storage.IsWatched(w.Address) {
+			continue
+		}
+		amount, err := utils.DecodeBig(w.Amount)
+		if err != nil {
+			ep.logger.Printf("Ledger: withdrawal %s of block %d has an invalid amount %q", w.Index, blockNum, w.Amount)
+			continue
+		}
+		if amount.Sign() == 0 {
+			continue
+		}
+		b.addLedgerEntry(models.LedgerEntry{
+			Address:     w.Address,
+			BlockNumber: blockNum,
+			Kind:        models.LedgerWithdrawal,
+			Direction:   models.LedgerCredit,
+			Amount:      amount.Mul(amount, gwei).String(),
+		})
+	}
+
+	if !ep.traceInternal {
+		return
+	}
+	traces, err := ep.client.TraceBlockByNumber(blockNum)
+	if err != nil {
+		ep.logger.Printf("Ledger: internal transfers of block %d not recorded: %v", blockNum, err)
+		return
+	}
+	for _, trace := range traces {
+		if trace.Result.Error != "" {
+			continue
+		}
+		// The top level call is the transaction itself, which
+		// recordTransaction accounts for.
+		for _, call := range trace.Result.Calls {
+			ep.recordInternalCall(b, trace.TxHash, call)
+		}
+	}
+}
+
+// recordInternalCall records the value moved by a call and its subcalls,
+// skipping calls that reverted along with everything below them.
+func (ep *EthParser) recordInternalCall(b *fetchedBlock, txHash string, call models.CallFrame) {
+	if call.Error != "" {
+		return
+	}
+	switch call.Type {
+	case "CALL", "CREATE", "CREATE2", "SELFDESTRUCT":
+		value, err := utils.DecodeBig(call.Value)
+		if err == nil && value.Sign() > 0 {
+			for _, side := range []struct {
+				address   string
+				direction models.LedgerDirection
+			}{{call.From, models.LedgerDebit}, {call.To, models.LedgerCredit}} {
+				if side.address == "" || !ep.storage.IsWatched(side.address) {
+					continue
+				}
+				b.addLedgerEntry(models.LedgerEntry{
+					Address:         side.address,
+					BlockNumber:     b.number,
+					TransactionHash: txHash,
+					Kind:            models.LedgerInternal,
+					Direction:       side.direction,
+					Amount:          value.String(),
+				})
+			}
+		}
+	}
+	for _, sub := range call.Calls {
+		ep.recordInternalCall(b, txHash, sub)
+	}
+}
+
+// addLedgerEntry adds entry to the ledger entries of b, folding it into an
+// entry of the same kind and direction for the same address and transaction,
+// such as a second withdrawal or internal call: storage keeps one of those
+// per block.
+func (b *fetchedBlock) addLedgerEntry(entry models.LedgerEntry) {
+	for i, e := range b.ledger {
+		if strings.EqualFold(e.Address, entry.Address) && strings.EqualFold(e.TransactionHash, entry.TransactionHash) &&
+			e.Kind == entry.Kind && e.Direction == entry.Direction {
+			sum, _ := new(big.Int).SetString(e.Amount, 10)
+			amount, _ := new(big.Int).SetString(entry.Amount, 10)
+			b.ledger[i].Amount = sum.Add(sum, amount).String()
+			return
+		}
+	}
+	b.ledger = append(b.ledger, entry)
+}
+
+// GetBalanceHistory returns the ledger of address in block order with the
+// running balance after each entry, if the tenant is subscribed to it.
+func (ep *EthParser) GetBalanceHistory(address string) []models.LedgerEntry {
+	if !ep.storage.IsSubscribed(address) {
+		return nil
+	}
+	entries := ep.storage.GetLedgerEntries(address)
+	// Blocks are processed concurrently, so entries are not stored in
+	// block order. Within a block an opening balance comes first.
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].BlockNumber != entries[j].BlockNumber {
+			return entries[i].BlockNumber < entries[j].BlockNumber
+		}
+		return entries[i].Kind == models.LedgerOpening && entries[j].Kind != models.LedgerOpening
+	})
+	balance := new(big.Int)
+	for i := range entries {
+		amount, ok := new(big.Int).SetString(entries[i].Amount, 10)
+		if !ok {
+			amount = new(big.Int)
+		}
+		switch {
+		case entries[i].Kind == models.LedgerOpening:
+			balance.Set(amount)
+		case entries[i].Direction == models.LedgerDebit:
+			balance.Sub(balance, amount)
+		default:
+			balance.Add(balance, amount)
+		}
+		entries[i].Balance = balance.String()
+	}
+	return entries
+}
+
+// ReconcileBalance compares the balance of address computed from its ledger
+// with the balance the node reports at block. A difference means activity
+// the ledger missed, e.g. internal transfers without tracing enabled.
+func (ep *EthParser) ReconcileBalance(address string, block int64) (models.Reconciliation, error) {
+	if !ep.ledger {
+		return models.Reconciliation{}, ErrLedgerDisabled
+	}
+	if !ep.storage.IsSubscribed(address) {
+		return models.Reconciliation{}, fmt.Errorf("%w: %s", ErrNotSubscribed, address)
+	}
+	if current := ep.storage.GetCurrentBlock(); block >= current || block < 0 {
+		return models.Reconciliation{}, fmt.Errorf("%w: block %d, processed up to %d", ErrBlockNotProcessed, block, current-1)
+	}
+
+	computed := new(big.Int)
+	for _, entry := range ep.GetBalanceHistory(address) {
+		if entry.BlockNumber > block {
+			break
+		}
+		computed.SetString(entry.Balance, 10)
+	}
+	onChain, err := ep.client.GetBalance(address, utils.EncodeUint64(uint64(block)))
+	if err != nil {
+		return models.Reconciliation{}, err
+	}
+	difference := new(big.Int).Sub(onChain, computed)
+	result := models.Reconciliation{
+		Address:     strings.ToLower(address),
+		BlockNumber: block,
+		Computed:    computed.String(),
+		OnChain:     onChain.String(),
+		Difference:  difference.String(),
+		Reconciled:  difference.Sign() == 0,
+	}
+	ep.logger.Printf("Reconciled %s at block %d: computed %s, node %s", address, block, computed, onChain)
+	return result, nil
+}
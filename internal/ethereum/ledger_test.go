@@ -0,0 +1,99 @@
+package ethereum
+
+import (
+	"errors"
+	"eth-parser/pkg/models"
+	"fmt"
+	"testing"
+)
+
+func TestParserLedger(t *testing.T) {
+	node := newFakeChain(3, 0)
+	subscribed := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	other := "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	contract := "0xdac17f958d2ee523a2206206994597c13d831ec7"
+
+	block := node.blocks[1]
+	block.Transactions = append(block.Transactions,
+		// 1 ETH sent.
+		models.Transaction{BlockNumber: block.Number, Hash: "0x01", From: subscribed, To: other, Value: "0xde0b6b3a7640000", GasPrice: "0x3b9aca00", Input: "0x"},
+		// 2 ETH that never left: the transaction failed, only the fee is paid.
+		models.Transaction{BlockNumber: block.Number, Hash: "0x02", From: subscribed, To: contract, Value: "0x1bc16d674ec80000", GasPrice: "0x3b9aca00", Input: "0x"},
+		// A contract paying out to the subscribed address.
+		models.Transaction{BlockNumber: block.Number, Hash: "0x03", From: other, To: contract, Value: "0x0", GasPrice: "0x3b9aca00", Input: "0x"},
+	)
+	node.blocks[1] = block
+	block = node.blocks[2]
+	block.Withdrawals = []models.Withdrawal{
+		{Index: "0x1", ValidatorIndex: "0x10", Address: subscribed, Amount: "0x3b9aca00"}, // 1 ETH in gwei
+		{Index: "0x2", ValidatorIndex: "0x11", Address: other, Amount: "0x3b9aca00"},
+	}
+	node.blocks[2] = block
+
+	node.receipts = map[string]models.Receipt{
+		"0x01": {TransactionHash: "0x01", Status: "0x1", GasUsed: "0x5208", EffectiveGasPrice: "0x3b9aca00"},
+		"0x02": {TransactionHash: "0x02", Status: "0x0", GasUsed: "0x7530", EffectiveGasPrice: "0x3b9aca00"},
+	}
+	node.traces = map[int64][]models.TransactionTrace{1: {
+		{TxHash: "0x01", Result: models.CallFrame{Type: "CALL", From: subscribed, To: other, Value: "0xde0b6b3a7640000"}},
+		{TxHash: "0x02", Result: models.CallFrame{Type: "CALL", From: subscribed, To: contract, Value: "0x1bc16d674ec80000", Error: "execution reverted",
+			Calls: []models.CallFrame{{Type: "CALL", From: contract, To: subscribed, Value: "0x1"}}}},
+		{TxHash: "0x03", Result: models.CallFrame{Type: "CALL", From: other, To: contract, Value: "0x0", Calls: []models.CallFrame{
+			{Type: "CALL", From: contract, To: subscribed, Value: "0x6f05b59d3b20000"}, // 0.5 ETH
+			{Type: "CALL", From: contract, To: subscribed, Value: "0x3782dace9d90000"}, // 0.25 ETH
+			{Type: "CALL", From: contract, To: subscribed, Value: "0x7ce66c50e2840000", Error: "out of gas"},
+			{Type: "DELEGATECALL", From: contract, To: other, Value: "0x6f05b59d3b20000"},
+		}}},
+	}}
+	// 10 ETH at block 0; 10 - 1 + 0.75 + 1 ETH and 51000 gwei of fees less
+	// at block 2.
+	node.balances = map[string]string{
+		subscribed + ":0x0": "0x8ac7230489e80000",
+		subscribed + ":0x2": "0x952f7da8ea90d000",
+	}
+
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	parser.SetLedger(true, true)
+	parser.SetCurrentBlock(1)
+	parser.Subscribe(subscribed)
+	if err := parser.processBatch(1, 3); err != nil {
+		t.Fatal(err)
+	}
+	if current := parser.GetCurrentBlock(); current != 3 {
+		t.Errorf("current block after the batch = %d, want 3", current)
+	}
+	// A batch processed again, as after a failure, is not recorded twice.
+	if err := parser.processBatch(1, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, e := range parser.GetBalanceHistory(subscribed) {
+		got = append(got, fmt.Sprintf("%d %s %s %s %s %s", e.BlockNumber, e.TransactionHash, e.Kind, e.Direction, e.Amount, e.Balance))
+	}
+	want := []string{
+		"0  opening credit 10000000000000000000 10000000000000000000",
+		"1 0x01 fee debit 21000000000000 9999979000000000000",
+		"1 0x01 transfer debit 1000000000000000000 8999979000000000000",
+		"1 0x02 fee debit 30000000000000 8999949000000000000",
+		"1 0x03 internal credit 750000000000000000 9749949000000000000",
+		"2  withdrawal credit 1000000000000000000 10749949000000000000",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("balance history:\n%s\nwant:\n%s", fmt.Sprintln(got), fmt.Sprintln(want))
+	}
+
+	reconciliation, err := parser.ReconcileBalance(subscribed, 2)
+	if err != nil || !reconciliation.Reconciled || reconciliation.Computed != "10749949000000000000" {
+		t.Errorf("ReconcileBalance(2) = %+v, %v", reconciliation, err)
+	}
+	if _, err := parser.ReconcileBalance(subscribed, 3); !errors.Is(err, ErrBlockNotProcessed) {
+		t.Errorf("ReconcileBalance of an unprocessed block: %v", err)
+	}
+
+	parser.SetLedger(false, false)
+	if _, err := parser.ReconcileBalance(subscribed, 2); !errors.Is(err, ErrLedgerDisabled) {
+		t.Errorf("ReconcileBalance without ledger: %v", err)
+	}
+}
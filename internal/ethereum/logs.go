@@ -0,0 +1,117 @@
+package ethereum
+
+import (
+	"errors"
+	"eth-parser/pkg/models"
+	"fmt"
+	"time"
+)
+
+// ErrEmptyLogFilter rejects filters without addresses or topics, which would
+// match every log of every block.
+var ErrEmptyLogFilter = errors.New("log filter needs an address or a topic")
+
+// SubscribeLogs stores filter and returns the new subscription. Logs it
+// matches in later blocks are kept under the subscription's ID.
+func (ep *EthParser) SubscribeLogs(filter models.LogFilter) (models.LogSubscription, error) {
+	constrained := len(filter.Addresses) > 0
+	for _, position := range filter.Topics {
+		constrained = constrained || len(position) > 0
+	}
+	if !constrained {
+		return models.LogSubscription{}, ErrEmptyLogFilter
+	}
+
+	id, err := newID()
+	if err != nil {
+		return models.LogSubscription{}, fmt.Errorf("failed to generate subscription id: %w", err)
+	}
+	sub := models.LogSubscription{
+		ID:        id,
+		Tenant:    ep.storage.Tenant(),
+		Filter:    filter,
+		CreatedAt: time.Now().UTC(),
+	}
+	ep.storage.AddLogSubscription(sub)
+	ep.logger.Printf("Subscribed to logs: %s, id: %s, tenant: %s", filter, sub.ID, sub.Tenant)
+	return sub, nil
+}
+
+// UnsubscribeLogs deletes a log subscription of the tenant. It returns false
+// if the tenant has no subscription with the ID.
+func (ep *EthParser) UnsubscribeLogs(id string) bool {
+	success := false
+	if _, ok := ep.getLogSubscription(id); ok {
+		success = ep.storage.RemoveLogSubscription(id)
+	}
+	ep.logger.Printf("Unsubscribed from logs: %s, tenant: %s, success: %v", id, ep.storage.Tenant(), success)
+	return success
+}
+
+// GetLogSubscriptions returns the log subscriptions of the tenant.
+func (ep *EthParser) GetLogSubscriptions() []models.LogSubscription {
+	var subs []models.LogSubscription
+	for _, sub := range ep.storage.GetLogSubscriptions() {
+		if sub.Tenant == ep.storage.Tenant() {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// GetSubscriptionLogs returns the logs matched by a subscription, and false
+// if the tenant has no such subscription.
+func (ep *EthParser) GetSubscriptionLogs(id string) ([]models.Log, bool) {
+	if _, ok := ep.getLogSubscription(id); !ok {
+		return nil, false
+	}
+	return ep.storage.GetSubscriptionLogs(id), true
+}
+
+// getLogSubscription returns the log subscription with id if it belongs to
+// the tenant.
+func (ep *EthParser) getLogSubscription(id string) (models.LogSubscription, bool) {
+	sub, ok := ep.storage.GetLogSubscription(id)
+	if !ok || sub.Tenant != ep.storage.Tenant() {
+		return models.LogSubscription{}, false
+	}
+	return sub, true
+}
+
+// matchLogs collects the logs of b that pass a log subscription. The logs
+// are only requested when the block's bloom may match one of them.
+func (ep *EthParser) matchLogs(b *fetchedBlock) error {
+	header := b.header
+	subs := ep.storage.GetLogSubscriptions()
+	if len(subs) == 0 {
+		return nil
+	}
+	bloom, err := ParseBloom(header.LogsBloom)
+	if err != nil {
+		ep.logger.Printf("Block %s: unreadable logs bloom, fetching logs: %v", header.Number, err)
+	}
+	candidates := subs[:0]
+	for _, sub := range subs {
+		if err != nil || bloom.MayMatch(sub.Filter) {
+			candidates = append(candidates, sub)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	logs, err := ep.client.GetBlockLogs(header.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to get logs of block %s: %w", header.Number, err)
+	}
+	for _, log := range logs {
+		ep.labelLog(&log)
+		for _, sub := range candidates {
+			if sub.Filter.Matches(log) {
+				b.logs = append(b.logs, subscriptionLog{id: sub.ID, log: log})
+				ep.logger.Printf("Detected log for subscription %s: %s in transaction %s", sub.ID, log.Address, log.TransactionHash)
+			}
+		}
+	}
+	return nil
+}
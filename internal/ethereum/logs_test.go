@@ -0,0 +1,98 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"eth-parser/pkg/models"
+	"testing"
+)
+
+func TestParserLogSubscriptions(t *testing.T) {
+	const (
+		governor = "0x408ed6354d4973f66138c91495f2f2fcbd8724c3"
+		// VoteCast(address,uint256,uint8,uint256,string)
+		voteCast = "0xb8e138887d0aa13bab447e82de9d5c1777041ecd21ca36ba824ff1e6c07ddda4"
+		voter    = "0x000000000000000000000000742d35cc6634c0532925a3b844bc454e4438f44e"
+		other    = "0x000000000000000000000000a0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	)
+	node := newFakeChain(6, 2)
+	node.logs = map[string][]models.Log{}
+	emit := func(number int64, logs ...models.Log) {
+		block := node.blocks[number]
+		bloom, _ := ParseBloom(block.LogsBloom)
+		for i := range logs {
+			logs[i].BlockHash = block.Hash
+			address, _ := hex.DecodeString(logs[i].Address[2:])
+			bloom.Add(address)
+			for _, topic := range logs[i].Topics {
+				raw, _ := hex.DecodeString(topic[2:])
+				bloom.Add(raw)
+			}
+		}
+		block.LogsBloom = "0x" + hex.EncodeToString(bloom[:])
+		node.blocks[number] = block
+		node.logs[block.Hash] = logs
+	}
+	emit(1,
+		models.Log{Address: governor, Topics: []string{voteCast, voter}, LogIndex: "0x0"},
+		models.Log{Address: governor, Topics: []string{voteCast, other}, LogIndex: "0x1"},
+	)
+	emit(2, models.Log{Address: governor, Topics: []string{voteCast}, LogIndex: "0x0"}) // too few topics
+	emit(4, models.Log{Address: other[:2] + other[26:], Topics: []string{voteCast, voter}, LogIndex: "0x0"})
+
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+
+	if _, err := parser.SubscribeLogs(models.LogFilter{Topics: [][]models.Hash{{}}}); !errors.Is(err, ErrEmptyLogFilter) {
+		t.Errorf("SubscribeLogs with an empty filter = %v, want ErrEmptyLogFilter", err)
+	}
+
+	var filter models.LogFilter
+	if err := json.Unmarshal([]byte(`{"address":"`+governor+`","topics":["`+voteCast+`",["`+voter+`"]]}`), &filter); err != nil {
+		t.Fatal(err)
+	}
+	votes, err := parser.SubscribeLogs(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"topics":["`+voteCast+`"]}`), &filter); err != nil {
+		t.Fatal(err)
+	}
+	anyVote, err := parser.SubscribeLogs(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := parser.processBatch(0, 6); err != nil {
+		t.Fatal(err)
+	}
+
+	logs, ok := parser.GetSubscriptionLogs(votes.ID)
+	if !ok || len(logs) != 1 || logs[0].Topics[1] != voter {
+		t.Errorf("logs of the voter subscription = %+v, %v", logs, ok)
+	} else if logs[0].Event != "VoteCast(address,uint256,uint8,uint256,string)" {
+		t.Errorf("log labelled %q", logs[0].Event)
+	}
+	logs, _ = parser.GetSubscriptionLogs(anyVote.ID)
+	if len(logs) != 4 {
+		t.Errorf("expected 4 VoteCast logs from any contract, got %d", len(logs))
+	}
+	// Only the blocks whose bloom matched were asked for logs.
+	if fetched := node.logFetches.Load(); fetched != 3 {
+		t.Errorf("logs fetched for %d blocks, want 3", fetched)
+	}
+
+	// Log subscriptions are not visible to other tenants.
+	tenant := parser.ForTenant("team-b")
+	if _, ok := tenant.GetSubscriptionLogs(votes.ID); ok || len(tenant.GetLogSubscriptions()) != 0 || tenant.UnsubscribeLogs(votes.ID) {
+		t.Error("another tenant can reach the log subscriptions")
+	}
+
+	if len(parser.GetLogSubscriptions()) != 2 || !parser.UnsubscribeLogs(votes.ID) {
+		t.Fatal("expected to remove one of two subscriptions")
+	}
+	if _, ok := parser.GetSubscriptionLogs(votes.ID); ok {
+		t.Error("logs of a removed subscription are still served")
+	}
+}
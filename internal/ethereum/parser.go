@@ -1,11 +1,16 @@
 package ethereum
 
 import (
+	"errors"
+	"eth-parser/internal/matcher"
 	"eth-parser/internal/rpc"
 	"eth-parser/internal/storage"
+	"eth-parser/pkg/fourbyte"
 	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 )
@@ -17,31 +22,226 @@ type Parser interface {
 	// add address to observer
 	Subscribe(address string) bool
 
+	// view of the parser scoped to a tenant's subscriptions; everything
+	// else is shared by all tenants
+	ForTenant(tenant string) Parser
+
 	// list of inbound or outbound transactions for an address
 	GetTransactions(address string) []models.Transaction
 
 	GetSubscribeList() []string
 	Unsubscribe(address string) bool
+
+	// subscriptions with their label, tags and other metadata
+	AddSubscription(sub models.Subscription) (bool, error)
+	UpdateSubscription(sub models.Subscription) (models.Subscription, error)
+	GetSubscriptions() []models.Subscription
+	GetTransactionsByTag(tag string) []models.Transaction
+	SubscribeBulk(subs []models.Subscription, atomic bool) []models.BulkResult
+	UnsubscribeBulk(addresses []string, atomic bool) []models.BulkResult
+
+	// blocks that failed verification
+	GetRejectedBlocks() []models.RejectedBlock
+
+	// contract ABIs used to decode call input
+	RegisterABI(address string, abiJSON []byte) error
+	GetABI(address string) ([]byte, bool)
+	UnregisterABI(address string) bool
+
+	// log subscriptions with eth_getLogs filter semantics
+	SubscribeLogs(filter models.LogFilter) (models.LogSubscription, error)
+	UnsubscribeLogs(id string) bool
+	GetLogSubscriptions() []models.LogSubscription
+	GetSubscriptionLogs(id string) ([]models.Log, bool)
+
+	// ERC-20 token metadata, resolved on first use
+	GetToken(address string) (models.Token, error)
+	GetTokens() []models.Token
+
+	// ETH balance ledger of subscribed addresses
+	GetBalanceHistory(address string) []models.LedgerEntry
+	ReconcileBalance(address string, block int64) (models.Reconciliation, error)
+
+	// balance snapshots taken from the node and drift between them
+	GetBalanceSnapshots(address, token string) []models.BalanceSnapshot
+	GetBalanceDrifts() []models.BalanceDrift
+
+	// alert rules evaluated after every block and the alerts they raised
+	CreateAlertRule(rule models.AlertRule) (models.AlertRule, error)
+	UpdateAlertRule(id string, rule models.AlertRule) (models.AlertRule, error)
+	DeleteAlertRule(id string) bool
+	GetAlertRule(id string) (models.AlertRule, bool)
+	GetAlertRules() []models.AlertRule
+	GetAlerts() []models.Alert
+
 	Start()
 	Stop()
 }
 
+// FetchMode controls how much of each block is requested from the node.
+type FetchMode int
+
+const (
+	// FetchFull requests every block with full transaction objects.
+	FetchFull FetchMode = iota
+	// FetchSelective requests the block header with transaction hashes first
+	// and only pulls the transaction bodies when the block can contain
+	// activity for a subscribed address: the logs bloom matches one of them,
+	// or there are more subscriptions than the bloom can usefully rule out.
+	// Plain ETH transfers emit no logs, so below the threshold this mode only
+	// catches activity that shows up in the bloom.
+	FetchSelective
+)
+
+const defaultFullBlockThreshold = 1000
+
+// defaultPollInterval matches the Ethereum mainnet block time.
+const defaultPollInterval = 12 * time.Second
+
 type EthParser struct {
-	storage   storage.Storage
-	stopCh    chan struct{}
-	logger    *log.Logger
-	batchSize int64
+	storage            storage.Storage
+	client             *rpc.Client
+	matcher            matcher.Matcher
+	stopCh             chan struct{}
+	logger             *log.Logger
+	batchSize          int64
+	fetchMode          FetchMode
+	fullBlockThreshold int
+	verifyBlocks       bool
+	verifySenders      bool
+	pollInterval       time.Duration
+	blobSchedule       BlobSchedule
+	confirmations      int64
+	ledger             bool
+	traceInternal      bool
+	snapshotInterval   int64
+	snapshotTokens     []models.Address
+	// lastSnapshot is the block of the newest snapshot round; only the
+	// background task touches it.
+	lastSnapshot int64
+
+	// tenantLimit and tenantLimits bound the subscriptions of a tenant,
+	// see SetTenantQuotas.
+	tenantLimit  int
+	tenantLimits map[string]int
+
+	// abis caches parsed contract ABIs by lowercase address.
+	abis       *sync.Map
+	signatures *fourbyte.DB
+	tokens     *TokenRegistry
+
+	// headers holds the verified hash and parent hash of recently processed
+	// blocks so that consecutive blocks can be checked for linkage.
+	headers *headerLinks
+}
+
+type headerLinks struct {
+	mu     sync.Mutex
+	blocks map[int64]headerLink
+}
+
+type headerLink struct {
+	hash       string
+	parentHash string
 }
 
+// headerLinkWindow is how many processed blocks are kept for linkage checks.
+const headerLinkWindow = 128
+
 func NewEthParser(storage storage.Storage, logger *log.Logger) *EthParser {
-	return &EthParser{
-		storage:   storage,
-		stopCh:    make(chan struct{}),
-		logger:    logger,
-		batchSize: 10, // Process 10 blocks concurrently
+	ep := &EthParser{
+		storage:            storage,
+		client:             rpc.DefaultClient(),
+		matcher:            matcher.NewAddressSet(0),
+		stopCh:             make(chan struct{}),
+		logger:             logger,
+		batchSize:          10, // Process 10 blocks concurrently
+		fetchMode:          FetchFull,
+		fullBlockThreshold: defaultFullBlockThreshold,
+		pollInterval:       defaultPollInterval,
+		blobSchedule:       MainnetBlobSchedule,
+		signatures:         fourbyte.Default(),
+		abis:               new(sync.Map),
+		headers:            &headerLinks{blocks: make(map[int64]headerLink)},
+	}
+	ep.tokens = NewTokenRegistry(ep.client, storage, logger)
+	// Storage stays the source of truth for subscriptions; the matcher is an
+	// index over the addresses of all tenants rebuilt on startup.
+	for _, address := range storage.GetWatchedAddresses() {
+		if parsed, err := models.HexToAddress(address); err == nil {
+			ep.matcher.Add(parsed)
+		}
+	}
+	return ep
+}
+
+// ForTenant returns a view of the parser whose subscriptions, and the
+// transactions, balances and drifts read through them, are those of tenant.
+// Blocks are fetched and matched once for the addresses of all tenants.
+func (ep *EthParser) ForTenant(tenant string) Parser {
+	view := *ep
+	view.storage = ep.storage.ForTenant(tenant)
+	return &view
+}
+
+// SetClient points the parser at the nodes of its chain. Parsers use the
+// package-level default client otherwise.
+func (ep *EthParser) SetClient(client *rpc.Client) {
+	ep.client = client
+	ep.tokens = NewTokenRegistry(client, ep.storage, ep.logger)
+}
+
+// SetBlockTime sets how often the parser polls for new blocks, normally the
+// block time of its chain.
+func (ep *EthParser) SetBlockTime(blockTime time.Duration) {
+	if blockTime > 0 {
+		ep.pollInterval = blockTime
+	}
+}
+
+// SetConfirmations makes the parser stay the given number of blocks behind
+// the chain head, so that blocks are only processed once they are unlikely
+// to be reorganized.
+func (ep *EthParser) SetConfirmations(confirmations int64) {
+	if confirmations >= 0 {
+		ep.confirmations = confirmations
 	}
 }
 
+// SetBlobSchedule sets the blob forks used to price blob gas, for chains
+// other than Ethereum mainnet.
+func (ep *EthParser) SetBlobSchedule(schedule BlobSchedule) {
+	ep.blobSchedule = schedule
+}
+
+// SetFetchMode switches between full and selective block retrieval. With
+// FetchSelective, blocks are always fetched in full once more than
+// fullBlockThreshold addresses are subscribed.
+func (ep *EthParser) SetFetchMode(mode FetchMode, fullBlockThreshold int) {
+	ep.fetchMode = mode
+	if fullBlockThreshold > 0 {
+		ep.fullBlockThreshold = fullBlockThreshold
+	}
+}
+
+// SetVerifyBlocks enables verification of everything the node returns: each
+// header must hash to the reported block hash and link to the previously
+// processed block through its parent hash, and the transaction bodies must
+// match the header's transactions root.
+func (ep *EthParser) SetVerifyBlocks(enabled bool) {
+	ep.verifyBlocks = enabled
+}
+
+// SetVerifySenders enables recovering the sender of every transaction in a
+// fetched block from its signature and checking it, along with the
+// transaction hash, against what the node reported. A transaction whose
+// sender disagrees is kept with RecoveredFrom set; a hash that disagrees
+// rejects the block like any other verification failure. Recovery costs
+// about a millisecond per transaction.
+func (ep *EthParser) SetVerifySenders(enabled bool) {
+	ep.verifySenders = enabled
+}
+
 func (ep *EthParser) GetCurrentBlock() int64 {
 	return ep.storage.GetCurrentBlock()
 }
@@ -54,24 +254,48 @@ func (ep *EthParser) GetSubscribeList() []string {
 	return ep.storage.GetSubscribeList()
 }
 
+// Subscribe subscribes address without metadata, see AddSubscription.
 func (ep *EthParser) Subscribe(address string) bool {
-	success := ep.storage.Subscribe(address)
-	ep.logger.Printf("Subscribed address: %s, success: %v", address, success)
+	success, _ := ep.AddSubscription(models.Subscription{Address: address})
 	return success
 }
 
+// Unsubscribe ends the tenant's subscription to address. The address stays
+// matched while other tenants watch it.
 func (ep *EthParser) Unsubscribe(address string) bool {
 	success := ep.storage.Unsubscribe(address)
-	ep.logger.Printf("Unsubscribed address: %s, success: %v", address, success)
+	if parsed, err := models.HexToAddress(address); err == nil && !ep.storage.IsWatched(address) {
+		ep.matcher.Remove(parsed)
+	}
+	ep.logger.Printf("Unsubscribed address: %s, tenant: %s, success: %v", address, ep.storage.Tenant(), success)
 	return success
 }
 
+// GetTransactions returns the transactions of address recorded since the
+// tenant's subscription started, and nothing if it is not subscribed.
 func (ep *EthParser) GetTransactions(address string) []models.Transaction {
-	return ep.storage.GetTransactions(address)
+	sub, ok := ep.storage.GetSubscription(address)
+	if !ok {
+		return nil
+	}
+	return ep.subscriptionTransactions(sub)
+}
+
+func (ep *EthParser) GetRejectedBlocks() []models.RejectedBlock {
+	return ep.storage.GetRejectedBlocks()
+}
+
+// confirmedBlock returns the newest block that has enough confirmations.
+func (ep *EthParser) confirmedBlock() (int64, error) {
+	latestBlock, err := ep.client.GetLatestBlockNumber()
+	if err != nil {
+		return 0, err
+	}
+	return latestBlock - ep.confirmations, nil
 }
 
 func (ep *EthParser) updateAndParseBlocks() error {
-	latestBlock, err := rpc.GetLatestBlockNumber()
+	latestBlock, err := ep.confirmedBlock()
 	if err != nil {
 		return fmt.Errorf("failed to get latest block number: %w", err)
 	}
@@ -89,39 +313,35 @@ func (ep *EthParser) updateAndParseBlocks() error {
 		}
 	}
 
-	for i := currentBlock; i <= latestBlock; i++ {
-		block, err := rpc.GetBlockByNumber(i)
-		if err != nil {
-			return err
-		}
-		for _, tx := range block.Transactions {
-			if ep.storage.IsSubscribed(tx.From) {
-				log.Printf("Detect tx for from address: %v", tx.From)
-				ep.storage.AddTransaction(tx)
-			} else if ep.storage.IsSubscribed(tx.To) {
-				log.Printf("Detect tx for to address: %v", tx.To)
-				ep.storage.AddTransaction(tx)
-			}
-		}
-	}
 	if currentBlock <= latestBlock {
-		ep.storage.SetCurrentBlock(latestBlock + 1)
+		ep.takeSnapshots(latestBlock)
+		ep.evaluateBalances(latestBlock)
 	}
 
 	return nil
 }
 
+// processBatch fetches the blocks of [start, end) concurrently and then
+// stores them in block order. Nothing is stored before every block has been
+// fetched, and each block is linked to its parent before anything found in
+// it is stored, so that a fabricated block never leaves data behind.
+// The current block advances past every block stored, so that a failure
+// only has the blocks after it processed again.
 func (ep *EthParser) processBatch(start, end int64) error {
 	var wg sync.WaitGroup
 	errCh := make(chan error, end-start)
+	blocks := make([]*fetchedBlock, end-start)
 
 	for i := start; i < end; i++ {
 		wg.Add(1)
 		go func(blockNum int64) {
 			defer wg.Done()
-			if err := ep.processBlock(blockNum); err != nil {
+			b, err := ep.processBlock(blockNum)
+			if err != nil {
 				errCh <- fmt.Errorf("failed to process block %d: %w", blockNum, err)
+				return
 			}
+			blocks[blockNum-start] = b
 		}(i)
 	}
 
@@ -131,28 +351,311 @@ func (ep *EthParser) processBatch(start, end int64) error {
 	if len(errCh) > 0 {
 		return <-errCh
 	}
+	for _, b := range blocks {
+		if err := ep.linkBlock(b); err != nil {
+			return fmt.Errorf("failed to process block %d: %w", b.number, err)
+		}
+		ep.commitBlock(b)
+		ep.storage.SetCurrentBlock(b.number + 1)
+	}
+	ep.pruneHeaders(end)
 	return nil
 }
 
-func (ep *EthParser) processBlock(blockNum int64) error {
-	block, err := rpc.GetBlockByNumber(blockNum)
+// processBlock fetches and matches one block. With verification enabled, a
+// block that fails verification is recorded as rejected and fetched again
+// from the next endpoint until every endpoint has been tried.
+func (ep *EthParser) processBlock(blockNum int64) (*fetchedBlock, error) {
+	for attempt := 1; ; attempt++ {
+		endpoint := ep.client.Endpoint()
+		b, err := ep.fetchAndMatchBlock(blockNum)
+		var rejection *blockRejection
+		if !errors.As(err, &rejection) {
+			return b, err
+		}
+		ep.rejectBlock(rejection, endpoint)
+		if attempt >= ep.client.EndpointCount() {
+			return nil, err
+		}
+	}
+}
+
+// fetchedBlock is what a block holds for the subscriptions: the matched
+// transactions, the ledger entries and the matching logs. It is only
+// stored by commitBlock.
+type fetchedBlock struct {
+	number int64
+	header models.Header
+	txs    []matchedTransaction
+	ledger []models.LedgerEntry
+	logs   []subscriptionLog
+}
+
+type matchedTransaction struct {
+	tx      models.Transaction
+	receipt *models.Receipt
+}
+
+type subscriptionLog struct {
+	id  string
+	log models.Log
+}
+
+// fetchAndMatchBlock fetches and verifies a block and collects what it holds
+// for the subscriptions without storing any of it.
+func (ep *EthParser) fetchAndMatchBlock(blockNum int64) (*fetchedBlock, error) {
+	if ep.fetchMode == FetchSelective {
+		header, err := ep.client.GetBlockHeaderByNumber(blockNum)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block header %d: %w", blockNum, err)
+		}
+		if !ep.needsFullBlock(header) {
+			ep.logger.Printf("Skipping block %d, transactions: %d", blockNum, len(header.TransactionHashes))
+			if err := ep.verifyHeader(blockNum, header.Header); err != nil {
+				return nil, err
+			}
+			b := &fetchedBlock{number: blockNum, header: header.Header}
+			if ep.ledger {
+				ep.recordBlock(b)
+			}
+			if err := ep.matchLogs(b); err != nil {
+				return nil, err
+			}
+			return b, nil
+		}
+	}
+
+	block, err := ep.client.GetBlockByNumber(blockNum)
 	if err != nil {
-		return fmt.Errorf("failed to get block %d: %w", blockNum, err)
+		return nil, fmt.Errorf("failed to get block %d: %w", blockNum, err)
+	}
+	if err := ep.verifyHeader(blockNum, block.Header); err != nil {
+		return nil, err
+	}
+	if ep.verifyBlocks {
+		err := VerifyTransactions(block)
+		if errors.Is(err, ErrUnverifiableTxType) {
+			ep.logger.Printf("Not verifying transactions of block %d: %v", blockNum, err)
+		} else if err != nil {
+			return nil, &blockRejection{number: blockNum, hash: block.Hash, err: err}
+		}
+	}
+	if ep.verifySenders {
+		for i, tx := range block.Transactions {
+			err := VerifyTransaction(tx)
+			if errors.Is(err, ErrSenderMismatch) {
+				// The block commits to the signature, not to the sender
+				// the node reported. The transaction is flagged and
+				// recorded for the recovered sender as well.
+				sender, _ := RecoverSender(tx)
+				block.Transactions[i].RecoveredFrom = sender.Checksum()
+				ep.logger.Printf("Block %d: %v", blockNum, err)
+			} else if err != nil {
+				return nil, &blockRejection{number: blockNum, hash: block.Hash, err: err}
+			}
+		}
 	}
 	ep.logger.Printf("Processing block %d, transactions: %d", blockNum, len(block.Transactions))
 
-	for _, tx := range block.Transactions {
-		if ep.storage.IsSubscribed(tx.From) || ep.storage.IsSubscribed(tx.To) {
-			ep.storage.AddTransaction(tx)
-			ep.logger.Printf("Detected transaction: from %s to %s, value: %s", tx.From, tx.To, tx.Value)
+	b := &fetchedBlock{number: blockNum, header: block.Header}
+	for i := range block.Transactions {
+		parseTokenTransfer(&block.Transactions[i])
+	}
+	matched := ep.matcher.Match(block.Transactions)
+	var rules []models.AlertRule
+	if len(matched) > 0 {
+		rules = ep.storage.GetAlertRules()
+	}
+	for _, tx := range matched {
+		var receipt *models.Receipt
+		if len(tx.BlobVersionedHashes) > 0 || ep.ledger || ep.needsReceipt(rules, tx) {
+			receipt = ep.attachFees(&tx, block.Header)
+		}
+		ep.decodeInput(&tx)
+		ep.attachTokenMetadata(&tx)
+		b.txs = append(b.txs, matchedTransaction{tx: tx, receipt: receipt})
+		if ep.ledger {
+			ep.recordTransaction(b, tx, receipt)
+		}
+		ep.logger.Printf("Detected transaction: from %s to %s, value: %s", tx.From, tx.To, tx.Value)
+	}
+
+	if ep.ledger {
+		ep.recordBlock(b)
+	}
+	if err := ep.matchLogs(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// commitBlock stores what was found in b, raises the alerts of its
+// transactions and records its header for the linkage check of the next
+// block.
+func (ep *EthParser) commitBlock(b *fetchedBlock) {
+	var rules []models.AlertRule
+	if len(b.txs) > 0 {
+		rules = ep.storage.GetAlertRules()
+	}
+	for _, m := range b.txs {
+		ep.storage.AddTransaction(m.tx)
+		ep.evaluateTransaction(rules, m.tx, m.receipt)
+	}
+	for _, entry := range b.ledger {
+		ep.storage.AddLedgerEntry(entry)
+	}
+	for _, l := range b.logs {
+		ep.storage.AddSubscriptionLog(l.id, l.log)
+	}
+	if ep.verifyBlocks {
+		ep.headers.mu.Lock()
+		ep.headers.blocks[b.number] = headerLink{hash: strings.ToLower(b.header.Hash), parentHash: strings.ToLower(b.header.ParentHash)}
+		ep.headers.mu.Unlock()
+	}
+}
+
+// attachFees fetches the receipt of a matched transaction and records what
+// it paid. A failure only costs the fee data, the transaction is still
+// recorded. It returns the receipt, or nil if it could not be fetched.
+func (ep *EthParser) attachFees(tx *models.Transaction, header models.Header) *models.Receipt {
+	receipt, err := ep.client.GetTransactionReceipt(tx.Hash)
+	if err != nil {
+		ep.logger.Printf("Fees of transaction %s unavailable: %v", tx.Hash, err)
+		return nil
+	}
+	fees, err := TransactionFees(*tx, header, receipt, ep.blobSchedule)
+	if err != nil {
+		ep.logger.Printf("Fees of transaction %s unavailable: %v", tx.Hash, err)
+		return &receipt
+	}
+	if receipt.BlobGasPrice != "" && len(tx.BlobVersionedHashes) > 0 {
+		// The node's price is used; a different one from the schedule
+		// points at a schedule that needs updating.
+		if computed, err := HeaderBlobBaseFee(header, ep.blobSchedule); err == nil && utils.EncodeBig(computed) != fees.BlobBaseFee {
+			ep.logger.Printf("Blob base fee of block %s: computed %s, node reported %s; the blob schedule may be outdated",
+				header.Number, utils.EncodeBig(computed), fees.BlobBaseFee)
 		}
 	}
+	tx.Fees = &fees
+	return &receipt
+}
+
+// needsFullBlock decides from the header alone whether the transaction bodies
+// of a block have to be fetched for matching.
+func (ep *EthParser) needsFullBlock(header models.BlockHeader) bool {
+	if len(header.TransactionHashes) == 0 {
+		return false
+	}
+
+	subscribed := ep.matcher.Len()
+	if subscribed == 0 {
+		return false
+	}
+	if subscribed > ep.fullBlockThreshold {
+		return true
+	}
+
+	bloom, err := ParseBloom(header.LogsBloom)
+	if err != nil {
+		ep.logger.Printf("Block %s: unreadable logs bloom, fetching full block: %v", header.Number, err)
+		return true
+	}
+	found := false
+	ep.matcher.Range(func(address models.Address) bool {
+		found = bloom.MayContainAddress(address)
+		return !found
+	})
+	return found
+}
+
+// verifyHeader checks the header hash when verification is enabled.
+func (ep *EthParser) verifyHeader(blockNum int64, header models.Header) error {
+	if !ep.verifyBlocks {
+		return nil
+	}
+	if err := VerifyHeader(header); err != nil {
+		return &blockRejection{number: blockNum, hash: header.Hash, err: err}
+	}
+	return nil
+}
+
+// linkBlock checks that b names the previously processed block as its
+// parent. A mismatch is either a reorg or a provider serving fabricated
+// blocks; the parent is fetched again to tell them apart, since after a
+// reorg the new parent verifies and links. A fabricated block is rejected
+// and the batch is retried on the next tick. After a reorg, the replacement
+// parent is linked in turn, walking back until the link holds, and then
+// takes the place of the orphaned block in storage.
+func (ep *EthParser) linkBlock(b *fetchedBlock) error {
+	if !ep.verifyBlocks {
+		return nil
+	}
+	ep.headers.mu.Lock()
+	parent, ok := ep.headers.blocks[b.number-1]
+	ep.headers.mu.Unlock()
+	if !ok || strings.EqualFold(b.header.ParentHash, parent.hash) {
+		return nil
+	}
 
+	endpoint := ep.client.Endpoint()
+	replacement, err := ep.processBlock(b.number - 1)
+	if err != nil {
+		return fmt.Errorf("failed to refetch block %d: %w", b.number-1, err)
+	}
+	if !strings.EqualFold(replacement.header.Hash, b.header.ParentHash) {
+		rejection := &blockRejection{
+			number: b.number,
+			hash:   b.header.Hash,
+			err:    fmt.Errorf("block %d: %w: expected %s, got %s", b.number, ErrParentHashMismatch, parent.hash, strings.ToLower(b.header.ParentHash)),
+		}
+		ep.rejectBlock(rejection, endpoint)
+		return rejection
+	}
+	ep.logger.Printf("Reorg detected at block %d: %s replaced by %s", b.number-1, parent.hash, replacement.header.Hash)
+	if err := ep.linkBlock(replacement); err != nil {
+		return err
+	}
+	ep.storage.RemoveBlock(b.number - 1)
+	ep.commitBlock(replacement)
 	return nil
 }
 
+// pruneHeaders forgets the headers that fell out of the linkage window.
+func (ep *EthParser) pruneHeaders(end int64) {
+	ep.headers.mu.Lock()
+	defer ep.headers.mu.Unlock()
+	for n := range ep.headers.blocks {
+		if n < end-headerLinkWindow {
+			delete(ep.headers.blocks, n)
+		}
+	}
+}
+
+// blockRejection is a verification failure of a block served by a node.
+type blockRejection struct {
+	number int64
+	hash   string
+	err    error
+}
+
+func (r *blockRejection) Error() string { return r.err.Error() }
+func (r *blockRejection) Unwrap() error { return r.err }
+
+// rejectBlock records the rejection and fails over to the next endpoint.
+func (ep *EthParser) rejectBlock(rejection *blockRejection, endpoint string) {
+	ep.storage.AddRejectedBlock(models.RejectedBlock{
+		Number:     rejection.number,
+		Hash:       rejection.hash,
+		Endpoint:   endpoint,
+		Reason:     rejection.err.Error(),
+		RejectedAt: time.Now().UTC(),
+	})
+	next := ep.client.Failover(endpoint)
+	ep.logger.Printf("Rejected block %d from %s: %v; now using %s", rejection.number, endpoint, rejection.err, next)
+}
+
 func (ep *EthParser) backgroundTask() {
-	ticker := time.NewTicker(12 * time.Second)
+	ticker := time.NewTicker(ep.pollInterval)
 	defer ticker.Stop()
 
 	for {
@@ -169,7 +672,7 @@ func (ep *EthParser) backgroundTask() {
 }
 
 func (ep *EthParser) Start() {
-	latestBlockNumber, err := rpc.GetLatestBlockNumber()
+	latestBlockNumber, err := ep.confirmedBlock()
 	if err != nil {
 		ep.logger.Fatalf("Failed to get latest block number: %v", err)
 	}
@@ -181,4 +684,5 @@ func (ep *EthParser) Start() {
 func (ep *EthParser) Stop() {
 	ep.logger.Println("Stopping parser")
 	close(ep.stopCh)
+	ep.tokens.Stop()
 }
@@ -0,0 +1,300 @@
+package ethereum
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var ErrUnknownRule = errors.New("unknown alert rule")
+
+// newID returns a random 128 bit identifier in hex.
+func newID() (string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id[:]), nil
+}
+
+// CreateAlertRule validates rule and stores it under a new ID. It is
+// evaluated from the next processed block on.
+func (ep *EthParser) CreateAlertRule(rule models.AlertRule) (models.AlertRule, error) {
+	if err := rule.Validate(); err != nil {
+		return models.AlertRule{}, err
+	}
+	id, err := newID()
+	if err != nil {
+		return models.AlertRule{}, fmt.Errorf("failed to generate rule id: %w", err)
+	}
+	rule.ID = id
+	rule.Tenant = ep.storage.Tenant()
+	rule.CreatedAt = time.Now().UTC()
+	rule.UpdatedAt = rule.CreatedAt
+	ep.storage.SetAlertRule(rule)
+	ep.logger.Printf("Created alert rule %s: %s %q", rule.ID, rule.Type, rule.Name)
+	return rule, nil
+}
+
+// UpdateAlertRule replaces the tenant's rule with the given ID, keeping its
+// creation time.
+func (ep *EthParser) UpdateAlertRule(id string, rule models.AlertRule) (models.AlertRule, error) {
+	existing, ok := ep.GetAlertRule(id)
+	if !ok {
+		return models.AlertRule{}, fmt.Errorf("%w: %s", ErrUnknownRule, id)
+	}
+	if err := rule.Validate(); err != nil {
+		return models.AlertRule{}, err
+	}
+	rule.ID = existing.ID
+	rule.Tenant = existing.Tenant
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = time.Now().UTC()
+	ep.storage.SetAlertRule(rule)
+	ep.logger.Printf("Updated alert rule %s: %s %q", rule.ID, rule.Type, rule.Name)
+	return rule, nil
+}
+
+// DeleteAlertRule deletes a rule of the tenant. It returns false if the
+// tenant has no rule with the ID.
+func (ep *EthParser) DeleteAlertRule(id string) bool {
+	success := false
+	if _, ok := ep.GetAlertRule(id); ok {
+		success = ep.storage.DeleteAlertRule(id)
+	}
+	ep.logger.Printf("Deleted alert rule %s, tenant: %s, success: %v", id, ep.storage.Tenant(), success)
+	return success
+}
+
+// GetAlertRule returns the rule with id if it belongs to the tenant.
+func (ep *EthParser) GetAlertRule(id string) (models.AlertRule, bool) {
+	rule, ok := ep.storage.GetAlertRule(id)
+	if !ok || rule.Tenant != ep.storage.Tenant() {
+		return models.AlertRule{}, false
+	}
+	return rule, true
+}
+
+// GetAlertRules returns the rules of the tenant, oldest first.
+func (ep *EthParser) GetAlertRules() []models.AlertRule {
+	var rules []models.AlertRule
+	for _, rule := range ep.storage.GetAlertRules() {
+		if rule.Tenant == ep.storage.Tenant() {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// GetAlerts returns the alerts raised for the tenant, oldest first.
+func (ep *EthParser) GetAlerts() []models.Alert {
+	var alerts []models.Alert
+	for _, alert := range ep.storage.GetAlerts() {
+		if alert.Tenant == ep.storage.Tenant() {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// ruleWatches reports whether rule applies to address, which its tenant
+// must be subscribed to.
+func (ep *EthParser) ruleWatches(rule models.AlertRule, address models.Address) bool {
+	return rule.Watches(address) && ep.storage.ForTenant(rule.Tenant).IsSubscribed(address.String())
+}
+
+// needsReceipt reports whether a rule has to know if tx, sent by a
+// subscribed address, reverted.
+func (ep *EthParser) needsReceipt(rules []models.AlertRule, tx models.Transaction) bool {
+	if len(rules) == 0 || !ep.storage.IsWatched(tx.From) {
+		return false
+	}
+	from, err := models.HexToAddress(tx.From)
+	if err != nil {
+		return false
+	}
+	for _, rule := range rules {
+		if rule.Type == models.RuleFailedTransaction && ep.ruleWatches(rule, from) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateTransaction checks a matched transaction against the rules. Every
+// transaction rule is about what a watched address sends, so only the sender
+// is considered. receipt is nil when it was not fetched.
+func (ep *EthParser) evaluateTransaction(rules []models.AlertRule, tx models.Transaction, receipt *models.Receipt) {
+	if len(rules) == 0 || !ep.storage.IsWatched(tx.From) {
+		return
+	}
+	from, err := models.HexToAddress(tx.From)
+	if err != nil {
+		return
+	}
+	blockNumber, _ := utils.HexToInt(tx.BlockNumber)
+	failed := receipt != nil && receipt.Status == "0x0"
+
+	for _, rule := range rules {
+		if !ep.ruleWatches(rule, from) {
+			continue
+		}
+		var message string
+		switch rule.Type {
+		case models.RuleOutboundValue:
+			if failed {
+				continue
+			}
+			amount, asset := outboundAmount(rule, tx, from)
+			threshold, err := rule.ThresholdValue()
+			if amount == nil || err != nil || amount.Cmp(threshold) <= 0 {
+				continue
+			}
+			message = fmt.Sprintf("%s sent %s %s, above %s", from.Checksum(), amount, asset, threshold)
+		case models.RuleCounterparty:
+			counterparty, ok := outboundCounterparty(tx, from)
+			if !ok || rule.Allows(counterparty) {
+				continue
+			}
+			message = fmt.Sprintf("%s sent to %s, which is not allowlisted", from.Checksum(), counterparty.Checksum())
+		case models.RuleFailedTransaction:
+			if !failed {
+				continue
+			}
+			message = fmt.Sprintf("transaction from %s reverted", from.Checksum())
+		default:
+			continue
+		}
+		ep.raiseAlert(rule, models.Alert{
+			DedupKey:        rule.ID + ":" + tx.Hash,
+			Address:         from.Checksum(),
+			TransactionHash: tx.Hash,
+			BlockNumber:     blockNumber,
+			Message:         message,
+		})
+	}
+}
+
+// outboundAmount returns what tx moves out of from in the asset the rule
+// watches: the value for ETH, or the amount of a transfer of the rule's
+// token. It returns nil when the transaction moves none of it.
+func outboundAmount(rule models.AlertRule, tx models.Transaction, from models.Address) (*big.Int, string) {
+	if rule.Token == nil {
+		value, err := utils.DecodeBig(tx.Value)
+		if err != nil {
+			return nil, ""
+		}
+		return value, "wei"
+	}
+	transfer := tx.TokenTransfer
+	if transfer == nil {
+		return nil, ""
+	}
+	token, err := models.HexToAddress(transfer.Token)
+	if err != nil || token != *rule.Token {
+		return nil, ""
+	}
+	sender, err := models.HexToAddress(transfer.From)
+	if err != nil || sender != from {
+		return nil, ""
+	}
+	amount, ok := new(big.Int).SetString(transfer.Amount, 10)
+	if !ok {
+		return nil, ""
+	}
+	return amount, "units of " + token.Checksum()
+}
+
+// outboundCounterparty returns who from pays with tx: the recipient of the
+// tokens of a transfer from from, otherwise the recipient of the
+// transaction. Contract creations have no counterparty.
+func outboundCounterparty(tx models.Transaction, from models.Address) (models.Address, bool) {
+	if transfer := tx.TokenTransfer; transfer != nil {
+		sender, err1 := models.HexToAddress(transfer.From)
+		recipient, err2 := models.HexToAddress(transfer.To)
+		if err1 == nil && err2 == nil && sender == from {
+			return recipient, true
+		}
+	}
+	to, err := models.HexToAddress(tx.To)
+	return to, err == nil
+}
+
+// evaluateBalances checks the balance rules against the balances the node
+// reports at block, the newest processed one. An address below the threshold
+// raises one alert, which is resolved once the balance recovers.
+func (ep *EthParser) evaluateBalances(block int64) {
+	var rules []models.AlertRule
+	for _, rule := range ep.storage.GetAlertRules() {
+		if rule.Type == models.RuleBalanceBelow {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	blockHex := utils.EncodeUint64(uint64(block))
+	for _, subscribed := range ep.storage.GetWatchedAddresses() {
+		address, err := models.HexToAddress(subscribed)
+		if err != nil {
+			continue
+		}
+		for _, rule := range rules {
+			if !ep.ruleWatches(rule, address) {
+				continue
+			}
+			threshold, err := rule.ThresholdValue()
+			if err != nil {
+				continue
+			}
+			var balance *big.Int
+			asset, key := "wei", rule.ID+":"+address.String()
+			if rule.Token == nil {
+				balance, err = ep.client.GetBalance(address.String(), blockHex)
+			} else {
+				balance, err = ep.tokenBalance(*rule.Token, address.String(), blockHex)
+				asset, key = "units of "+rule.Token.Checksum(), key+":"+rule.Token.String()
+			}
+			if err != nil {
+				ep.logger.Printf("Alert rule %s: balance of %s at block %d unavailable: %v", rule.ID, address.Checksum(), block, err)
+				continue
+			}
+			if balance.Cmp(threshold) >= 0 {
+				if ep.storage.ResolveAlert(key, time.Now().UTC()) {
+					ep.logger.Printf("Alert resolved: rule %s, %s balance %s %s", rule.ID, address.Checksum(), balance, asset)
+				}
+				continue
+			}
+			ep.raiseAlert(rule, models.Alert{
+				DedupKey:    key,
+				Address:     address.Checksum(),
+				BlockNumber: block,
+				Message:     fmt.Sprintf("%s balance %s %s is below %s", address.Checksum(), balance, asset, threshold),
+			})
+		}
+	}
+}
+
+// raiseAlert stores alert for rule unless its dedup key is already active.
+func (ep *EthParser) raiseAlert(rule models.AlertRule, alert models.Alert) {
+	id, err := newID()
+	if err != nil {
+		ep.logger.Printf("Alert rule %s: failed to generate alert id: %v", rule.ID, err)
+		return
+	}
+	alert.ID = id
+	alert.Tenant = rule.Tenant
+	alert.RuleID = rule.ID
+	alert.RuleName = rule.Name
+	alert.Severity = rule.Severity
+	alert.RaisedAt = time.Now().UTC()
+	if ep.storage.AddAlert(alert) {
+		ep.logger.Printf("Alert [%s] %s: %s", alert.Severity, rule.Name, alert.Message)
+	}
+}
@@ -0,0 +1,129 @@
+package ethereum
+
+import (
+	"errors"
+	"eth-parser/pkg/models"
+	"sort"
+	"testing"
+)
+
+func TestParserAlertRules(t *testing.T) {
+	node := newFakeChain(3, 0)
+	hot := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	exchange := "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	contract := "0xdac17f958d2ee523a2206206994597c13d831ec7"
+	hotAddress, _ := models.HexToAddress(hot)
+	exchangeAddress, _ := models.HexToAddress(exchange)
+
+	block := node.blocks[1]
+	block.Transactions = append(block.Transactions,
+		// 2 ETH to an allowlisted exchange.
+		models.Transaction{BlockNumber: block.Number, Hash: "0x01", From: hot, To: exchange, Value: "0x1bc16d674ec80000", GasPrice: "0x3b9aca00", Input: "0x"},
+		// A failed call to an unknown contract.
+		models.Transaction{BlockNumber: block.Number, Hash: "0x02", From: hot, To: contract, Value: "0x1", GasPrice: "0x3b9aca00", Input: "0x"},
+		// Inbound activity is never alerted on.
+		models.Transaction{BlockNumber: block.Number, Hash: "0x03", From: contract, To: hot, Value: "0x1bc16d674ec80000", GasPrice: "0x3b9aca00", Input: "0x"},
+	)
+	node.blocks[1] = block
+	node.receipts = map[string]models.Receipt{
+		"0x01": {TransactionHash: "0x01", Status: "0x1", GasUsed: "0x5208", EffectiveGasPrice: "0x3b9aca00"},
+		"0x02": {TransactionHash: "0x02", Status: "0x0", GasUsed: "0x7530", EffectiveGasPrice: "0x3b9aca00"},
+	}
+	node.balances = map[string]string{hot + ":0x2": "0x5"}
+
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	parser.SetCurrentBlock(1)
+	parser.Subscribe(hot)
+
+	create := func(rule models.AlertRule) models.AlertRule {
+		t.Helper()
+		created, err := parser.CreateAlertRule(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return created
+	}
+	outbound := create(models.AlertRule{Type: models.RuleOutboundValue, Severity: models.SeverityCritical, Threshold: "1000000000000000000"})
+	counterparty := create(models.AlertRule{Type: models.RuleCounterparty, Allowlist: []models.Address{exchangeAddress}})
+	failed := create(models.AlertRule{Type: models.RuleFailedTransaction, Addresses: []models.Address{hotAddress}})
+	balance := create(models.AlertRule{Name: "hot wallet low", Type: models.RuleBalanceBelow, Threshold: "10"})
+	create(models.AlertRule{Type: models.RuleFailedTransaction, Addresses: []models.Address{exchangeAddress}})
+	// Rules of another tenant only watch the addresses it subscribes to.
+	tenant := parser.ForTenant("team-b")
+	tenantRule, err := tenant.CreateAlertRule(models.AlertRule{Type: models.RuleFailedTransaction})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := parser.processBatch(1, 3); err != nil {
+		t.Fatal(err)
+	}
+	parser.evaluateBalances(2)
+	parser.evaluateBalances(2)
+
+	alerts := parser.GetAlerts()
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].DedupKey < alerts[j].DedupKey })
+	want := map[string]models.AlertSeverity{
+		outbound.ID + ":0x01":     models.SeverityCritical,
+		counterparty.ID + ":0x02": models.SeverityWarning,
+		failed.ID + ":0x02":       models.SeverityWarning,
+		balance.ID + ":" + hot:    models.SeverityWarning,
+	}
+	if len(alerts) != len(want) {
+		t.Fatalf("expected %d alerts, got %+v", len(want), alerts)
+	}
+	if alerts := tenant.GetAlerts(); len(alerts) != 0 {
+		t.Errorf("alerts of another tenant = %+v", alerts)
+	}
+	for _, alert := range alerts {
+		severity, ok := want[alert.DedupKey]
+		if !ok || alert.Severity != severity {
+			t.Errorf("unexpected alert %+v", alert)
+		}
+		if alert.Address != hotAddress.Checksum() {
+			t.Errorf("alert %s: address = %s", alert.DedupKey, alert.Address)
+		}
+	}
+
+	// The balance recovers, resolving the alert; a new drop raises again.
+	node.balances[hot+":0x2"] = "0x20"
+	parser.evaluateBalances(2)
+	node.balances[hot+":0x2"] = "0x1"
+	parser.evaluateBalances(2)
+	var episodes []models.Alert
+	for _, alert := range parser.GetAlerts() {
+		if alert.RuleID == balance.ID {
+			episodes = append(episodes, alert)
+		}
+	}
+	if len(episodes) != 2 || episodes[0].ResolvedAt == nil || episodes[1].ResolvedAt != nil {
+		t.Errorf("balance alerts = %+v", episodes)
+	}
+
+	updated, err := parser.UpdateAlertRule(balance.ID, models.AlertRule{Type: models.RuleBalanceBelow, Threshold: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ID != balance.ID || !updated.CreatedAt.Equal(balance.CreatedAt) || updated.Name != string(models.RuleBalanceBelow) {
+		t.Errorf("updated rule = %+v", updated)
+	}
+	if _, err := parser.UpdateAlertRule("missing", updated); !errors.Is(err, ErrUnknownRule) {
+		t.Errorf("update of a missing rule: %v", err)
+	}
+	if _, err := parser.CreateAlertRule(models.AlertRule{Type: models.RuleOutboundValue}); !errors.Is(err, models.ErrInvalidRule) {
+		t.Errorf("rule without threshold: %v", err)
+	}
+	if !parser.DeleteAlertRule(balance.ID) || len(parser.GetAlertRules()) != 4 {
+		t.Errorf("rules after delete = %+v", parser.GetAlertRules())
+	}
+	if rules := tenant.GetAlertRules(); len(rules) != 1 || rules[0].ID != tenantRule.ID {
+		t.Errorf("rules of another tenant = %+v", rules)
+	}
+	if _, ok := tenant.GetAlertRule(outbound.ID); ok || tenant.DeleteAlertRule(outbound.ID) {
+		t.Error("another tenant can reach the rule")
+	}
+	if _, err := tenant.UpdateAlertRule(outbound.ID, updated); !errors.Is(err, ErrUnknownRule) {
+		t.Errorf("update of another tenant's rule: %v", err)
+	}
+}
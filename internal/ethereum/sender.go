@@ -0,0 +1,159 @@
+package ethereum
+
+import (
+	"errors"
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/secp256k1"
+	"eth-parser/pkg/utils"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrInvalidSignature        = errors.New("invalid transaction signature")
+	ErrSenderMismatch          = errors.New("sender mismatch")
+	ErrTransactionHashMismatch = errors.New("transaction hash mismatch")
+	ErrUnsignedTransaction     = errors.New("transaction type carries no signature")
+)
+
+// TransactionHash returns keccak256 of the consensus encoding of tx.
+func TransactionHash(tx models.Transaction) (models.Hash, error) {
+	encoded, err := EncodeTransaction(tx)
+	if err != nil {
+		return models.Hash{}, err
+	}
+	return utils.Keccak256(encoded), nil
+}
+
+// SigningHash returns the hash the sender signed and the recovery id of the
+// signature. Legacy transactions are either unprotected (v = 27 or 28) or
+// commit to a chain id as in EIP-155 (v = chainId*2 + 35 or 36); typed
+// transactions sign type || rlp(fields) and carry the y parity directly.
+func SigningHash(tx models.Transaction) (models.Hash, byte, error) {
+	txType, err := TransactionType(tx)
+	if err != nil {
+		return models.Hash{}, 0, fmt.Errorf("transaction %s: field type: %w", tx.Hash, err)
+	}
+	if txType == models.DepositTxType || tx.IsArbitrumSystemTx() {
+		return models.Hash{}, 0, fmt.Errorf("transaction %s: %w", tx.Hash, ErrUnsignedTransaction)
+	}
+
+	e := &fieldEncoder{}
+	fields, err := unsignedFields(e, tx, txType)
+	if err != nil {
+		return models.Hash{}, 0, err
+	}
+
+	var recoveryID byte
+	if txType == LegacyTxType {
+		v, err := utils.DecodeBig(tx.V)
+		if err != nil {
+			return models.Hash{}, 0, fmt.Errorf("transaction %s: field v: %w", tx.Hash, err)
+		}
+		switch {
+		case v.Cmp(big.NewInt(27)) == 0 || v.Cmp(big.NewInt(28)) == 0:
+			recoveryID = byte(v.Uint64() - 27)
+		case v.Cmp(big.NewInt(35)) >= 0:
+			// EIP-155: chainId, 0, 0 replace the signature in the signed list.
+			chainID := new(big.Int).Sub(v, big.NewInt(35))
+			recoveryID = byte(chainID.Bit(0))
+			chainID.Rsh(chainID, 1)
+			fields = append(fields, e.quantity("v", utils.EncodeBig(chainID)), e.quantity("r", "0x0"), e.quantity("s", "0x0"))
+		default:
+			return models.Hash{}, 0, fmt.Errorf("transaction %s: %w: v = %s", tx.Hash, ErrInvalidSignature, tx.V)
+		}
+	} else {
+		yParity := tx.YParity
+		if yParity == "" {
+			yParity = tx.V
+		}
+		parity, err := utils.DecodeUint64(yParity)
+		if err != nil {
+			return models.Hash{}, 0, fmt.Errorf("transaction %s: field yParity: %w", tx.Hash, err)
+		}
+		if parity > 1 {
+			return models.Hash{}, 0, fmt.Errorf("transaction %s: %w: yParity = %d", tx.Hash, ErrInvalidSignature, parity)
+		}
+		recoveryID = byte(parity)
+	}
+
+	if e.err != nil {
+		return models.Hash{}, 0, fmt.Errorf("transaction %s: %w", tx.Hash, e.err)
+	}
+	return utils.Keccak256(envelope(txType, fields)), recoveryID, nil
+}
+
+// RecoverSender derives the sender of tx from its signature. Signatures with
+// s in the upper half of the curve order are rejected as in EIP-2, since
+// every transaction since Homestead has a low s.
+func RecoverSender(tx models.Transaction) (models.Address, error) {
+	hash, recoveryID, err := SigningHash(tx)
+	if err != nil {
+		return models.Address{}, err
+	}
+	r, err := utils.DecodeBig(tx.R)
+	if err != nil {
+		return models.Address{}, fmt.Errorf("transaction %s: field r: %w", tx.Hash, err)
+	}
+	s, err := utils.DecodeBig(tx.S)
+	if err != nil {
+		return models.Address{}, fmt.Errorf("transaction %s: field s: %w", tx.Hash, err)
+	}
+	if s.Cmp(secp256k1.HalfN) > 0 {
+		return models.Address{}, fmt.Errorf("transaction %s: %w: high s", tx.Hash, ErrInvalidSignature)
+	}
+
+	x, y, err := secp256k1.RecoverPublicKey(hash[:], r, s, recoveryID)
+	if err != nil {
+		return models.Address{}, fmt.Errorf("transaction %s: %w: %v", tx.Hash, ErrInvalidSignature, err)
+	}
+	return PublicKeyToAddress(x, y), nil
+}
+
+// PublicKeyToAddress returns the last 20 bytes of keccak256(x || y).
+func PublicKeyToAddress(x, y *big.Int) models.Address {
+	var pub [64]byte
+	x.FillBytes(pub[:32])
+	y.FillBytes(pub[32:])
+	hash := utils.Keccak256(pub[:])
+
+	var address models.Address
+	copy(address[:], hash[12:])
+	return address
+}
+
+// VerifyTransaction checks that the hash and sender reported for tx are the
+// ones its encoding and signature commit to. Deposits have no signature, so
+// only their hash is checked; Arbitrum system transactions are not checked.
+func VerifyTransaction(tx models.Transaction) error {
+	if tx.IsArbitrumSystemTx() {
+		return nil
+	}
+	hash, err := TransactionHash(tx)
+	if err != nil {
+		return err
+	}
+	reported, err := models.HexToHash(tx.Hash)
+	if err != nil {
+		return fmt.Errorf("transaction %s: invalid hash", tx.Hash)
+	}
+	if hash != reported {
+		return fmt.Errorf("transaction %s: %w: computed %s", tx.Hash, ErrTransactionHashMismatch, hash)
+	}
+	if tx.IsDeposit() {
+		return nil
+	}
+
+	sender, err := RecoverSender(tx)
+	if err != nil {
+		return err
+	}
+	from, err := models.HexToAddress(tx.From)
+	if err != nil {
+		return fmt.Errorf("transaction %s: invalid from %q", tx.Hash, tx.From)
+	}
+	if sender != from {
+		return fmt.Errorf("transaction %s: %w: reported %s, recovered %s", tx.Hash, ErrSenderMismatch, from.Checksum(), sender.Checksum())
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package ethereum
+
+import (
+	"errors"
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/secp256k1"
+	"eth-parser/pkg/utils"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// signTransaction signs tx with priv and fills in the signature, sender and
+// hash. The nonce is derived from the key and hash, which is good enough for
+// tests only.
+func signTransaction(t *testing.T, tx *models.Transaction, priv *big.Int) {
+	t.Helper()
+	txType, err := TransactionType(*tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Placeholders so the signing hash can be computed; fixed up below.
+	if txType == LegacyTxType && tx.V == "" {
+		tx.V = "0x25"
+	}
+	if txType != LegacyTxType {
+		tx.YParity = "0x0"
+	}
+	hash, _, err := SigningHash(*tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seed := utils.Keccak256(priv.Bytes(), hash[:])
+	k := new(big.Int).SetBytes(seed[:])
+	k.Mod(k, secp256k1.N)
+	rx, ry := secp256k1.ScalarBaseMult(k)
+	r := new(big.Int).Mod(rx, secp256k1.N)
+	s := new(big.Int).Mul(r, priv)
+	s.Add(s, new(big.Int).SetBytes(hash[:]))
+	s.Mul(s, new(big.Int).ModInverse(k, secp256k1.N))
+	s.Mod(s, secp256k1.N)
+	parity := ry.Bit(0)
+	if s.Cmp(secp256k1.HalfN) > 0 {
+		s.Sub(secp256k1.N, s)
+		parity ^= 1
+	}
+
+	tx.R, tx.S = utils.EncodeBig(r), utils.EncodeBig(s)
+	if txType == LegacyTxType {
+		v, _ := utils.DecodeBig(tx.V)
+		if v.Cmp(big.NewInt(28)) <= 0 {
+			v.SetInt64(27) // unprotected
+		} else {
+			// chainId*2 + 35
+			v.Sub(v, big.NewInt(35)).Rsh(v, 1).Lsh(v, 1).Add(v, big.NewInt(35))
+		}
+		tx.V = utils.EncodeBig(v.Add(v, big.NewInt(int64(parity))))
+	} else {
+		tx.YParity = fmt.Sprintf("0x%x", parity)
+		tx.V = tx.YParity
+	}
+
+	x, y := secp256k1.ScalarBaseMult(priv)
+	tx.From = PublicKeyToAddress(x, y).String()
+	txHash, err := TransactionHash(*tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Hash = txHash.String()
+}
+
+func TestSigningHashEIP155(t *testing.T) {
+	tx := eip155Transaction()
+	hash, recoveryID, err := SigningHash(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash.String() != "0xdaf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53" {
+		t.Errorf("signing hash = %s", hash)
+	}
+	if recoveryID != 0 {
+		t.Errorf("recovery id = %d, want 0", recoveryID)
+	}
+
+	sender, err := RecoverSender(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender.Checksum() != "0x9d8A62f656a8d1615C1294fd71e9CFb3E4855A4F" {
+		t.Errorf("sender = %s", sender.Checksum())
+	}
+}
+
+func TestPublicKeyToAddress(t *testing.T) {
+	x, y := secp256k1.ScalarBaseMult(big.NewInt(1))
+	if got := PublicKeyToAddress(x, y).Checksum(); got != "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf" {
+		t.Errorf("address of private key 1 = %s", got)
+	}
+}
+
+func TestRecoverSenderAllTypes(t *testing.T) {
+	priv := new(big.Int).SetBytes([]byte("eth-parser test key"))
+	for _, tc := range []struct {
+		name string
+		tx   models.Transaction
+	}{
+		{"legacy unprotected", models.Transaction{V: "0x1b"}},
+		{"legacy EIP-155", models.Transaction{V: "0x25"}},
+		{"legacy EIP-155 chain 137", models.Transaction{V: "0x135"}},
+		{"access list", models.Transaction{Type: "0x1", ChainID: "0x1", GasPrice: "0x4a817c800",
+			AccessList: []models.AccessListEntry{{
+				Address:     "0xdac17f958d2ee523a2206206994597c13d831ec7",
+				StorageKeys: []string{"0x0000000000000000000000000000000000000000000000000000000000000003"},
+			}}}},
+		{"dynamic fee", models.Transaction{Type: "0x2", ChainID: "0x1"}},
+		{"blob", models.Transaction{Type: "0x3", ChainID: "0x1", MaxFeePerBlobGas: "0x3b9aca00",
+			BlobVersionedHashes: []string{"0x01b0a4cdd5f55589f5c5b4d46c76704bb6ce95c0a8c09f77f197a57808dded28"}}},
+		{"set code", models.Transaction{Type: "0x4", ChainID: "0x1",
+			AuthorizationList: []models.Authorization{{
+				ChainID: "0x1", Address: "0x742d35cc6634c0532925a3b844bc454e4438f44e", Nonce: "0x0",
+				YParity: "0x1", R: "0x1", S: "0x2",
+			}}}},
+	} {
+		tx := tc.tx
+		tx.Nonce, tx.Gas, tx.Value, tx.Input = "0x7", "0x5208", "0xde0b6b3a7640000", "0xa9059cbb"
+		tx.To = "0x3535353535353535353535353535353535353535"
+		if tx.GasPrice == "" && tx.Type == "" {
+			tx.GasPrice = "0x4a817c800"
+		}
+		tx.MaxFeePerGas, tx.MaxPriorityFeePerGas = "0x6fc23ac00", "0x3b9aca00"
+		signTransaction(t, &tx, priv)
+
+		if err := VerifyTransaction(tx); err != nil {
+			t.Errorf("%s: VerifyTransaction: %v", tc.name, err)
+			continue
+		}
+
+		forged := tx
+		forged.From = "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+		if err := VerifyTransaction(forged); !errors.Is(err, ErrSenderMismatch) {
+			t.Errorf("%s: forged sender: err = %v, want ErrSenderMismatch", tc.name, err)
+		}
+
+		tampered := tx
+		tampered.Value = "0x1"
+		if err := VerifyTransaction(tampered); !errors.Is(err, ErrTransactionHashMismatch) {
+			t.Errorf("%s: tampered value: err = %v, want ErrTransactionHashMismatch", tc.name, err)
+		}
+	}
+}
+
+func TestRecoverSenderInvalid(t *testing.T) {
+	highS := eip155Transaction()
+	s, _ := utils.DecodeBig(highS.S)
+	highS.S = utils.EncodeBig(s.Sub(secp256k1.N, s))
+	if _, err := RecoverSender(highS); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("high s: err = %v, want ErrInvalidSignature", err)
+	}
+
+	badV := eip155Transaction()
+	badV.V = "0x1d"
+	if _, err := RecoverSender(badV); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("v = 29: err = %v, want ErrInvalidSignature", err)
+	}
+
+	badParity := eip155Transaction()
+	badParity.Type, badParity.ChainID, badParity.YParity = "0x1", "0x1", "0x2"
+	if _, err := RecoverSender(badParity); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("yParity = 2: err = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestParserVerifySenders(t *testing.T) {
+	node := newFakeChain(3, 0)
+	priv := big.NewInt(0x5eed)
+	block := node.blocks[1]
+	for i := 0; i < 3; i++ {
+		tx := eip155Transaction()
+		tx.Nonce = fmt.Sprintf("0x%x", i)
+		tx.V = ""
+		signTransaction(t, &tx, priv)
+		block.Transactions = append(block.Transactions, tx)
+	}
+	node.blocks[1] = block
+	sealChain(t, node)
+
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	parser.SetVerifySenders(true)
+	if err := parser.processBatch(0, 3); err != nil {
+		t.Fatalf("processBatch with valid signatures: %v", err)
+	}
+
+	// The node lies about a sender; the transactions root does not cover it.
+	// The block is kept and the transaction flagged with the real sender.
+	forger := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	block = node.blocks[1]
+	block.Transactions = append([]models.Transaction(nil), block.Transactions...)
+	block.Transactions[2].From = forger
+	node.blocks[1] = block
+	sender, err := RecoverSender(block.Transactions[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.Subscribe(forger)
+	if err := parser.processBatch(1, 2); err != nil {
+		t.Errorf("processBatch with a forged sender: %v", err)
+	}
+	if rejected := parser.GetRejectedBlocks(); len(rejected) != 0 {
+		t.Errorf("unexpected rejected blocks %+v", rejected)
+	}
+	txs := parser.GetTransactions(forger)
+	if len(txs) != 1 || txs[0].RecoveredFrom != sender.Checksum() {
+		t.Errorf("transactions of the reported sender = %+v, want one flagged with %s", txs, sender.Checksum())
+	}
+
+	// A transaction whose hash does not match its contents still rejects
+	// the block.
+	block.Transactions[1].Nonce = "0x9"
+	node.blocks[1] = block
+	if err := parser.processBatch(1, 2); !errors.Is(err, ErrTransactionHashMismatch) {
+		t.Errorf("processBatch with a forged transaction = %v, want ErrTransactionHashMismatch", err)
+	}
+	if rejected := parser.GetRejectedBlocks(); len(rejected) != 1 || rejected[0].Number != 1 {
+		t.Errorf("unexpected rejected blocks %+v", rejected)
+	}
+}
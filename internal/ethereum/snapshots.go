@@ -0,0 +1,197 @@
+package ethereum
+
+import (
+	"eth-parser/pkg/abi"
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var erc20BalanceOf = mustMethod("balanceOf(address)")
+
+// SetSnapshots makes the parser read the ETH balance of every subscribed
+// address, and its balance of each of tokens, at every interval-th block.
+// Snapshots are taken once the block has been processed, so a balance change
+// between two snapshots that the activity recorded in between does not
+// account for is activity the parser missed, which is recorded as drift.
+// ETH drift needs the ledger. An interval of 0 disables snapshots.
+func (ep *EthParser) SetSnapshots(interval int64, tokens []models.Address) {
+	if interval < 0 {
+		interval = 0
+	}
+	ep.snapshotInterval = interval
+	ep.snapshotTokens = append([]models.Address(nil), tokens...)
+}
+
+// takeSnapshots snapshots the balances at the newest multiple of the
+// interval up to processed, unless that block has been snapshotted already.
+// After downtime only the newest block is snapshotted, not every interval
+// missed.
+func (ep *EthParser) takeSnapshots(processed int64) {
+	if ep.snapshotInterval == 0 {
+		return
+	}
+	block := processed - processed%ep.snapshotInterval
+	if block <= ep.lastSnapshot {
+		return
+	}
+	ep.lastSnapshot = block
+
+	blockHex := utils.EncodeUint64(uint64(block))
+	for _, address := range ep.storage.GetWatchedAddresses() {
+		balance, err := ep.client.GetBalance(address, blockHex)
+		if err != nil {
+			ep.logger.Printf("Snapshot of %s at block %d failed: %v", address, block, err)
+		} else {
+			ep.recordSnapshot(address, "", block, balance)
+		}
+
+		for _, token := range ep.snapshotTokens {
+			balance, err := ep.tokenBalance(token, address, blockHex)
+			if err != nil {
+				ep.logger.Printf("Snapshot of %s in token %s at block %d failed: %v", address, token.Checksum(), block, err)
+				continue
+			}
+			ep.recordSnapshot(address, token.Checksum(), block, balance)
+		}
+	}
+}
+
+// tokenBalance calls balanceOf(holder) on token at block.
+func (ep *EthParser) tokenBalance(token models.Address, holder string, block string) (*big.Int, error) {
+	data, err := erc20BalanceOf.EncodeCall(holder)
+	if err != nil {
+		return nil, err
+	}
+	result, err := ep.client.Call(token.String(), data, block)
+	if err != nil {
+		return nil, err
+	}
+	values, err := abi.Decode(uint256Output, result)
+	if err != nil {
+		return nil, fmt.Errorf("balanceOf returned %s: %w", utils.EncodeBytes(result), err)
+	}
+	return values[0].(*big.Int), nil
+}
+
+// recordSnapshot stores a snapshot and compares it with the previous one of
+// the same series.
+func (ep *EthParser) recordSnapshot(address, token string, block int64, balance *big.Int) {
+	series := ep.storage.GetBalanceSnapshots(address, token)
+	now := time.Now().UTC()
+	ep.storage.AddBalanceSnapshot(models.BalanceSnapshot{
+		Address:     address,
+		Token:       token,
+		BlockNumber: block,
+		Balance:     balance.String(),
+		TakenAt:     now,
+	})
+	if len(series) == 0 {
+		return
+	}
+
+	previous := series[len(series)-1]
+	previousBalance, ok := new(big.Int).SetString(previous.Balance, 10)
+	if !ok {
+		return
+	}
+	change := new(big.Int).Sub(balance, previousBalance)
+	recorded, known := ep.recordedChange(address, token, previous.BlockNumber, block)
+	if !known || change.Cmp(recorded) == 0 {
+		return
+	}
+	drift := models.BalanceDrift{
+		Address:         address,
+		Token:           token,
+		FromBlock:       previous.BlockNumber,
+		ToBlock:         block,
+		PreviousBalance: previous.Balance,
+		Balance:         balance.String(),
+		Change:          change.String(),
+		Recorded:        recorded.String(),
+		DetectedAt:      now,
+	}
+	ep.storage.AddBalanceDrift(drift)
+	asset := "ETH"
+	if token != "" {
+		asset = token
+	}
+	ep.logger.Printf("Balance drift: %s %s balance changed by %s between blocks %d and %d, recorded activity accounts for %s",
+		address, asset, drift.Change, drift.FromBlock, drift.ToBlock, drift.Recorded)
+}
+
+// recordedChange returns how much the balance of address in token, or in
+// ETH when token is empty, moved in blocks (from, to] according to what was
+// recorded: the ledger entries for ETH, or the token transfers. ETH is only
+// known with the ledger, as transactions alone tell neither whether their
+// value moved nor the L1 fee of OP Stack chains.
+func (ep *EthParser) recordedChange(address, token string, from, to int64) (*big.Int, bool) {
+	inRange := func(n int64) bool { return n > from && n <= to }
+	change := new(big.Int)
+	if token == "" && ep.ledger {
+		for _, entry := range ep.storage.GetLedgerEntries(address) {
+			if entry.Kind == models.LedgerOpening || !inRange(entry.BlockNumber) {
+				continue
+			}
+			amount, ok := new(big.Int).SetString(entry.Amount, 10)
+			if !ok {
+				return nil, false
+			}
+			if entry.Direction == models.LedgerDebit {
+				change.Sub(change, amount)
+			} else {
+				change.Add(change, amount)
+			}
+		}
+		return change, true
+	}
+	if token == "" {
+		return nil, false
+	}
+
+	for _, tx := range ep.storage.GetTransactions(address) {
+		n, err := utils.HexToInt(tx.BlockNumber)
+		if err != nil || !inRange(n) {
+			continue
+		}
+		transfer := tx.TokenTransfer
+		if transfer == nil || !strings.EqualFold(transfer.Token, token) {
+			continue
+		}
+		amount, ok := new(big.Int).SetString(transfer.Amount, 10)
+		if !ok {
+			return nil, false
+		}
+		if strings.EqualFold(transfer.From, address) {
+			change.Sub(change, amount)
+		}
+		if strings.EqualFold(transfer.To, address) {
+			change.Add(change, amount)
+		}
+	}
+	return change, true
+}
+
+// GetBalanceSnapshots returns the balance series of address in token, or in
+// ETH when token is empty, oldest first, if the tenant is subscribed to it.
+func (ep *EthParser) GetBalanceSnapshots(address, token string) []models.BalanceSnapshot {
+	if !ep.storage.IsSubscribed(address) {
+		return nil
+	}
+	return ep.storage.GetBalanceSnapshots(address, token)
+}
+
+// GetBalanceDrifts returns the drifts detected for the addresses the tenant
+// is subscribed to, oldest first.
+func (ep *EthParser) GetBalanceDrifts() []models.BalanceDrift {
+	var drifts []models.BalanceDrift
+	for _, drift := range ep.storage.GetBalanceDrifts() {
+		if ep.storage.IsSubscribed(drift.Address) {
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts
+}
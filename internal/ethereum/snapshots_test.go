@@ -0,0 +1,96 @@
+package ethereum
+
+import (
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
+	"testing"
+)
+
+func TestParserBalanceSnapshots(t *testing.T) {
+	node := newFakeChain(21, 0)
+	quiet := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	active := "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	token, _ := models.HexToAddress("0xdac17f958d2ee523a2206206994597c13d831ec7")
+
+	other := "0x9f8f72aa9304c8b593d555f12ef6589cc3a579a2"
+
+	block := node.blocks[15]
+	block.Transactions = append(block.Transactions,
+		models.Transaction{BlockNumber: block.Number, Hash: "0x01", From: active, To: other, Value: "0x8", Input: "0x"},
+		// Reverted: only the fee leaves.
+		models.Transaction{BlockNumber: block.Number, Hash: "0x04", From: active, To: other, Value: "0x5", Input: "0x"},
+		// 1 wei recorded, but the balance grows by 2.
+		models.Transaction{BlockNumber: block.Number, Hash: "0x02", From: other, To: quiet, Value: "0x1", Input: "0x"},
+		// transfer(active, 500), which explains its new token balance.
+		models.Transaction{BlockNumber: block.Number, Hash: "0x03", From: other, To: token.String(), Value: "0x0",
+			Input: "0xa9059cbb000000000000000000000000a0b86991c6218b36c1d19d4a2e9eb0ce3606eb4800000000000000000000000000000000000000000000000000000000000001f4"},
+	)
+	node.blocks[15] = block
+	node.balances = map[string]string{
+		quiet + ":0xa":   "0x5",
+		quiet + ":0x14":  "0x7",
+		active + ":0xa":  "0x10",
+		active + ":0x14": "0x6",
+	}
+	node.receipts = map[string]models.Receipt{
+		"0x01": {TransactionHash: "0x01", Status: "0x1", GasUsed: "0x1", EffectiveGasPrice: "0x1"},
+		"0x04": {TransactionHash: "0x04", Status: "0x0", GasUsed: "0x1", EffectiveGasPrice: "0x1"},
+	}
+	balanceOf, err := erc20BalanceOf.EncodeCall(active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.calls = map[string]string{
+		token.String() + ":0x70a08231":                                utils.EncodeBytes(encodeOutput(t, uint256Output, 1000)),
+		token.String() + ":" + utils.EncodeBytes(balanceOf) + ":0x14": utils.EncodeBytes(encodeOutput(t, uint256Output, 1500)),
+	}
+
+	// The chain first ends at block 10.
+	later := map[int64]models.Block{}
+	for number := int64(11); number <= 20; number++ {
+		later[number] = node.blocks[number]
+		delete(node.blocks, number)
+	}
+
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	parser.SetLedger(true, false)
+	parser.SetSnapshots(10, []models.Address{token})
+	parser.Subscribe(quiet)
+	parser.Subscribe(active)
+	if err := parser.updateAndParseBlocks(); err != nil {
+		t.Fatal(err)
+	}
+	for number, b := range later {
+		node.blocks[number] = b
+	}
+	if err := parser.updateAndParseBlocks(); err != nil {
+		t.Fatal(err)
+	}
+
+	series := parser.GetBalanceSnapshots(quiet, "")
+	if len(series) != 2 || series[0].BlockNumber != 10 || series[0].Balance != "5" || series[1].BlockNumber != 20 || series[1].Balance != "7" {
+		t.Errorf("ETH snapshots = %+v", series)
+	}
+	if series := parser.GetBalanceSnapshots(quiet, token.Checksum()); len(series) != 2 || series[1].Balance != "1000" {
+		t.Errorf("token snapshots = %+v", series)
+	}
+
+	if series := parser.GetBalanceSnapshots(active, token.Checksum()); len(series) != 2 || series[1].Balance != "1500" {
+		t.Errorf("token snapshots of the recipient = %+v", series)
+	}
+
+	drifts := parser.GetBalanceDrifts()
+	if len(drifts) != 1 {
+		t.Fatalf("expected 1 drift, got %+v", drifts)
+	}
+	if d := drifts[0]; d.Address != quiet || d.Token != "" || d.FromBlock != 10 || d.ToBlock != 20 || d.Change != "2" || d.Recorded != "1" {
+		t.Errorf("drift = %+v", d)
+	}
+
+	// Without the ledger, ETH changes are not compared.
+	parser.SetLedger(false, false)
+	if _, known := parser.recordedChange(quiet, "", 10, 20); known {
+		t.Error("recorded ETH change known without the ledger")
+	}
+}
@@ -0,0 +1,152 @@
+package ethereum
+
+import (
+	"errors"
+	"eth-parser/internal/storage"
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	ErrNotSubscribed       = errors.New("address is not subscribed")
+	ErrStartBlockProcessed = errors.New("start block already processed")
+	ErrQuotaExceeded       = storage.ErrQuotaExceeded
+)
+
+// SetTenantQuotas bounds the number of addresses a tenant may subscribe to
+// on the chain: limits by tenant, and defaultLimit for the others. Zero is
+// unlimited.
+func (ep *EthParser) SetTenantQuotas(defaultLimit int, limits map[string]int) {
+	ep.tenantLimit = defaultLimit
+	ep.tenantLimits = limits
+}
+
+// tenantQuota returns the subscription limit of the tenant, zero if it has
+// none.
+func (ep *EthParser) tenantQuota() int {
+	if limit, ok := ep.tenantLimits[ep.storage.Tenant()]; ok {
+		return limit
+	}
+	return ep.tenantLimit
+}
+
+// AddSubscription subscribes the tenant to sub.Address with its metadata and
+// reports whether it was not subscribed yet; the metadata of an existing
+// subscription is left alone. A zero start block is the next block to be
+// processed. Earlier blocks are rejected, since processed blocks are not
+// scanned again. A tenant at its quota gets ErrQuotaExceeded.
+func (ep *EthParser) AddSubscription(sub models.Subscription) (bool, error) {
+	sub, err := ep.prepareSubscription(sub)
+	if err != nil {
+		return false, err
+	}
+	sub.CreatedAt = time.Now().UTC()
+
+	watched := ep.storage.IsWatched(sub.Address)
+	success, err := ep.storage.AddSubscription(sub, ep.tenantQuota())
+	if err != nil {
+		ep.logger.Printf("Subscribe address %s: %v", sub.Address, err)
+		return false, err
+	}
+	if parsed, err := models.HexToAddress(sub.Address); err == nil {
+		ep.matcher.Add(parsed)
+	}
+	// Another tenant's subscription already keeps the ledger.
+	if success && !watched && ep.ledger {
+		ep.openLedger(sub.Address)
+	}
+	ep.logger.Printf("Subscribed address: %s, tenant: %s, label: %q, tags: %v, success: %v", sub.Address, ep.storage.Tenant(), sub.Label, sub.Tags, success)
+	return success, nil
+}
+
+// prepareSubscription normalizes the tags of sub and fills in its start
+// block.
+func (ep *EthParser) prepareSubscription(sub models.Subscription) (models.Subscription, error) {
+	tags, err := models.NormalizeTags(sub.Tags)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+	sub.Tags = tags
+	current := ep.storage.GetCurrentBlock()
+	if sub.StartBlock == 0 {
+		sub.StartBlock = current
+	} else if sub.StartBlock < current {
+		return models.Subscription{}, fmt.Errorf("%w: start block %d, next block %d", ErrStartBlockProcessed, sub.StartBlock, current)
+	}
+	return sub, nil
+}
+
+// UpdateSubscription replaces the label, tags, owner team and notes of a
+// subscribed address. Its start block and creation time stay.
+func (ep *EthParser) UpdateSubscription(sub models.Subscription) (models.Subscription, error) {
+	existing, ok := ep.storage.GetSubscription(sub.Address)
+	if !ok {
+		return models.Subscription{}, fmt.Errorf("%w: %s", ErrNotSubscribed, sub.Address)
+	}
+	tags, err := models.NormalizeTags(sub.Tags)
+	if err != nil {
+		return models.Subscription{}, err
+	}
+	existing.Label = sub.Label
+	existing.Tags = tags
+	existing.OwnerTeam = sub.OwnerTeam
+	existing.Notes = sub.Notes
+	if !ep.storage.UpdateSubscription(existing) {
+		return models.Subscription{}, fmt.Errorf("%w: %s", ErrNotSubscribed, sub.Address)
+	}
+	ep.logger.Printf("Updated subscription: %s, label: %q, tags: %v", existing.Address, existing.Label, existing.Tags)
+	return existing, nil
+}
+
+// GetSubscriptions returns the subscribed addresses with their metadata,
+// oldest first.
+func (ep *EthParser) GetSubscriptions() []models.Subscription {
+	return ep.storage.GetSubscriptions()
+}
+
+// subscriptionTransactions returns the transactions recorded for the address
+// of sub from its start block on. Other tenants may have watched the address
+// for longer.
+func (ep *EthParser) subscriptionTransactions(sub models.Subscription) []models.Transaction {
+	var txs []models.Transaction
+	for _, tx := range ep.storage.GetTransactions(sub.Address) {
+		if block, err := utils.HexToInt(tx.BlockNumber); err == nil && block < sub.StartBlock {
+			continue
+		}
+		txs = append(txs, ep.withTokenMetadata(tx))
+	}
+	return txs
+}
+
+// GetTransactionsByTag returns the transactions of every address tagged
+// with tag in block order. A transaction between two such addresses is
+// returned once.
+func (ep *EthParser) GetTransactionsByTag(tag string) []models.Transaction {
+	seen := make(map[string]bool)
+	var txs []models.Transaction
+	for _, sub := range ep.storage.GetSubscriptions() {
+		if !sub.HasTag(tag) {
+			continue
+		}
+		for _, tx := range ep.subscriptionTransactions(sub) {
+			if !seen[tx.Hash] {
+				seen[tx.Hash] = true
+				txs = append(txs, tx)
+			}
+		}
+	}
+	sort.SliceStable(txs, func(i, j int) bool {
+		bi, _ := utils.HexToInt(txs[i].BlockNumber)
+		bj, _ := utils.HexToInt(txs[j].BlockNumber)
+		if bi != bj {
+			return bi < bj
+		}
+		ii, _ := utils.HexToInt(txs[i].TransactionIndex)
+		ij, _ := utils.HexToInt(txs[j].TransactionIndex)
+		return ii < ij
+	})
+	return txs
+}
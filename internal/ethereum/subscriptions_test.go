@@ -0,0 +1,119 @@
+package ethereum
+
+import (
+	"errors"
+	"eth-parser/pkg/models"
+	"reflect"
+	"testing"
+)
+
+func TestParserSubscriptionTags(t *testing.T) {
+	node := newFakeChain(3, 0)
+	vault := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	payroll := "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	other := "0xdac17f958d2ee523a2206206994597c13d831ec7"
+
+	block := node.blocks[1]
+	block.Transactions = append(block.Transactions,
+		models.Transaction{BlockNumber: block.Number, TransactionIndex: "0x1", Hash: "0x02", From: vault, To: payroll, Value: "0x1", Input: "0x"},
+		models.Transaction{BlockNumber: block.Number, TransactionIndex: "0x0", Hash: "0x01", From: other, To: vault, Value: "0x1", Input: "0x"},
+	)
+	node.blocks[1] = block
+	block = node.blocks[2]
+	block.Transactions = append(block.Transactions,
+		models.Transaction{BlockNumber: block.Number, TransactionIndex: "0x0", Hash: "0x03", From: payroll, To: other, Value: "0x1", Input: "0x"},
+	)
+	node.blocks[2] = block
+
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	parser.SetCurrentBlock(1)
+	for _, sub := range []models.Subscription{
+		{Address: vault, Label: "Vault", Tags: []string{"Treasury"}},
+		{Address: payroll, Tags: []string{"treasury", "payroll"}, OwnerTeam: "finance"},
+		{Address: other, Tags: []string{"exchange"}},
+	} {
+		if ok, err := parser.AddSubscription(sub); !ok || err != nil {
+			t.Fatalf("AddSubscription(%s) = %v, %v", sub.Address, ok, err)
+		}
+	}
+	if _, err := parser.AddSubscription(models.Subscription{Address: "0x9f8f72aa9304c8b593d555f12ef6589cc3a579a2"}); err != nil {
+		t.Errorf("default start block: %v", err)
+	}
+	parser.SetCurrentBlock(2)
+	if _, err := parser.AddSubscription(models.Subscription{Address: "0x1f9840a85d5af5bf1d1762f925bdaddc4201f984", StartBlock: 1}); !errors.Is(err, ErrStartBlockProcessed) {
+		t.Errorf("start block already processed: %v", err)
+	}
+	if err := parser.processBatch(1, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	var hashes []string
+	for _, tx := range parser.GetTransactionsByTag("TREASURY") {
+		hashes = append(hashes, tx.Hash)
+	}
+	if want := []string{"0x01", "0x02", "0x03"}; !reflect.DeepEqual(hashes, want) {
+		t.Errorf("transactions tagged treasury = %v, want %v", hashes, want)
+	}
+
+	updated, err := parser.UpdateSubscription(models.Subscription{Address: vault, Label: "Cold vault"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Label != "Cold vault" || len(updated.Tags) != 0 || updated.StartBlock != 1 || updated.CreatedAt.IsZero() {
+		t.Errorf("updated subscription = %+v", updated)
+	}
+	if _, err := parser.UpdateSubscription(models.Subscription{Address: "0x1f9840a85d5af5bf1d1762f925bdaddc4201f984"}); !errors.Is(err, ErrNotSubscribed) {
+		t.Errorf("update of an unsubscribed address: %v", err)
+	}
+}
+
+func TestParserTenants(t *testing.T) {
+	node := newFakeChain(3, 0)
+	shared := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	other := "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	for number, hash := range map[int64]string{1: "0x01", 2: "0x02"} {
+		block := node.blocks[number]
+		block.Transactions = append(block.Transactions,
+			models.Transaction{BlockNumber: block.Number, Hash: hash, From: shared, To: other, Value: "0x1", Input: "0x"})
+		node.blocks[number] = block
+	}
+
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	parser.SetCurrentBlock(1)
+	parser.SetTenantQuotas(0, map[string]int{"team-b": 1})
+	teamA, teamB := parser.ForTenant("team-a"), parser.ForTenant("team-b")
+	teamA.Subscribe(shared)
+	if ok, err := teamB.AddSubscription(models.Subscription{Address: shared, StartBlock: 2}); !ok || err != nil {
+		t.Fatalf("team-b subscription = %v, %v", ok, err)
+	}
+	if _, err := teamB.AddSubscription(models.Subscription{Address: other}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("subscription over quota: %v", err)
+	}
+	if parser.matcher.Len() != 1 {
+		t.Errorf("matcher holds %d addresses, want the shared one once", parser.matcher.Len())
+	}
+	if err := parser.processBatch(1, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	if txs := teamA.GetTransactions(shared); len(txs) != 2 {
+		t.Errorf("team-a transactions = %+v", txs)
+	}
+	if txs := teamB.GetTransactions(shared); len(txs) != 1 || txs[0].Hash != "0x02" {
+		t.Errorf("team-b should only see transactions from its start block, got %+v", txs)
+	}
+	if txs := parser.GetTransactions(shared); len(txs) != 0 || len(parser.GetSubscribeList()) != 0 {
+		t.Error("the default tenant should not see other tenants' subscriptions")
+	}
+
+	teamA.Unsubscribe(shared)
+	if parser.matcher.Len() != 1 || len(teamB.GetTransactions(shared)) != 1 {
+		t.Error("an address watched by another tenant should stay matched")
+	}
+	teamB.Unsubscribe(shared)
+	if parser.matcher.Len() != 0 {
+		t.Error("an address nobody watches should no longer be matched")
+	}
+}
@@ -0,0 +1,349 @@
+package ethereum
+
+import (
+	"bytes"
+	"errors"
+	"eth-parser/internal/rpc"
+	"eth-parser/internal/storage"
+	"eth-parser/pkg/abi"
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	erc20Name         = mustMethod("name()")
+	erc20Symbol       = mustMethod("symbol()")
+	erc20Decimals     = mustMethod("decimals()")
+	erc20Transfer     = mustMethod("transfer(address,uint256)")
+	erc20TransferFrom = mustMethod("transferFrom(address,address,uint256)")
+
+	// The selectors in hex, the way they start the input of a call.
+	erc20TransferInput     = selectorInput(erc20Transfer)
+	erc20TransferFromInput = selectorInput(erc20TransferFrom)
+
+	stringOutput  = mustArguments("string")
+	uint256Output = mustArguments("uint256")
+)
+
+// maxBackgroundResolutions bounds the tokens resolved in the background at
+// the same time.
+const maxBackgroundResolutions = 4
+
+func mustMethod(signature string) abi.Method {
+	method, err := abi.NewMethod(signature)
+	if err != nil {
+		panic(err)
+	}
+	return method
+}
+
+func selectorInput(method abi.Method) string {
+	selector := method.Selector()
+	return utils.EncodeBytes(selector[:])
+}
+
+func mustArguments(types string) []abi.Argument {
+	_, args, err := abi.ParseSignature("f(" + types + ")")
+	if err != nil {
+		panic(err)
+	}
+	return args
+}
+
+// TokenRegistry resolves the metadata of ERC-20 tokens the first time they
+// are seen and caches it in storage, so every token costs three eth_calls
+// once. Block processing only reads the cache and leaves resolution to the
+// background, see Resolve.
+type TokenRegistry struct {
+	client  *rpc.Client
+	storage storage.Storage
+	logger  *log.Logger
+
+	// pending holds the resolutions in flight by address, closed when done,
+	// so that a token is not resolved twice at the same time.
+	mu         sync.Mutex
+	pending    map[string]chan struct{}
+	stopped    bool
+	slots      chan struct{}
+	background sync.WaitGroup
+}
+
+func NewTokenRegistry(client *rpc.Client, storage storage.Storage, logger *log.Logger) *TokenRegistry {
+	return &TokenRegistry{
+		client:  client,
+		storage: storage,
+		logger:  logger,
+		pending: make(map[string]chan struct{}),
+		slots:   make(chan struct{}, maxBackgroundResolutions),
+	}
+}
+
+// Token returns the metadata of the token at address, resolving it on first
+// use. A function the contract does not implement leaves its field empty and
+// is not asked again; a node that cannot be reached is an error and nothing
+// is cached.
+func (r *TokenRegistry) Token(contract models.Address) (models.Token, error) {
+	address := contract.String()
+	for {
+		if token, ok := r.storage.GetToken(address); ok {
+			return token, nil
+		}
+		r.mu.Lock()
+		if done, ok := r.pending[address]; ok {
+			r.mu.Unlock()
+			<-done
+			continue
+		}
+		done := make(chan struct{})
+		r.pending[address] = done
+		r.mu.Unlock()
+
+		token, err := r.resolve(contract)
+		r.mu.Lock()
+		delete(r.pending, address)
+		r.mu.Unlock()
+		close(done)
+		return token, err
+	}
+}
+
+// Resolve resolves the token at contract in the background unless it is
+// cached or already being resolved. When maxBackgroundResolutions are in
+// flight the token is left for the next transfer that involves it, or for
+// the first read of its metadata.
+func (r *TokenRegistry) Resolve(contract models.Address) {
+	address := contract.String()
+	if _, ok := r.storage.GetToken(address); ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, busy := r.pending[address]; busy || r.stopped {
+		return
+	}
+	select {
+	case r.slots <- struct{}{}:
+	default:
+		return
+	}
+	r.background.Add(1)
+	go func() {
+		defer r.background.Done()
+		defer func() { <-r.slots }()
+		if _, err := r.Token(contract); err != nil {
+			r.logger.Printf("Token %s unresolved: %v", address, err)
+		}
+	}()
+}
+
+// Wait waits for the resolutions started by Resolve.
+func (r *TokenRegistry) Wait() {
+	r.background.Wait()
+}
+
+// Stop waits for the resolutions started by Resolve, which starts none
+// afterwards.
+func (r *TokenRegistry) Stop() {
+	r.mu.Lock()
+	r.stopped = true
+	r.mu.Unlock()
+	r.background.Wait()
+}
+
+func (r *TokenRegistry) resolve(contract models.Address) (models.Token, error) {
+	address := contract.String()
+	token := models.Token{Address: contract.Checksum()}
+	name, err := r.call(address, erc20Name)
+	if err != nil {
+		return models.Token{}, err
+	}
+	token.Name, _ = decodeTokenString(name)
+	symbol, err := r.call(address, erc20Symbol)
+	if err != nil {
+		return models.Token{}, err
+	}
+	token.Symbol, _ = decodeTokenString(symbol)
+	decimals, err := r.call(address, erc20Decimals)
+	if err != nil {
+		return models.Token{}, err
+	}
+	if d, ok := decodeDecimals(decimals); ok {
+		token.Decimals = &d
+	}
+	token.ResolvedAt = time.Now().UTC()
+
+	r.storage.SetToken(token)
+	r.logger.Printf("Resolved token %s: name %q, symbol %q, decimals %s", address, token.Name, token.Symbol, formatDecimals(token.Decimals))
+	return token, nil
+}
+
+// call calls a function without arguments. A call that fails on the node
+// returns no data rather than an error.
+func (r *TokenRegistry) call(address string, method abi.Method) ([]byte, error) {
+	data, err := method.EncodeCall()
+	if err != nil {
+		return nil, err
+	}
+	result, err := r.client.Call(address, data, "latest")
+	if errors.Is(err, rpc.ErrCallFailed) {
+		r.logger.Printf("Token %s: %s failed: %v", address, method.Signature(), err)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve token %s: %w", address, err)
+	}
+	return result, nil
+}
+
+// decodeTokenString decodes the result of name() or symbol(). Most tokens
+// return a string; some early ones, MKR among them, return a bytes32 padded
+// with zeros.
+func decodeTokenString(data []byte) (string, bool) {
+	if values, err := abi.Decode(stringOutput, data); err == nil {
+		return values[0].(string), true
+	}
+	if len(data) != 32 {
+		return "", false
+	}
+	s := bytes.TrimRight(data, "\x00")
+	if !utf8.Valid(s) || bytes.IndexByte(s, 0) >= 0 {
+		return "", false
+	}
+	return string(s), true
+}
+
+// decodeDecimals decodes the result of decimals(). The standard declares a
+// uint8, but the value is read as a uint256 since some tokens declare a wider
+// type.
+func decodeDecimals(data []byte) (uint8, bool) {
+	values, err := abi.Decode(uint256Output, data)
+	if err != nil {
+		return 0, false
+	}
+	d := values[0].(*big.Int)
+	if !d.IsUint64() || d.Uint64() > 255 {
+		return 0, false
+	}
+	return uint8(d.Uint64()), true
+}
+
+func formatDecimals(decimals *uint8) string {
+	if decimals == nil {
+		return "unknown"
+	}
+	return fmt.Sprint(*decimals)
+}
+
+// GetToken returns the metadata of the token at address, resolving it with
+// eth_call if it has not been seen yet.
+func (ep *EthParser) GetToken(address string) (models.Token, error) {
+	contract, err := models.HexToAddress(address)
+	if err != nil {
+		return models.Token{}, err
+	}
+	return ep.tokens.Token(contract)
+}
+
+// GetTokens returns the tokens resolved so far.
+func (ep *EthParser) GetTokens() []models.Token {
+	return ep.storage.GetTokens()
+}
+
+// parseTokenTransfer records the token movement of a call to transfer or
+// transferFrom without its metadata. It runs on every transaction of a block
+// before matching, so that a transfer to or from a subscribed address is
+// matched whoever sends it.
+func parseTokenTransfer(tx *models.Transaction) {
+	if tx.To == "" || len(tx.Input) < 10 {
+		return
+	}
+	// The selector is compared in hex so that other calls are not decoded.
+	transferFrom := strings.EqualFold(tx.Input[:10], erc20TransferFromInput)
+	if !transferFrom && !strings.EqualFold(tx.Input[:10], erc20TransferInput) {
+		return
+	}
+	input, err := utils.DecodeBytes(tx.Input)
+	if err != nil {
+		return
+	}
+	var from, to models.Address
+	var amount *big.Int
+	if transferFrom {
+		values, err := abi.Decode(erc20TransferFrom.Inputs, input[4:])
+		if err != nil {
+			return
+		}
+		from, to, amount = values[0].(models.Address), values[1].(models.Address), values[2].(*big.Int)
+	} else {
+		values, err := abi.Decode(erc20Transfer.Inputs, input[4:])
+		if err != nil {
+			return
+		}
+		if from, err = models.HexToAddress(tx.From); err != nil {
+			return
+		}
+		to, amount = values[0].(models.Address), values[1].(*big.Int)
+	}
+	contract, err := models.HexToAddress(tx.To)
+	if err != nil {
+		return
+	}
+	tx.TokenTransfer = &models.TokenTransfer{
+		Token:  contract.Checksum(),
+		From:   from.Checksum(),
+		To:     to.Checksum(),
+		Amount: amount.String(),
+	}
+}
+
+// attachTokenMetadata adds the symbol and decimals of the token to the
+// token transfer of a matched transaction if they are cached, and otherwise
+// has the token resolved in the background; withTokenMetadata fills them in
+// when the transaction is read.
+func (ep *EthParser) attachTokenMetadata(tx *models.Transaction) {
+	if tx.TokenTransfer == nil {
+		return
+	}
+	contract, err := models.HexToAddress(tx.TokenTransfer.Token)
+	if err != nil {
+		return
+	}
+	if token, ok := ep.storage.GetToken(contract.String()); ok {
+		formatTokenTransfer(tx.TokenTransfer, token)
+		return
+	}
+	ep.tokens.Resolve(contract)
+}
+
+// withTokenMetadata returns tx with the metadata of its token transfer
+// filled in from the cache if it was stored before the token was resolved.
+func (ep *EthParser) withTokenMetadata(tx models.Transaction) models.Transaction {
+	if tx.TokenTransfer == nil || tx.TokenTransfer.Symbol != "" || tx.TokenTransfer.Decimals != nil {
+		return tx
+	}
+	token, ok := ep.storage.GetToken(tx.TokenTransfer.Token)
+	if !ok {
+		return tx
+	}
+	transfer := *tx.TokenTransfer
+	formatTokenTransfer(&transfer, token)
+	tx.TokenTransfer = &transfer
+	return tx
+}
+
+// formatTokenTransfer adds the symbol and decimals of token to transfer, with
+// the amount in whole tokens when the decimals are known.
+func formatTokenTransfer(transfer *models.TokenTransfer, token models.Token) {
+	transfer.Symbol = token.Symbol
+	transfer.Decimals = token.Decimals
+	if amount, ok := new(big.Int).SetString(transfer.Amount, 10); ok && token.Decimals != nil {
+		transfer.FormattedAmount = utils.FormatUnits(amount, *token.Decimals)
+	}
+}
@@ -0,0 +1,162 @@
+package ethereum
+
+import (
+	"eth-parser/pkg/abi"
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
+	"testing"
+)
+
+func encodeOutput(t *testing.T, args []abi.Argument, value interface{}) []byte {
+	t.Helper()
+	data, err := abi.Encode(args, []interface{}{value})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func bytes32(s string) []byte {
+	b := make([]byte, 32)
+	copy(b, s)
+	return b
+}
+
+func TestDecodeTokenString(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+		ok   bool
+	}{
+		{"string", encodeOutput(t, stringOutput, "Tether USD"), "Tether USD", true},
+		{"bytes32", bytes32("MKR"), "MKR", true},
+		{"empty bytes32", make([]byte, 32), "", true},
+		{"bytes32 with inner zero", append([]byte("A\x00B"), make([]byte, 29)...), "", false},
+		{"nothing", nil, "", false},
+		{"short", []byte{1, 2, 3}, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := decodeTokenString(tt.data)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: decodeTokenString() = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDecodeDecimals(t *testing.T) {
+	if d, ok := decodeDecimals(encodeOutput(t, uint256Output, 18)); !ok || d != 18 {
+		t.Errorf("decodeDecimals(18) = %d, %v", d, ok)
+	}
+	if _, ok := decodeDecimals(encodeOutput(t, uint256Output, 256)); ok {
+		t.Error("decodeDecimals accepted 256")
+	}
+	if _, ok := decodeDecimals(nil); ok {
+		t.Error("decodeDecimals accepted an empty result")
+	}
+}
+
+func TestParserTokenTransfers(t *testing.T) {
+	node := newFakeChain(3, 0)
+	subscribed := "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	usdt := "0xdac17f958d2ee523a2206206994597c13d831ec7"
+	mkr := "0x9f8f72aa9304c8b593d555f12ef6589cc3a579a2"
+	node.calls = map[string]string{
+		usdt + ":0x06fdde03": utils.EncodeBytes(encodeOutput(t, stringOutput, "Tether USD")),
+		usdt + ":0x95d89b41": utils.EncodeBytes(encodeOutput(t, stringOutput, "USDT")),
+		usdt + ":0x313ce567": utils.EncodeBytes(encodeOutput(t, uint256Output, 6)),
+		mkr + ":0x06fdde03":  utils.EncodeBytes(bytes32("Maker")),
+		mkr + ":0x95d89b41":  utils.EncodeBytes(bytes32("MKR")),
+		// decimals() reverts: amounts stay unformatted.
+	}
+	block := node.blocks[1]
+	block.Transactions = append(block.Transactions,
+		// transfer(0xa0b8..., 1500000)
+		models.Transaction{BlockNumber: block.Number, Hash: "0x01", From: subscribed, To: usdt,
+			Input: "0xa9059cbb000000000000000000000000a0b86991c6218b36c1d19d4a2e9eb0ce3606eb48000000000000000000000000000000000000000000000000000000000016e360"},
+		// transferFrom(subscribed, 0xa0b8..., 1)
+		models.Transaction{BlockNumber: block.Number, Hash: "0x02", From: subscribed, To: usdt,
+			Input: "0x23b872dd000000000000000000000000742d35cc6634c0532925a3b844bc454e4438f44e000000000000000000000000a0b86991c6218b36c1d19d4a2e9eb0ce3606eb480000000000000000000000000000000000000000000000000000000000000001"},
+		models.Transaction{BlockNumber: block.Number, Hash: "0x03", From: subscribed, To: mkr,
+			Input: "0xa9059cbb000000000000000000000000a0b86991c6218b36c1d19d4a2e9eb0ce3606eb480000000000000000000000000000000000000000000000000de0b6b3a7640000"},
+		// transfer(subscribed, 2000000) sent by someone else.
+		models.Transaction{BlockNumber: block.Number, Hash: "0x04", From: "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", To: usdt,
+			Input: "0xa9059cbb000000000000000000000000742d35cc6634c0532925a3b844bc454e4438f44e00000000000000000000000000000000000000000000000000000000001e8480"},
+	)
+	node.blocks[1] = block
+
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	parser.Subscribe(subscribed)
+	if err := parser.processBatch(0, 3); err != nil {
+		t.Fatal(err)
+	}
+	// Tokens are resolved in the background and their metadata filled in
+	// when transactions are read.
+	parser.tokens.Wait()
+
+	txs := parser.GetTransactions(subscribed)
+	if len(txs) != 4 {
+		t.Fatalf("expected 4 transactions, got %d", len(txs))
+	}
+	for i, want := range []models.TokenTransfer{
+		{Token: "0xdAC17F958D2ee523a2206206994597C13D831ec7", Symbol: "USDT", From: "0x742d35Cc6634C0532925a3b844Bc454e4438f44e",
+			To: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", Amount: "1500000", FormattedAmount: "1.5"},
+		{Token: "0xdAC17F958D2ee523a2206206994597C13D831ec7", Symbol: "USDT", From: "0x742d35Cc6634C0532925a3b844Bc454e4438f44e",
+			To: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", Amount: "1", FormattedAmount: "0.000001"},
+		{Token: "0x9f8F72aA9304c8B593d555F12eF6589cC3A579A2", Symbol: "MKR", From: "0x742d35Cc6634C0532925a3b844Bc454e4438f44e",
+			To: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", Amount: "1000000000000000000"},
+		{Token: "0xdAC17F958D2ee523a2206206994597C13D831ec7", Symbol: "USDT", From: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48",
+			To: "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", Amount: "2000000", FormattedAmount: "2"},
+	} {
+		got := txs[i].TokenTransfer
+		if got == nil {
+			t.Errorf("transaction %d: no token transfer", i)
+			continue
+		}
+		got.Decimals = nil
+		if *got != want {
+			t.Errorf("transaction %d: token transfer = %+v, want %+v", i, *got, want)
+		}
+	}
+
+	// Each token is resolved once, with three calls.
+	if got := node.callCount.Load(); got != 6 {
+		t.Errorf("expected 6 eth_calls, got %d", got)
+	}
+	token, err := parser.GetToken(mkr)
+	if err != nil || token.Name != "Maker" || token.Decimals != nil {
+		t.Errorf("GetToken(MKR) = %+v, %v", token, err)
+	}
+	if tokens := parser.GetTokens(); len(tokens) != 2 {
+		t.Errorf("GetTokens() = %+v", tokens)
+	}
+}
+
+func TestTokenRegistryBackgroundResolutions(t *testing.T) {
+	node := newFakeChain(1, 0)
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	registry := parser.tokens
+	usdt, _ := models.HexToAddress("0xdac17f958d2ee523a2206206994597c13d831ec7")
+
+	// Every slot is taken: the token is left for later.
+	for i := 0; i < maxBackgroundResolutions; i++ {
+		registry.slots <- struct{}{}
+	}
+	registry.Resolve(usdt)
+	registry.Wait()
+	if _, ok := parser.storage.GetToken(usdt.String()); ok {
+		t.Error("token resolved without a free slot")
+	}
+	for i := 0; i < maxBackgroundResolutions; i++ {
+		<-registry.slots
+	}
+
+	registry.Stop()
+	registry.Resolve(usdt)
+	registry.Wait()
+	if _, ok := parser.storage.GetToken(usdt.String()); ok {
+		t.Error("token resolved after Stop")
+	}
+}
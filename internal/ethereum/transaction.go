@@ -0,0 +1,236 @@
+package ethereum
+
+import (
+	"errors"
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/rlp"
+	"eth-parser/pkg/trie"
+	"eth-parser/pkg/utils"
+	"fmt"
+)
+
+// Transaction types as carried in the EIP-2718 envelope.
+const (
+	LegacyTxType     = 0x00
+	AccessListTxType = 0x01 // EIP-2930
+	DynamicFeeTxType = 0x02 // EIP-1559
+	BlobTxType       = 0x03 // EIP-4844
+	SetCodeTxType    = 0x04 // EIP-7702
+)
+
+var (
+	ErrUnsupportedTxType = errors.New("unsupported transaction type")
+	// ErrUnverifiableTxType marks Arbitrum system transactions, whose
+	// consensus encoding is not implemented. Blocks containing them cannot
+	// have their transactions root checked.
+	ErrUnverifiableTxType       = errors.New("transaction type cannot be verified")
+	ErrTransactionsRootMismatch = errors.New("transactions root mismatch")
+)
+
+// TransactionType returns the EIP-2718 type of tx; a missing type field means
+// a legacy transaction.
+func TransactionType(tx models.Transaction) (uint64, error) {
+	if tx.Type == "" {
+		return LegacyTxType, nil
+	}
+	return utils.DecodeUint64(tx.Type)
+}
+
+// EncodeTransaction returns the consensus encoding of tx: rlp(fields) for
+// legacy transactions and type || rlp(fields) for typed ones. This is what
+// the transactions trie commits to and what the transaction hash is taken of.
+func EncodeTransaction(tx models.Transaction) ([]byte, error) {
+	txType, err := TransactionType(tx)
+	if err != nil {
+		return nil, fmt.Errorf("transaction %s: field type: %w", tx.Hash, err)
+	}
+
+	if tx.IsArbitrumSystemTx() {
+		return nil, fmt.Errorf("transaction %s: %w 0x%x", tx.Hash, ErrUnverifiableTxType, txType)
+	}
+	e := &fieldEncoder{}
+	if txType == models.DepositTxType {
+		return encodeDeposit(e, tx)
+	}
+	fields, err := unsignedFields(e, tx, txType)
+	if err != nil {
+		return nil, err
+	}
+	if txType == LegacyTxType {
+		fields = append(fields, e.quantity("v", tx.V))
+	} else {
+		yParity := tx.YParity
+		if yParity == "" {
+			yParity = tx.V
+		}
+		fields = append(fields, e.quantity("yParity", yParity))
+	}
+	fields = append(fields, e.quantity("r", tx.R), e.quantity("s", tx.S))
+	if e.err != nil {
+		return nil, fmt.Errorf("transaction %s: %w", tx.Hash, e.err)
+	}
+	return envelope(txType, fields), nil
+}
+
+// unsignedFields returns the encoded fields of tx that precede the signature.
+func unsignedFields(e *fieldEncoder, tx models.Transaction, txType uint64) ([][]byte, error) {
+	switch txType {
+	case LegacyTxType:
+		return [][]byte{
+			e.quantity("nonce", tx.Nonce),
+			e.quantity("gasPrice", tx.GasPrice),
+			e.quantity("gas", tx.Gas),
+			e.optionalAddress("to", tx.To),
+			e.quantity("value", tx.Value),
+			e.data("input", tx.Input),
+		}, nil
+	case AccessListTxType:
+		return [][]byte{
+			e.quantity("chainId", tx.ChainID),
+			e.quantity("nonce", tx.Nonce),
+			e.quantity("gasPrice", tx.GasPrice),
+			e.quantity("gas", tx.Gas),
+			e.optionalAddress("to", tx.To),
+			e.quantity("value", tx.Value),
+			e.data("input", tx.Input),
+			e.accessList(tx.AccessList),
+		}, nil
+	case DynamicFeeTxType, BlobTxType, SetCodeTxType:
+		fields := [][]byte{
+			e.quantity("chainId", tx.ChainID),
+			e.quantity("nonce", tx.Nonce),
+			e.quantity("maxPriorityFeePerGas", tx.MaxPriorityFeePerGas),
+			e.quantity("maxFeePerGas", tx.MaxFeePerGas),
+			e.quantity("gas", tx.Gas),
+		}
+		// Blob and set code transactions cannot create contracts.
+		if txType == DynamicFeeTxType {
+			fields = append(fields, e.optionalAddress("to", tx.To))
+		} else {
+			fields = append(fields, e.fixed("to", tx.To, models.AddressLength))
+		}
+		fields = append(fields,
+			e.quantity("value", tx.Value),
+			e.data("input", tx.Input),
+			e.accessList(tx.AccessList),
+		)
+		switch txType {
+		case BlobTxType:
+			fields = append(fields,
+				e.quantity("maxFeePerBlobGas", tx.MaxFeePerBlobGas),
+				e.hashList("blobVersionedHashes", tx.BlobVersionedHashes),
+			)
+		case SetCodeTxType:
+			fields = append(fields, e.authorizationList(tx.AuthorizationList))
+		}
+		return fields, nil
+	}
+	return nil, fmt.Errorf("transaction %s: %w 0x%x", tx.Hash, ErrUnsupportedTxType, txType)
+}
+
+// encodeDeposit encodes an OP Stack deposit, which has no signature; its
+// sender is authenticated by the L1 transaction that created it.
+func encodeDeposit(e *fieldEncoder, tx models.Transaction) ([]byte, error) {
+	mint := tx.Mint
+	if mint == "" {
+		mint = "0x0"
+	}
+	isSystemTx := rlp.EncodeUint64(0)
+	if tx.IsSystemTx {
+		isSystemTx = rlp.EncodeUint64(1)
+	}
+	fields := [][]byte{
+		e.hash("sourceHash", tx.SourceHash),
+		e.fixed("from", tx.From, models.AddressLength),
+		e.optionalAddress("to", tx.To),
+		e.quantity("mint", mint),
+		e.quantity("value", tx.Value),
+		e.quantity("gas", tx.Gas),
+		isSystemTx,
+		e.data("input", tx.Input),
+	}
+	if e.err != nil {
+		return nil, fmt.Errorf("transaction %s: %w", tx.Hash, e.err)
+	}
+	return envelope(models.DepositTxType, fields), nil
+}
+
+func envelope(txType uint64, fields [][]byte) []byte {
+	payload := rlp.EncodeList(fields...)
+	if txType == LegacyTxType {
+		return payload
+	}
+	return append([]byte{byte(txType)}, payload...)
+}
+
+// TransactionsRoot computes the root of the transactions trie of a block.
+func TransactionsRoot(txs []models.Transaction) (models.Hash, error) {
+	encoded := make([][]byte, len(txs))
+	for i, tx := range txs {
+		enc, err := EncodeTransaction(tx)
+		if err != nil {
+			return models.Hash{}, err
+		}
+		encoded[i] = enc
+	}
+	return trie.DeriveRoot(encoded), nil
+}
+
+// VerifyTransactions checks that the transaction bodies served with a block
+// are exactly the ones its header commits to.
+func VerifyTransactions(block models.Block) error {
+	computed, err := TransactionsRoot(block.Transactions)
+	if err != nil {
+		return fmt.Errorf("block %s: %w", block.Number, err)
+	}
+	reported, err := models.HexToHash(block.TransactionsRoot)
+	if err != nil {
+		return fmt.Errorf("block %s: invalid transactions root %q", block.Number, block.TransactionsRoot)
+	}
+	if computed != reported {
+		return fmt.Errorf("block %s: %w: header %s, computed %s", block.Number, ErrTransactionsRootMismatch, reported, computed)
+	}
+	return nil
+}
+
+// optionalAddress encodes a recipient, which is empty for contract creation.
+func (e *fieldEncoder) optionalAddress(name, value string) []byte {
+	if value == "" {
+		return rlp.EmptyString
+	}
+	return e.fixed(name, value, models.AddressLength)
+}
+
+func (e *fieldEncoder) hashList(name string, values []string) []byte {
+	items := make([][]byte, len(values))
+	for i, value := range values {
+		items[i] = e.hash(name, value)
+	}
+	return rlp.EncodeList(items...)
+}
+
+func (e *fieldEncoder) accessList(entries []models.AccessListEntry) []byte {
+	items := make([][]byte, len(entries))
+	for i, entry := range entries {
+		items[i] = rlp.EncodeList(
+			e.fixed("accessList.address", entry.Address, models.AddressLength),
+			e.hashList("accessList.storageKeys", entry.StorageKeys),
+		)
+	}
+	return rlp.EncodeList(items...)
+}
+
+func (e *fieldEncoder) authorizationList(auths []models.Authorization) []byte {
+	items := make([][]byte, len(auths))
+	for i, auth := range auths {
+		items[i] = rlp.EncodeList(
+			e.quantity("authorizationList.chainId", auth.ChainID),
+			e.fixed("authorizationList.address", auth.Address, models.AddressLength),
+			e.quantity("authorizationList.nonce", auth.Nonce),
+			e.quantity("authorizationList.yParity", auth.YParity),
+			e.quantity("authorizationList.r", auth.R),
+			e.quantity("authorizationList.s", auth.S),
+		)
+	}
+	return rlp.EncodeList(items...)
+}
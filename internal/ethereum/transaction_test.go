@@ -0,0 +1,226 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"errors"
+	"eth-parser/internal/rpc"
+	"eth-parser/internal/storage"
+	"eth-parser/pkg/models"
+	"io"
+	"log"
+	"math/rand"
+	"net/http/httptest"
+	"testing"
+)
+
+// eip155Transaction is the example transaction from EIP-155.
+func eip155Transaction() models.Transaction {
+	return models.Transaction{
+		Nonce:    "0x9",
+		GasPrice: "0x4a817c800",
+		Gas:      "0x5208",
+		To:       "0x3535353535353535353535353535353535353535",
+		Value:    "0xde0b6b3a7640000",
+		Input:    "0x",
+		V:        "0x25",
+		R:        "0x28ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276",
+		S:        "0x67cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83",
+	}
+}
+
+func TestEncodeTransaction(t *testing.T) {
+	encoded, err := EncodeTransaction(eip155Transaction())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83"
+	if got := hex.EncodeToString(encoded); got != want {
+		t.Errorf("EncodeTransaction = %s, want %s", got, want)
+	}
+
+	// Typed transactions are prefixed with their type byte.
+	for _, txType := range []string{"0x1", "0x2", "0x3", "0x4"} {
+		tx := eip155Transaction()
+		tx.Type, tx.ChainID, tx.V, tx.YParity = txType, "0x1", "0x0", "0x0"
+		tx.MaxFeePerGas, tx.MaxPriorityFeePerGas = "0x4a817c800", "0x3b9aca00"
+		tx.MaxFeePerBlobGas = "0x1"
+		encoded, err := EncodeTransaction(tx)
+		if err != nil {
+			t.Fatalf("type %s: %v", txType, err)
+		}
+		if encoded[0] != txType[2]-'0' || encoded[1] < 0xc0 {
+			t.Errorf("type %s: unexpected envelope %x", txType, encoded[:2])
+		}
+	}
+
+	// Blob transactions cannot create contracts.
+	blob := eip155Transaction()
+	blob.Type, blob.ChainID, blob.MaxFeePerGas, blob.MaxPriorityFeePerGas, blob.MaxFeePerBlobGas = "0x3", "0x1", "0x1", "0x1", "0x1"
+	blob.To = ""
+	if _, err := EncodeTransaction(blob); err == nil {
+		t.Error("EncodeTransaction accepted a blob transaction without a recipient")
+	}
+
+	unknown := eip155Transaction()
+	unknown.Type = "0x7f"
+	if _, err := EncodeTransaction(unknown); !errors.Is(err, ErrUnsupportedTxType) {
+		t.Errorf("EncodeTransaction with type 0x7f = %v, want ErrUnsupportedTxType", err)
+	}
+}
+
+func TestTransactionsRoot(t *testing.T) {
+	root, err := TransactionsRoot(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.String() != "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421" {
+		t.Errorf("empty transactions root = %s", root)
+	}
+
+	node := newFakeChain(3, 4)
+	sealChain(t, node)
+	block := node.blocks[1]
+	if err := VerifyTransactions(block); err != nil {
+		t.Fatalf("VerifyTransactions on a sealed block: %v", err)
+	}
+	block.Transactions = append([]models.Transaction(nil), block.Transactions...)
+	block.Transactions[0], block.Transactions[1] = block.Transactions[1], block.Transactions[0]
+	if err := VerifyTransactions(block); !errors.Is(err, ErrTransactionsRootMismatch) {
+		t.Errorf("VerifyTransactions with reordered transactions = %v, want ErrTransactionsRootMismatch", err)
+	}
+}
+
+func TestParserFailover(t *testing.T) {
+	honest := newFakeChain(4, 3)
+	sealChain(t, honest)
+
+	// The dishonest node serves the same headers but swaps the recipient of a
+	// transaction in block 2.
+	dishonest := &fakeNode{blocks: make(map[int64]models.Block)}
+	for number, block := range honest.blocks {
+		dishonest.blocks[number] = block
+	}
+	forged := dishonest.blocks[2]
+	forged.Transactions = append([]models.Transaction(nil), forged.Transactions...)
+	forged.Transactions[0].To = "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	dishonest.blocks[2] = forged
+
+	dishonestServer := httptest.NewServer(dishonest)
+	defer dishonestServer.Close()
+	honestServer := httptest.NewServer(honest)
+	defer honestServer.Close()
+	rpc.SetEndpoints(dishonestServer.URL, honestServer.URL)
+
+	store := storage.NewMemoryStorage()
+	parser := NewEthParser(store, log.New(io.Discard, "", 0))
+	parser.SetVerifyBlocks(true)
+	parser.Subscribe("0x742d35cc6634c0532925a3b844bc454e4438f44e")
+
+	if err := parser.processBatch(0, 4); err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+	if got := rpc.Endpoint(); got != honestServer.URL {
+		t.Errorf("endpoint after failover = %s, want %s", got, honestServer.URL)
+	}
+	if txs := parser.GetTransactions("0x742d35cc6634c0532925a3b844bc454e4438f44e"); len(txs) != 0 {
+		t.Errorf("forged transaction was stored: %+v", txs)
+	}
+
+	rejected := parser.GetRejectedBlocks()
+	if len(rejected) != 1 {
+		t.Fatalf("expected 1 rejected block, got %d", len(rejected))
+	}
+	if rejected[0].Number != 2 || rejected[0].Hash != forged.Hash || rejected[0].Endpoint != dishonestServer.URL {
+		t.Errorf("unexpected rejected block %+v", rejected[0])
+	}
+
+	// With no honest endpoint left the block is not processed.
+	rpc.SetEndpoints(dishonestServer.URL)
+	if err := parser.processBatch(2, 3); !errors.Is(err, ErrTransactionsRootMismatch) {
+		t.Errorf("processBatch without an honest endpoint = %v, want ErrTransactionsRootMismatch", err)
+	}
+}
+
+func depositTransaction() models.Transaction {
+	return models.Transaction{
+		From:       "0xdeaddeaddeaddeaddeaddeaddeaddeaddead0001",
+		To:         "0x4200000000000000000000000000000000000015",
+		Gas:        "0xf4240",
+		Value:      "0x0",
+		Input:      "0x440a5e20",
+		Type:       "0x7e",
+		SourceHash: "0x4e3a3754410177e6937ef1f84bba68ea139e8d1a2258c5f85db9f1cd715a1bdd",
+		IsSystemTx: false,
+	}
+}
+
+func TestDepositTransaction(t *testing.T) {
+	tx := depositTransaction()
+	encoded, err := EncodeTransaction(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0x7e || rlp([sourceHash, from, to, mint, value, gas, isSystemTx, data])
+	want := "7ef8" + "57" +
+		"a04e3a3754410177e6937ef1f84bba68ea139e8d1a2258c5f85db9f1cd715a1bdd" +
+		"94deaddeaddeaddeaddeaddeaddeaddeaddead0001" +
+		"944200000000000000000000000000000000000015" +
+		"80" + "80" + "830f4240" + "80" + "84440a5e20"
+	if got := hex.EncodeToString(encoded); got != want {
+		t.Errorf("EncodeTransaction = %s, want %s", got, want)
+	}
+
+	hash, err := TransactionHash(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Hash = hash.String()
+	if err := VerifyTransaction(tx); err != nil {
+		t.Errorf("VerifyTransaction on a deposit: %v", err)
+	}
+	if _, err := RecoverSender(tx); !errors.Is(err, ErrUnsignedTransaction) {
+		t.Errorf("RecoverSender on a deposit = %v, want ErrUnsignedTransaction", err)
+	}
+
+	minted := tx
+	minted.Mint = "0xde0b6b3a7640000"
+	if err := VerifyTransaction(minted); !errors.Is(err, ErrTransactionHashMismatch) {
+		t.Errorf("VerifyTransaction with a forged mint = %v, want ErrTransactionHashMismatch", err)
+	}
+}
+
+func TestParserArbitrumSystemTransactions(t *testing.T) {
+	node := newFakeChain(3, 2)
+	block := node.blocks[1]
+	block.Transactions = append(block.Transactions, models.Transaction{
+		BlockNumber: block.Number,
+		Hash:        randomHex(rand.New(rand.NewSource(1)), 32),
+		Type:        "0x64",
+		RequestID:   "0x00000000000000000000000000000000000000000000000000000000000a1b2c",
+		From:        "0x977f82a600a1414e583f7f13623f1ac5d58b1c0b",
+		To:          "0x742d35cc6634c0532925a3b844bc454e4438f44e",
+		Value:       "0xde0b6b3a7640000",
+	})
+	node.blocks[1] = block
+	sealChain(t, node)
+
+	if err := VerifyTransactions(node.blocks[1]); !errors.Is(err, ErrUnverifiableTxType) {
+		t.Fatalf("VerifyTransactions = %v, want ErrUnverifiableTxType", err)
+	}
+
+	// The block is processed without a transactions root check instead of
+	// being rejected, and the deposit reaches its recipient.
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	parser.SetVerifyBlocks(true)
+	parser.Subscribe("0x742d35cc6634c0532925a3b844bc454e4438f44e")
+	if err := parser.processBatch(0, 3); err != nil {
+		t.Fatalf("processBatch: %v", err)
+	}
+	if rejected := parser.GetRejectedBlocks(); len(rejected) != 0 {
+		t.Errorf("unexpected rejected blocks %+v", rejected)
+	}
+	if txs := parser.GetTransactions("0x742d35cc6634c0532925a3b844bc454e4438f44e"); len(txs) != 1 {
+		t.Errorf("expected the Arbitrum deposit to be recorded, got %d transactions", len(txs))
+	}
+}
@@ -0,0 +1,128 @@
+package ethereum
+
+import (
+	"errors"
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/rlp"
+	"eth-parser/pkg/utils"
+	"fmt"
+)
+
+var (
+	ErrHeaderHashMismatch = errors.New("header hash mismatch")
+	ErrParentHashMismatch = errors.New("parent hash mismatch")
+)
+
+// HeaderHash recomputes the block hash as keccak256(rlp(header)). Fields
+// introduced by later forks (London base fee, Shanghai withdrawals root,
+// Cancun blob gas and beacon root, Prague requests hash) are appended when
+// present, which covers every header layout since genesis.
+func HeaderHash(h models.Header) (models.Hash, error) {
+	e := &fieldEncoder{}
+	items := [][]byte{
+		e.fixed("parentHash", h.ParentHash, models.HashLength),
+		e.fixed("sha3Uncles", h.Sha3Uncles, models.HashLength),
+		e.fixed("miner", h.Miner, models.AddressLength),
+		e.fixed("stateRoot", h.StateRoot, models.HashLength),
+		e.fixed("transactionsRoot", h.TransactionsRoot, models.HashLength),
+		e.fixed("receiptsRoot", h.ReceiptsRoot, models.HashLength),
+		e.fixed("logsBloom", h.LogsBloom, bloomByteLength),
+		e.quantity("difficulty", h.Difficulty),
+		e.quantity("number", h.Number),
+		e.quantity("gasLimit", h.GasLimit),
+		e.quantity("gasUsed", h.GasUsed),
+		e.quantity("timestamp", h.Timestamp),
+		e.data("extraData", h.ExtraData),
+		e.fixed("mixHash", h.MixHash, models.HashLength),
+		e.fixed("nonce", h.Nonce, 8),
+	}
+
+	forkFields := []struct {
+		name   string
+		value  string
+		encode func(name, value string) []byte
+	}{
+		{"baseFeePerGas", h.BaseFeePerGas, e.quantity},
+		{"withdrawalsRoot", h.WithdrawalsRoot, e.hash},
+		{"blobGasUsed", h.BlobGasUsed, e.quantity},
+		{"excessBlobGas", h.ExcessBlobGas, e.quantity},
+		{"parentBeaconBlockRoot", h.ParentBeaconBlockRoot, e.hash},
+		{"requestsHash", h.RequestsHash, e.hash},
+	}
+	missing := ""
+	for _, f := range forkFields {
+		if f.value == "" {
+			if missing == "" {
+				missing = f.name
+			}
+			continue
+		}
+		if missing != "" {
+			return models.Hash{}, fmt.Errorf("header has %s but not %s", f.name, missing)
+		}
+		items = append(items, f.encode(f.name, f.value))
+	}
+
+	if e.err != nil {
+		return models.Hash{}, e.err
+	}
+	return utils.Keccak256(rlp.EncodeList(items...)), nil
+}
+
+// VerifyHeader checks that the hash reported by the node matches the header
+// fields it sent along.
+func VerifyHeader(h models.Header) error {
+	computed, err := HeaderHash(h)
+	if err != nil {
+		return fmt.Errorf("block %s: %w", h.Number, err)
+	}
+	reported, err := models.HexToHash(h.Hash)
+	if err != nil {
+		return fmt.Errorf("block %s: invalid hash %q", h.Number, h.Hash)
+	}
+	if computed != reported {
+		return fmt.Errorf("block %s: %w: reported %s, computed %s", h.Number, ErrHeaderHashMismatch, reported, computed)
+	}
+	return nil
+}
+
+// fieldEncoder RLP-encodes hex header and transaction fields and keeps the
+// first error.
+type fieldEncoder struct {
+	err error
+}
+
+func (e *fieldEncoder) fail(name string, err error) []byte {
+	if e.err == nil {
+		e.err = fmt.Errorf("field %s: %w", name, err)
+	}
+	return nil
+}
+
+func (e *fieldEncoder) quantity(name, value string) []byte {
+	v, err := utils.DecodeBig(value)
+	if err != nil {
+		return e.fail(name, err)
+	}
+	return rlp.EncodeBig(v)
+}
+
+func (e *fieldEncoder) data(name, value string) []byte {
+	b, err := utils.DecodeBytes(value)
+	if err != nil {
+		return e.fail(name, err)
+	}
+	return rlp.EncodeBytes(b)
+}
+
+func (e *fieldEncoder) fixed(name, value string, length int) []byte {
+	b := make([]byte, length)
+	if err := utils.DecodeFixedBytes(value, b); err != nil {
+		return e.fail(name, err)
+	}
+	return rlp.EncodeBytes(b)
+}
+
+func (e *fieldEncoder) hash(name, value string) []byte {
+	return e.fixed(name, value, models.HashLength)
+}
@@ -0,0 +1,203 @@
+package ethereum
+
+import (
+	"errors"
+	"eth-parser/pkg/models"
+	"strings"
+	"testing"
+)
+
+// mainnetGenesis is the header of Ethereum mainnet block 0.
+func mainnetGenesis() models.Header {
+	return models.Header{
+		ParentHash:       "0x0000000000000000000000000000000000000000000000000000000000000000",
+		Sha3Uncles:       "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347",
+		Miner:            "0x0000000000000000000000000000000000000000",
+		StateRoot:        "0xd7f8974fb5ac78d9ac099b9ad5018bedc2ce0a72dad1827a1709da30580f0544",
+		TransactionsRoot: "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421",
+		ReceiptsRoot:     "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421",
+		LogsBloom:        "0x" + strings.Repeat("00", 256),
+		Difficulty:       "0x400000000",
+		Number:           "0x0",
+		GasLimit:         "0x1388",
+		GasUsed:          "0x0",
+		Timestamp:        "0x0",
+		ExtraData:        "0x11bbe8db4e347b4e8c937c1c8370e4b5ed33adb3db69cbdb7a38e1e50b1b82fa",
+		MixHash:          "0x0000000000000000000000000000000000000000000000000000000000000000",
+		Nonce:            "0x0000000000000042",
+		Hash:             "0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3",
+	}
+}
+
+func TestHeaderHash(t *testing.T) {
+	header := mainnetGenesis()
+	if err := VerifyHeader(header); err != nil {
+		t.Fatalf("VerifyHeader(genesis): %v", err)
+	}
+
+	tampered := header
+	tampered.StateRoot = "0x" + strings.Repeat("11", 32)
+	if err := VerifyHeader(tampered); !errors.Is(err, ErrHeaderHashMismatch) {
+		t.Errorf("VerifyHeader(tampered) = %v, want ErrHeaderHashMismatch", err)
+	}
+
+	malformed := header
+	malformed.Number = "0x00"
+	if err := VerifyHeader(malformed); err == nil || !strings.Contains(err.Error(), "number") {
+		t.Errorf("VerifyHeader(malformed) = %v, want an error naming the field", err)
+	}
+}
+
+func TestHeaderHashForkFields(t *testing.T) {
+	header := mainnetGenesis()
+	base, _ := HeaderHash(header)
+
+	// Each fork appends fields, so every variant hashes differently.
+	seen := map[models.Hash]string{base: "frontier"}
+	forks := []struct {
+		name  string
+		apply func(h *models.Header)
+	}{
+		{"london", func(h *models.Header) { h.BaseFeePerGas = "0x3b9aca00" }},
+		{"shanghai", func(h *models.Header) {
+			h.WithdrawalsRoot = "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"
+		}},
+		{"cancun", func(h *models.Header) {
+			h.BlobGasUsed, h.ExcessBlobGas = "0x20000", "0x0"
+			h.ParentBeaconBlockRoot = "0x" + strings.Repeat("ab", 32)
+		}},
+		{"prague", func(h *models.Header) { h.RequestsHash = "0x" + strings.Repeat("cd", 32) }},
+	}
+	for _, fork := range forks {
+		fork.apply(&header)
+		hash, err := HeaderHash(header)
+		if err != nil {
+			t.Fatalf("%s: %v", fork.name, err)
+		}
+		if previous, ok := seen[hash]; ok {
+			t.Errorf("%s header hashes like %s", fork.name, previous)
+		}
+		seen[hash] = fork.name
+	}
+
+	// A Cancun field without the Shanghai withdrawals root is malformed.
+	header.WithdrawalsRoot = ""
+	if _, err := HeaderHash(header); err == nil {
+		t.Error("HeaderHash accepted a header with a gap in the fork fields")
+	}
+}
+
+// sealChain fills in valid header fields for every block of the fake node and
+// links them with correct hashes, starting from block 0.
+func sealChain(t *testing.T, node *fakeNode) {
+	t.Helper()
+	parentHash := "0x" + strings.Repeat("00", 32)
+	for number := int64(0); number < int64(len(node.blocks)); number++ {
+		block := node.blocks[number]
+		sealBlock(t, &block, parentHash)
+		node.blocks[number] = block
+		parentHash = block.Hash
+	}
+}
+
+// sealBlock commits to the block's transactions and seals its header.
+func sealBlock(t *testing.T, b *models.Block, parentHash string) {
+	t.Helper()
+	// Blocks with Arbitrum system transactions get a zero root, which the
+	// parser does not check.
+	root, err := TransactionsRoot(b.Transactions)
+	if err != nil && !errors.Is(err, ErrUnverifiableTxType) {
+		t.Fatal(err)
+	}
+	sealHeader(t, &b.Header, parentHash)
+	b.TransactionsRoot = root.String()
+	hash, err := HeaderHash(b.Header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Hash = hash.String()
+}
+
+func sealHeader(t *testing.T, h *models.Header, parentHash string) {
+	t.Helper()
+	zero := "0x" + strings.Repeat("00", 32)
+	h.ParentHash = parentHash
+	h.Sha3Uncles = "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347"
+	h.Miner = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5"
+	h.StateRoot, h.TransactionsRoot, h.ReceiptsRoot, h.MixHash = zero, zero, zero, zero
+	h.Difficulty, h.GasLimit, h.GasUsed, h.Timestamp = "0x0", "0x1c9c380", "0x0", "0x665f0a2b"
+	if h.ExtraData == "" {
+		h.ExtraData = "0x"
+	}
+	h.Nonce = "0x0000000000000000"
+	h.BaseFeePerGas = "0x3b9aca00"
+	hash, err := HeaderHash(*h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Hash = hash.String()
+}
+
+func TestParserVerifyBlocks(t *testing.T) {
+	node := newFakeChain(6, 2)
+	sealChain(t, node)
+	parser, closeServer := newTestParser(node)
+	defer closeServer()
+	parser.SetVerifyBlocks(true)
+	orphaned := node.blocks[2].Transactions[0].From
+	moved := node.blocks[2].Transactions[1].From
+	fabricatedSender := node.blocks[5].Transactions[0].From
+	for _, address := range []string{orphaned, moved, fabricatedSender} {
+		parser.Subscribe(address)
+	}
+
+	if err := parser.processBatch(0, 4); err != nil {
+		t.Fatalf("processBatch on a valid chain: %v", err)
+	}
+
+	// A reorg replaces blocks 2 and 3: one transaction of block 2 is
+	// dropped, the other moves to block 3. Block 4 builds on the new chain.
+	replaced := node.blocks[2]
+	tx := replaced.Transactions[1]
+	tx.BlockNumber = "0x3"
+	replaced.Transactions = []models.Transaction{}
+	sealBlock(t, &replaced, node.blocks[1].Hash)
+	node.blocks[2] = replaced
+	reorged := node.blocks[3]
+	reorged.Transactions = []models.Transaction{tx}
+	sealBlock(t, &reorged, replaced.Hash)
+	node.blocks[3] = reorged
+	next := node.blocks[4]
+	sealBlock(t, &next, reorged.Hash)
+	node.blocks[4] = next
+	if err := parser.processBatch(4, 5); err != nil {
+		t.Fatalf("processBatch after a reorg: %v", err)
+	}
+	if txs := parser.GetTransactions(orphaned); len(txs) != 0 {
+		t.Errorf("orphaned transactions kept after a reorg: %+v", txs)
+	}
+	if txs := parser.GetTransactions(moved); len(txs) != 1 || txs[0].BlockNumber != "0x3" {
+		t.Errorf("transactions after a reorg = %+v, want one in block 3", txs)
+	}
+
+	// A block whose contents do not match its hash is rejected.
+	tampered := node.blocks[5]
+	sealBlock(t, &tampered, next.Hash)
+	tampered.Miner = "0x0000000000000000000000000000000000000001"
+	node.blocks[5] = tampered
+	if err := parser.processBatch(5, 6); !errors.Is(err, ErrHeaderHashMismatch) {
+		t.Errorf("processBatch with a tampered header = %v, want ErrHeaderHashMismatch", err)
+	}
+
+	// A self-consistent block that does not link to its parent is rejected
+	// before anything in it is stored.
+	fabricated := node.blocks[5]
+	sealBlock(t, &fabricated, "0x"+strings.Repeat("ee", 32))
+	node.blocks[5] = fabricated
+	if err := parser.processBatch(5, 6); !errors.Is(err, ErrParentHashMismatch) {
+		t.Errorf("processBatch with a fabricated parent = %v, want ErrParentHashMismatch", err)
+	}
+	if txs := parser.GetTransactions(fabricatedSender); len(txs) != 0 {
+		t.Errorf("transactions of a fabricated block stored: %+v", txs)
+	}
+}
@@ -0,0 +1,290 @@
+package matcher
+
+import (
+	"encoding/binary"
+	"eth-parser/pkg/models"
+	"runtime"
+	"sync"
+)
+
+// Matcher decides which transactions of a block involve a watched address.
+type Matcher interface {
+	Add(address models.Address) bool
+	Remove(address models.Address) bool
+	Contains(address models.Address) bool
+	Len() int
+	Range(fn func(address models.Address) bool)
+	Match(txs []models.Transaction) []models.Transaction
+}
+
+const (
+	shardBits  = 8
+	shardCount = 1 << shardBits
+
+	initialShardSize = 16
+
+	// Blocks with fewer transactions are matched on the calling goroutine;
+	// below this size the fan-out costs more than it saves.
+	parallelThreshold = 256
+)
+
+// AddressSet is a Matcher backed by an open-addressing hash set of fixed 20
+// byte keys. Addresses are spread over 256 independently locked shards so
+// that subscription changes do not stall matching, and each shard stores its
+// keys in one contiguous slice to keep lookups within a few cache lines.
+type AddressSet struct {
+	shards  [shardCount]shard
+	workers int
+}
+
+// shard uses linear probing with the zero address as the empty-slot marker;
+// the zero address itself is tracked by hasZero.
+type shard struct {
+	mu      sync.RWMutex
+	slots   []models.Address
+	count   int
+	hasZero bool
+}
+
+// NewAddressSet returns an empty set that matches blocks using up to workers
+// goroutines. workers <= 0 means GOMAXPROCS.
+func NewAddressSet(workers int) *AddressSet {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &AddressSet{workers: workers}
+}
+
+func hashAddress(a *models.Address) uint64 {
+	h := binary.LittleEndian.Uint64(a[0:8]) ^
+		binary.LittleEndian.Uint64(a[8:16])*0x9e3779b97f4a7c15 ^
+		uint64(binary.LittleEndian.Uint32(a[16:20]))*0xc2b2ae3d27d4eb4f
+	// splitmix64 finalizer: vanity addresses share long prefixes.
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+func (s *AddressSet) shardFor(h uint64) *shard {
+	return &s.shards[h>>(64-shardBits)]
+}
+
+func (s *AddressSet) Add(address models.Address) bool {
+	h := hashAddress(&address)
+	sh := s.shardFor(h)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.insert(address, h)
+}
+
+func (s *AddressSet) Remove(address models.Address) bool {
+	h := hashAddress(&address)
+	sh := s.shardFor(h)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.remove(address, h)
+}
+
+func (s *AddressSet) Contains(address models.Address) bool {
+	h := hashAddress(&address)
+	sh := s.shardFor(h)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	return sh.contains(address, h)
+}
+
+func (s *AddressSet) Len() int {
+	total := 0
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		total += sh.count
+		if sh.hasZero {
+			total++
+		}
+		sh.mu.RUnlock()
+	}
+	return total
+}
+
+// Range calls fn for every address until fn returns false. Each shard is
+// copied before iterating, so fn may modify the set.
+func (s *AddressSet) Range(fn func(address models.Address) bool) {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.RLock()
+		var addresses []models.Address
+		if sh.hasZero {
+			addresses = append(addresses, models.Address{})
+		}
+		for _, slot := range sh.slots {
+			if slot != (models.Address{}) {
+				addresses = append(addresses, slot)
+			}
+		}
+		sh.mu.RUnlock()
+
+		for _, address := range addresses {
+			if !fn(address) {
+				return
+			}
+		}
+	}
+}
+
+// Match returns the transactions touching an address in the set, in block
+// order: sender and recipient, plus the type specific accounts listed by
+// models.Transaction.Addresses. Large blocks are split across the configured
+// workers.
+func (s *AddressSet) Match(txs []models.Transaction) []models.Transaction {
+	if len(txs) < parallelThreshold || s.workers == 1 {
+		return s.matchRange(txs)
+	}
+
+	chunk := (len(txs) + s.workers - 1) / s.workers
+	results := make([][]models.Transaction, s.workers)
+	var wg sync.WaitGroup
+	for w := 0; w < s.workers; w++ {
+		start := w * chunk
+		if start >= len(txs) {
+			break
+		}
+		end := start + chunk
+		if end > len(txs) {
+			end = len(txs)
+		}
+		wg.Add(1)
+		go func(w, start, end int) {
+			defer wg.Done()
+			results[w] = s.matchRange(txs[start:end])
+		}(w, start, end)
+	}
+	wg.Wait()
+
+	var matched []models.Transaction
+	for _, r := range results {
+		matched = append(matched, r...)
+	}
+	return matched
+}
+
+func (s *AddressSet) matchRange(txs []models.Transaction) []models.Transaction {
+	var matched []models.Transaction
+	var buf [8]string
+	for _, tx := range txs {
+		for _, address := range tx.Addresses(buf[:0]) {
+			if s.containsHex(address) {
+				matched = append(matched, tx)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+func (s *AddressSet) containsHex(address string) bool {
+	if address == "" {
+		return false
+	}
+	a, err := models.HexToAddress(address)
+	if err != nil {
+		return false
+	}
+	return s.Contains(a)
+}
+
+func (sh *shard) contains(a models.Address, h uint64) bool {
+	if a == (models.Address{}) {
+		return sh.hasZero
+	}
+	if len(sh.slots) == 0 {
+		return false
+	}
+	mask := uint64(len(sh.slots) - 1)
+	for i := h & mask; ; i = (i + 1) & mask {
+		switch sh.slots[i] {
+		case a:
+			return true
+		case models.Address{}:
+			return false
+		}
+	}
+}
+
+func (sh *shard) insert(a models.Address, h uint64) bool {
+	if a == (models.Address{}) {
+		added := !sh.hasZero
+		sh.hasZero = true
+		return added
+	}
+	if sh.contains(a, h) {
+		return false
+	}
+	// Keep the load factor at or below 3/4.
+	if (sh.count+1)*4 > len(sh.slots)*3 {
+		sh.grow()
+	}
+	sh.place(a, h)
+	sh.count++
+	return true
+}
+
+func (sh *shard) place(a models.Address, h uint64) {
+	mask := uint64(len(sh.slots) - 1)
+	i := h & mask
+	for sh.slots[i] != (models.Address{}) {
+		i = (i + 1) & mask
+	}
+	sh.slots[i] = a
+}
+
+func (sh *shard) grow() {
+	size := len(sh.slots) * 2
+	if size == 0 {
+		size = initialShardSize
+	}
+	old := sh.slots
+	sh.slots = make([]models.Address, size)
+	for i := range old {
+		if old[i] != (models.Address{}) {
+			sh.place(old[i], hashAddress(&old[i]))
+		}
+	}
+}
+
+// remove deletes a with backward-shift deletion, which keeps probe sequences
+// intact without tombstones.
+func (sh *shard) remove(a models.Address, h uint64) bool {
+	if a == (models.Address{}) {
+		removed := sh.hasZero
+		sh.hasZero = false
+		return removed
+	}
+	if len(sh.slots) == 0 {
+		return false
+	}
+	mask := uint64(len(sh.slots) - 1)
+	i := h & mask
+	for sh.slots[i] != a {
+		if sh.slots[i] == (models.Address{}) {
+			return false
+		}
+		i = (i + 1) & mask
+	}
+
+	for j := (i + 1) & mask; sh.slots[j] != (models.Address{}); j = (j + 1) & mask {
+		home := hashAddress(&sh.slots[j]) & mask
+		// Move slots[j] into the hole at i unless its home lies cyclically
+		// in (i, j], in which case it is already reachable.
+		if (j > i && (home <= i || home > j)) || (j < i && home <= i && home > j) {
+			sh.slots[i] = sh.slots[j]
+			i = j
+		}
+	}
+	sh.slots[i] = models.Address{}
+	sh.count--
+	return true
+}
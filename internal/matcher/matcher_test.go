@@ -0,0 +1,256 @@
+package matcher
+
+import (
+	"eth-parser/pkg/models"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func randomAddresses(r *rand.Rand, n int) []models.Address {
+	addresses := make([]models.Address, n)
+	for i := range addresses {
+		r.Read(addresses[i][:])
+	}
+	return addresses
+}
+
+func TestAddressSet(t *testing.T) {
+	set := NewAddressSet(4)
+	r := rand.New(rand.NewSource(1))
+	addresses := randomAddresses(r, 5000)
+	// Colliding prefixes and the zero address are stored like any other key.
+	addresses = append(addresses, models.Address{}, models.Address{19: 1}, models.Address{19: 2})
+
+	reference := make(map[models.Address]bool)
+	for _, a := range addresses {
+		if got := set.Add(a); got != !reference[a] {
+			t.Fatalf("Add(%s) = %v, want %v", a, got, !reference[a])
+		}
+		reference[a] = true
+	}
+	if set.Len() != len(reference) {
+		t.Fatalf("Len() = %d, want %d", set.Len(), len(reference))
+	}
+
+	// Remove every other address and make sure the survivors stay reachable
+	// after backward-shift deletion.
+	for i, a := range addresses {
+		if i%2 == 0 {
+			if !set.Remove(a) {
+				t.Fatalf("Remove(%s) = false for a member", a)
+			}
+			delete(reference, a)
+		}
+	}
+	if set.Remove(addresses[0]) {
+		t.Error("Remove returned true for an address that was already removed")
+	}
+	for _, a := range addresses {
+		if set.Contains(a) != reference[a] {
+			t.Fatalf("Contains(%s) = %v, want %v", a, !reference[a], reference[a])
+		}
+	}
+
+	seen := 0
+	set.Range(func(a models.Address) bool {
+		if !reference[a] {
+			t.Errorf("Range yielded non-member %s", a)
+		}
+		seen++
+		return true
+	})
+	if seen != len(reference) {
+		t.Errorf("Range yielded %d addresses, want %d", seen, len(reference))
+	}
+}
+
+func TestAddressSetMatch(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	watched := randomAddresses(r, 3)
+	set := NewAddressSet(4)
+	for _, a := range watched {
+		set.Add(a)
+	}
+
+	var txs []models.Transaction
+	for i := 0; i < 1000; i++ {
+		from := randomAddresses(r, 1)[0].String()
+		to := randomAddresses(r, 1)[0].String()
+		switch i {
+		case 10:
+			from = strings.ToUpper(watched[0].String()[2:])
+			from = "0x" + from
+		case 500:
+			to = watched[1].String()
+		case 999:
+			to = "" // contract creation
+			from = watched[2].String()
+		}
+		txs = append(txs, models.Transaction{From: from, To: to})
+	}
+
+	matched := set.Match(txs)
+	if len(matched) != 3 {
+		t.Fatalf("Match returned %d transactions, want 3", len(matched))
+	}
+	if matched[0].From != txs[10].From || matched[1].To != txs[500].To || matched[2].From != txs[999].From {
+		t.Error("Match did not preserve block order")
+	}
+
+	serial := NewAddressSet(1)
+	for _, a := range watched {
+		serial.Add(a)
+	}
+	if got := serial.Match(txs); len(got) != len(matched) {
+		t.Errorf("serial Match returned %d transactions, parallel %d", len(got), len(matched))
+	}
+}
+
+func TestAddressSetMatchL2Types(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	watched := randomAddresses(r, 1)[0]
+	set := NewAddressSet(1)
+	set.Add(watched)
+
+	other := randomAddresses(r, 1)[0].String()
+	retryable := models.Transaction{
+		Type:    "0x69",
+		From:    other,
+		To:      "0x000000000000000000000000000000000000006e",
+		RetryTo: watched.String(),
+	}
+	deposit := models.Transaction{Type: "0x7e", From: other, To: watched.String(), Mint: "0x1"}
+	// retryTo only means something on a submit retryable transaction.
+	plain := models.Transaction{Type: "0x2", From: other, To: other, RetryTo: watched.String()}
+
+	matched := set.Match([]models.Transaction{retryable, deposit, plain})
+	if len(matched) != 2 || matched[0].Type != "0x69" || matched[1].Type != "0x7e" {
+		t.Errorf("Match returned %+v, want the retryable and the deposit", matched)
+	}
+}
+
+func TestAddressSetConcurrentUpdates(t *testing.T) {
+	set := NewAddressSet(0)
+	addresses := randomAddresses(rand.New(rand.NewSource(3)), 2000)
+	txs := make([]models.Transaction, len(addresses))
+	for i, a := range addresses {
+		txs[i] = models.Transaction{From: a.String()}
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(addresses); i += 4 {
+				set.Add(addresses[i])
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			set.Match(txs)
+		}
+	}()
+	wg.Wait()
+
+	if got := len(set.Match(txs)); got != len(addresses) {
+		t.Errorf("Match returned %d transactions after concurrent adds, want %d", got, len(addresses))
+	}
+}
+
+const benchSetSize = 1_000_000
+
+var (
+	benchOnce      sync.Once
+	benchAddresses []models.Address
+	benchSet       *AddressSet
+	benchSyncMap   sync.Map
+)
+
+func setupBench(b *testing.B) {
+	b.Helper()
+	benchOnce.Do(func() {
+		benchAddresses = randomAddresses(rand.New(rand.NewSource(4)), benchSetSize)
+		benchSet = NewAddressSet(0)
+		for _, a := range benchAddresses {
+			benchSet.Add(a)
+			benchSyncMap.Store(a.String(), true)
+		}
+	})
+}
+
+// benchBlock returns txCount transactions of which roughly 1% touch the set.
+func benchBlock(txCount int) []models.Transaction {
+	r := rand.New(rand.NewSource(5))
+	txs := make([]models.Transaction, txCount)
+	for i := range txs {
+		from := randomAddresses(r, 1)[0]
+		if i%100 == 0 {
+			from = benchAddresses[r.Intn(len(benchAddresses))]
+		}
+		txs[i] = models.Transaction{From: from.String(), To: randomAddresses(r, 1)[0].String()}
+	}
+	return txs
+}
+
+func BenchmarkAddressSetContains1M(b *testing.B) {
+	setupBench(b)
+	probe := randomAddresses(rand.New(rand.NewSource(6)), 1024)
+	for i := range probe {
+		if i%2 == 0 {
+			probe[i] = benchAddresses[i]
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchSet.Contains(probe[i%len(probe)])
+	}
+}
+
+// BenchmarkMatch1M reports matching throughput against a 1M address set for
+// the previous sync.Map of lowercased strings and for AddressSet with one and
+// GOMAXPROCS workers.
+func BenchmarkMatch1M(b *testing.B) {
+	setupBench(b)
+	for _, size := range []struct {
+		name    string
+		txCount int
+	}{
+		{"Block200", 200},
+		{"Block2000", 2000},
+	} {
+		txs := benchBlock(size.txCount)
+
+		b.Run(size.name+"/SyncMap", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, tx := range txs {
+					if _, ok := benchSyncMap.Load(strings.ToLower(tx.From)); ok {
+						continue
+					}
+					benchSyncMap.Load(strings.ToLower(tx.To))
+				}
+			}
+			b.ReportMetric(float64(b.N*len(txs))/b.Elapsed().Seconds(), "tx/s")
+		})
+		b.Run(size.name+"/Serial", func(b *testing.B) {
+			workers := benchSet.workers
+			benchSet.workers = 1
+			defer func() { benchSet.workers = workers }()
+			for i := 0; i < b.N; i++ {
+				benchSet.Match(txs)
+			}
+			b.ReportMetric(float64(b.N*len(txs))/b.Elapsed().Seconds(), "tx/s")
+		})
+		b.Run(size.name+"/Parallel", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				benchSet.Match(txs)
+			}
+			b.ReportMetric(float64(b.N*len(txs))/b.Elapsed().Seconds(), "tx/s")
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	limiter := NewLimiter(Limit{Rate: 2, Burst: 3})
+	now := time.Unix(1700000000, 0)
+
+	for i := 2; i >= 0; i-- {
+		result := limiter.Allow("a", now)
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("request %d: %+v", 3-i, result)
+		}
+	}
+	result := limiter.Allow("a", now)
+	if result.Allowed || result.RetryAfter != 500*time.Millisecond || result.Reset != 1500*time.Millisecond {
+		t.Errorf("empty bucket: %+v", result)
+	}
+	if !limiter.Allow("b", now).Allowed {
+		t.Error("clients should have their own buckets")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if result := limiter.Allow("a", now); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("after refilling a token: %+v", result)
+	}
+	now = now.Add(time.Hour)
+	if result := limiter.Allow("a", now); result.Remaining != 2 {
+		t.Errorf("the bucket should not fill beyond its burst: %+v", result)
+	}
+	if _, ok := limiter.buckets["b"]; ok {
+		t.Error("full buckets should be swept")
+	}
+}
@@ -3,56 +3,239 @@ package rpc
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"eth-parser/common"
 	"eth-parser/pkg/models"
 	"eth-parser/pkg/utils"
 	"fmt"
 	"io"
+	"math/big"
 	"net/http"
+	"sync"
 	"time"
 )
 
-var (
+// ErrCallFailed is returned by Call when the node executed the call and it
+// failed, e.g. reverted, as opposed to the node being unreachable.
+var ErrCallFailed = errors.New("call failed")
+
+// Client talks JSON-RPC to the nodes of one chain. Endpoints are tried in
+// order; the current one stays in use until a failover moves on to the next.
+type Client struct {
 	httpClient *http.Client
-)
 
-func init() {
-	httpClient = &http.Client{
-		Timeout: 10 * time.Second,
+	mu        sync.RWMutex
+	endpoints []string
+	current   int
+}
+
+// NewClient returns a client using a primary endpoint followed by fallbacks.
+func NewClient(urls ...string) *Client {
+	c := &Client{
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
 	}
+	c.SetEndpoints(urls...)
+	return c
+}
+
+var defaultClient = NewClient(common.CloudFlareRpcUrl)
+
+// DefaultClient returns the client used by the package-level functions.
+func DefaultClient() *Client {
+	return defaultClient
+}
+
+// SetEndpoint changes the JSON-RPC endpoint used by the default client.
+func SetEndpoint(url string) {
+	defaultClient.SetEndpoints(url)
+}
+
+// SetEndpoints configures the endpoints of the default client.
+func SetEndpoints(urls ...string) {
+	defaultClient.SetEndpoints(urls...)
+}
+
+// Endpoint returns the endpoint the default client currently uses.
+func Endpoint() string {
+	return defaultClient.Endpoint()
+}
+
+// EndpointCount returns the number of endpoints of the default client.
+func EndpointCount() int {
+	return defaultClient.EndpointCount()
+}
+
+// Failover moves the default client on to its next endpoint.
+func Failover(failed string) string {
+	return defaultClient.Failover(failed)
 }
 
 func GetLatestBlockNumber() (int64, error) {
-	response, err := jsonRPCCall(common.EthBlockNumber, nil)
+	return defaultClient.GetLatestBlockNumber()
+}
+
+func GetBlockByNumber(blockNumber int64) (models.Block, error) {
+	return defaultClient.GetBlockByNumber(blockNumber)
+}
+
+func GetBlockHeaderByNumber(blockNumber int64) (models.BlockHeader, error) {
+	return defaultClient.GetBlockHeaderByNumber(blockNumber)
+}
+
+func GetTransactionReceipt(hash string) (models.Receipt, error) {
+	return defaultClient.GetTransactionReceipt(hash)
+}
+
+func GetBlockLogs(blockHash string) ([]models.Log, error) {
+	return defaultClient.GetBlockLogs(blockHash)
+}
+
+func Call(to string, data []byte, block string) ([]byte, error) {
+	return defaultClient.Call(to, data, block)
+}
+
+func GetBalance(address string, block string) (*big.Int, error) {
+	return defaultClient.GetBalance(address, block)
+}
+
+func TraceBlockByNumber(blockNumber int64) ([]models.TransactionTrace, error) {
+	return defaultClient.TraceBlockByNumber(blockNumber)
+}
+
+// SetEndpoints configures a primary endpoint followed by fallbacks that
+// Failover switches to.
+func (c *Client) SetEndpoints(urls ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.endpoints = append([]string(nil), urls...)
+	c.current = 0
+}
+
+// Endpoint returns the endpoint currently in use.
+func (c *Client) Endpoint() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.endpoints[c.current]
+}
+
+// EndpointCount returns the number of configured endpoints.
+func (c *Client) EndpointCount() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.endpoints)
+}
+
+// Failover moves on to the next endpoint if failed is still the one in use,
+// so concurrent callers reporting the same endpoint only switch once. It
+// returns the endpoint in use afterwards.
+func (c *Client) Failover(failed string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.endpoints[c.current] == failed {
+		c.current = (c.current + 1) % len(c.endpoints)
+	}
+	return c.endpoints[c.current]
+}
+
+// ChainID returns the chain ID reported by the current endpoint.
+func (c *Client) ChainID() (uint64, error) {
+	response, err := c.jsonRPCCall(common.EthChainId, nil)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get latest block number: %w", err)
+		return 0, fmt.Errorf("failed to get chain id: %w", err)
+	}
+	if response.Error != nil {
+		return 0, fmt.Errorf("failed to get chain id: %v", response.Error)
 	}
+	chainHex, ok := response.Result.(string)
+	if !ok {
+		return 0, fmt.Errorf("failed to get chain id: unexpected result %v", response.Result)
+	}
+	return utils.DecodeUint64(chainHex)
+}
 
-	blockHex := response.Result.(string)
+func (c *Client) GetLatestBlockNumber() (int64, error) {
+	response, err := c.jsonRPCCall(common.EthBlockNumber, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest block number: %w", err)
+	}
+	if response.Error != nil {
+		return 0, fmt.Errorf("failed to get latest block number: %v", response.Error)
+	}
+	blockHex, ok := response.Result.(string)
+	if !ok {
+		return 0, fmt.Errorf("failed to get latest block number: unexpected result %v", response.Result)
+	}
 	return utils.HexToInt(blockHex)
 }
 
-func GetBlockByNumber(blockNumber int64) (models.Block, error) {
+func (c *Client) GetBlockByNumber(blockNumber int64) (models.Block, error) {
+	var block models.Block
+	if err := c.getBlock(blockNumber, true, &block); err != nil {
+		return models.Block{}, err
+	}
+	return block, nil
+}
+
+// GetBlockHeaderByNumber fetches a block without transaction bodies. The
+// response only carries the transaction hashes, which makes it much cheaper
+// than GetBlockByNumber for blocks that turn out to be irrelevant.
+func (c *Client) GetBlockHeaderByNumber(blockNumber int64) (models.BlockHeader, error) {
+	var header models.BlockHeader
+	if err := c.getBlock(blockNumber, false, &header); err != nil {
+		return models.BlockHeader{}, err
+	}
+	return header, nil
+}
+
+// GetTransactionReceipt fetches the receipt of a mined transaction.
+func (c *Client) GetTransactionReceipt(hash string) (models.Receipt, error) {
+	response, err := c.jsonRPCCall(common.EthGetTransactionReceipt, []interface{}{hash})
+	if err != nil {
+		return models.Receipt{}, fmt.Errorf("failed to get receipt %s: %w", hash, err)
+	}
+	if response.Result == nil {
+		return models.Receipt{}, fmt.Errorf("failed to get receipt %s: not found", hash)
+	}
+
+	resultBytes, err := json.Marshal(response.Result)
+	if err != nil {
+		return models.Receipt{}, fmt.Errorf("failed to marshal receipt %s: %w", hash, err)
+	}
+	var receipt models.Receipt
+	if err := json.Unmarshal(resultBytes, &receipt); err != nil {
+		return models.Receipt{}, fmt.Errorf("failed to unmarshal receipt %s: %w", hash, err)
+	}
+	return receipt, nil
+}
+
+func (c *Client) getBlock(blockNumber int64, fullTransactions bool, out interface{}) error {
 	blockHex := fmt.Sprintf("0x%x", blockNumber)
-	response, err := jsonRPCCall(common.EthGetBlockByNumber, []interface{}{blockHex, true})
+	response, err := c.jsonRPCCall(common.EthGetBlockByNumber, []interface{}{blockHex, fullTransactions})
 	if err != nil {
-		return models.Block{}, fmt.Errorf("failed to get block %d: %w", blockNumber, err)
+		return fmt.Errorf("failed to get block %d: %w", blockNumber, err)
+	}
+	if response.Error != nil {
+		return fmt.Errorf("failed to get block %d: %v", blockNumber, response.Error)
+	}
+	if response.Result == nil {
+		return fmt.Errorf("failed to get block %d: not found", blockNumber)
 	}
 
 	resultBytes, err := json.Marshal(response.Result)
 	if err != nil {
-		return models.Block{}, fmt.Errorf("failed to marshal block %d: %w", blockNumber, err)
+		return fmt.Errorf("failed to marshal block %d: %w", blockNumber, err)
 	}
 
-	var block models.Block
-	if err := json.Unmarshal(resultBytes, &block); err != nil {
-		return models.Block{}, fmt.Errorf("failed to unmarshal block %d: %w", blockNumber, err)
+	if err := json.Unmarshal(resultBytes, out); err != nil {
+		return fmt.Errorf("failed to unmarshal block %d: %w", blockNumber, err)
 	}
 
-	return block, nil
+	return nil
 }
 
-func jsonRPCCall(method string, params []interface{}) (models.JSONRPCResponse, error) {
+func (c *Client) jsonRPCCall(method string, params []interface{}) (models.JSONRPCResponse, error) {
 
 	request := models.JSONRPCRequest{
 		JsonRPC: common.JsonRpcVersion,
@@ -66,7 +249,7 @@ func jsonRPCCall(method string, params []interface{}) (models.JSONRPCResponse, e
 		return models.JSONRPCResponse{}, fmt.Errorf("[jsonRPCCall] request body wrong, err=%v", err)
 	}
 
-	resp, err := http.Post(common.CloudFlareRpcUrl, common.ApplicationJsonContentType, bytes.NewBuffer(requestBody))
+	resp, err := c.httpClient.Post(c.Endpoint(), common.ApplicationJsonContentType, bytes.NewBuffer(requestBody))
 	if err != nil {
 		return models.JSONRPCResponse{}, fmt.Errorf("[jsonRPCCall] get response wrong, err=%v", err)
 	}
@@ -86,3 +269,103 @@ func jsonRPCCall(method string, params []interface{}) (models.JSONRPCResponse, e
 
 	return response, nil
 }
+
+// GetBlockLogs fetches every log emitted in the block with the given hash
+// (eth_getLogs with an EIP-234 blockHash filter).
+func (c *Client) GetBlockLogs(blockHash string) ([]models.Log, error) {
+	filter := map[string]string{"blockHash": blockHash}
+	response, err := c.jsonRPCCall(common.EthGetLogs, []interface{}{filter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs of block %s: %w", blockHash, err)
+	}
+	// A null result would read as a block without logs.
+	if response.Error != nil {
+		return nil, fmt.Errorf("failed to get logs of block %s: %v", blockHash, response.Error)
+	}
+
+	resultBytes, err := json.Marshal(response.Result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal logs of block %s: %w", blockHash, err)
+	}
+	var logs []models.Log
+	if err := json.Unmarshal(resultBytes, &logs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal logs of block %s: %w", blockHash, err)
+	}
+	return logs, nil
+}
+
+// Call executes a read-only message call to the contract at to with the
+// given call data (eth_call) and returns what it returned. block is a block
+// number as a hex quantity or a tag such as "latest", which is used when
+// block is empty. A reverted call is an ErrCallFailed.
+func (c *Client) Call(to string, data []byte, block string) ([]byte, error) {
+	if block == "" {
+		block = "latest"
+	}
+	call := map[string]string{"to": to, "data": utils.EncodeBytes(data)}
+	response, err := c.jsonRPCCall(common.EthCall, []interface{}{call, block})
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", to, err)
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("failed to call %s: %w: %v", to, ErrCallFailed, response.Error)
+	}
+	resultHex, ok := response.Result.(string)
+	if !ok {
+		return nil, fmt.Errorf("failed to call %s: unexpected result %v", to, response.Result)
+	}
+	result, err := utils.DecodeBytes(resultHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode result of call to %s: %w", to, err)
+	}
+	return result, nil
+}
+
+// GetBalance returns the balance in wei of address at block, a hex block
+// number or a tag such as "latest", which is used when block is empty.
+func (c *Client) GetBalance(address string, block string) (*big.Int, error) {
+	if block == "" {
+		block = "latest"
+	}
+	response, err := c.jsonRPCCall(common.EthGetBalance, []interface{}{address, block})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance of %s: %w", address, err)
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("failed to get balance of %s: %v", address, response.Error)
+	}
+	balanceHex, ok := response.Result.(string)
+	if !ok {
+		return nil, fmt.Errorf("failed to get balance of %s: unexpected result %v", address, response.Result)
+	}
+	balance, err := utils.DecodeBig(balanceHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode balance of %s: %w", address, err)
+	}
+	return balance, nil
+}
+
+// TraceBlockByNumber returns the call tree of every transaction in a block,
+// in block order, using the callTracer of debug_traceBlockByNumber. Nodes
+// without the debug namespace return an error.
+func (c *Client) TraceBlockByNumber(blockNumber int64) ([]models.TransactionTrace, error) {
+	blockHex := fmt.Sprintf("0x%x", blockNumber)
+	tracer := map[string]string{"tracer": "callTracer"}
+	response, err := c.jsonRPCCall(common.DebugTraceBlockByNumber, []interface{}{blockHex, tracer})
+	if err != nil {
+		return nil, fmt.Errorf("failed to trace block %d: %w", blockNumber, err)
+	}
+	if response.Error != nil {
+		return nil, fmt.Errorf("failed to trace block %d: %v", blockNumber, response.Error)
+	}
+
+	resultBytes, err := json.Marshal(response.Result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal traces of block %d: %w", blockNumber, err)
+	}
+	var traces []models.TransactionTrace
+	if err := json.Unmarshal(resultBytes, &traces); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal traces of block %d: %w", blockNumber, err)
+	}
+	return traces, nil
+}
@@ -1,14 +1,61 @@
 package storage
 
-import "eth-parser/pkg/models"
+import (
+	"eth-parser/pkg/models"
+	"time"
+)
 
 type Storage interface {
 	GetCurrentBlock() int64
 	SetCurrentBlock(number int64)
 	GetSubscribeList() []string
 	Subscribe(address string) bool
+	AddSubscription(sub models.Subscription, limit int) (bool, error)
+	UpdateSubscription(sub models.Subscription) bool
+	GetSubscription(address string) (models.Subscription, bool)
+	GetSubscriptions() []models.Subscription
 	Unsubscribe(address string) bool
 	IsSubscribed(address string) bool
+	IsWatched(address string) bool
+	GetWatchedAddresses() []string
+	ForTenant(tenant string) Storage
+	Tenant() string
 	GetTransactions(address string) []models.Transaction
 	AddTransaction(tx models.Transaction)
+	AddRejectedBlock(block models.RejectedBlock)
+	GetRejectedBlocks() []models.RejectedBlock
+	RemoveBlock(number int64)
+	SetContractABI(address string, abi []byte)
+	GetContractABI(address string) ([]byte, bool)
+	DeleteContractABI(address string) bool
+	AddLogSubscription(sub models.LogSubscription)
+	GetLogSubscription(id string) (models.LogSubscription, bool)
+	GetLogSubscriptions() []models.LogSubscription
+	RemoveLogSubscription(id string) bool
+	AddSubscriptionLog(id string, log models.Log)
+	GetSubscriptionLogs(id string) []models.Log
+	SetToken(token models.Token)
+	GetToken(address string) (models.Token, bool)
+	GetTokens() []models.Token
+	AddLedgerEntry(entry models.LedgerEntry)
+	GetLedgerEntries(address string) []models.LedgerEntry
+	AddBalanceSnapshot(snapshot models.BalanceSnapshot)
+	GetBalanceSnapshots(address, token string) []models.BalanceSnapshot
+	AddBalanceDrift(drift models.BalanceDrift)
+	GetBalanceDrifts() []models.BalanceDrift
+	SetAlertRule(rule models.AlertRule)
+	GetAlertRule(id string) (models.AlertRule, bool)
+	GetAlertRules() []models.AlertRule
+	DeleteAlertRule(id string) bool
+	AddAlert(alert models.Alert) bool
+	ResolveAlert(dedupKey string, at time.Time) bool
+	GetAlerts() []models.Alert
+	AddAPIKey(key models.APIKey)
+	TouchAPIKey(id string, at time.Time)
+	RevokeAPIKey(id string, at time.Time) (models.APIKey, bool)
+	GetAPIKey(id string) (models.APIKey, bool)
+	GetAPIKeyByHash(hash string) (models.APIKey, bool)
+	GetAPIKeys() []models.APIKey
+	AppendAuditRecord(record models.AuditRecord) models.AuditRecord
+	GetAuditRecords(after int64) []models.AuditRecord
 }
@@ -1,90 +1,776 @@
 package storage
 
 import (
+	"errors"
 	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
+// DefaultChainID is the chain a MemoryStorage is scoped to unless ForChain
+// says otherwise: Ethereum mainnet.
+const DefaultChainID = 1
+
+// DefaultTenant owns the subscriptions made without naming a tenant.
+const DefaultTenant = "default"
+
+// ErrQuotaExceeded is returned when a tenant has as many subscriptions as
+// its quota allows.
+var ErrQuotaExceeded = errors.New("subscription quota exceeded")
+
+// MemoryStorage keeps everything in memory. All keys are prefixed with the
+// chain ID, so views returned by ForChain share one store without seeing
+// each other's subscriptions or transactions.
+//
+// Subscriptions also belong to a tenant, the one of the view they were made
+// through, see ForTenant. Everything recorded for an address is stored once
+// however many tenants watch it.
 type MemoryStorage struct {
-	currentBlock        int64
-	subscribedAddresses sync.Map
-	transactions        sync.Map
-	mu                  sync.RWMutex
+	chainID   uint64
+	keyPrefix string
+	tenant    string
+	data      *memoryData
+}
+
+type memoryData struct {
+	currentBlocks    map[uint64]int64
+	transactions     sync.Map
+	contractABIs     sync.Map
+	tokens           sync.Map
+	rejectedBlocks   map[uint64][]models.RejectedBlock
+	logSubscriptions map[string]models.LogSubscription
+	subscriptionLogs map[string][]models.Log
+	ledgers          map[string][]models.LedgerEntry
+	// storedKeys holds the keys of stored transactions, logs and ledger
+	// entries, see transactionKey, logKey and ledgerKey, so that a block
+	// processed again after a failed batch is not recorded twice.
+	storedKeys   map[string]struct{}
+	snapshots    map[string][]models.BalanceSnapshot
+	drifts       map[uint64][]models.BalanceDrift
+	alertRules   map[string]models.AlertRule
+	alerts       map[uint64][]models.Alert
+	activeAlerts map[string]int // chain-scoped dedup key to index in alerts
+	apiKeys      map[string]models.APIKey
+	apiKeyHashes map[string]string // secret hash to key ID
+	auditRecords []models.AuditRecord
+	mu           sync.RWMutex
+
+	// subscriptions maps chain-scoped address keys to the tenants
+	// subscribed to the address.
+	subscriptions map[string]map[string]models.Subscription
+	// subscriptionCounts counts the subscriptions of each tenant per
+	// chain, keyed by tenantKey.
+	subscriptionCounts map[string]int
+	subsMu             sync.RWMutex
 }
 
 func NewMemoryStorage() *MemoryStorage {
-	return &MemoryStorage{}
+	data := &memoryData{
+		currentBlocks:      make(map[uint64]int64),
+		rejectedBlocks:     make(map[uint64][]models.RejectedBlock),
+		logSubscriptions:   make(map[string]models.LogSubscription),
+		subscriptionLogs:   make(map[string][]models.Log),
+		ledgers:            make(map[string][]models.LedgerEntry),
+		storedKeys:         make(map[string]struct{}),
+		snapshots:          make(map[string][]models.BalanceSnapshot),
+		drifts:             make(map[uint64][]models.BalanceDrift),
+		alertRules:         make(map[string]models.AlertRule),
+		alerts:             make(map[uint64][]models.Alert),
+		activeAlerts:       make(map[string]int),
+		apiKeys:            make(map[string]models.APIKey),
+		apiKeyHashes:       make(map[string]string),
+		subscriptions:      make(map[string]map[string]models.Subscription),
+		subscriptionCounts: make(map[string]int),
+	}
+	return newView(data, DefaultChainID, DefaultTenant)
+}
+
+func newView(data *memoryData, chainID uint64, tenant string) *MemoryStorage {
+	return &MemoryStorage{
+		chainID:   chainID,
+		keyPrefix: strconv.FormatUint(chainID, 10) + ":",
+		tenant:    tenant,
+		data:      data,
+	}
+}
+
+// ForChain returns a view of the same store scoped to chainID.
+func (ms *MemoryStorage) ForChain(chainID uint64) *MemoryStorage {
+	return newView(ms.data, chainID, ms.tenant)
+}
+
+// ForTenant returns a view of the same store whose subscriptions are those
+// of tenant.
+func (ms *MemoryStorage) ForTenant(tenant string) Storage {
+	return newView(ms.data, ms.chainID, tenant)
+}
+
+// ChainID returns the chain this view is scoped to.
+func (ms *MemoryStorage) ChainID() uint64 {
+	return ms.chainID
+}
+
+// Tenant returns the tenant this view is scoped to.
+func (ms *MemoryStorage) Tenant() string {
+	return ms.tenant
+}
+
+// key returns the chain-scoped key of an address.
+func (ms *MemoryStorage) key(address string) string {
+	return ms.keyPrefix + strings.ToLower(address)
+}
+
+// tenantKey returns the chain-scoped key of the tenant.
+func (ms *MemoryStorage) tenantKey() string {
+	return ms.keyPrefix + ms.tenant
 }
 
 func (ms *MemoryStorage) GetCurrentBlock() int64 {
-	ms.mu.RLock()
-	defer ms.mu.RUnlock()
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
 
-	return ms.currentBlock
+	return ms.data.currentBlocks[ms.chainID]
 }
 
 func (ms *MemoryStorage) SetCurrentBlock(block int64) {
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
-	ms.currentBlock = block
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	ms.data.currentBlocks[ms.chainID] = block
 }
 
+// GetSubscribeList returns the addresses the tenant is subscribed to.
 func (ms *MemoryStorage) GetSubscribeList() []string {
+	ms.data.subsMu.RLock()
+	defer ms.data.subsMu.RUnlock()
 	var addresses []string
-
-	ms.subscribedAddresses.Range(func(key, value interface{}) bool {
-		addresses = append(addresses, key.(string))
-		return true
-	})
+	for key, tenants := range ms.data.subscriptions {
+		if address, ok := strings.CutPrefix(key, ms.keyPrefix); ok {
+			if _, ok := tenants[ms.tenant]; ok {
+				addresses = append(addresses, address)
+			}
+		}
+	}
 	return addresses
 }
 
+// Subscribe subscribes the tenant to address without metadata.
 func (ms *MemoryStorage) Subscribe(address string) bool {
-	address = strings.ToLower(address)
-	_, loaded := ms.subscribedAddresses.LoadOrStore(address, true)
+	added, _ := ms.AddSubscription(models.Subscription{Address: address, CreatedAt: time.Now().UTC()}, 0)
+	return added
+}
 
-	return !loaded
+// AddSubscription subscribes the tenant to sub.Address unless it is
+// subscribed already, and reports whether it was added. A positive limit is
+// the number of subscriptions the tenant may have on the chain.
+func (ms *MemoryStorage) AddSubscription(sub models.Subscription, limit int) (bool, error) {
+	sub.Address = strings.ToLower(sub.Address)
+	key := ms.key(sub.Address)
+	ms.data.subsMu.Lock()
+	defer ms.data.subsMu.Unlock()
+	if _, ok := ms.data.subscriptions[key][ms.tenant]; ok {
+		return false, nil
+	}
+	if limit > 0 && ms.data.subscriptionCounts[ms.tenantKey()] >= limit {
+		return false, fmt.Errorf("%w: tenant %s has %d subscriptions", ErrQuotaExceeded, ms.tenant, limit)
+	}
+	if ms.data.subscriptions[key] == nil {
+		ms.data.subscriptions[key] = make(map[string]models.Subscription)
+	}
+	ms.data.subscriptions[key][ms.tenant] = sub
+	ms.data.subscriptionCounts[ms.tenantKey()]++
+	return true, nil
 }
 
-func (ms *MemoryStorage) Unsubscribe(address string) bool {
-	address = strings.ToLower(address)
-	_, loaded := ms.subscribedAddresses.LoadAndDelete(address)
+// UpdateSubscription replaces the tenant's metadata of a subscribed address.
+// It returns false if the tenant is not subscribed to the address.
+func (ms *MemoryStorage) UpdateSubscription(sub models.Subscription) bool {
+	sub.Address = strings.ToLower(sub.Address)
+	key := ms.key(sub.Address)
+	ms.data.subsMu.Lock()
+	defer ms.data.subsMu.Unlock()
+	if _, ok := ms.data.subscriptions[key][ms.tenant]; !ok {
+		return false
+	}
+	ms.data.subscriptions[key][ms.tenant] = sub
+	return true
+}
 
-	return loaded
+func (ms *MemoryStorage) GetSubscription(address string) (models.Subscription, bool) {
+	ms.data.subsMu.RLock()
+	defer ms.data.subsMu.RUnlock()
+	sub, ok := ms.data.subscriptions[ms.key(address)][ms.tenant]
+	return sub, ok
 }
 
-func (ms *MemoryStorage) IsSubscribed(address string) bool {
-	address = strings.ToLower(address)
-	_, ok := ms.subscribedAddresses.Load(address)
+// GetSubscriptions returns the subscriptions of the tenant on the chain,
+// oldest first.
+func (ms *MemoryStorage) GetSubscriptions() []models.Subscription {
+	ms.data.subsMu.RLock()
+	var subs []models.Subscription
+	for key, tenants := range ms.data.subscriptions {
+		if sub, ok := tenants[ms.tenant]; ok && strings.HasPrefix(key, ms.keyPrefix) {
+			subs = append(subs, sub)
+		}
+	}
+	ms.data.subsMu.RUnlock()
+	sort.Slice(subs, func(i, j int) bool {
+		if !subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+		}
+		return subs[i].Address < subs[j].Address
+	})
+	return subs
+}
+
+// Unsubscribe ends the tenant's subscription to address. What was recorded
+// for the address is kept for other tenants and later subscriptions.
+func (ms *MemoryStorage) Unsubscribe(address string) bool {
+	key := ms.key(address)
+	ms.data.subsMu.Lock()
+	defer ms.data.subsMu.Unlock()
+	tenants := ms.data.subscriptions[key]
+	if _, ok := tenants[ms.tenant]; !ok {
+		return false
+	}
+	delete(tenants, ms.tenant)
+	if len(tenants) == 0 {
+		delete(ms.data.subscriptions, key)
+	}
+	count := ms.tenantKey()
+	ms.data.subscriptionCounts[count]--
+	if ms.data.subscriptionCounts[count] == 0 {
+		delete(ms.data.subscriptionCounts, count)
+	}
+	return true
+}
 
+// IsSubscribed reports whether the tenant is subscribed to address.
+func (ms *MemoryStorage) IsSubscribed(address string) bool {
+	_, ok := ms.GetSubscription(address)
 	return ok
 }
 
-func (ms *MemoryStorage) GetTransactions(address string) []models.Transaction {
-	address = strings.ToLower(address)
+// IsWatched reports whether any tenant is subscribed to address. Ingestion
+// records activity for every watched address.
+func (ms *MemoryStorage) IsWatched(address string) bool {
+	ms.data.subsMu.RLock()
+	defer ms.data.subsMu.RUnlock()
+	return len(ms.data.subscriptions[ms.key(address)]) > 0
+}
+
+// GetWatchedAddresses returns the addresses any tenant is subscribed to.
+func (ms *MemoryStorage) GetWatchedAddresses() []string {
+	ms.data.subsMu.RLock()
+	defer ms.data.subsMu.RUnlock()
+	var addresses []string
+	for key := range ms.data.subscriptions {
+		if address, ok := strings.CutPrefix(key, ms.keyPrefix); ok {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// watchedFrom returns the earliest start block of the subscriptions to
+// address, and false if no tenant is subscribed to it.
+func (ms *MemoryStorage) watchedFrom(address string) (int64, bool) {
+	ms.data.subsMu.RLock()
+	defer ms.data.subsMu.RUnlock()
+	tenants := ms.data.subscriptions[ms.key(address)]
+	if len(tenants) == 0 {
+		return 0, false
+	}
+	first := int64(math.MaxInt64)
+	for _, sub := range tenants {
+		first = min(first, sub.StartBlock)
+	}
+	return first, true
+}
 
-	if txs, ok := ms.transactions.Load(address); ok {
+func (ms *MemoryStorage) GetTransactions(address string) []models.Transaction {
+	if txs, ok := ms.data.transactions.Load(ms.key(address)); ok {
 		return txs.([]models.Transaction)
 	}
 	return nil
 }
 
+// AddTransaction records tx for every subscribed address it touches, see
+// models.Transaction.Addresses. A transaction already recorded for an
+// address is not recorded again.
 func (ms *MemoryStorage) AddTransaction(tx models.Transaction) {
-	ms.addTransactionForAddress(strings.ToLower(tx.From), tx)
-	ms.addTransactionForAddress(strings.ToLower(tx.To), tx)
+	var buf [8]string
+	for _, address := range tx.Addresses(buf[:0]) {
+		ms.addTransactionForAddress(address, tx)
+	}
 }
 
+// addTransactionForAddress records tx for address if a tenant is subscribed
+// to it and tx is not from a block before every subscription's start block.
 func (ms *MemoryStorage) addTransactionForAddress(address string, tx models.Transaction) {
-	if ms.IsSubscribed(address) {
-		ms.mu.Lock()
-		defer ms.mu.Unlock()
+	startBlock, ok := ms.watchedFrom(address)
+	if !ok {
+		return
+	}
+	if block, err := utils.HexToInt(tx.BlockNumber); err == nil && block < startBlock {
+		return
+	}
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+
+	key := ms.key(address)
+	if _, ok := ms.data.storedKeys[transactionKey(key, tx)]; ok {
+		return
+	}
+	ms.data.storedKeys[transactionKey(key, tx)] = struct{}{}
+	var txs []models.Transaction
+	if existingTxs, ok := ms.data.transactions.Load(key); ok {
+		txs = existingTxs.([]models.Transaction)
+	}
+	txs = append(txs, tx)
+	ms.data.transactions.Store(key, txs)
+}
+
+// transactionKey identifies tx among those recorded under an address key.
+func transactionKey(key string, tx models.Transaction) string {
+	return "tx/" + key + "/" + strings.ToLower(tx.Hash)
+}
+
+// logKey identifies log among those of the subscription with key.
+func logKey(key string, log models.Log) string {
+	return "log/" + key + "/" + strings.ToLower(log.BlockHash) + "/" + strings.ToLower(log.TransactionHash) + "/" + strings.ToLower(log.LogIndex)
+}
+
+// ledgerKey identifies entry in the ledger with key. A block has at most one
+// entry of each kind and direction per address and transaction.
+func ledgerKey(key string, entry models.LedgerEntry) string {
+	return fmt.Sprintf("ledger/%s/%d/%s/%s/%s", key, entry.BlockNumber, strings.ToLower(entry.TransactionHash), entry.Kind, entry.Direction)
+}
+
+func (ms *MemoryStorage) AddRejectedBlock(block models.RejectedBlock) {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	block.ChainID = ms.chainID
+	ms.data.rejectedBlocks[ms.chainID] = append(ms.data.rejectedBlocks[ms.chainID], block)
+}
+
+func (ms *MemoryStorage) GetRejectedBlocks() []models.RejectedBlock {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	return append([]models.RejectedBlock(nil), ms.data.rejectedBlocks[ms.chainID]...)
+}
+
+// RemoveBlock drops what was recorded from block number of the chain, which
+// a reorg orphaned: its transactions, logs, ledger entries and the alerts
+// its transactions raised. Opening balances are not part of a block and
+// stay.
+func (ms *MemoryStorage) RemoveBlock(number int64) {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	inBlock := func(hexNumber string) bool {
+		block, err := utils.HexToInt(hexNumber)
+		return err == nil && block == number
+	}
+
+	ms.data.transactions.Range(func(key, value interface{}) bool {
+		if !strings.HasPrefix(key.(string), ms.keyPrefix) {
+			return true
+		}
+		var kept []models.Transaction
+		for _, tx := range value.([]models.Transaction) {
+			if inBlock(tx.BlockNumber) {
+				delete(ms.data.storedKeys, transactionKey(key.(string), tx))
+			} else {
+				kept = append(kept, tx)
+			}
+		}
+		ms.data.transactions.Store(key, kept)
+		return true
+	})
+	for key, logs := range ms.data.subscriptionLogs {
+		if !strings.HasPrefix(key, ms.keyPrefix) {
+			continue
+		}
+		kept := logs[:0]
+		for _, log := range logs {
+			if inBlock(log.BlockNumber) {
+				delete(ms.data.storedKeys, logKey(key, log))
+			} else {
+				kept = append(kept, log)
+			}
+		}
+		ms.data.subscriptionLogs[key] = kept
+	}
+	for key, entries := range ms.data.ledgers {
+		if !strings.HasPrefix(key, ms.keyPrefix) {
+			continue
+		}
+		kept := entries[:0]
+		for _, entry := range entries {
+			if entry.BlockNumber != number || entry.Kind == models.LedgerOpening {
+				kept = append(kept, entry)
+			} else {
+				delete(ms.data.storedKeys, ledgerKey(key, entry))
+			}
+		}
+		ms.data.ledgers[key] = kept
+	}
+
+	var alerts []models.Alert
+	for _, alert := range ms.data.alerts[ms.chainID] {
+		if alert.TransactionHash == "" || alert.BlockNumber != number {
+			alerts = append(alerts, alert)
+		}
+	}
+	ms.data.alerts[ms.chainID] = alerts
+	for key := range ms.data.activeAlerts {
+		if strings.HasPrefix(key, ms.keyPrefix) {
+			delete(ms.data.activeAlerts, key)
+		}
+	}
+	for i, alert := range alerts {
+		if alert.ResolvedAt == nil {
+			ms.data.activeAlerts[ms.keyPrefix+alert.DedupKey] = i
+		}
+	}
+}
+
+// SetContractABI stores the ABI JSON of the contract at address, replacing
+// any earlier one.
+func (ms *MemoryStorage) SetContractABI(address string, abi []byte) {
+	ms.data.contractABIs.Store(ms.key(address), append([]byte(nil), abi...))
+}
+
+func (ms *MemoryStorage) GetContractABI(address string) ([]byte, bool) {
+	abi, ok := ms.data.contractABIs.Load(ms.key(address))
+	if !ok {
+		return nil, false
+	}
+	return abi.([]byte), true
+}
+
+func (ms *MemoryStorage) DeleteContractABI(address string) bool {
+	_, loaded := ms.data.contractABIs.LoadAndDelete(ms.key(address))
+	return loaded
+}
+
+// SetToken caches the metadata of the token at token.Address.
+func (ms *MemoryStorage) SetToken(token models.Token) {
+	ms.data.tokens.Store(ms.key(token.Address), token)
+}
+
+func (ms *MemoryStorage) GetToken(address string) (models.Token, bool) {
+	token, ok := ms.data.tokens.Load(ms.key(address))
+	if !ok {
+		return models.Token{}, false
+	}
+	return token.(models.Token), true
+}
+
+// GetTokens returns the cached tokens of the chain ordered by address.
+func (ms *MemoryStorage) GetTokens() []models.Token {
+	var tokens []models.Token
+	ms.data.tokens.Range(func(key, value interface{}) bool {
+		if strings.HasPrefix(key.(string), ms.keyPrefix) {
+			tokens = append(tokens, value.(models.Token))
+		}
+		return true
+	})
+	sort.Slice(tokens, func(i, j int) bool {
+		return strings.ToLower(tokens[i].Address) < strings.ToLower(tokens[j].Address)
+	})
+	return tokens
+}
+
+// AddLedgerEntry appends an entry to the ledger of entry.Address unless it
+// already has an entry of the same kind and direction for the same block and
+// transaction. Entries are kept in the order they were added, which need
+// not be block order.
+func (ms *MemoryStorage) AddLedgerEntry(entry models.LedgerEntry) {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	key := ms.key(entry.Address)
+	if _, ok := ms.data.storedKeys[ledgerKey(key, entry)]; ok {
+		return
+	}
+	ms.data.storedKeys[ledgerKey(key, entry)] = struct{}{}
+	ms.data.ledgers[key] = append(ms.data.ledgers[key], entry)
+}
+
+func (ms *MemoryStorage) GetLedgerEntries(address string) []models.LedgerEntry {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	return append([]models.LedgerEntry(nil), ms.data.ledgers[ms.key(address)]...)
+}
+
+// snapshotKey is the key of the balance series of address in token, ETH
+// when token is empty.
+func (ms *MemoryStorage) snapshotKey(address, token string) string {
+	return ms.key(address) + "/" + strings.ToLower(token)
+}
+
+// AddBalanceSnapshot appends a snapshot to the series of its address and
+// token. Snapshots are expected in block order.
+func (ms *MemoryStorage) AddBalanceSnapshot(snapshot models.BalanceSnapshot) {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	key := ms.snapshotKey(snapshot.Address, snapshot.Token)
+	ms.data.snapshots[key] = append(ms.data.snapshots[key], snapshot)
+}
+
+// GetBalanceSnapshots returns the balance series of address in token, or in
+// ETH when token is empty.
+func (ms *MemoryStorage) GetBalanceSnapshots(address, token string) []models.BalanceSnapshot {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	return append([]models.BalanceSnapshot(nil), ms.data.snapshots[ms.snapshotKey(address, token)]...)
+}
+
+func (ms *MemoryStorage) AddBalanceDrift(drift models.BalanceDrift) {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	ms.data.drifts[ms.chainID] = append(ms.data.drifts[ms.chainID], drift)
+}
 
-		var txs []models.Transaction
-		if existingTxs, ok := ms.transactions.Load(address); ok {
-			txs = existingTxs.([]models.Transaction)
+func (ms *MemoryStorage) GetBalanceDrifts() []models.BalanceDrift {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	return append([]models.BalanceDrift(nil), ms.data.drifts[ms.chainID]...)
+}
+
+// SetAlertRule stores rule, replacing the rule with the same ID.
+func (ms *MemoryStorage) SetAlertRule(rule models.AlertRule) {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	ms.data.alertRules[ms.keyPrefix+rule.ID] = rule
+}
+
+func (ms *MemoryStorage) GetAlertRule(id string) (models.AlertRule, bool) {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	rule, ok := ms.data.alertRules[ms.keyPrefix+id]
+	return rule, ok
+}
+
+// GetAlertRules returns the alert rules of the chain, oldest first.
+func (ms *MemoryStorage) GetAlertRules() []models.AlertRule {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	var rules []models.AlertRule
+	for key, rule := range ms.data.alertRules {
+		if strings.HasPrefix(key, ms.keyPrefix) {
+			rules = append(rules, rule)
 		}
-		txs = append(txs, tx)
-		ms.transactions.Store(address, txs)
 	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].CreatedAt.Before(rules[j].CreatedAt) })
+	return rules
+}
+
+// DeleteAlertRule deletes a rule. Alerts it raised are kept.
+func (ms *MemoryStorage) DeleteAlertRule(id string) bool {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	key := ms.keyPrefix + id
+	_, ok := ms.data.alertRules[key]
+	delete(ms.data.alertRules, key)
+	return ok
+}
+
+// AddAlert appends alert unless an unresolved alert with the same dedup key
+// exists, and reports whether it was added.
+func (ms *MemoryStorage) AddAlert(alert models.Alert) bool {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	key := ms.keyPrefix + alert.DedupKey
+	if _, ok := ms.data.activeAlerts[key]; ok {
+		return false
+	}
+	ms.data.activeAlerts[key] = len(ms.data.alerts[ms.chainID])
+	ms.data.alerts[ms.chainID] = append(ms.data.alerts[ms.chainID], alert)
+	return true
+}
+
+// ResolveAlert marks the unresolved alert with dedupKey as resolved at the
+// given time, so that the key can raise a new alert.
+func (ms *MemoryStorage) ResolveAlert(dedupKey string, at time.Time) bool {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	key := ms.keyPrefix + dedupKey
+	i, ok := ms.data.activeAlerts[key]
+	if !ok {
+		return false
+	}
+	delete(ms.data.activeAlerts, key)
+	ms.data.alerts[ms.chainID][i].ResolvedAt = &at
+	return true
+}
+
+// GetAlerts returns the alerts of the chain in the order they were raised.
+func (ms *MemoryStorage) GetAlerts() []models.Alert {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	return append([]models.Alert(nil), ms.data.alerts[ms.chainID]...)
+}
+
+func (ms *MemoryStorage) AddLogSubscription(sub models.LogSubscription) {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	ms.data.logSubscriptions[ms.keyPrefix+sub.ID] = sub
+}
+
+func (ms *MemoryStorage) GetLogSubscription(id string) (models.LogSubscription, bool) {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	sub, ok := ms.data.logSubscriptions[ms.keyPrefix+id]
+	return sub, ok
+}
+
+// GetLogSubscriptions returns the log subscriptions of the chain, oldest
+// first.
+func (ms *MemoryStorage) GetLogSubscriptions() []models.LogSubscription {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	var subs []models.LogSubscription
+	for key, sub := range ms.data.logSubscriptions {
+		if strings.HasPrefix(key, ms.keyPrefix) {
+			subs = append(subs, sub)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].CreatedAt.Before(subs[j].CreatedAt) })
+	return subs
+}
+
+// RemoveLogSubscription deletes a subscription together with its logs.
+func (ms *MemoryStorage) RemoveLogSubscription(id string) bool {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	key := ms.keyPrefix + id
+	_, ok := ms.data.logSubscriptions[key]
+	for _, log := range ms.data.subscriptionLogs[key] {
+		delete(ms.data.storedKeys, logKey(key, log))
+	}
+	delete(ms.data.logSubscriptions, key)
+	delete(ms.data.subscriptionLogs, key)
+	return ok
+}
+
+// AddSubscriptionLog records log for the subscription with id, unless it
+// is unknown or already has the log.
+func (ms *MemoryStorage) AddSubscriptionLog(id string, log models.Log) {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	key := ms.keyPrefix + id
+	if _, ok := ms.data.logSubscriptions[key]; !ok {
+		return
+	}
+	if _, ok := ms.data.storedKeys[logKey(key, log)]; ok {
+		return
+	}
+	ms.data.storedKeys[logKey(key, log)] = struct{}{}
+	ms.data.subscriptionLogs[key] = append(ms.data.subscriptionLogs[key], log)
+}
+
+func (ms *MemoryStorage) GetSubscriptionLogs(id string) []models.Log {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	return append([]models.Log(nil), ms.data.subscriptionLogs[ms.keyPrefix+id]...)
+}
+
+// AddAPIKey stores key. API keys are not scoped to a chain or tenant.
+func (ms *MemoryStorage) AddAPIKey(key models.APIKey) {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	ms.data.apiKeys[key.ID] = key
+	ms.data.apiKeyHashes[key.Hash] = key.ID
+}
+
+// TouchAPIKey records that a key was used at.
+func (ms *MemoryStorage) TouchAPIKey(id string, at time.Time) {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	if key, ok := ms.data.apiKeys[id]; ok {
+		key.LastUsedAt = &at
+		ms.data.apiKeys[id] = key
+	}
+}
+
+// RevokeAPIKey marks a key revoked at, unless it is already, and returns
+// it. It returns false if there is no key with the ID.
+func (ms *MemoryStorage) RevokeAPIKey(id string, at time.Time) (models.APIKey, bool) {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	key, ok := ms.data.apiKeys[id]
+	if !ok {
+		return models.APIKey{}, false
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &at
+		ms.data.apiKeys[id] = key
+	}
+	return key, true
+}
+
+func (ms *MemoryStorage) GetAPIKey(id string) (models.APIKey, bool) {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	key, ok := ms.data.apiKeys[id]
+	return key, ok
+}
+
+// GetAPIKeyByHash returns the key whose secret hashes to hash.
+func (ms *MemoryStorage) GetAPIKeyByHash(hash string) (models.APIKey, bool) {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	id, ok := ms.data.apiKeyHashes[hash]
+	if !ok {
+		return models.APIKey{}, false
+	}
+	return ms.data.apiKeys[id], true
+}
+
+// GetAPIKeys returns every key, revoked ones included, oldest first.
+func (ms *MemoryStorage) GetAPIKeys() []models.APIKey {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	keys := make([]models.APIKey, 0, len(ms.data.apiKeys))
+	for _, key := range ms.data.apiKeys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys
+}
+
+// AppendAuditRecord numbers record, chains it to the last record and stores
+// it. Audit records are not scoped to a chain or tenant and are never
+// changed or removed.
+func (ms *MemoryStorage) AppendAuditRecord(record models.AuditRecord) models.AuditRecord {
+	ms.data.mu.Lock()
+	defer ms.data.mu.Unlock()
+	record.Sequence = int64(len(ms.data.auditRecords)) + 1
+	record.PrevHash = models.AuditGenesisHash
+	if n := len(ms.data.auditRecords); n > 0 {
+		record.PrevHash = ms.data.auditRecords[n-1].Hash
+	}
+	record.Hash = record.ComputeHash()
+	ms.data.auditRecords = append(ms.data.auditRecords, record)
+	return record
+}
+
+// GetAuditRecords returns the audit records after sequence number after,
+// oldest first.
+func (ms *MemoryStorage) GetAuditRecords(after int64) []models.AuditRecord {
+	ms.data.mu.RLock()
+	defer ms.data.mu.RUnlock()
+	if after < 0 {
+		after = 0
+	}
+	if after >= int64(len(ms.data.auditRecords)) {
+		return nil
+	}
+	return append([]models.AuditRecord(nil), ms.data.auditRecords[after:]...)
 }
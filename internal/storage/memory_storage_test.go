@@ -1,10 +1,12 @@
 package storage
 
 import (
+	"errors"
 	"eth-parser/pkg/models"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestMemoryStorage(t *testing.T) {
@@ -126,6 +128,11 @@ func TestMemoryStorage(t *testing.T) {
 		if !reflect.DeepEqual(fromTxs[0], tx) || !reflect.DeepEqual(toTxs[0], tx) {
 			t.Error("Stored transaction does not match the original")
 		}
+
+		ms.AddTransaction(tx)
+		if len(ms.GetTransactions(from)) != 1 || len(ms.GetTransactions(to)) != 1 {
+			t.Error("A transaction added again should not be recorded twice")
+		}
 	})
 
 	t.Run("GetTransactions", func(t *testing.T) {
@@ -164,4 +171,296 @@ func TestMemoryStorage(t *testing.T) {
 			t.Error("Transaction retrieval should be case-insensitive")
 		}
 	})
+
+	t.Run("ChainScoping", func(t *testing.T) {
+		mainnet := NewMemoryStorage()
+		base := mainnet.ForChain(8453)
+		address := "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+
+		base.Subscribe(address)
+		if mainnet.IsSubscribed(address) {
+			t.Error("Subscription leaked into another chain")
+		}
+		if list := base.GetSubscribeList(); len(list) != 1 || !strings.EqualFold(list[0], address) {
+			t.Errorf("GetSubscribeList() = %v, want [%s]", list, address)
+		}
+
+		mainnet.Subscribe(address)
+		base.AddTransaction(models.Transaction{From: address, To: "0xdef", Value: "100"})
+		if txs := mainnet.GetTransactions(address); len(txs) != 0 {
+			t.Errorf("Expected no transactions on mainnet, got %d", len(txs))
+		}
+		if txs := mainnet.ForChain(8453).GetTransactions(address); len(txs) != 1 {
+			t.Errorf("Expected 1 transaction on a second view of the chain, got %d", len(txs))
+		}
+
+		base.SetCurrentBlock(42)
+		if mainnet.GetCurrentBlock() != 0 || base.GetCurrentBlock() != 42 {
+			t.Error("Current block is not scoped to the chain")
+		}
+
+		base.AddRejectedBlock(models.RejectedBlock{Number: 7})
+		if len(mainnet.GetRejectedBlocks()) != 0 {
+			t.Error("Rejected block leaked into another chain")
+		}
+		if rejected := base.GetRejectedBlocks(); len(rejected) != 1 || rejected[0].ChainID != 8453 {
+			t.Errorf("GetRejectedBlocks() = %+v", rejected)
+		}
+	})
+
+	t.Run("ContractABI", func(t *testing.T) {
+		ms := NewMemoryStorage()
+		address := "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+		ms.SetContractABI(address, []byte(`[]`))
+
+		if abi, ok := ms.GetContractABI(strings.ToLower(address)); !ok || string(abi) != `[]` {
+			t.Errorf("GetContractABI() = %s, %v", abi, ok)
+		}
+		if _, ok := ms.ForChain(8453).GetContractABI(address); ok {
+			t.Error("ABI leaked into another chain")
+		}
+		if !ms.DeleteContractABI(address) || ms.DeleteContractABI(address) {
+			t.Error("DeleteContractABI should succeed exactly once")
+		}
+	})
+
+	t.Run("LogSubscriptions", func(t *testing.T) {
+		ms := NewMemoryStorage()
+		ms.AddLogSubscription(models.LogSubscription{ID: "a"})
+		ms.AddSubscriptionLog("a", models.Log{TransactionHash: "0x1", LogIndex: "0x0"})
+		ms.AddSubscriptionLog("a", models.Log{TransactionHash: "0x1", LogIndex: "0x0"})
+		ms.AddSubscriptionLog("missing", models.Log{LogIndex: "0x1"})
+
+		if logs := ms.GetSubscriptionLogs("a"); len(logs) != 1 {
+			t.Errorf("GetSubscriptionLogs() = %+v", logs)
+		}
+		if len(ms.GetSubscriptionLogs("missing")) != 0 {
+			t.Error("Stored a log for an unknown subscription")
+		}
+		if len(ms.ForChain(8453).GetLogSubscriptions()) != 0 {
+			t.Error("Log subscription leaked into another chain")
+		}
+		if !ms.RemoveLogSubscription("a") || len(ms.GetSubscriptionLogs("a")) != 0 {
+			t.Error("RemoveLogSubscription should drop the subscription and its logs")
+		}
+	})
+
+	t.Run("RemoveBlock", func(t *testing.T) {
+		ms := NewMemoryStorage()
+		address := "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+		ms.Subscribe(address)
+		ms.AddTransaction(models.Transaction{Hash: "0x1", From: address, BlockNumber: "0x1"})
+		ms.AddTransaction(models.Transaction{Hash: "0x2", From: address, BlockNumber: "0x2"})
+		ms.AddLedgerEntry(models.LedgerEntry{Address: address, BlockNumber: 2, Kind: models.LedgerOpening})
+		ms.AddLedgerEntry(models.LedgerEntry{Address: address, BlockNumber: 2, Kind: models.LedgerFee})
+		ms.AddLogSubscription(models.LogSubscription{ID: "a"})
+		ms.AddSubscriptionLog("a", models.Log{BlockNumber: "0x2", LogIndex: "0x0"})
+		ms.AddAlert(models.Alert{DedupKey: "r:0x2", TransactionHash: "0x2", BlockNumber: 2})
+		ms.AddAlert(models.Alert{DedupKey: "r:balance", BlockNumber: 2})
+		ms.ForChain(8453).Subscribe(address)
+		ms.ForChain(8453).AddTransaction(models.Transaction{Hash: "0x3", From: address, BlockNumber: "0x2"})
+
+		ms.RemoveBlock(2)
+		if txs := ms.GetTransactions(address); len(txs) != 1 || txs[0].Hash != "0x1" {
+			t.Errorf("GetTransactions() = %+v", txs)
+		}
+		if entries := ms.GetLedgerEntries(address); len(entries) != 1 || entries[0].Kind != models.LedgerOpening {
+			t.Errorf("GetLedgerEntries() = %+v", entries)
+		}
+		if logs := ms.GetSubscriptionLogs("a"); len(logs) != 0 {
+			t.Errorf("GetSubscriptionLogs() = %+v", logs)
+		}
+		if alerts := ms.GetAlerts(); len(alerts) != 1 || alerts[0].DedupKey != "r:balance" {
+			t.Errorf("GetAlerts() = %+v", alerts)
+		}
+		if !ms.ResolveAlert("r:balance", time.Now()) || !ms.AddAlert(models.Alert{DedupKey: "r:0x2"}) {
+			t.Error("Alerts of the removed block should no longer be active")
+		}
+		if len(ms.ForChain(8453).GetTransactions(address)) != 1 {
+			t.Error("RemoveBlock reached into another chain")
+		}
+	})
+
+	t.Run("Tokens", func(t *testing.T) {
+		ms := NewMemoryStorage()
+		ms.SetToken(models.Token{Address: "0xdAC17F958D2ee523a2206206994597C13D831ec7", Symbol: "USDT"})
+		ms.SetToken(models.Token{Address: "0x6B175474E89094C44Da98b954EedeAC495271d0F", Symbol: "DAI"})
+
+		if token, ok := ms.GetToken("0xdac17f958d2ee523a2206206994597c13d831ec7"); !ok || token.Symbol != "USDT" {
+			t.Errorf("GetToken() = %+v, %v", token, ok)
+		}
+		if tokens := ms.GetTokens(); len(tokens) != 2 || tokens[0].Symbol != "DAI" {
+			t.Errorf("GetTokens() = %+v", tokens)
+		}
+		if _, ok := ms.ForChain(8453).GetToken("0xdAC17F958D2ee523a2206206994597C13D831ec7"); ok {
+			t.Error("Token leaked into another chain")
+		}
+	})
+
+	t.Run("Ledger", func(t *testing.T) {
+		ms := NewMemoryStorage()
+		address := "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+		ms.AddLedgerEntry(models.LedgerEntry{Address: address, BlockNumber: 2, Kind: models.LedgerFee})
+		ms.AddLedgerEntry(models.LedgerEntry{Address: address, BlockNumber: 1, Kind: models.LedgerTransfer})
+		ms.AddLedgerEntry(models.LedgerEntry{Address: strings.ToLower(address), BlockNumber: 2, Kind: models.LedgerFee})
+
+		entries := ms.GetLedgerEntries(strings.ToLower(address))
+		if len(entries) != 2 || entries[0].Kind != models.LedgerFee {
+			t.Errorf("GetLedgerEntries() = %+v", entries)
+		}
+		if len(ms.ForChain(8453).GetLedgerEntries(address)) != 0 {
+			t.Error("Ledger entry leaked into another chain")
+		}
+	})
+
+	t.Run("BalanceSnapshots", func(t *testing.T) {
+		ms := NewMemoryStorage()
+		address := "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+		token := "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+		ms.AddBalanceSnapshot(models.BalanceSnapshot{Address: address, BlockNumber: 10, Balance: "1"})
+		ms.AddBalanceSnapshot(models.BalanceSnapshot{Address: address, Token: token, BlockNumber: 10, Balance: "2"})
+		ms.AddBalanceSnapshot(models.BalanceSnapshot{Address: address, BlockNumber: 20, Balance: "3"})
+
+		if series := ms.GetBalanceSnapshots(strings.ToLower(address), ""); len(series) != 2 || series[1].Balance != "3" {
+			t.Errorf("ETH series = %+v", series)
+		}
+		if series := ms.GetBalanceSnapshots(address, strings.ToLower(token)); len(series) != 1 || series[0].Balance != "2" {
+			t.Errorf("token series = %+v", series)
+		}
+		ms.AddBalanceDrift(models.BalanceDrift{Address: address})
+		if len(ms.GetBalanceDrifts()) != 1 || len(ms.ForChain(8453).GetBalanceDrifts()) != 0 {
+			t.Error("drifts should be scoped to their chain")
+		}
+	})
+
+	t.Run("Alerts", func(t *testing.T) {
+		ms := NewMemoryStorage()
+		ms.SetAlertRule(models.AlertRule{ID: "r1", Type: models.RuleFailedTransaction})
+		if _, ok := ms.ForChain(8453).GetAlertRule("r1"); ok {
+			t.Error("rule leaked into another chain")
+		}
+
+		alert := models.Alert{RuleID: "r1", DedupKey: "r1:0xabc"}
+		if !ms.AddAlert(alert) {
+			t.Fatal("first alert should be added")
+		}
+		if ms.AddAlert(alert) {
+			t.Error("alert with an active dedup key should be suppressed")
+		}
+		if !ms.ForChain(8453).AddAlert(alert) {
+			t.Error("dedup keys should be scoped to their chain")
+		}
+		if !ms.ResolveAlert("r1:0xabc", time.Now()) || ms.ResolveAlert("r1:0xabc", time.Now()) {
+			t.Error("an active alert should resolve exactly once")
+		}
+		if !ms.AddAlert(alert) {
+			t.Error("a resolved dedup key should raise again")
+		}
+		alerts := ms.GetAlerts()
+		if len(alerts) != 2 || alerts[0].ResolvedAt == nil || alerts[1].ResolvedAt != nil {
+			t.Errorf("alerts = %+v", alerts)
+		}
+
+		if !ms.DeleteAlertRule("r1") || ms.DeleteAlertRule("r1") || len(ms.GetAlertRules()) != 0 {
+			t.Error("rule should be deleted once")
+		}
+	})
+
+	t.Run("SubscriptionMetadata", func(t *testing.T) {
+		ms := NewMemoryStorage()
+		address := "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+		sub := models.Subscription{Address: address, Label: "treasury", Tags: []string{"treasury"}, StartBlock: 10}
+		if added, _ := ms.AddSubscription(sub, 0); !added {
+			t.Fatal("subscription should be added")
+		}
+		if added, _ := ms.AddSubscription(sub, 0); added {
+			t.Fatal("subscription should be added once")
+		}
+		stored, ok := ms.GetSubscription(strings.ToLower(address))
+		if !ok || stored.Address != strings.ToLower(address) || stored.Label != "treasury" {
+			t.Errorf("GetSubscription = %+v, %v", stored, ok)
+		}
+
+		ms.AddTransaction(models.Transaction{Hash: "0x01", BlockNumber: "0x9", From: address})
+		ms.AddTransaction(models.Transaction{Hash: "0x02", BlockNumber: "0xa", From: address})
+		if txs := ms.GetTransactions(address); len(txs) != 1 || txs[0].Hash != "0x02" {
+			t.Errorf("transactions before the start block should be skipped, got %+v", txs)
+		}
+
+		stored.Notes = "cold storage"
+		if !ms.UpdateSubscription(stored) {
+			t.Error("update of a subscribed address failed")
+		}
+		if subs := ms.GetSubscriptions(); len(subs) != 1 || subs[0].Notes != "cold storage" {
+			t.Errorf("GetSubscriptions = %+v", subs)
+		}
+		ms.Unsubscribe(address)
+		if ms.UpdateSubscription(stored) || ms.IsSubscribed(address) {
+			t.Error("update should not resubscribe an address")
+		}
+	})
+
+	t.Run("Tenants", func(t *testing.T) {
+		ms := NewMemoryStorage()
+		teamA := ms.ForTenant("team-a")
+		teamB := ms.ForTenant("team-b")
+		address := "0x742d35Cc6634C0532925a3b844Bc454e4438f44e"
+		other := "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+
+		teamA.AddSubscription(models.Subscription{Address: address, StartBlock: 5}, 0)
+		teamB.AddSubscription(models.Subscription{Address: address, Label: "b", StartBlock: 10}, 0)
+		if ms.IsSubscribed(address) || !teamA.IsSubscribed(address) {
+			t.Error("subscriptions should be scoped to their tenant")
+		}
+		if !ms.IsWatched(address) || len(ms.GetWatchedAddresses()) != 1 {
+			t.Error("an address subscribed by any tenant should be watched")
+		}
+		if sub, _ := teamA.GetSubscription(address); sub.Label != "" {
+			t.Errorf("tenants should keep their own metadata, got %+v", sub)
+		}
+
+		// Stored from the earliest start block of any tenant.
+		ms.AddTransaction(models.Transaction{Hash: "0x01", BlockNumber: "0x4", From: address})
+		ms.AddTransaction(models.Transaction{Hash: "0x02", BlockNumber: "0x6", From: address})
+		if txs := ms.GetTransactions(address); len(txs) != 1 || txs[0].Hash != "0x02" {
+			t.Errorf("transactions = %+v", txs)
+		}
+
+		if _, err := teamA.AddSubscription(models.Subscription{Address: other}, 1); !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("subscription over quota: %v", err)
+		}
+		if added, err := teamB.AddSubscription(models.Subscription{Address: address}, 1); added || err != nil {
+			t.Errorf("an existing subscription should not count against the quota: %v, %v", added, err)
+		}
+
+		teamA.Unsubscribe(address)
+		if !ms.IsWatched(address) || !teamB.IsSubscribed(address) {
+			t.Error("unsubscribing one tenant should not affect another")
+		}
+		if added, err := teamA.AddSubscription(models.Subscription{Address: other}, 1); !added || err != nil {
+			t.Errorf("unsubscribing should free the quota: %v, %v", added, err)
+		}
+		if added, err := teamA.(*MemoryStorage).ForChain(2).AddSubscription(models.Subscription{Address: other}, 1); !added || err != nil {
+			t.Errorf("quotas should be counted per chain: %v, %v", added, err)
+		}
+		teamB.Unsubscribe(address)
+		if ms.IsWatched(address) {
+			t.Error("an address no tenant subscribes to should not be watched")
+		}
+	})
+
+	t.Run("AuditRecords", func(t *testing.T) {
+		ms := NewMemoryStorage()
+		first := ms.AppendAuditRecord(models.AuditRecord{Action: models.AuditSubscribe, Target: "0x01"})
+		second := ms.ForChain(8453).AppendAuditRecord(models.AuditRecord{Action: models.AuditUnsubscribe, Target: "0x01"})
+		if first.Sequence != 1 || first.PrevHash != models.AuditGenesisHash || second.Sequence != 2 || second.PrevHash != first.Hash {
+			t.Errorf("records = %+v, %+v", first, second)
+		}
+		if records := ms.GetAuditRecords(1); len(records) != 1 || records[0].Hash != second.Hash {
+			t.Errorf("records after 1 = %+v", records)
+		}
+		if err := models.VerifyAuditChain(ms.GetAuditRecords(0), models.AuditGenesisHash); err != nil {
+			t.Error(err)
+		}
+	})
 }
@@ -0,0 +1,213 @@
+package abi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"eth-parser/pkg/models"
+	"eth-parser/pkg/utils"
+	"fmt"
+	"math/big"
+)
+
+var ErrUnknownMethod = errors.New("no method with this selector")
+
+// Method is a contract function.
+type Method struct {
+	Name            string
+	Inputs          []Argument
+	Outputs         []Argument
+	StateMutability string
+}
+
+// Signature returns the canonical signature, e.g. "transfer(address,uint256)".
+func (m Method) Signature() string {
+	return m.Name + "(" + typeList(m.Inputs) + ")"
+}
+
+// Selector returns the first four bytes of the keccak256 of the signature,
+// which prefix the call data of the method.
+func (m Method) Selector() [4]byte {
+	hash := utils.Keccak256([]byte(m.Signature()))
+	var selector [4]byte
+	copy(selector[:], hash[:4])
+	return selector
+}
+
+// EncodeCall returns the call data of the method with args.
+func (m Method) EncodeCall(args ...interface{}) ([]byte, error) {
+	encoded, err := Encode(m.Inputs, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m.Name, err)
+	}
+	selector := m.Selector()
+	return append(selector[:], encoded...), nil
+}
+
+// Event is a contract event.
+type Event struct {
+	Name      string
+	Inputs    []Argument
+	Anonymous bool
+}
+
+// Signature returns the canonical signature, e.g.
+// "Transfer(address,address,uint256)".
+func (e Event) Signature() string {
+	return e.Name + "(" + typeList(e.Inputs) + ")"
+}
+
+// ID returns the keccak256 of the signature, the first topic of logs of
+// non-anonymous events.
+func (e Event) ID() models.Hash {
+	return utils.Keccak256([]byte(e.Signature()))
+}
+
+// ABI is a parsed contract ABI.
+type ABI struct {
+	Methods []Method
+	Events  []Event
+
+	bySelector map[[4]byte]int
+}
+
+type argumentJSON struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Indexed    bool           `json:"indexed"`
+	Components []argumentJSON `json:"components"`
+}
+
+type entryJSON struct {
+	Type            string         `json:"type"`
+	Name            string         `json:"name"`
+	Inputs          []argumentJSON `json:"inputs"`
+	Outputs         []argumentJSON `json:"outputs"`
+	StateMutability string         `json:"stateMutability"`
+	Anonymous       bool           `json:"anonymous"`
+}
+
+// Parse parses an ABI in the JSON format emitted by solc. Constructors,
+// errors, fallback and receive entries are accepted and skipped.
+func Parse(data []byte) (*ABI, error) {
+	var entries []entryJSON
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse abi: %w", err)
+	}
+	a := &ABI{bySelector: make(map[[4]byte]int)}
+	for _, entry := range entries {
+		switch entry.Type {
+		case "function", "":
+			inputs, err := newArguments(entry.Inputs)
+			if err != nil {
+				return nil, fmt.Errorf("parse abi: function %s: %w", entry.Name, err)
+			}
+			outputs, err := newArguments(entry.Outputs)
+			if err != nil {
+				return nil, fmt.Errorf("parse abi: function %s: %w", entry.Name, err)
+			}
+			method := Method{Name: entry.Name, Inputs: inputs, Outputs: outputs, StateMutability: entry.StateMutability}
+			a.bySelector[method.Selector()] = len(a.Methods)
+			a.Methods = append(a.Methods, method)
+		case "event":
+			inputs, err := newArguments(entry.Inputs)
+			if err != nil {
+				return nil, fmt.Errorf("parse abi: event %s: %w", entry.Name, err)
+			}
+			a.Events = append(a.Events, Event{Name: entry.Name, Inputs: inputs, Anonymous: entry.Anonymous})
+		}
+	}
+	return a, nil
+}
+
+func newArguments(raw []argumentJSON) ([]Argument, error) {
+	args := make([]Argument, 0, len(raw))
+	for _, r := range raw {
+		components, err := newArguments(r.Components)
+		if err != nil {
+			return nil, err
+		}
+		typ, err := NewType(r.Type, components)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, Argument{Name: r.Name, Type: typ, Indexed: r.Indexed})
+	}
+	return args, nil
+}
+
+// MethodBySelector looks a method up by the first four bytes of call data.
+func (a *ABI) MethodBySelector(selector []byte) (Method, bool) {
+	if len(selector) < 4 {
+		return Method{}, false
+	}
+	i, ok := a.bySelector[[4]byte(selector[:4])]
+	if !ok {
+		return Method{}, false
+	}
+	return a.Methods[i], true
+}
+
+// MethodByName returns the first method called name.
+func (a *ABI) MethodByName(name string) (Method, bool) {
+	for _, m := range a.Methods {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Method{}, false
+}
+
+// DecodeCall decodes call data into the method called and its arguments.
+func (a *ABI) DecodeCall(input []byte) (models.DecodedInput, error) {
+	method, ok := a.MethodBySelector(input)
+	if !ok {
+		if len(input) < 4 {
+			return models.DecodedInput{}, fmt.Errorf("%w: input of %d bytes", ErrUnknownMethod, len(input))
+		}
+		return models.DecodedInput{}, fmt.Errorf("%w: 0x%x", ErrUnknownMethod, input[:4])
+	}
+	values, err := Decode(method.Inputs, input[4:])
+	if err != nil {
+		return models.DecodedInput{}, fmt.Errorf("%s: %w", method.Signature(), err)
+	}
+	selector := method.Selector()
+	return models.DecodedInput{
+		Method:    method.Name,
+		Signature: method.Signature(),
+		Selector:  "0x" + hex.EncodeToString(selector[:]),
+		Params:    Params(method.Inputs, values),
+	}, nil
+}
+
+// Params pairs decoded values with their arguments in the JSON friendly form
+// of models.DecodedParam: integers as decimal strings, addresses checksummed,
+// bytes as hex, arrays as lists and tuples as lists of named params.
+func Params(args []Argument, values []interface{}) []models.DecodedParam {
+	params := make([]models.DecodedParam, len(args))
+	for i, arg := range args {
+		params[i] = models.DecodedParam{Name: arg.Name, Type: arg.Type.String(), Value: jsonValue(arg.Type, values[i])}
+	}
+	return params
+}
+
+func jsonValue(t Type, value interface{}) interface{} {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case models.Address:
+		return v.Checksum()
+	case []byte:
+		return "0x" + hex.EncodeToString(v)
+	case []interface{}:
+		if t.Kind == TupleKind {
+			return Params(t.Components, v)
+		}
+		elems := make([]interface{}, len(v))
+		for i, elem := range v {
+			elems[i] = jsonValue(*t.Elem, elem)
+		}
+		return elems
+	}
+	return value
+}
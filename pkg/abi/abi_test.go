@@ -0,0 +1,313 @@
+package abi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"eth-parser/pkg/models"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const erc20ABI = `[
+	{"type":"function","name":"transfer","stateMutability":"nonpayable",
+	 "inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],
+	 "outputs":[{"name":"","type":"bool"}]},
+	{"type":"event","name":"Transfer","anonymous":false,
+	 "inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]},
+	{"type":"constructor","inputs":[]}
+]`
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func mustArgs(t *testing.T, types ...string) []Argument {
+	t.Helper()
+	args := make([]Argument, len(types))
+	for i, typ := range types {
+		parsed, err := NewType(typ, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args[i].Type = parsed
+	}
+	return args
+}
+
+func TestNewType(t *testing.T) {
+	for _, typ := range []string{"uint8", "int256", "address", "bool", "bytes1", "bytes32", "bytes", "string",
+		"uint256[]", "address[3]", "bytes[][2]", "string[2][]"} {
+		parsed, err := NewType(typ, nil)
+		if err != nil {
+			t.Errorf("NewType(%q): %v", typ, err)
+			continue
+		}
+		if parsed.String() != typ {
+			t.Errorf("NewType(%q).String() = %q", typ, parsed.String())
+		}
+	}
+	if parsed, _ := NewType("uint", nil); parsed.String() != "uint256" {
+		t.Errorf("uint is an alias of %s", parsed)
+	}
+	for _, typ := range []string{"uint7", "uint264", "int0", "bytes0", "bytes33", "address[0]", "uint256[", "fixed128x18", "",
+		"string[100000000000]", "uint256[65537]", "uint256[65536][65536]", "uint8[4096][4096][4096][4096][4096]"} {
+		if _, err := NewType(typ, nil); !errors.Is(err, ErrInvalidType) {
+			t.Errorf("NewType(%q) = %v, want ErrInvalidType", typ, err)
+		}
+	}
+
+	tuple, err := NewType("tuple[]", mustArgs(t, "address", "bytes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tuple.String() != "(address,bytes)[]" || !tuple.Elem.IsDynamic() {
+		t.Errorf("tuple[] = %s", tuple)
+	}
+}
+
+// The examples from the Solidity ABI specification.
+func TestSpecificationExamples(t *testing.T) {
+	f := Method{Name: "f", Inputs: mustArgs(t, "uint256", "uint32[]", "bytes10", "bytes")}
+	data, err := f.EncodeCall(
+		big.NewInt(0x123),
+		[]interface{}{big.NewInt(0x456), big.NewInt(0x789)},
+		[]byte("1234567890"),
+		[]byte("Hello, world!"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := mustHex(t, `8be65246
+		0000000000000000000000000000000000000000000000000000000000000123
+		0000000000000000000000000000000000000000000000000000000000000080
+		3132333435363738393000000000000000000000000000000000000000000000
+		00000000000000000000000000000000000000000000000000000000000000e0
+		0000000000000000000000000000000000000000000000000000000000000002
+		0000000000000000000000000000000000000000000000000000000000000456
+		0000000000000000000000000000000000000000000000000000000000000789
+		000000000000000000000000000000000000000000000000000000000000000d
+		48656c6c6f2c20776f726c642100000000000000000000000000000000000000`)
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("f encoding:\n got %x\nwant %x", data, want)
+	}
+	values, err := Decode(f.Inputs, data[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values[0].(*big.Int).Int64() != 0x123 || string(values[3].([]byte)) != "Hello, world!" {
+		t.Errorf("f decoded to %v", values)
+	}
+
+	g := Method{Name: "g", Inputs: mustArgs(t, "uint256[][]", "string[]")}
+	data, err = g.EncodeCall(
+		[]interface{}{
+			[]interface{}{big.NewInt(1), big.NewInt(2)},
+			[]interface{}{big.NewInt(3)},
+		},
+		[]interface{}{"one", "two", "three"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = mustHex(t, `2289b18c
+		0000000000000000000000000000000000000000000000000000000000000040
+		0000000000000000000000000000000000000000000000000000000000000140
+		0000000000000000000000000000000000000000000000000000000000000002
+		0000000000000000000000000000000000000000000000000000000000000040
+		00000000000000000000000000000000000000000000000000000000000000a0
+		0000000000000000000000000000000000000000000000000000000000000002
+		0000000000000000000000000000000000000000000000000000000000000001
+		0000000000000000000000000000000000000000000000000000000000000002
+		0000000000000000000000000000000000000000000000000000000000000001
+		0000000000000000000000000000000000000000000000000000000000000003
+		0000000000000000000000000000000000000000000000000000000000000003
+		0000000000000000000000000000000000000000000000000000000000000060
+		00000000000000000000000000000000000000000000000000000000000000a0
+		00000000000000000000000000000000000000000000000000000000000000e0
+		0000000000000000000000000000000000000000000000000000000000000003
+		6f6e650000000000000000000000000000000000000000000000000000000000
+		0000000000000000000000000000000000000000000000000000000000000003
+		74776f0000000000000000000000000000000000000000000000000000000000
+		0000000000000000000000000000000000000000000000000000000000000005
+		7468726565000000000000000000000000000000000000000000000000000000`)
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("g encoding:\n got %x\nwant %x", data, want)
+	}
+	values, err = Decode(g.Inputs, data[4:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(values[1], []interface{}{"one", "two", "three"}) {
+		t.Errorf("g decoded to %v", values)
+	}
+}
+
+func TestTupleRoundTrip(t *testing.T) {
+	order, err := NewType("tuple", []Argument{
+		{Name: "maker", Type: mustArgs(t, "address")[0].Type},
+		{Name: "amounts", Type: mustArgs(t, "int64[]")[0].Type},
+		{Name: "data", Type: mustArgs(t, "bytes")[0].Type},
+		{Name: "salt", Type: mustArgs(t, "bytes32")[0].Type},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []Argument{{Name: "orders", Type: Type{Kind: SliceKind, Elem: &order}}, {Name: "flag", Type: Type{Kind: BoolKind}}}
+
+	maker, _ := models.HexToAddress("0x742d35cc6634c0532925a3b844bc454e4438f44e")
+	salt := make([]byte, 32)
+	salt[31] = 7
+	values := []interface{}{
+		[]interface{}{
+			[]interface{}{maker, []interface{}{big.NewInt(-1), big.NewInt(1 << 40)}, []byte{0xca, 0xfe}, salt},
+			[]interface{}{maker, []interface{}{}, []byte{}, salt},
+		},
+		true,
+	}
+	data, err := Encode(args, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(args, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(decoded) != fmt.Sprint(values) {
+		t.Errorf("round trip mismatch:\n got %v\nwant %v", decoded, values)
+	}
+	reencoded, err := Encode(args, decoded)
+	if err != nil || !bytes.Equal(reencoded, data) {
+		t.Errorf("re-encoding the decoded values: %v\n got %x\nwant %x", err, reencoded, data)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	word := func(b byte) []byte {
+		w := make([]byte, 32)
+		w[31] = b
+		return w
+	}
+	cat := func(words ...[]byte) []byte {
+		var out []byte
+		for _, w := range words {
+			out = append(out, w...)
+		}
+		return out
+	}
+	huge := make([]byte, 32)
+	huge[0] = 0xff
+
+	for _, tc := range []struct {
+		name  string
+		types []string
+		data  []byte
+	}{
+		{"short static", []string{"uint256", "uint256"}, word(1)},
+		{"uint8 overflow", []string{"uint8"}, cat(make([]byte, 30), []byte{1, 0})},
+		{"bool 2", []string{"bool"}, word(2)},
+		{"dirty address", []string{"address"}, huge},
+		{"offset past end", []string{"bytes"}, word(0x40)},
+		{"length past end", []string{"bytes"}, cat(word(0x20), word(0x40))},
+		{"huge slice length", []string{"uint256[]"}, cat(word(0x20), huge)},
+		{"array longer than data", []string{"string[65536]"}, cat(word(0x20), word(0x20))},
+		{"slice of arrays longer than data", []string{"uint256[8][]"}, cat(word(0x20), word(2), word(1))},
+		{"invalid utf-8", []string{"string"}, cat(word(0x20), word(1), []byte{0xff, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})},
+	} {
+		if _, err := Decode(mustArgs(t, tc.types...), tc.data); !errors.Is(err, ErrInvalidEncoding) {
+			t.Errorf("%s: err = %v, want ErrInvalidEncoding", tc.name, err)
+		}
+	}
+}
+
+func TestSignedIntegers(t *testing.T) {
+	args := mustArgs(t, "int8")
+	for _, v := range []int64{-128, -1, 0, 127} {
+		data, err := Encode(args, []interface{}{v})
+		if err != nil {
+			t.Fatalf("Encode(%d): %v", v, err)
+		}
+		decoded, err := Decode(args, data)
+		if err != nil || decoded[0].(*big.Int).Int64() != v {
+			t.Errorf("int8 %d decoded to %v, %v", v, decoded, err)
+		}
+	}
+	for _, v := range []int64{-129, 128} {
+		if _, err := Encode(args, []interface{}{v}); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("Encode(%d) as int8 = %v, want ErrInvalidValue", v, err)
+		}
+	}
+}
+
+func TestDecodeCall(t *testing.T) {
+	contract, err := Parse([]byte(erc20ABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contract.Methods) != 1 || len(contract.Events) != 1 {
+		t.Fatalf("parsed %d methods and %d events", len(contract.Methods), len(contract.Events))
+	}
+	if id := contract.Events[0].ID().String(); id != "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef" {
+		t.Errorf("Transfer event id = %s", id)
+	}
+
+	input := mustHex(t, `a9059cbb
+		000000000000000000000000742d35cc6634c0532925a3b844bc454e4438f44e
+		00000000000000000000000000000000000000000000000000000000000f4240`)
+	decoded, err := contract.DecodeCall(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"method":"transfer","signature":"transfer(address,uint256)","selector":"0xa9059cbb","params":[` +
+		`{"name":"to","type":"address","value":"0x742d35Cc6634C0532925a3b844Bc454e4438f44e"},` +
+		`{"name":"amount","type":"uint256","value":"1000000"}]}`
+	if string(data) != want {
+		t.Errorf("decoded input:\n got %s\nwant %s", data, want)
+	}
+
+	if _, err := contract.DecodeCall(mustHex(t, "deadbeef")); !errors.Is(err, ErrUnknownMethod) {
+		t.Errorf("unknown selector: err = %v, want ErrUnknownMethod", err)
+	}
+	if _, err := contract.DecodeCall(input[:40]); !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("truncated input: err = %v, want ErrInvalidEncoding", err)
+	}
+	if _, err := Parse([]byte(`[{"type":"function","name":"f","inputs":[{"type":"uint7"}]}]`)); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("invalid type: err = %v, want ErrInvalidType", err)
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	for signature, want := range map[string]string{
+		"transfer(address,uint)":                   "transfer(address,uint256)",
+		"totalSupply()":                            "totalSupply()",
+		"aggregate3((address,bool,bytes)[])":       "aggregate3((address,bool,bytes)[])",
+		"fill(((uint8,bytes32)[2],address),bytes)": "fill(((uint8,bytes32)[2],address),bytes)",
+	} {
+		method, err := NewMethod(signature)
+		if err != nil {
+			t.Errorf("NewMethod(%q): %v", signature, err)
+			continue
+		}
+		if method.Signature() != want {
+			t.Errorf("NewMethod(%q).Signature() = %q, want %q", signature, method.Signature(), want)
+		}
+	}
+	for _, signature := range []string{"", "transfer", "(address)", "1st(address)", "f(address", "f(address,)", "f((address)", "f(address))", "f(uint7)"} {
+		if _, err := NewMethod(signature); err == nil {
+			t.Errorf("NewMethod(%q) accepted an invalid signature", signature)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package abi
+
+import (
+	"errors"
+	"eth-parser/pkg/models"
+	"fmt"
+	"math/big"
+	"unicode/utf8"
+)
+
+var ErrInvalidEncoding = errors.New("invalid abi encoding")
+
+// Decode decodes data as the tuple of args, e.g. the call data of a method
+// after its selector. Values are returned as:
+//
+//	uint<N>, int<N>   *big.Int
+//	address           models.Address
+//	bool              bool
+//	bytes<N>, bytes   []byte
+//	string            string
+//	T[], T[k], tuple  []interface{}
+func Decode(args []Argument, data []byte) ([]interface{}, error) {
+	return decodeTuple(args, data)
+}
+
+func decodeTuple(args []Argument, data []byte) ([]interface{}, error) {
+	values := make([]interface{}, len(args))
+	head := 0
+	for i, arg := range args {
+		value, err := decodeAt(arg.Type, data, head)
+		if err != nil {
+			if arg.Name != "" {
+				return nil, fmt.Errorf("%s: %w", arg.Name, err)
+			}
+			return nil, err
+		}
+		values[i] = value
+		head += arg.Type.headSize()
+	}
+	return values, nil
+}
+
+// decodeAt decodes the value whose head starts at data[head:]. data is the
+// encoding of the enclosing tuple, which offsets are relative to.
+func decodeAt(t Type, data []byte, head int) (interface{}, error) {
+	if !t.IsDynamic() {
+		if head+t.headSize() > len(data) {
+			return nil, fmt.Errorf("%w: %s at %d past end of data", ErrInvalidEncoding, t, head)
+		}
+		return decodeValue(t, data[head:])
+	}
+	offset, err := readLength(data, head)
+	if err != nil {
+		return nil, err
+	}
+	return decodeValue(t, data[offset:])
+}
+
+// readLength reads a word used as an offset or length and checks that it
+// points inside data.
+func readLength(data []byte, at int) (int, error) {
+	if at+32 > len(data) {
+		return 0, fmt.Errorf("%w: word at %d past end of data", ErrInvalidEncoding, at)
+	}
+	v := new(big.Int).SetBytes(data[at : at+32])
+	if !v.IsInt64() || v.Int64() > int64(len(data)) {
+		return 0, fmt.Errorf("%w: offset or length %s out of range", ErrInvalidEncoding, v)
+	}
+	return int(v.Int64()), nil
+}
+
+func decodeValue(t Type, data []byte) (interface{}, error) {
+	switch t.Kind {
+	case UintKind, IntKind:
+		return decodeInteger(t, data[:32])
+	case AddressKind:
+		if !isZero(data[:12]) {
+			return nil, fmt.Errorf("%w: address with dirty high bytes", ErrInvalidEncoding)
+		}
+		var address models.Address
+		copy(address[:], data[12:32])
+		return address, nil
+	case BoolKind:
+		if !isZero(data[:31]) || data[31] > 1 {
+			return nil, fmt.Errorf("%w: bool is neither 0 nor 1", ErrInvalidEncoding)
+		}
+		return data[31] == 1, nil
+	case FixedBytesKind:
+		if !isZero(data[t.Size:32]) {
+			return nil, fmt.Errorf("%w: %s with dirty low bytes", ErrInvalidEncoding, t)
+		}
+		return clone(data[:t.Size]), nil
+	case BytesKind, StringKind:
+		length, err := readLength(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		if 32+length > len(data) {
+			return nil, fmt.Errorf("%w: %s of length %d past end of data", ErrInvalidEncoding, t, length)
+		}
+		b := data[32 : 32+length]
+		if t.Kind == StringKind {
+			if !utf8.Valid(b) {
+				return nil, fmt.Errorf("%w: string is not valid UTF-8", ErrInvalidEncoding)
+			}
+			return string(b), nil
+		}
+		return clone(b), nil
+	case SliceKind:
+		length, err := readLength(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		// Every element takes at least one word, which bounds the
+		// allocation by the size of the input.
+		if length*32 > len(data)-32 {
+			return nil, fmt.Errorf("%w: %s of length %d past end of data", ErrInvalidEncoding, t, length)
+		}
+		return decodeArray(t, *t.Elem, length, data[32:])
+	case ArrayKind:
+		return decodeArray(t, *t.Elem, t.Length, data)
+	case TupleKind:
+		return decodeTuple(t.Components, data)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrInvalidType, t)
+}
+
+func decodeInteger(t Type, word []byte) (*big.Int, error) {
+	v := new(big.Int).SetBytes(word)
+	if t.Kind == IntKind && word[0]&0x80 != 0 {
+		v.Sub(v, twoTo256)
+	}
+	if !fits(t, v) {
+		return nil, fmt.Errorf("%w: %s out of range for %s", ErrInvalidEncoding, v, t)
+	}
+	return v, nil
+}
+
+var twoTo256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// fits reports whether v is in the range of the integer type t.
+func fits(t Type, v *big.Int) bool {
+	if t.Kind == UintKind {
+		return v.Sign() >= 0 && v.BitLen() <= t.Size
+	}
+	if v.Sign() >= 0 {
+		return v.BitLen() < t.Size
+	}
+	// -2^(n-1) is the smallest value; its magnitude minus one has n-1 bits.
+	return new(big.Int).Add(v, big.NewInt(1)).BitLen() < t.Size
+}
+
+// decodeArray decodes the n elements of the array or slice t, which are laid
+// out like the fields of a tuple. The heads of the elements have to fit in
+// data before anything is allocated for them.
+func decodeArray(t, elem Type, n int, data []byte) ([]interface{}, error) {
+	size := elem.headSize()
+	if size > 0 && n > len(data)/size {
+		return nil, fmt.Errorf("%w: %s of length %d past end of data", ErrInvalidEncoding, t, n)
+	}
+	values := make([]interface{}, n)
+	for i := range values {
+		value, err := decodeAt(elem, data, i*size)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func repeat(elem Type, n int) []Argument {
+	args := make([]Argument, n)
+	for i := range args {
+		args[i].Type = elem
+	}
+	return args
+}
+
+// clone copies b; empty values decode to an empty, non-nil slice.
+func clone(b []byte) []byte {
+	out := make([]byte, len(b))
+	copy(out, b)
+	return out
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package abi
+
+import (
+	"errors"
+	"eth-parser/pkg/models"
+	"fmt"
+	"math/big"
+)
+
+var ErrInvalidValue = errors.New("invalid value for abi type")
+
+// Encode encodes values as the tuple of args. Values take the types Decode
+// returns; integers may also be int, int64 or uint64, addresses a hex string,
+// and fixed bytes a byte array of the right size.
+func Encode(args []Argument, values []interface{}) ([]byte, error) {
+	if len(values) != len(args) {
+		return nil, fmt.Errorf("%w: %d values for %d arguments", ErrInvalidValue, len(values), len(args))
+	}
+	return encodeTuple(args, values)
+}
+
+func encodeTuple(args []Argument, values []interface{}) ([]byte, error) {
+	headSize := 0
+	for _, arg := range args {
+		headSize += arg.Type.headSize()
+	}
+	var head, tail []byte
+	for i, arg := range args {
+		encoded, err := encodeValue(arg.Type, values[i])
+		if err != nil {
+			if arg.Name != "" {
+				return nil, fmt.Errorf("%s: %w", arg.Name, err)
+			}
+			return nil, err
+		}
+		if arg.Type.IsDynamic() {
+			head = append(head, word(big.NewInt(int64(headSize+len(tail))))...)
+			tail = append(tail, encoded...)
+		} else {
+			head = append(head, encoded...)
+		}
+	}
+	return append(head, tail...), nil
+}
+
+func encodeValue(t Type, value interface{}) ([]byte, error) {
+	switch t.Kind {
+	case UintKind, IntKind:
+		v, ok := toBig(value)
+		if !ok || !fits(t, v) {
+			return nil, fmt.Errorf("%w: %v as %s", ErrInvalidValue, value, t)
+		}
+		if v.Sign() < 0 {
+			v = new(big.Int).Add(v, twoTo256)
+		}
+		return word(v), nil
+	case AddressKind:
+		var address models.Address
+		switch v := value.(type) {
+		case models.Address:
+			address = v
+		case string:
+			parsed, err := models.HexToAddress(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v as address", ErrInvalidValue, value)
+			}
+			address = parsed
+		default:
+			return nil, fmt.Errorf("%w: %T as address", ErrInvalidValue, value)
+		}
+		out := make([]byte, 32)
+		copy(out[12:], address[:])
+		return out, nil
+	case BoolKind:
+		v, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T as bool", ErrInvalidValue, value)
+		}
+		out := make([]byte, 32)
+		if v {
+			out[31] = 1
+		}
+		return out, nil
+	case FixedBytesKind:
+		b, ok := toBytes(value)
+		if !ok || len(b) != t.Size {
+			return nil, fmt.Errorf("%w: %v as %s", ErrInvalidValue, value, t)
+		}
+		return padRight(b), nil
+	case BytesKind, StringKind:
+		var b []byte
+		switch v := value.(type) {
+		case []byte:
+			b = v
+		case string:
+			b = []byte(v)
+		default:
+			return nil, fmt.Errorf("%w: %T as %s", ErrInvalidValue, value, t)
+		}
+		return append(word(big.NewInt(int64(len(b)))), padRight(b)...), nil
+	case SliceKind, ArrayKind:
+		elems, ok := value.([]interface{})
+		if !ok || (t.Kind == ArrayKind && len(elems) != t.Length) {
+			return nil, fmt.Errorf("%w: %v as %s", ErrInvalidValue, value, t)
+		}
+		encoded, err := encodeTuple(repeat(*t.Elem, len(elems)), elems)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind == SliceKind {
+			return append(word(big.NewInt(int64(len(elems)))), encoded...), nil
+		}
+		return encoded, nil
+	case TupleKind:
+		fields, ok := value.([]interface{})
+		if !ok || len(fields) != len(t.Components) {
+			return nil, fmt.Errorf("%w: %v as %s", ErrInvalidValue, value, t)
+		}
+		return encodeTuple(t.Components, fields)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrInvalidType, t)
+}
+
+func toBig(value interface{}) (*big.Int, bool) {
+	switch v := value.(type) {
+	case *big.Int:
+		return v, v != nil
+	case int:
+		return big.NewInt(int64(v)), true
+	case int64:
+		return big.NewInt(v), true
+	case uint64:
+		return new(big.Int).SetUint64(v), true
+	}
+	return nil, false
+}
+
+func toBytes(value interface{}) ([]byte, bool) {
+	switch v := value.(type) {
+	case []byte:
+		return v, true
+	case [4]byte:
+		return v[:], true
+	case [32]byte:
+		return v[:], true
+	case models.Hash:
+		return v[:], true
+	}
+	return nil, false
+}
+
+// word returns the 32 byte big-endian encoding of a value below 2^256.
+func word(v *big.Int) []byte {
+	return v.FillBytes(make([]byte, 32))
+}
+
+// padRight pads b with zeros to a multiple of 32 bytes.
+func padRight(b []byte) []byte {
+	out := make([]byte, (len(b)+31)/32*32)
+	copy(out, b)
+	return out
+}
@@ -0,0 +1,100 @@
+package abi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseSignature parses a text signature such as "transfer(address,uint256)"
+// or "fill((address,uint256)[],bytes)" into its name and unnamed arguments.
+func ParseSignature(signature string) (string, []Argument, error) {
+	open := strings.IndexByte(signature, '(')
+	if open <= 0 || !strings.HasSuffix(signature, ")") {
+		return "", nil, fmt.Errorf("%w: signature %q", ErrInvalidType, signature)
+	}
+	name := signature[:open]
+	for i, c := range name {
+		if !(c == '_' || c == '$' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+			return "", nil, fmt.Errorf("%w: signature %q", ErrInvalidType, signature)
+		}
+	}
+	args, err := parseTypeList(signature[open+1 : len(signature)-1])
+	if err != nil {
+		return "", nil, fmt.Errorf("signature %q: %w", signature, err)
+	}
+	return name, args, nil
+}
+
+// NewMethod returns the method described by a text signature.
+func NewMethod(signature string) (Method, error) {
+	name, args, err := ParseSignature(signature)
+	if err != nil {
+		return Method{}, err
+	}
+	return Method{Name: name, Inputs: args}, nil
+}
+
+// NewEvent returns the event described by a text signature. Which
+// parameters are indexed is not part of the signature.
+func NewEvent(signature string) (Event, error) {
+	name, args, err := ParseSignature(signature)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Name: name, Inputs: args}, nil
+}
+
+// parseTypeList parses comma separated types, where tuples are written as
+// parenthesised lists.
+func parseTypeList(s string) ([]Argument, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var args []Argument
+	depth, start := 0, 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) {
+			switch s[i] {
+			case '(':
+				depth++
+				continue
+			case ')':
+				depth--
+				if depth < 0 {
+					return nil, fmt.Errorf("%w: unbalanced parentheses in %q", ErrInvalidType, s)
+				}
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		if depth != 0 {
+			return nil, fmt.Errorf("%w: unbalanced parentheses in %q", ErrInvalidType, s)
+		}
+		typ, err := parseTypeString(s[start:i])
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, Argument{Type: typ})
+		start = i + 1
+	}
+	return args, nil
+}
+
+// parseTypeString parses a canonical type, e.g. "uint256[]" or
+// "(address,bytes)[2]".
+func parseTypeString(s string) (Type, error) {
+	if !strings.HasPrefix(s, "(") {
+		return NewType(s, nil)
+	}
+	closing := strings.LastIndexByte(s, ')')
+	components, err := parseTypeList(s[1:closing])
+	if err != nil {
+		return Type{}, err
+	}
+	return NewType("tuple"+s[closing+1:], components)
+}
@@ -0,0 +1,188 @@
+// Package abi encodes and decodes values in the Solidity contract ABI, the
+// format of call data, return data and event logs.
+package abi
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kind is the family a Type belongs to.
+type Kind int
+
+const (
+	UintKind Kind = iota
+	IntKind
+	AddressKind
+	BoolKind
+	FixedBytesKind // bytes1 to bytes32
+	BytesKind
+	StringKind
+	SliceKind // T[]
+	ArrayKind // T[k]
+	TupleKind
+)
+
+var ErrInvalidType = errors.New("invalid abi type")
+
+// maxArrayLength bounds the length of fixed-size arrays and maxStaticSize
+// the encoded size of static types, far above what any contract uses, so
+// that sizes computed from a type cannot overflow and decoding cannot be
+// made to allocate for elements the data does not hold.
+const (
+	maxArrayLength = 1 << 16
+	maxStaticSize  = 1 << 32
+)
+
+// Type is a parsed ABI type.
+type Type struct {
+	Kind Kind
+	// Size is the width in bits of integers and in bytes of fixed bytes.
+	Size int
+	// Length is the number of elements of a fixed-size array.
+	Length int
+	// Elem is the element type of arrays and slices.
+	Elem *Type
+	// Components are the fields of a tuple.
+	Components []Argument
+}
+
+// Argument is a named parameter of a method, event or tuple.
+type Argument struct {
+	Name    string
+	Type    Type
+	Indexed bool // event parameters only
+}
+
+// NewType parses a type as written in an ABI JSON file. components are the
+// fields of a tuple type and ignored otherwise.
+func NewType(typ string, components []Argument) (Type, error) {
+	if i := strings.LastIndexByte(typ, '['); i >= 0 {
+		if !strings.HasSuffix(typ, "]") {
+			return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, typ)
+		}
+		elem, err := NewType(typ[:i], components)
+		if err != nil {
+			return Type{}, err
+		}
+		size := typ[i+1 : len(typ)-1]
+		if size == "" {
+			return Type{Kind: SliceKind, Elem: &elem}, nil
+		}
+		length, err := strconv.Atoi(size)
+		if err != nil || length <= 0 || length > maxArrayLength || elem.headSize() > maxStaticSize/length {
+			return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, typ)
+		}
+		return Type{Kind: ArrayKind, Length: length, Elem: &elem}, nil
+	}
+
+	switch {
+	case typ == "address":
+		return Type{Kind: AddressKind, Size: 160}, nil
+	case typ == "bool":
+		return Type{Kind: BoolKind}, nil
+	case typ == "string":
+		return Type{Kind: StringKind}, nil
+	case typ == "bytes":
+		return Type{Kind: BytesKind}, nil
+	case typ == "tuple":
+		return Type{Kind: TupleKind, Components: components}, nil
+	case typ == "uint" || typ == "int":
+		// Aliases of the 256-bit types.
+		return NewType(typ+"256", nil)
+	case strings.HasPrefix(typ, "uint"):
+		return integerType(UintKind, typ, typ[4:])
+	case strings.HasPrefix(typ, "int"):
+		return integerType(IntKind, typ, typ[3:])
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := strconv.Atoi(typ[5:])
+		if err != nil || size < 1 || size > 32 {
+			return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, typ)
+		}
+		return Type{Kind: FixedBytesKind, Size: size}, nil
+	}
+	return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, typ)
+}
+
+func integerType(kind Kind, typ, bits string) (Type, error) {
+	size, err := strconv.Atoi(bits)
+	if err != nil || size < 8 || size > 256 || size%8 != 0 {
+		return Type{}, fmt.Errorf("%w: %q", ErrInvalidType, typ)
+	}
+	return Type{Kind: kind, Size: size}, nil
+}
+
+// String returns the canonical form of the type used in signatures, with
+// tuples written out as their component types.
+func (t Type) String() string {
+	switch t.Kind {
+	case UintKind:
+		return "uint" + strconv.Itoa(t.Size)
+	case IntKind:
+		return "int" + strconv.Itoa(t.Size)
+	case AddressKind:
+		return "address"
+	case BoolKind:
+		return "bool"
+	case FixedBytesKind:
+		return "bytes" + strconv.Itoa(t.Size)
+	case BytesKind:
+		return "bytes"
+	case StringKind:
+		return "string"
+	case SliceKind:
+		return t.Elem.String() + "[]"
+	case ArrayKind:
+		return t.Elem.String() + "[" + strconv.Itoa(t.Length) + "]"
+	case TupleKind:
+		return "(" + typeList(t.Components) + ")"
+	}
+	return "unknown"
+}
+
+func typeList(args []Argument) string {
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = arg.Type.String()
+	}
+	return strings.Join(types, ",")
+}
+
+// IsDynamic reports whether values of the type are encoded out of line,
+// behind an offset in the head of the enclosing tuple.
+func (t Type) IsDynamic() bool {
+	switch t.Kind {
+	case BytesKind, StringKind, SliceKind:
+		return true
+	case ArrayKind:
+		return t.Elem.IsDynamic()
+	case TupleKind:
+		for _, c := range t.Components {
+			if c.Type.IsDynamic() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headSize returns the number of bytes the type takes in the head of the
+// enclosing tuple: 32 for dynamic types, the full encoding otherwise.
+func (t Type) headSize() int {
+	if t.IsDynamic() {
+		return 32
+	}
+	switch t.Kind {
+	case ArrayKind:
+		return t.Length * t.Elem.headSize()
+	case TupleKind:
+		size := 0
+		for _, c := range t.Components {
+			size += c.Type.headSize()
+		}
+		return size
+	}
+	return 32
+}
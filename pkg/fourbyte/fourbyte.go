@@ -0,0 +1,173 @@
+// Package fourbyte maps function selectors and event topics back to the text
+// signatures they were hashed from, so that calls and logs can be labelled
+// without the ABI of the contract.
+package fourbyte
+
+import (
+	"bufio"
+	_ "embed"
+	"eth-parser/pkg/abi"
+	"eth-parser/pkg/models"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:embed signatures.txt
+var embedded string
+
+// DB is a signature database. It is safe for concurrent use.
+type DB struct {
+	mu        sync.RWMutex
+	functions map[[4]byte][]string
+	events    map[models.Hash][]string
+}
+
+// New returns an empty database.
+func New() *DB {
+	return &DB{
+		functions: make(map[[4]byte][]string),
+		events:    make(map[models.Hash][]string),
+	}
+}
+
+// Default returns a database holding the embedded signatures of common
+// token, exchange, governance and bridge contracts.
+func Default() *DB {
+	db := New()
+	if _, err := db.Load(strings.NewReader(embedded)); err != nil {
+		panic("fourbyte: embedded signatures: " + err.Error())
+	}
+	return db
+}
+
+// AddFunction adds a function signature such as "transfer(address,uint256)".
+// The signature is normalised to its canonical form, e.g. uint to uint256.
+func (db *DB) AddFunction(signature string) error {
+	method, err := abi.NewMethod(signature)
+	if err != nil {
+		return err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	selector := method.Selector()
+	db.functions[selector] = appendNew(db.functions[selector], method.Signature())
+	return nil
+}
+
+// AddEvent adds an event signature such as
+// "Transfer(address,address,uint256)".
+func (db *DB) AddEvent(signature string) error {
+	event, err := abi.NewEvent(signature)
+	if err != nil {
+		return err
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	id := event.ID()
+	db.events[id] = appendNew(db.events[id], event.Signature())
+	return nil
+}
+
+func appendNew(list []string, signature string) []string {
+	for _, existing := range list {
+		if existing == signature {
+			return list
+		}
+	}
+	return append(list, signature)
+}
+
+// Load adds the signatures read from r and returns how many lines it added.
+// Each line holds a function signature, optionally prefixed with "function",
+// or an event signature prefixed with "event". Blank lines and lines
+// starting with # are skipped.
+func (db *DB) Load(r io.Reader) (int, error) {
+	scanner := bufio.NewScanner(r)
+	added := 0
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var err error
+		if signature, ok := strings.CutPrefix(text, "event "); ok {
+			err = db.AddEvent(strings.TrimSpace(signature))
+		} else {
+			signature, _ := strings.CutPrefix(text, "function ")
+			err = db.AddFunction(strings.TrimSpace(signature))
+		}
+		if err != nil {
+			return added, fmt.Errorf("line %d: %w", line, err)
+		}
+		added++
+	}
+	return added, scanner.Err()
+}
+
+// LoadFile adds the signatures in the file at path, see Load.
+func (db *DB) LoadFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	added, err := db.Load(f)
+	if err != nil {
+		return added, fmt.Errorf("%s: %w", path, err)
+	}
+	return added, nil
+}
+
+// Function returns the signature of the function with the selector in the
+// first four bytes of input. When several signatures share the selector, the
+// one added first is returned.
+func (db *DB) Function(input []byte) (string, bool) {
+	if len(input) < 4 {
+		return "", false
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	candidates := db.functions[[4]byte(input[:4])]
+	if len(candidates) == 0 {
+		return "", false
+	}
+	return candidates[0], true
+}
+
+// Functions returns every known signature with the selector in the first
+// four bytes of input.
+func (db *DB) Functions(input []byte) []string {
+	if len(input) < 4 {
+		return nil
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return append([]string(nil), db.functions[[4]byte(input[:4])]...)
+}
+
+// Event returns the signature of the event with the given first topic.
+func (db *DB) Event(topic models.Hash) (string, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	candidates := db.events[topic]
+	if len(candidates) == 0 {
+		return "", false
+	}
+	return candidates[0], true
+}
+
+// Len returns the number of distinct function and event signatures.
+func (db *DB) Len() (functions, events int) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	for _, list := range db.functions {
+		functions += len(list)
+	}
+	for _, list := range db.events {
+		events += len(list)
+	}
+	return functions, events
+}
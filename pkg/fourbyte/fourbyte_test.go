@@ -0,0 +1,84 @@
+package fourbyte
+
+import (
+	"encoding/hex"
+	"eth-parser/pkg/models"
+	"strings"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	db := Default()
+	for selector, want := range map[string]string{
+		"a9059cbb": "transfer(address,uint256)",
+		"095ea7b3": "approve(address,uint256)",
+		"23b872dd": "transferFrom(address,address,uint256)",
+		"70a08231": "balanceOf(address)",
+		"d0e30db0": "deposit()",
+		"38ed1739": "swapExactTokensForTokens(uint256,uint256,address[],address,uint256)",
+		"414bf389": "exactInputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160))",
+		"82ad56cb": "aggregate3((address,bool,bytes)[])",
+	} {
+		input, _ := hex.DecodeString(selector + "0000")
+		if got, ok := db.Function(input); !ok || got != want {
+			t.Errorf("Function(0x%s) = %q, %v, want %q", selector, got, ok, want)
+		}
+	}
+
+	for topic, want := range map[string]string{
+		"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef": "Transfer(address,address,uint256)",
+		"0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925": "Approval(address,address,uint256)",
+		"0xd78ad95fa46c994b6551d0da85fc275fe613ce37657fb8d5e3d130840159d822": "Swap(address,uint256,uint256,uint256,uint256,address)",
+		"0xb8e138887d0aa13bab447e82de9d5c1777041ecd21ca36ba824ff1e6c07ddda4": "VoteCast(address,uint256,uint8,uint256,string)",
+	} {
+		hash, _ := models.HexToHash(topic)
+		if got, ok := db.Event(hash); !ok || got != want {
+			t.Errorf("Event(%s) = %q, %v, want %q", topic, got, ok, want)
+		}
+	}
+
+	if _, ok := db.Function([]byte{0xde, 0xad, 0xbe, 0xef}); ok {
+		t.Error("unknown selector resolved")
+	}
+	if _, ok := db.Function([]byte{0xa9, 0x05}); ok {
+		t.Error("short input resolved")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	db := New()
+	added, err := db.Load(strings.NewReader(`
+# comment
+transfer(address,uint)
+function approve(address,uint256)
+event Transfer(address,address,uint256)
+
+function transfer(address,uint256)
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 4 {
+		t.Errorf("added = %d, want 4", added)
+	}
+	// uint is normalised to uint256, so the last line is a duplicate.
+	if functions, events := db.Len(); functions != 2 || events != 1 {
+		t.Errorf("Len() = %d, %d", functions, events)
+	}
+
+	// Colliding signatures are all kept, the first one wins.
+	if err := db.AddFunction("many_msg_babbage(bytes1)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddFunction("transfer(bytes4[9],bytes5[6],int48[11])"); err != nil {
+		t.Fatal(err)
+	}
+	if got := db.Functions([]byte{0xa9, 0x05, 0x9c, 0xbb}); len(got) != 3 || got[0] != "transfer(address,uint256)" {
+		t.Errorf("Functions(0xa9059cbb) = %v", got)
+	}
+
+	_, err = db.Load(strings.NewReader("approve(address,uint256)\nevent Broken(address\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("invalid line: err = %v", err)
+	}
+}
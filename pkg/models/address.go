@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/hex"
+	"errors"
+	"eth-parser/pkg/utils"
+)
+
+const AddressLength = 20
+
+// Address is a 20 byte account address. Unlike the lowercased hex strings used
+// elsewhere it compares with == and can be used directly as a map or set key.
+type Address [AddressLength]byte
+
+var errInvalidAddress = errors.New("invalid address")
+
+// ParseAddress strictly parses a user supplied address, rejecting mixed-case
+// input with a bad EIP-55 checksum. See utils.ParseAddress.
+func ParseAddress(s string) (Address, error) {
+	a, err := utils.ParseAddress(s)
+	return Address(a), err
+}
+
+// HexToAddress parses a 0x-prefixed, 40 hex digit address in any letter case.
+func HexToAddress(s string) (Address, error) {
+	var a Address
+	if len(s) != 2+2*AddressLength || s[0] != '0' || (s[1] != 'x' && s[1] != 'X') {
+		return a, errInvalidAddress
+	}
+	// Decoded by hand rather than with hex.Decode to avoid allocating on the
+	// matching hot path.
+	for i := 0; i < AddressLength; i++ {
+		hi, ok1 := fromHexChar(s[2+2*i])
+		lo, ok2 := fromHexChar(s[3+2*i])
+		if !ok1 || !ok2 {
+			return Address{}, errInvalidAddress
+		}
+		a[i] = hi<<4 | lo
+	}
+	return a, nil
+}
+
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// String returns the lowercase 0x-prefixed hex form, which is also the form
+// used as storage key.
+func (a Address) String() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+// Checksum returns the EIP-55 mixed-case form.
+func (a Address) Checksum() string {
+	return utils.ChecksumAddress(a)
+}
+
+// MarshalText encodes the EIP-55 checksummed form.
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.Checksum()), nil
+}
+
+func (a *Address) UnmarshalText(text []byte) error {
+	parsed, err := HexToAddress(string(text))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
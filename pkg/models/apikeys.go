@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidRole   = errors.New("invalid role")
+	ErrInvalidTenant = errors.New("invalid tenant")
+)
+
+// Role is what an API key may do. Each role includes the ones before it.
+type Role string
+
+const (
+	// RoleReadOnly reads subscriptions, transactions, balances and alerts.
+	RoleReadOnly Role = "read-only"
+	// RoleSubscriber also manages subscriptions and log subscriptions.
+	RoleSubscriber Role = "subscriber"
+	// RoleAdmin also manages the tenant's alert rules and API keys.
+	RoleAdmin Role = "admin"
+	// RoleGlobalAdmin also acts for any tenant, manages the API keys of
+	// every tenant and the ABIs all tenants share, and reads the audit log.
+	RoleGlobalAdmin Role = "global-admin"
+)
+
+var roleRanks = map[Role]int{RoleReadOnly: 1, RoleSubscriber: 2, RoleAdmin: 3, RoleGlobalAdmin: 4}
+
+// ParseRole returns the role named s.
+func ParseRole(s string) (Role, error) {
+	if _, ok := roleRanks[Role(s)]; !ok {
+		return "", fmt.Errorf("%w %q, expected %s, %s, %s or %s", ErrInvalidRole, s, RoleReadOnly, RoleSubscriber, RoleAdmin, RoleGlobalAdmin)
+	}
+	return Role(s), nil
+}
+
+// Includes reports whether r may do what required may.
+func (r Role) Includes(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+const maxTenantLength = 64
+
+// ValidateTenant checks a tenant name: lowercase letters, digits, - and _,
+// at most 64 characters.
+func ValidateTenant(tenant string) error {
+	if tenant == "" || len(tenant) > maxTenantLength {
+		return fmt.Errorf("%w: tenant must be 1 to %d characters", ErrInvalidTenant, maxTenantLength)
+	}
+	for _, c := range tenant {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return fmt.Errorf("%w %q, expected lowercase letters, digits, - and _", ErrInvalidTenant, tenant)
+		}
+	}
+	return nil
+}
+
+// APIKey is a key clients authenticate with. Only the SHA-256 hash of the
+// secret is kept; Prefix, the start of the secret, tells keys apart.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Role       Role       `json:"role"`
+	Tenant     string     `json:"tenant"`
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
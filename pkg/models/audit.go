@@ -0,0 +1,81 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrAuditChainBroken = errors.New("audit chain broken")
+
+// AuditAction is the kind of change an audit record is about.
+type AuditAction string
+
+const (
+	AuditSubscribe          AuditAction = "subscription.create"
+	AuditUpdateSubscription AuditAction = "subscription.update"
+	AuditUnsubscribe        AuditAction = "subscription.delete"
+	AuditSubscribeLogs      AuditAction = "log_subscription.create"
+	AuditUnsubscribeLogs    AuditAction = "log_subscription.delete"
+	AuditRegisterABI        AuditAction = "abi.register"
+	AuditUnregisterABI      AuditAction = "abi.unregister"
+	AuditCreateRule         AuditAction = "rule.create"
+	AuditUpdateRule         AuditAction = "rule.update"
+	AuditDeleteRule         AuditAction = "rule.delete"
+	AuditCreateKey          AuditAction = "key.create"
+	AuditRevokeKey          AuditAction = "key.revoke"
+)
+
+// AuditGenesisHash is the previous hash of the first audit record.
+var AuditGenesisHash = strings.Repeat("0", 64)
+
+// AuditRecord is one entry of the audit trail: who changed what, when, from
+// where and in which request. Each record carries the hash of the one
+// before it, so changing or dropping a stored record breaks the chain.
+type AuditRecord struct {
+	Sequence  int64             `json:"sequence"`
+	Time      time.Time         `json:"time"`
+	Actor     string            `json:"actor"`
+	ActorName string            `json:"actorName,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	RequestID string            `json:"requestId,omitempty"`
+	Action    AuditAction       `json:"action"`
+	Target    string            `json:"target"`
+	ChainID   uint64            `json:"chainId,omitempty"`
+	Tenant    string            `json:"tenant,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	PrevHash  string            `json:"prevHash"`
+	Hash      string            `json:"hash"`
+}
+
+// ComputeHash returns the SHA-256 of the record's JSON encoding without its
+// hash. Map keys are encoded sorted, so the encoding is stable.
+func (r AuditRecord) ComputeHash() string {
+	r.Hash = ""
+	encoded, _ := json.Marshal(r)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAuditChain checks that records, consecutive and oldest first, each
+// hash to their hash and link to the one before. The first record links to
+// prevHash, AuditGenesisHash for a complete trail.
+func VerifyAuditChain(records []AuditRecord, prevHash string) error {
+	for i, record := range records {
+		if i > 0 && record.Sequence != records[i-1].Sequence+1 {
+			return fmt.Errorf("%w: record %d follows %d", ErrAuditChainBroken, record.Sequence, records[i-1].Sequence)
+		}
+		if record.PrevHash != prevHash {
+			return fmt.Errorf("%w: record %d does not link to the record before it", ErrAuditChainBroken, record.Sequence)
+		}
+		if record.ComputeHash() != record.Hash {
+			return fmt.Errorf("%w: record %d does not match its hash", ErrAuditChainBroken, record.Sequence)
+		}
+		prevHash = record.Hash
+	}
+	return nil
+}
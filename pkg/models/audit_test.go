@@ -0,0 +1,45 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerifyAuditChain(t *testing.T) {
+	prev := AuditGenesisHash
+	var records []AuditRecord
+	for i, target := range []string{"0x01", "0x02", "0x03"} {
+		record := AuditRecord{
+			Sequence: int64(i + 1),
+			Time:     time.Date(2024, 6, 4, 12, 0, i, 0, time.UTC),
+			Actor:    "anonymous",
+			Action:   AuditSubscribe,
+			Target:   target,
+			Details:  map[string]string{"label": "vault", "tags": "treasury"},
+			PrevHash: prev,
+		}
+		record.Hash = record.ComputeHash()
+		prev = record.Hash
+		records = append(records, record)
+	}
+	if err := VerifyAuditChain(records, AuditGenesisHash); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyAuditChain(records[1:], records[0].Hash); err != nil {
+		t.Errorf("a chain should verify from a known hash: %v", err)
+	}
+
+	tampered := append([]AuditRecord(nil), records...)
+	tampered[1].Target = "0x04"
+	if err := VerifyAuditChain(tampered, AuditGenesisHash); !errors.Is(err, ErrAuditChainBroken) {
+		t.Errorf("changed record: %v", err)
+	}
+	tampered[1].Hash = tampered[1].ComputeHash()
+	if err := VerifyAuditChain(tampered, AuditGenesisHash); !errors.Is(err, ErrAuditChainBroken) {
+		t.Errorf("rehashed record: %v", err)
+	}
+	if err := VerifyAuditChain([]AuditRecord{records[0], records[2]}, AuditGenesisHash); !errors.Is(err, ErrAuditChainBroken) {
+		t.Errorf("dropped record: %v", err)
+	}
+}
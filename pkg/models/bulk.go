@@ -0,0 +1,26 @@
+package models
+
+// BulkStatus is the outcome of one row of a bulk subscribe or unsubscribe.
+type BulkStatus string
+
+const (
+	BulkAdded         BulkStatus = "added"
+	BulkExisting      BulkStatus = "existing"
+	BulkRemoved       BulkStatus = "removed"
+	BulkNotSubscribed BulkStatus = "not_subscribed"
+	BulkInvalid       BulkStatus = "invalid"
+	// BulkSkipped marks valid rows that were not applied because the
+	// request was atomic and another row failed.
+	BulkSkipped BulkStatus = "skipped"
+)
+
+// BulkResult is the outcome of one row, numbered from 1 in request order.
+// StartBlock is the start block an added subscription got, which differs
+// from the row's when that block had already been processed.
+type BulkResult struct {
+	Row        int        `json:"row"`
+	Address    string     `json:"address"`
+	Status     BulkStatus `json:"status"`
+	StartBlock int64      `json:"startBlock,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
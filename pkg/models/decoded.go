@@ -0,0 +1,724 @@
+package models
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"eth-parser/pkg/utils"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodedTransaction is a Transaction with every field parsed into its native
+// type. Numbers marshal to JSON in decimal (big values as strings so they
+// survive JavaScript clients); the raw Transaction remains the hex form.
+type DecodedTransaction struct {
+	BlockHash            Hash
+	BlockNumber          uint64
+	From                 Address
+	To                   *Address // nil for contract creation
+	Gas                  uint64
+	GasPrice             *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	Hash                 Hash
+	Input                []byte
+	Nonce                uint64
+	TransactionIndex     uint64
+	Value                *big.Int
+	Type                 uint64
+	AccessList           []DecodedAccessListEntry
+	ChainID              *big.Int
+	V                    *big.Int
+	R                    *big.Int
+	S                    *big.Int
+	YParity              *uint64
+
+	// Blob transactions (type 0x3) only.
+	MaxFeePerBlobGas    *big.Int
+	BlobVersionedHashes []Hash
+
+	// Fees is set once the receipt of the transaction has been seen.
+	Fees *DecodedFees
+	// DecodedInput is already decoded; it is carried over as is.
+	DecodedInput *DecodedInput
+	// Method is the text signature of the called function, if known.
+	Method string
+	// TokenTransfer is carried over as is.
+	TokenTransfer *TokenTransfer
+	// RecoveredFrom is the sender recovered from the signature when the
+	// node reported another one.
+	RecoveredFrom *Address
+
+	// Deposit is set for OP Stack deposits (type 0x7e).
+	Deposit *DecodedDeposit
+	// Arbitrum is set for Arbitrum system transactions (types 0x64-0x6a).
+	Arbitrum *DecodedArbitrumFields
+}
+
+// DecodedDeposit holds the fields specific to an OP Stack deposit. Mint is
+// the ETH created on L2 and credited to the sender before the transaction
+// runs; Value is then transferred from the sender to the recipient.
+type DecodedDeposit struct {
+	SourceHash Hash
+	Mint       *big.Int
+	IsSystemTx bool
+}
+
+// DecodedArbitrumFields holds the fields specific to Arbitrum system
+// transactions. Which of them are set depends on the type.
+type DecodedArbitrumFields struct {
+	RequestID           *Hash
+	TicketID            *Hash
+	RefundTo            *Address
+	MaxRefund           *big.Int
+	SubmissionFeeRefund *big.Int
+	L1BaseFee           *big.Int
+	DepositValue        *big.Int
+	RetryTo             *Address
+	RetryValue          *big.Int
+	RetryData           []byte
+	Beneficiary         *Address
+	MaxSubmissionFee    *big.Int
+}
+
+// DecodedFees is Fees with every amount parsed. The blob fields are nil for
+// transactions without blobs.
+type DecodedFees struct {
+	GasUsed           uint64
+	EffectiveGasPrice *big.Int
+	ExecutionFee      *big.Int
+	BlobGasUsed       *uint64
+	BlobBaseFee       *big.Int
+	BlobFee           *big.Int
+	TotalFee          *big.Int
+}
+
+type DecodedAccessListEntry struct {
+	Address     Address `json:"address"`
+	StorageKeys []Hash  `json:"storageKeys"`
+}
+
+// DecodedBlock is a Block with every field parsed into its native type.
+type DecodedBlock struct {
+	Difficulty       *big.Int
+	ExtraData        []byte
+	GasLimit         uint64
+	GasUsed          uint64
+	Hash             Hash
+	LogsBloom        []byte
+	Miner            Address
+	MixHash          Hash
+	Nonce            uint64
+	Number           uint64
+	ParentHash       Hash
+	ReceiptsRoot     Hash
+	Sha3Uncles       Hash
+	Size             uint64
+	StateRoot        Hash
+	Timestamp        time.Time
+	TotalDifficulty  *big.Int
+	Transactions     []DecodedTransaction
+	TransactionsRoot Hash
+	Uncles           []Hash
+
+	// Set from London and Cancun on respectively.
+	BaseFeePerGas *big.Int
+	BlobGasUsed   *uint64
+	ExcessBlobGas *uint64
+}
+
+// Decode parses the hex fields of tx. Fields that are absent for the
+// transaction type (e.g. MaxFeePerGas on legacy transactions) are left nil.
+func (tx Transaction) Decode() (DecodedTransaction, error) {
+	var d DecodedTransaction
+	p := fieldParser{}
+	d.BlockHash = p.hash("blockHash", tx.BlockHash)
+	d.BlockNumber = p.uint64("blockNumber", tx.BlockNumber)
+	d.From = p.address("from", tx.From)
+	if tx.To != "" {
+		to := p.address("to", tx.To)
+		d.To = &to
+	}
+	d.Gas = p.uint64("gas", tx.Gas)
+	d.GasPrice = p.big("gasPrice", tx.GasPrice)
+	d.MaxFeePerGas = p.big("maxFeePerGas", tx.MaxFeePerGas)
+	d.MaxPriorityFeePerGas = p.big("maxPriorityFeePerGas", tx.MaxPriorityFeePerGas)
+	d.Hash = p.hash("hash", tx.Hash)
+	d.Input = p.bytes("input", tx.Input)
+	d.Nonce = p.uint64("nonce", tx.Nonce)
+	d.TransactionIndex = p.uint64("transactionIndex", tx.TransactionIndex)
+	d.Value = p.big("value", tx.Value)
+	d.Type = p.uint64("type", tx.Type)
+	for _, entry := range tx.AccessList {
+		decoded := DecodedAccessListEntry{Address: p.address("accessList.address", entry.Address)}
+		for _, key := range entry.StorageKeys {
+			decoded.StorageKeys = append(decoded.StorageKeys, p.hash("accessList.storageKeys", key))
+		}
+		d.AccessList = append(d.AccessList, decoded)
+	}
+	d.ChainID = p.big("chainId", tx.ChainID)
+	d.V = p.big("v", tx.V)
+	d.R = p.big("r", tx.R)
+	d.S = p.big("s", tx.S)
+	if tx.YParity != "" {
+		yParity := p.uint64("yParity", tx.YParity)
+		d.YParity = &yParity
+	}
+	d.MaxFeePerBlobGas = p.big("maxFeePerBlobGas", tx.MaxFeePerBlobGas)
+	for _, hash := range tx.BlobVersionedHashes {
+		d.BlobVersionedHashes = append(d.BlobVersionedHashes, p.hash("blobVersionedHashes", hash))
+	}
+	if f := tx.Fees; f != nil {
+		d.Fees = &DecodedFees{
+			GasUsed:           p.uint64("fees.gasUsed", f.GasUsed),
+			EffectiveGasPrice: p.big("fees.effectiveGasPrice", f.EffectiveGasPrice),
+			ExecutionFee:      p.big("fees.executionFee", f.ExecutionFee),
+			BlobGasUsed:       p.optionalUint64("fees.blobGasUsed", f.BlobGasUsed),
+			BlobBaseFee:       p.big("fees.blobBaseFee", f.BlobBaseFee),
+			BlobFee:           p.big("fees.blobFee", f.BlobFee),
+			TotalFee:          p.big("fees.totalFee", f.TotalFee),
+		}
+	}
+	d.DecodedInput = tx.DecodedInput
+	d.Method = tx.Method
+	d.TokenTransfer = tx.TokenTransfer
+	d.RecoveredFrom = p.optionalAddress("recoveredFrom", tx.RecoveredFrom)
+	switch {
+	case d.Type == DepositTxType:
+		d.Deposit = &DecodedDeposit{
+			SourceHash: p.hash("sourceHash", tx.SourceHash),
+			Mint:       p.big("mint", tx.Mint),
+			IsSystemTx: tx.IsSystemTx,
+		}
+	case tx.IsArbitrumSystemTx():
+		d.Arbitrum = &DecodedArbitrumFields{
+			RequestID:           p.optionalHash("requestId", tx.RequestID),
+			TicketID:            p.optionalHash("ticketId", tx.TicketID),
+			RefundTo:            p.optionalAddress("refundTo", tx.RefundTo),
+			MaxRefund:           p.big("maxRefund", tx.MaxRefund),
+			SubmissionFeeRefund: p.big("submissionFeeRefund", tx.SubmissionFeeRefund),
+			L1BaseFee:           p.big("l1BaseFee", tx.L1BaseFee),
+			DepositValue:        p.big("depositValue", tx.DepositValue),
+			RetryTo:             p.optionalAddress("retryTo", tx.RetryTo),
+			RetryValue:          p.big("retryValue", tx.RetryValue),
+			RetryData:           p.bytes("retryData", tx.RetryData),
+			Beneficiary:         p.optionalAddress("beneficiary", tx.Beneficiary),
+			MaxSubmissionFee:    p.big("maxSubmissionFee", tx.MaxSubmissionFee),
+		}
+	}
+	if p.err != nil {
+		return DecodedTransaction{}, fmt.Errorf("decode transaction %s: %w", tx.Hash, p.err)
+	}
+	return d, nil
+}
+
+// Decode parses the hex fields of the block and all of its transactions.
+func (b Block) Decode() (DecodedBlock, error) {
+	var d DecodedBlock
+	p := fieldParser{}
+	d.Difficulty = p.big("difficulty", b.Difficulty)
+	d.ExtraData = p.bytes("extraData", b.ExtraData)
+	d.GasLimit = p.uint64("gasLimit", b.GasLimit)
+	d.GasUsed = p.uint64("gasUsed", b.GasUsed)
+	d.Hash = p.hash("hash", b.Hash)
+	d.LogsBloom = p.bytes("logsBloom", b.LogsBloom)
+	d.Miner = p.address("miner", b.Miner)
+	d.MixHash = p.hash("mixHash", b.MixHash)
+	d.Nonce = p.blockNonce("nonce", b.Nonce)
+	d.Number = p.uint64("number", b.Number)
+	d.ParentHash = p.hash("parentHash", b.ParentHash)
+	d.ReceiptsRoot = p.hash("receiptsRoot", b.ReceiptsRoot)
+	d.Sha3Uncles = p.hash("sha3Uncles", b.Sha3Uncles)
+	d.Size = p.uint64("size", b.Size)
+	d.StateRoot = p.hash("stateRoot", b.StateRoot)
+	d.Timestamp = time.Unix(int64(p.uint64("timestamp", b.Timestamp)), 0).UTC()
+	d.TotalDifficulty = p.big("totalDifficulty", b.TotalDifficulty)
+	d.TransactionsRoot = p.hash("transactionsRoot", b.TransactionsRoot)
+	for _, uncle := range b.Uncles {
+		d.Uncles = append(d.Uncles, p.hash("uncles", uncle))
+	}
+	d.BaseFeePerGas = p.big("baseFeePerGas", b.BaseFeePerGas)
+	d.BlobGasUsed = p.optionalUint64("blobGasUsed", b.BlobGasUsed)
+	d.ExcessBlobGas = p.optionalUint64("excessBlobGas", b.ExcessBlobGas)
+	if p.err != nil {
+		return DecodedBlock{}, fmt.Errorf("decode block %s: %w", b.Number, p.err)
+	}
+
+	for _, tx := range b.Transactions {
+		decoded, err := tx.Decode()
+		if err != nil {
+			return DecodedBlock{}, fmt.Errorf("decode block %s: %w", b.Number, err)
+		}
+		d.Transactions = append(d.Transactions, decoded)
+	}
+	return d, nil
+}
+
+// fieldParser parses hex fields and keeps the first error, so Decode can read
+// as a flat list of assignments.
+type fieldParser struct {
+	err error
+}
+
+func (p *fieldParser) fail(field string, err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("field %s: %w", field, err)
+	}
+}
+
+func (p *fieldParser) uint64(field, s string) uint64 {
+	if s == "" {
+		return 0
+	}
+	v, err := utils.DecodeUint64(s)
+	if err != nil {
+		p.fail(field, err)
+	}
+	return v
+}
+
+func (p *fieldParser) optionalUint64(field, s string) *uint64 {
+	if s == "" {
+		return nil
+	}
+	v := p.uint64(field, s)
+	return &v
+}
+
+func (p *fieldParser) big(field, s string) *big.Int {
+	if s == "" {
+		return nil
+	}
+	v, err := utils.DecodeBig(s)
+	if err != nil {
+		p.fail(field, err)
+	}
+	return v
+}
+
+func (p *fieldParser) bytes(field, s string) []byte {
+	if s == "" {
+		return nil
+	}
+	v, err := utils.DecodeBytes(s)
+	if err != nil {
+		p.fail(field, err)
+	}
+	return v
+}
+
+// blockNonce parses the 8 byte DATA nonce of a block header.
+func (p *fieldParser) blockNonce(field, s string) uint64 {
+	if s == "" {
+		return 0
+	}
+	var nonce [8]byte
+	if err := utils.DecodeFixedBytes(s, nonce[:]); err != nil {
+		p.fail(field, err)
+	}
+	return binary.BigEndian.Uint64(nonce[:])
+}
+
+func (p *fieldParser) address(field, s string) Address {
+	if s == "" {
+		return Address{}
+	}
+	v, err := HexToAddress(s)
+	if err != nil {
+		p.fail(field, err)
+	}
+	return v
+}
+
+func (p *fieldParser) hash(field, s string) Hash {
+	if s == "" {
+		return Hash{}
+	}
+	v, err := HexToHash(s)
+	if err != nil {
+		p.fail(field, err)
+	}
+	return v
+}
+
+func (p *fieldParser) optionalAddress(field, s string) *Address {
+	if s == "" {
+		return nil
+	}
+	v := p.address(field, s)
+	return &v
+}
+
+func (p *fieldParser) optionalHash(field, s string) *Hash {
+	if s == "" {
+		return nil
+	}
+	v := p.hash(field, s)
+	return &v
+}
+
+// JSON encoding. Numbers are written in decimal; on input both decimal and
+// hex quantities are accepted so that either form round-trips.
+
+type decimalUint64 uint64
+
+func (n decimalUint64) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatUint(uint64(n), 10)), nil
+}
+
+func (n *decimalUint64) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	var (
+		v   uint64
+		err error
+	)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		v, err = utils.DecodeUint64(s)
+	} else {
+		v, err = strconv.ParseUint(s, 10, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid number %s", data)
+	}
+	*n = decimalUint64(v)
+	return nil
+}
+
+type decimalBig struct {
+	*big.Int
+}
+
+func (n decimalBig) MarshalJSON() ([]byte, error) {
+	if n.Int == nil {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + n.Int.String() + `"`), nil
+}
+
+func (n *decimalBig) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		n.Int = nil
+		return nil
+	}
+	s := strings.Trim(string(data), `"`)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		v, err := utils.DecodeBig(s)
+		if err != nil {
+			return fmt.Errorf("invalid number %s", data)
+		}
+		n.Int = v
+		return nil
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return fmt.Errorf("invalid number %s", data)
+	}
+	n.Int = v
+	return nil
+}
+
+// hexBytes marshals as 0x-prefixed hex, or null when absent.
+type hexBytes []byte
+
+func (b hexBytes) MarshalJSON() ([]byte, error) {
+	if b == nil {
+		return []byte("null"), nil
+	}
+	return []byte(`"0x` + hex.EncodeToString(b) + `"`), nil
+}
+
+func (b *hexBytes) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*b = nil
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := utils.DecodeBytes(s)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+type decodedTransactionJSON struct {
+	BlockHash            Hash                     `json:"blockHash"`
+	BlockNumber          decimalUint64            `json:"blockNumber"`
+	From                 Address                  `json:"from"`
+	To                   *Address                 `json:"to"`
+	Gas                  decimalUint64            `json:"gas"`
+	GasPrice             decimalBig               `json:"gasPrice"`
+	MaxFeePerGas         decimalBig               `json:"maxFeePerGas,omitzero"`
+	MaxPriorityFeePerGas decimalBig               `json:"maxPriorityFeePerGas,omitzero"`
+	Hash                 Hash                     `json:"hash"`
+	Input                hexBytes                 `json:"input"`
+	Nonce                decimalUint64            `json:"nonce"`
+	TransactionIndex     decimalUint64            `json:"transactionIndex"`
+	Value                decimalBig               `json:"value"`
+	Type                 decimalUint64            `json:"type"`
+	AccessList           []DecodedAccessListEntry `json:"accessList,omitzero"`
+	ChainID              decimalBig               `json:"chainId,omitzero"`
+	V                    decimalBig               `json:"v"`
+	R                    decimalBig               `json:"r"`
+	S                    decimalBig               `json:"s"`
+	YParity              *decimalUint64           `json:"yParity,omitzero"`
+	MaxFeePerBlobGas     decimalBig               `json:"maxFeePerBlobGas,omitzero"`
+	BlobVersionedHashes  []Hash                   `json:"blobVersionedHashes,omitzero"`
+	Fees                 *decodedFeesJSON         `json:"fees,omitzero"`
+	DecodedInput         *DecodedInput            `json:"decodedInput,omitzero"`
+	Method               string                   `json:"method,omitzero"`
+	TokenTransfer        *TokenTransfer           `json:"tokenTransfer,omitzero"`
+	RecoveredFrom        *Address                 `json:"recoveredFrom,omitzero"`
+
+	// Type specific fields, flattened as in the node's JSON.
+	SourceHash          *Hash      `json:"sourceHash,omitzero"`
+	Mint                decimalBig `json:"mint,omitzero"`
+	IsSystemTx          bool       `json:"isSystemTx,omitzero"`
+	RequestID           *Hash      `json:"requestId,omitzero"`
+	TicketID            *Hash      `json:"ticketId,omitzero"`
+	RefundTo            *Address   `json:"refundTo,omitzero"`
+	MaxRefund           decimalBig `json:"maxRefund,omitzero"`
+	SubmissionFeeRefund decimalBig `json:"submissionFeeRefund,omitzero"`
+	L1BaseFee           decimalBig `json:"l1BaseFee,omitzero"`
+	DepositValue        decimalBig `json:"depositValue,omitzero"`
+	RetryTo             *Address   `json:"retryTo,omitzero"`
+	RetryValue          decimalBig `json:"retryValue,omitzero"`
+	RetryData           hexBytes   `json:"retryData,omitzero"`
+	Beneficiary         *Address   `json:"beneficiary,omitzero"`
+	MaxSubmissionFee    decimalBig `json:"maxSubmissionFee,omitzero"`
+}
+
+func (d DecodedTransaction) MarshalJSON() ([]byte, error) {
+	w := decodedTransactionJSON{
+		BlockHash:            d.BlockHash,
+		BlockNumber:          decimalUint64(d.BlockNumber),
+		From:                 d.From,
+		To:                   d.To,
+		Gas:                  decimalUint64(d.Gas),
+		GasPrice:             decimalBig{d.GasPrice},
+		MaxFeePerGas:         decimalBig{d.MaxFeePerGas},
+		MaxPriorityFeePerGas: decimalBig{d.MaxPriorityFeePerGas},
+		Hash:                 d.Hash,
+		Input:                d.Input,
+		Nonce:                decimalUint64(d.Nonce),
+		TransactionIndex:     decimalUint64(d.TransactionIndex),
+		Value:                decimalBig{d.Value},
+		Type:                 decimalUint64(d.Type),
+		AccessList:           d.AccessList,
+		ChainID:              decimalBig{d.ChainID},
+		V:                    decimalBig{d.V},
+		R:                    decimalBig{d.R},
+		S:                    decimalBig{d.S},
+		YParity:              (*decimalUint64)(d.YParity),
+		MaxFeePerBlobGas:     decimalBig{d.MaxFeePerBlobGas},
+		BlobVersionedHashes:  d.BlobVersionedHashes,
+		DecodedInput:         d.DecodedInput,
+		Method:               d.Method,
+		TokenTransfer:        d.TokenTransfer,
+		RecoveredFrom:        d.RecoveredFrom,
+	}
+	if f := d.Fees; f != nil {
+		w.Fees = &decodedFeesJSON{
+			GasUsed:           decimalUint64(f.GasUsed),
+			EffectiveGasPrice: decimalBig{f.EffectiveGasPrice},
+			ExecutionFee:      decimalBig{f.ExecutionFee},
+			BlobGasUsed:       (*decimalUint64)(f.BlobGasUsed),
+			BlobBaseFee:       decimalBig{f.BlobBaseFee},
+			BlobFee:           decimalBig{f.BlobFee},
+			TotalFee:          decimalBig{f.TotalFee},
+		}
+	}
+	if dep := d.Deposit; dep != nil {
+		w.SourceHash = &dep.SourceHash
+		w.Mint = decimalBig{dep.Mint}
+		w.IsSystemTx = dep.IsSystemTx
+	}
+	if arb := d.Arbitrum; arb != nil {
+		w.RequestID = arb.RequestID
+		w.TicketID = arb.TicketID
+		w.RefundTo = arb.RefundTo
+		w.MaxRefund = decimalBig{arb.MaxRefund}
+		w.SubmissionFeeRefund = decimalBig{arb.SubmissionFeeRefund}
+		w.L1BaseFee = decimalBig{arb.L1BaseFee}
+		w.DepositValue = decimalBig{arb.DepositValue}
+		w.RetryTo = arb.RetryTo
+		w.RetryValue = decimalBig{arb.RetryValue}
+		w.RetryData = arb.RetryData
+		w.Beneficiary = arb.Beneficiary
+		w.MaxSubmissionFee = decimalBig{arb.MaxSubmissionFee}
+	}
+	return json.Marshal(w)
+}
+
+func (d *DecodedTransaction) UnmarshalJSON(data []byte) error {
+	var w decodedTransactionJSON
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	*d = DecodedTransaction{
+		BlockHash:            w.BlockHash,
+		BlockNumber:          uint64(w.BlockNumber),
+		From:                 w.From,
+		To:                   w.To,
+		Gas:                  uint64(w.Gas),
+		GasPrice:             w.GasPrice.Int,
+		MaxFeePerGas:         w.MaxFeePerGas.Int,
+		MaxPriorityFeePerGas: w.MaxPriorityFeePerGas.Int,
+		Hash:                 w.Hash,
+		Input:                w.Input,
+		Nonce:                uint64(w.Nonce),
+		TransactionIndex:     uint64(w.TransactionIndex),
+		Value:                w.Value.Int,
+		Type:                 uint64(w.Type),
+		AccessList:           w.AccessList,
+		ChainID:              w.ChainID.Int,
+		V:                    w.V.Int,
+		R:                    w.R.Int,
+		S:                    w.S.Int,
+		YParity:              (*uint64)(w.YParity),
+		MaxFeePerBlobGas:     w.MaxFeePerBlobGas.Int,
+		BlobVersionedHashes:  w.BlobVersionedHashes,
+		DecodedInput:         w.DecodedInput,
+		Method:               w.Method,
+		TokenTransfer:        w.TokenTransfer,
+		RecoveredFrom:        w.RecoveredFrom,
+	}
+	if f := w.Fees; f != nil {
+		d.Fees = &DecodedFees{
+			GasUsed:           uint64(f.GasUsed),
+			EffectiveGasPrice: f.EffectiveGasPrice.Int,
+			ExecutionFee:      f.ExecutionFee.Int,
+			BlobGasUsed:       (*uint64)(f.BlobGasUsed),
+			BlobBaseFee:       f.BlobBaseFee.Int,
+			BlobFee:           f.BlobFee.Int,
+			TotalFee:          f.TotalFee.Int,
+		}
+	}
+	switch {
+	case d.Type == DepositTxType:
+		d.Deposit = &DecodedDeposit{Mint: w.Mint.Int, IsSystemTx: w.IsSystemTx}
+		if w.SourceHash != nil {
+			d.Deposit.SourceHash = *w.SourceHash
+		}
+	case isArbitrumSystemType(d.Type):
+		d.Arbitrum = &DecodedArbitrumFields{
+			RequestID:           w.RequestID,
+			TicketID:            w.TicketID,
+			RefundTo:            w.RefundTo,
+			MaxRefund:           w.MaxRefund.Int,
+			SubmissionFeeRefund: w.SubmissionFeeRefund.Int,
+			L1BaseFee:           w.L1BaseFee.Int,
+			DepositValue:        w.DepositValue.Int,
+			RetryTo:             w.RetryTo,
+			RetryValue:          w.RetryValue.Int,
+			RetryData:           w.RetryData,
+			Beneficiary:         w.Beneficiary,
+			MaxSubmissionFee:    w.MaxSubmissionFee.Int,
+		}
+	}
+	return nil
+}
+
+type decodedFeesJSON struct {
+	GasUsed           decimalUint64  `json:"gasUsed"`
+	EffectiveGasPrice decimalBig     `json:"effectiveGasPrice"`
+	ExecutionFee      decimalBig     `json:"executionFee"`
+	BlobGasUsed       *decimalUint64 `json:"blobGasUsed,omitzero"`
+	BlobBaseFee       decimalBig     `json:"blobBaseFee,omitzero"`
+	BlobFee           decimalBig     `json:"blobFee,omitzero"`
+	TotalFee          decimalBig     `json:"totalFee"`
+}
+
+type decodedBlockJSON struct {
+	Difficulty       decimalBig           `json:"difficulty"`
+	ExtraData        hexBytes             `json:"extraData"`
+	GasLimit         decimalUint64        `json:"gasLimit"`
+	GasUsed          decimalUint64        `json:"gasUsed"`
+	Hash             Hash                 `json:"hash"`
+	LogsBloom        hexBytes             `json:"logsBloom"`
+	Miner            Address              `json:"miner"`
+	MixHash          Hash                 `json:"mixHash"`
+	Nonce            decimalUint64        `json:"nonce"`
+	Number           decimalUint64        `json:"number"`
+	ParentHash       Hash                 `json:"parentHash"`
+	ReceiptsRoot     Hash                 `json:"receiptsRoot"`
+	Sha3Uncles       Hash                 `json:"sha3Uncles"`
+	Size             decimalUint64        `json:"size"`
+	StateRoot        Hash                 `json:"stateRoot"`
+	Timestamp        time.Time            `json:"timestamp"`
+	TotalDifficulty  decimalBig           `json:"totalDifficulty,omitzero"`
+	Transactions     []DecodedTransaction `json:"transactions"`
+	TransactionsRoot Hash                 `json:"transactionsRoot"`
+	Uncles           []Hash               `json:"uncles"`
+	BaseFeePerGas    decimalBig           `json:"baseFeePerGas,omitzero"`
+	BlobGasUsed      *decimalUint64       `json:"blobGasUsed,omitzero"`
+	ExcessBlobGas    *decimalUint64       `json:"excessBlobGas,omitzero"`
+}
+
+func (d DecodedBlock) MarshalJSON() ([]byte, error) {
+	return json.Marshal(decodedBlockJSON{
+		Difficulty:       decimalBig{d.Difficulty},
+		ExtraData:        d.ExtraData,
+		GasLimit:         decimalUint64(d.GasLimit),
+		GasUsed:          decimalUint64(d.GasUsed),
+		Hash:             d.Hash,
+		LogsBloom:        d.LogsBloom,
+		Miner:            d.Miner,
+		MixHash:          d.MixHash,
+		Nonce:            decimalUint64(d.Nonce),
+		Number:           decimalUint64(d.Number),
+		ParentHash:       d.ParentHash,
+		ReceiptsRoot:     d.ReceiptsRoot,
+		Sha3Uncles:       d.Sha3Uncles,
+		Size:             decimalUint64(d.Size),
+		StateRoot:        d.StateRoot,
+		Timestamp:        d.Timestamp,
+		TotalDifficulty:  decimalBig{d.TotalDifficulty},
+		Transactions:     d.Transactions,
+		TransactionsRoot: d.TransactionsRoot,
+		Uncles:           d.Uncles,
+		BaseFeePerGas:    decimalBig{d.BaseFeePerGas},
+		BlobGasUsed:      (*decimalUint64)(d.BlobGasUsed),
+		ExcessBlobGas:    (*decimalUint64)(d.ExcessBlobGas),
+	})
+}
+
+func (d *DecodedBlock) UnmarshalJSON(data []byte) error {
+	var w decodedBlockJSON
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	*d = DecodedBlock{
+		Difficulty:       w.Difficulty.Int,
+		ExtraData:        w.ExtraData,
+		GasLimit:         uint64(w.GasLimit),
+		GasUsed:          uint64(w.GasUsed),
+		Hash:             w.Hash,
+		LogsBloom:        w.LogsBloom,
+		Miner:            w.Miner,
+		MixHash:          w.MixHash,
+		Nonce:            uint64(w.Nonce),
+		Number:           uint64(w.Number),
+		ParentHash:       w.ParentHash,
+		ReceiptsRoot:     w.ReceiptsRoot,
+		Sha3Uncles:       w.Sha3Uncles,
+		Size:             uint64(w.Size),
+		StateRoot:        w.StateRoot,
+		Timestamp:        w.Timestamp,
+		TotalDifficulty:  w.TotalDifficulty.Int,
+		Transactions:     w.Transactions,
+		TransactionsRoot: w.TransactionsRoot,
+		Uncles:           w.Uncles,
+		BaseFeePerGas:    w.BaseFeePerGas.Int,
+		BlobGasUsed:      (*uint64)(w.BlobGasUsed),
+		ExcessBlobGas:    (*uint64)(w.ExcessBlobGas),
+	}
+	return nil
+}
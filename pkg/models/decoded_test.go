@@ -0,0 +1,288 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleTransaction() Transaction {
+	return Transaction{
+		BlockHash:            "0x4e3a3754410177e6937ef1f84bba68ea139e8d1a2258c5f85db9f1cd715a1bdd",
+		BlockNumber:          "0x1312d00",
+		From:                 "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48",
+		Gas:                  "0x5208",
+		GasPrice:             "0x4a817c800",
+		MaxFeePerGas:         "0x6fc23ac00",
+		MaxPriorityFeePerGas: "0x3b9aca00",
+		Hash:                 "0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060",
+		Input:                "0x",
+		Nonce:                "0x2a",
+		To:                   "0xdac17f958d2ee523a2206206994597c13d831ec7",
+		TransactionIndex:     "0x0",
+		// 100,000 ETH: does not fit in an int64.
+		Value:   "0x152d02c7e14af6800000",
+		Type:    "0x2",
+		ChainID: "0x1",
+		V:       "0x1",
+		R:       "0x88ff6cf0fefd94db46111149ae4bfc179e9b94721fffd821d38d16464b3f71d0",
+		S:       "0x45e0aff800961cfce805daef7016b9b675c137a6a41a548f7b60a3484c06a33a",
+		YParity: "0x1",
+		AccessList: []AccessListEntry{{
+			Address:     "0xdac17f958d2ee523a2206206994597c13d831ec7",
+			StorageKeys: []string{"0x0000000000000000000000000000000000000000000000000000000000000003"},
+		}},
+	}
+}
+
+func TestTransactionDecode(t *testing.T) {
+	decoded, err := sampleTransaction().Decode()
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if decoded.Value.String() != "100000000000000000000000" {
+		t.Errorf("Value = %s", decoded.Value)
+	}
+	if decoded.BlockNumber != 20000000 || decoded.Gas != 21000 || decoded.Nonce != 42 || decoded.Type != 2 {
+		t.Errorf("unexpected numeric fields: %+v", decoded)
+	}
+	if decoded.From.String() != "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48" {
+		t.Errorf("From = %s", decoded.From)
+	}
+	if decoded.To == nil || decoded.To.String() != "0xdac17f958d2ee523a2206206994597c13d831ec7" {
+		t.Errorf("To = %v", decoded.To)
+	}
+	if decoded.YParity == nil || *decoded.YParity != 1 {
+		t.Errorf("YParity = %v", decoded.YParity)
+	}
+	if len(decoded.Input) != 0 {
+		t.Errorf("Input = %x", decoded.Input)
+	}
+
+	legacy := sampleTransaction()
+	legacy.MaxFeePerGas, legacy.MaxPriorityFeePerGas, legacy.YParity, legacy.To = "", "", "", ""
+	decoded, err = legacy.Decode()
+	if err != nil {
+		t.Fatalf("Decode legacy: %v", err)
+	}
+	if decoded.MaxFeePerGas != nil || decoded.YParity != nil || decoded.To != nil {
+		t.Error("absent fields should decode to nil")
+	}
+
+	bad := sampleTransaction()
+	bad.Gas = "21000"
+	if _, err := bad.Decode(); err == nil || !strings.Contains(err.Error(), "gas") {
+		t.Errorf("expected an error naming the gas field, got %v", err)
+	}
+}
+
+func TestDecodedTransactionJSON(t *testing.T) {
+	tx := sampleTransaction()
+	tx.RecoveredFrom = "0x742d35cc6634c0532925a3b844bc454e4438f44e"
+	decoded, err := tx.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"value":"100000000000000000000000"`,
+		`"gas":21000`,
+		`"blockNumber":20000000`,
+		`"from":"0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"`,
+		`"input":"0x"`,
+		`"recoveredFrom":"0x742d35Cc6634C0532925a3b844Bc454e4438f44e"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON %s does not contain %s", data, want)
+		}
+	}
+
+	var roundTrip DecodedTransaction
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip, decoded) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", roundTrip, decoded)
+	}
+
+	// Hex quantities are accepted on input as well.
+	var fromHex DecodedTransaction
+	if err := json.Unmarshal([]byte(`{"gas":"0x5208","value":"0xde0b6b3a7640000"}`), &fromHex); err != nil {
+		t.Fatal(err)
+	}
+	if fromHex.Gas != 21000 || fromHex.Value.String() != "1000000000000000000" {
+		t.Errorf("hex input decoded to gas=%d value=%s", fromHex.Gas, fromHex.Value)
+	}
+}
+
+func TestBlockDecode(t *testing.T) {
+	var block Block
+	block.Number = "0x1312d00"
+	block.Timestamp = "0x665f0a2b"
+	block.GasLimit = "0x1c9c380"
+	block.Hash = "0xd24fd73f794058a3807db926d8898c6481e902b7edb91ce0d479d6760f276183"
+	block.Miner = "0x95222290dd7278aa3ddd389cc1e1d165cc4bafe5"
+	block.Nonce = "0x0000000000000000"
+	block.Transactions = []Transaction{sampleTransaction()}
+
+	decoded, err := block.Decode()
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if decoded.Number != 20000000 || decoded.GasLimit != 30000000 {
+		t.Errorf("unexpected numeric fields: number=%d gasLimit=%d", decoded.Number, decoded.GasLimit)
+	}
+	if !decoded.Timestamp.Equal(time.Unix(0x665f0a2b, 0)) {
+		t.Errorf("Timestamp = %v", decoded.Timestamp)
+	}
+	if len(decoded.Transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(decoded.Transactions))
+	}
+
+	data, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roundTrip DecodedBlock
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip, decoded) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", roundTrip, decoded)
+	}
+}
+
+func TestDecodeDeposit(t *testing.T) {
+	tx := Transaction{
+		BlockNumber: "0x1",
+		From:        "0x977f82a600a1414e583f7f13623f1ac5d58b1c0b",
+		To:          "0x742d35cc6634c0532925a3b844bc454e4438f44e",
+		Gas:         "0x186a0",
+		Hash:        "0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060",
+		Input:       "0x",
+		Value:       "0xde0b6b3a7640000",
+		Type:        "0x7e",
+		SourceHash:  "0x4e3a3754410177e6937ef1f84bba68ea139e8d1a2258c5f85db9f1cd715a1bdd",
+		Mint:        "0xde0b6b3a7640000",
+	}
+	decoded, err := tx.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Deposit == nil || decoded.Arbitrum != nil {
+		t.Fatalf("expected deposit fields only, got %+v", decoded)
+	}
+	if decoded.Deposit.Mint.String() != "1000000000000000000" || decoded.Deposit.SourceHash.String() != tx.SourceHash {
+		t.Errorf("unexpected deposit fields %+v", decoded.Deposit)
+	}
+
+	data, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"mint":"1000000000000000000"`) {
+		t.Errorf("JSON %s does not contain the mint", data)
+	}
+	var roundTrip DecodedTransaction
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip, decoded) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", roundTrip, decoded)
+	}
+}
+
+func TestDecodeArbitrumSubmitRetryable(t *testing.T) {
+	tx := Transaction{
+		From:             "0x977f82a600a1414e583f7f13623f1ac5d58b1c0b",
+		To:               "0x000000000000000000000000000000000000006e",
+		Hash:             "0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060",
+		Type:             "0x69",
+		RequestID:        "0x00000000000000000000000000000000000000000000000000000000000a1b2c",
+		DepositValue:     "0x2386f26fc10000",
+		RetryTo:          "0x742d35cc6634c0532925a3b844bc454e4438f44e",
+		RetryValue:       "0x2386f26fc10000",
+		RetryData:        "0x",
+		Beneficiary:      "0xdac17f958d2ee523a2206206994597c13d831ec7",
+		RefundTo:         "0xdac17f958d2ee523a2206206994597c13d831ec7",
+		MaxSubmissionFee: "0x1",
+	}
+	decoded, err := tx.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	arb := decoded.Arbitrum
+	if arb == nil || decoded.Deposit != nil {
+		t.Fatalf("expected Arbitrum fields only, got %+v", decoded)
+	}
+	if arb.RetryTo == nil || arb.RetryTo.String() != tx.RetryTo || arb.DepositValue.String() != "10000000000000000" || arb.TicketID != nil {
+		t.Errorf("unexpected Arbitrum fields %+v", arb)
+	}
+
+	data, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roundTrip DecodedTransaction
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip, decoded) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", roundTrip, decoded)
+	}
+
+	// Retry recipient, beneficiary and refund address are all touched;
+	// duplicates are listed once.
+	addresses := tx.Addresses(nil)
+	if want := []string{tx.From, tx.To, tx.RetryTo, tx.Beneficiary}; !reflect.DeepEqual(addresses, want) {
+		t.Errorf("Addresses() = %v, want %v", addresses, want)
+	}
+}
+
+func TestDecodeBlobTransaction(t *testing.T) {
+	tx := sampleTransaction()
+	tx.Type = "0x3"
+	tx.MaxFeePerBlobGas = "0x3b9aca00"
+	tx.BlobVersionedHashes = []string{"0x01b0a4cdd5f55589f5c5b4d46c76704bb6ce95c0a8c09f77f197a57808dded28"}
+	tx.Fees = &Fees{
+		GasUsed:           "0x5208",
+		EffectiveGasPrice: "0x3b9aca00",
+		ExecutionFee:      "0x1319718a5000",
+		BlobGasUsed:       "0x20000",
+		BlobBaseFee:       "0x7c647c",
+		BlobFee:           "0xf8c8f80000",
+		TotalFee:          "0x14123a825000",
+	}
+	decoded, err := tx.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.MaxFeePerBlobGas.String() != "1000000000" || len(decoded.BlobVersionedHashes) != 1 {
+		t.Errorf("unexpected blob fields %+v", decoded)
+	}
+	if f := decoded.Fees; f == nil || f.BlobGasUsed == nil || *f.BlobGasUsed != 131072 || f.TotalFee.String() != "22068523585536" {
+		t.Errorf("unexpected fees %+v", decoded.Fees)
+	}
+
+	data, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"blobFee":"1068523585536"`) {
+		t.Errorf("JSON %s does not contain the blob fee", data)
+	}
+	var roundTrip DecodedTransaction
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(roundTrip, decoded) {
+		t.Errorf("round trip mismatch:\n got %+v\nwant %+v", roundTrip, decoded)
+	}
+}
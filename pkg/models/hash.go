@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/hex"
+	"errors"
+	"eth-parser/pkg/utils"
+)
+
+const HashLength = 32
+
+// Hash is a 32 byte Keccak-256 digest such as a block or transaction hash.
+type Hash [HashLength]byte
+
+var errInvalidHash = errors.New("invalid hash")
+
+// HexToHash parses a 0x-prefixed, 64 hex digit hash in any letter case.
+func HexToHash(s string) (Hash, error) {
+	var h Hash
+	if err := utils.DecodeFixedBytes(s, h[:]); err != nil {
+		return Hash{}, errInvalidHash
+	}
+	return h, nil
+}
+
+// String returns the lowercase 0x-prefixed hex form.
+func (h Hash) String() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *Hash) UnmarshalText(text []byte) error {
+	parsed, err := HexToHash(string(text))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"eth-parser/pkg/utils"
+	"strings"
+)
+
+// Transaction types used by L2 chains next to the Ethereum ones (0x0-0x4).
+const (
+	// DepositTxType is an OP Stack (Optimism, Base) deposit: a message from
+	// L1 that can mint ETH on L2. It carries no signature.
+	DepositTxType = 0x7e
+
+	// Arbitrum system transactions, created by the sequencer from L1
+	// messages rather than signed by users.
+	ArbitrumDepositTxType         = 0x64 // ETH deposit from L1
+	ArbitrumUnsignedTxType        = 0x65 // L1 contract call to L2
+	ArbitrumContractTxType        = 0x66 // L1 contract call with a request id
+	ArbitrumRetryTxType           = 0x68 // redemption of a retryable ticket
+	ArbitrumSubmitRetryableTxType = 0x69 // creation of a retryable ticket
+	ArbitrumInternalTxType        = 0x6a // ArbOS bookkeeping
+)
+
+// TxType returns the EIP-2718 type of tx, 0 for legacy transactions and
+// for types that cannot be parsed.
+func (tx Transaction) TxType() uint64 {
+	if tx.Type == "" {
+		return 0
+	}
+	txType, err := utils.DecodeUint64(tx.Type)
+	if err != nil {
+		return 0
+	}
+	return txType
+}
+
+// IsDeposit reports whether tx is an OP Stack deposit.
+func (tx Transaction) IsDeposit() bool {
+	return tx.TxType() == DepositTxType
+}
+
+// IsArbitrumSystemTx reports whether tx is one of the Arbitrum transaction
+// types created from L1 messages or by ArbOS.
+func (tx Transaction) IsArbitrumSystemTx() bool {
+	return isArbitrumSystemType(tx.TxType())
+}
+
+// isArbitrumSystemType excludes 0x67, which Arbitrum does not use.
+func isArbitrumSystemType(txType uint64) bool {
+	return txType >= ArbitrumDepositTxType && txType <= ArbitrumInternalTxType && txType != 0x67
+}
+
+// Addresses appends every account whose balance tx can credit or debit
+// directly: the sender, the recovered sender when it differs, the
+// recipient, the parties of a token transfer, and for Arbitrum retryable
+// tickets also the retry recipient, the beneficiary and the refund address.
+// Empty and repeated addresses are skipped; buf lets callers avoid an
+// allocation.
+func (tx Transaction) Addresses(buf []string) []string {
+	buf = appendAddress(buf, tx.From)
+	buf = appendAddress(buf, tx.RecoveredFrom)
+	buf = appendAddress(buf, tx.To)
+	if tx.TokenTransfer != nil {
+		buf = appendAddress(buf, tx.TokenTransfer.From)
+		buf = appendAddress(buf, tx.TokenTransfer.To)
+	}
+	switch tx.TxType() {
+	case ArbitrumSubmitRetryableTxType:
+		buf = appendAddress(buf, tx.RetryTo)
+		buf = appendAddress(buf, tx.Beneficiary)
+		buf = appendAddress(buf, tx.RefundTo)
+	case ArbitrumRetryTxType:
+		buf = appendAddress(buf, tx.RefundTo)
+	}
+	return buf
+}
+
+func appendAddress(buf []string, address string) []string {
+	if address == "" {
+		return buf
+	}
+	for _, existing := range buf {
+		if strings.EqualFold(existing, address) {
+			return buf
+		}
+	}
+	return append(buf, address)
+}
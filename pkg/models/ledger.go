@@ -0,0 +1,55 @@
+package models
+
+// LedgerDirection says whether a ledger entry adds to or takes from the
+// balance of its address.
+type LedgerDirection string
+
+const (
+	LedgerCredit LedgerDirection = "credit"
+	LedgerDebit  LedgerDirection = "debit"
+)
+
+// LedgerKind is the activity behind a ledger entry.
+type LedgerKind string
+
+const (
+	// LedgerOpening is the balance read from the node when the ledger of an
+	// address was opened. It replaces the running balance rather than
+	// adding to it.
+	LedgerOpening LedgerKind = "opening"
+	// LedgerTransfer is the value of a transaction sent or received.
+	LedgerTransfer LedgerKind = "transfer"
+	// LedgerFee is the gas, blob gas and L1 data fee paid by a sender.
+	LedgerFee LedgerKind = "fee"
+	// LedgerInternal is value moved by a contract call inside a transaction.
+	LedgerInternal LedgerKind = "internal"
+	// LedgerWithdrawal is a validator withdrawal.
+	LedgerWithdrawal LedgerKind = "withdrawal"
+	// LedgerMint is ETH minted by an OP Stack deposit.
+	LedgerMint LedgerKind = "mint"
+)
+
+// LedgerEntry is one change to the ETH balance of an address. Amount and
+// Balance are decimal wei; Balance is the running balance after the entry
+// and is only set in balance histories.
+type LedgerEntry struct {
+	Address         string          `json:"address"`
+	BlockNumber     int64           `json:"blockNumber"`
+	TransactionHash string          `json:"transactionHash,omitempty"`
+	Kind            LedgerKind      `json:"kind"`
+	Direction       LedgerDirection `json:"direction"`
+	Amount          string          `json:"amount"`
+	Balance         string          `json:"balance,omitempty"`
+}
+
+// Reconciliation compares the balance computed from the ledger of an
+// address with the balance the node reports at the same block. Difference is
+// OnChain minus Computed, in decimal wei.
+type Reconciliation struct {
+	Address     string `json:"address"`
+	BlockNumber int64  `json:"blockNumber"`
+	Computed    string `json:"computed"`
+	OnChain     string `json:"onChain"`
+	Difference  string `json:"difference"`
+	Reconciled  bool   `json:"reconciled"`
+}
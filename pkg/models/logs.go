@@ -0,0 +1,190 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxFilterTopics is the number of topic positions a log can have: the event
+// signature and up to three indexed parameters.
+const MaxFilterTopics = 4
+
+var errInvalidFilter = errors.New("invalid log filter")
+
+// LogFilter selects logs with the semantics of eth_getLogs filter objects. A
+// log matches when it was emitted by one of Addresses (any contract when
+// empty) and, for every position i in Topics, its i-th topic is one of
+// Topics[i]. An empty position matches any topic, but the log must still
+// have a topic there.
+type LogFilter struct {
+	Addresses []Address
+	Topics    [][]Hash
+}
+
+// logFilterJSON is the wire form, as accepted by eth_getLogs: address is a
+// single address or a list, and each topic position is null, a single topic
+// or a list of alternatives.
+type logFilterJSON struct {
+	Address json.RawMessage   `json:"address,omitempty"`
+	Topics  []json.RawMessage `json:"topics,omitempty"`
+}
+
+func (f LogFilter) MarshalJSON() ([]byte, error) {
+	topics := make([]interface{}, len(f.Topics))
+	for i, position := range f.Topics {
+		if len(position) > 0 {
+			topics[i] = position
+		}
+	}
+	addresses := f.Addresses
+	if addresses == nil {
+		addresses = []Address{}
+	}
+	return json.Marshal(struct {
+		Address []Address     `json:"address"`
+		Topics  []interface{} `json:"topics"`
+	}{addresses, topics})
+}
+
+// UnmarshalJSON parses user input strictly: mixed-case addresses must carry a
+// valid EIP-55 checksum and topics must be 32 byte hex values.
+func (f *LogFilter) UnmarshalJSON(data []byte) error {
+	var w logFilterJSON
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	var filter LogFilter
+
+	var addresses []string
+	if err := unmarshalOneOrMany(w.Address, &addresses); err != nil {
+		return fmt.Errorf("%w: address: %v", errInvalidFilter, err)
+	}
+	for _, s := range addresses {
+		address, err := ParseAddress(s)
+		if err != nil {
+			return fmt.Errorf("%w: address %q: %v", errInvalidFilter, s, err)
+		}
+		filter.Addresses = append(filter.Addresses, address)
+	}
+
+	if len(w.Topics) > MaxFilterTopics {
+		return fmt.Errorf("%w: %d topic positions, at most %d", errInvalidFilter, len(w.Topics), MaxFilterTopics)
+	}
+	for i, raw := range w.Topics {
+		var topics []string
+		if err := unmarshalOneOrMany(raw, &topics); err != nil {
+			return fmt.Errorf("%w: topics[%d]: %v", errInvalidFilter, i, err)
+		}
+		position := []Hash{}
+		for _, s := range topics {
+			topic, err := HexToHash(s)
+			if err != nil {
+				return fmt.Errorf("%w: topics[%d] %q: %v", errInvalidFilter, i, s, err)
+			}
+			position = append(position, topic)
+		}
+		filter.Topics = append(filter.Topics, position)
+	}
+
+	*f = filter
+	return nil
+}
+
+// unmarshalOneOrMany accepts null, a single string or a list of strings.
+func unmarshalOneOrMany(data json.RawMessage, out *[]string) error {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	if data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*out = []string{s}
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// Matches reports whether l passes the filter.
+func (f LogFilter) Matches(l Log) bool {
+	if len(f.Addresses) > 0 {
+		address, err := HexToAddress(l.Address)
+		if err != nil || !containsAddress(f.Addresses, address) {
+			return false
+		}
+	}
+	if len(f.Topics) > len(l.Topics) {
+		return false
+	}
+	for i, position := range f.Topics {
+		if len(position) == 0 {
+			continue
+		}
+		topic, err := HexToHash(l.Topics[i])
+		if err != nil || !containsHash(position, topic) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAddress(addresses []Address, address Address) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+func containsHash(hashes []Hash, hash Hash) bool {
+	for _, h := range hashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// LogSubscription is a stored log filter. Logs it matched are retrieved by
+// its ID, by the tenant that created it only.
+type LogSubscription struct {
+	ID        string    `json:"id"`
+	Tenant    string    `json:"tenant"`
+	Filter    LogFilter `json:"filter"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// String describes the filter for log lines.
+func (f LogFilter) String() string {
+	var b strings.Builder
+	b.WriteString("address=[")
+	for i, a := range f.Addresses {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(a.String())
+	}
+	b.WriteString("] topics=[")
+	for i, position := range f.Topics {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if len(position) == 0 {
+			b.WriteString("*")
+			continue
+		}
+		for j, topic := range position {
+			if j > 0 {
+				b.WriteByte('|')
+			}
+			b.WriteString(topic.String())
+		}
+	}
+	b.WriteString("]")
+	return b.String()
+}
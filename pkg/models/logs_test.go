@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLogFilterJSON(t *testing.T) {
+	const (
+		token    = "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+		transfer = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+		from     = "0x000000000000000000000000742d35cc6634c0532925a3b844bc454e4438f44e"
+		to       = "0x000000000000000000000000a0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+	)
+	var filter LogFilter
+	input := `{"address":"` + token + `","topics":["` + transfer + `",null,["` + from + `","` + to + `"]]}`
+	if err := json.Unmarshal([]byte(input), &filter); err != nil {
+		t.Fatal(err)
+	}
+	if len(filter.Addresses) != 1 || len(filter.Topics) != 3 || len(filter.Topics[1]) != 0 || len(filter.Topics[2]) != 2 {
+		t.Fatalf("parsed filter %s", filter)
+	}
+
+	data, err := json.Marshal(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"address":["` + token + `"],"topics":[["` + transfer + `"],null,["` + from + `","` + to + `"]]}`
+	if string(data) != want {
+		t.Errorf("marshaled filter:\n got %s\nwant %s", data, want)
+	}
+
+	for _, invalid := range []string{
+		`{"address":"0xDAC17F958D2ee523a2206206994597C13D831ec7"}`,
+		`{"address":["0x1234"]}`,
+		`{"topics":["0x1234"]}`,
+		`{"topics":[null,null,null,null,null]}`,
+		`{"topics":[42]}`,
+	} {
+		if err := json.Unmarshal([]byte(invalid), &filter); err == nil {
+			t.Errorf("accepted invalid filter %s", invalid)
+		}
+	}
+}
+
+func TestLogFilterMatches(t *testing.T) {
+	const (
+		token    = "0xdac17f958d2ee523a2206206994597c13d831ec7"
+		transfer = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+		approval = "0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"
+		from     = "0x000000000000000000000000742d35cc6634c0532925a3b844bc454e4438f44e"
+	)
+	mustFilter := func(s string) LogFilter {
+		var f LogFilter
+		if err := json.Unmarshal([]byte(s), &f); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	log := Log{Address: token, Topics: []string{transfer, from, from}}
+
+	for _, tc := range []struct {
+		filter string
+		want   bool
+	}{
+		{`{"address":"` + token + `"}`, true},
+		{`{"address":["0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"]}`, false},
+		{`{"topics":["` + transfer + `"]}`, true},
+		{`{"topics":[["` + approval + `","` + transfer + `"]]}`, true},
+		{`{"topics":["` + approval + `"]}`, false},
+		{`{"topics":[null,null,"` + from + `"]}`, true},
+		{`{"topics":[null,"` + transfer + `"]}`, false},
+		// The log has three topics; a fourth position cannot match even as
+		// a wildcard.
+		{`{"topics":[null,null,null,null]}`, false},
+	} {
+		if got := mustFilter(tc.filter).Matches(log); got != tc.want {
+			t.Errorf("%s matches = %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+}
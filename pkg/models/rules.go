@@ -0,0 +1,202 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ErrInvalidRule is returned for alert rules that cannot be evaluated.
+var ErrInvalidRule = errors.New("invalid alert rule")
+
+// RuleType is the condition an alert rule checks.
+type RuleType string
+
+const (
+	// RuleOutboundValue fires on a transaction sent by a watched address
+	// moving more than Threshold, in wei or, with Token, in the token's
+	// smallest unit.
+	RuleOutboundValue RuleType = "outbound_value"
+	// RuleCounterparty fires on a transaction sent by a watched address to
+	// an address that is not in Allowlist. For token transfers the
+	// counterparty is the recipient of the tokens.
+	RuleCounterparty RuleType = "counterparty_not_allowlisted"
+	// RuleBalanceBelow fires when the balance of a watched address drops
+	// below Threshold, and again only after it recovered.
+	RuleBalanceBelow RuleType = "balance_below"
+	// RuleFailedTransaction fires on a reverted transaction sent by a
+	// watched address.
+	RuleFailedTransaction RuleType = "failed_transaction"
+)
+
+// AlertSeverity ranks alerts for whoever is paged by them.
+type AlertSeverity string
+
+const (
+	SeverityInfo     AlertSeverity = "info"
+	SeverityWarning  AlertSeverity = "warning"
+	SeverityCritical AlertSeverity = "critical"
+)
+
+// AlertRule is a condition evaluated against the activity of the addresses
+// its tenant subscribes to after every block. Addresses limits the rule to
+// some of them; when empty it watches every one.
+type AlertRule struct {
+	ID        string        `json:"id"`
+	Tenant    string        `json:"tenant"`
+	Name      string        `json:"name"`
+	Type      RuleType      `json:"type"`
+	Severity  AlertSeverity `json:"severity"`
+	Addresses []Address     `json:"addresses,omitempty"`
+	Token     *Address      `json:"token,omitempty"`
+	Threshold string        `json:"threshold,omitempty"`
+	Allowlist []Address     `json:"allowlist,omitempty"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+// alertRuleJSON is the wire form, with addresses as strings so that they
+// are parsed strictly.
+type alertRuleJSON struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Type      RuleType      `json:"type"`
+	Severity  AlertSeverity `json:"severity"`
+	Addresses []string      `json:"addresses"`
+	Token     string        `json:"token"`
+	Threshold string        `json:"threshold"`
+	Allowlist []string      `json:"allowlist"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
+
+// UnmarshalJSON parses user input strictly: mixed-case addresses must carry
+// a valid EIP-55 checksum. The rule is not validated, see Validate.
+func (r *AlertRule) UnmarshalJSON(data []byte) error {
+	var w alertRuleJSON
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	rule := AlertRule{
+		ID:        w.ID,
+		Name:      w.Name,
+		Type:      w.Type,
+		Severity:  w.Severity,
+		Threshold: w.Threshold,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+	var err error
+	if rule.Addresses, err = parseAddressList("addresses", w.Addresses); err != nil {
+		return err
+	}
+	if rule.Allowlist, err = parseAddressList("allowlist", w.Allowlist); err != nil {
+		return err
+	}
+	if w.Token != "" {
+		token, err := ParseAddress(w.Token)
+		if err != nil {
+			return fmt.Errorf("%w: token %q: %v", ErrInvalidRule, w.Token, err)
+		}
+		rule.Token = &token
+	}
+	*r = rule
+	return nil
+}
+
+func parseAddressList(field string, list []string) ([]Address, error) {
+	var addresses []Address
+	for _, s := range list {
+		address, err := ParseAddress(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s %q: %v", ErrInvalidRule, field, s, err)
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// Validate checks that the fields the rule's type needs are set, and fills
+// in the default severity, warning, and the default name, its type.
+func (r *AlertRule) Validate() error {
+	switch r.Severity {
+	case "":
+		r.Severity = SeverityWarning
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("%w: unknown severity %q", ErrInvalidRule, r.Severity)
+	}
+	if r.Name == "" {
+		r.Name = string(r.Type)
+	}
+
+	switch r.Type {
+	case RuleOutboundValue, RuleBalanceBelow:
+		if _, err := r.ThresholdValue(); err != nil {
+			return err
+		}
+	case RuleCounterparty:
+		if len(r.Allowlist) == 0 {
+			return fmt.Errorf("%w: %s needs an allowlist", ErrInvalidRule, r.Type)
+		}
+	case RuleFailedTransaction:
+	case "":
+		return fmt.Errorf("%w: missing type", ErrInvalidRule)
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidRule, r.Type)
+	}
+	if r.Token != nil && r.Type != RuleOutboundValue && r.Type != RuleBalanceBelow {
+		return fmt.Errorf("%w: %s does not take a token", ErrInvalidRule, r.Type)
+	}
+	if r.Threshold != "" && r.Type != RuleOutboundValue && r.Type != RuleBalanceBelow {
+		return fmt.Errorf("%w: %s does not take a threshold", ErrInvalidRule, r.Type)
+	}
+	if len(r.Allowlist) > 0 && r.Type != RuleCounterparty {
+		return fmt.Errorf("%w: %s does not take an allowlist", ErrInvalidRule, r.Type)
+	}
+	return nil
+}
+
+// ThresholdValue parses Threshold, a non-negative decimal integer.
+func (r AlertRule) ThresholdValue() (*big.Int, error) {
+	if r.Threshold == "" {
+		return nil, fmt.Errorf("%w: %s needs a threshold", ErrInvalidRule, r.Type)
+	}
+	threshold, ok := new(big.Int).SetString(r.Threshold, 10)
+	if !ok || threshold.Sign() < 0 {
+		return nil, fmt.Errorf("%w: threshold %q is not a non-negative decimal integer", ErrInvalidRule, r.Threshold)
+	}
+	return threshold, nil
+}
+
+// Watches reports whether the rule applies to address.
+func (r AlertRule) Watches(address Address) bool {
+	return len(r.Addresses) == 0 || containsAddress(r.Addresses, address)
+}
+
+// Allows reports whether address is on the rule's allowlist.
+func (r AlertRule) Allows(address Address) bool {
+	return containsAddress(r.Allowlist, address)
+}
+
+// Alert is raised when a rule matches. DedupKey identifies the condition
+// that raised it: an alert whose key is already active is not raised again,
+// so a rule fires once per transaction, and once per episode of a balance
+// below its threshold. ResolvedAt is set when such an episode ends. Alerts
+// belong to the tenant of their rule.
+type Alert struct {
+	ID              string        `json:"id"`
+	Tenant          string        `json:"tenant"`
+	RuleID          string        `json:"ruleId"`
+	RuleName        string        `json:"ruleName"`
+	Severity        AlertSeverity `json:"severity"`
+	DedupKey        string        `json:"dedupKey"`
+	Address         string        `json:"address"`
+	TransactionHash string        `json:"transactionHash,omitempty"`
+	BlockNumber     int64         `json:"blockNumber"`
+	Message         string        `json:"message"`
+	RaisedAt        time.Time     `json:"raisedAt"`
+	ResolvedAt      *time.Time    `json:"resolvedAt,omitempty"`
+}
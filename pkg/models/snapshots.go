@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// BalanceSnapshot is the balance of an address read from the node at a
+// block. Token is empty for ETH and otherwise the ERC-20 contract whose
+// balanceOf was called; Balance is a decimal integer in wei or the token's
+// smallest unit.
+type BalanceSnapshot struct {
+	Address     string    `json:"address"`
+	Token       string    `json:"token,omitempty"`
+	BlockNumber int64     `json:"blockNumber"`
+	Balance     string    `json:"balance"`
+	TakenAt     time.Time `json:"takenAt"`
+}
+
+// BalanceDrift is a balance change between two consecutive snapshots that
+// the activity recorded in between does not account for, i.e. activity the
+// parser did not match. Change is Balance minus PreviousBalance; Recorded is
+// the change the recorded ledger entries or token transfers add up to.
+type BalanceDrift struct {
+	Address         string    `json:"address"`
+	Token           string    `json:"token,omitempty"`
+	FromBlock       int64     `json:"fromBlock"`
+	ToBlock         int64     `json:"toBlock"`
+	PreviousBalance string    `json:"previousBalance"`
+	Balance         string    `json:"balance"`
+	Change          string    `json:"change"`
+	Recorded        string    `json:"recorded"`
+	DetectedAt      time.Time `json:"detectedAt"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+// Subscription is a watched address with the metadata its owners keep on
+// it. Transactions of blocks before StartBlock are not recorded for it.
+type Subscription struct {
+	Address    string    `json:"address"`
+	Label      string    `json:"label,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	OwnerTeam  string    `json:"ownerTeam,omitempty"`
+	Notes      string    `json:"notes,omitempty"`
+	StartBlock int64     `json:"startBlock"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// NormalizeTags lowercases and sorts tags and drops duplicates. Tags must
+// not be empty or contain spaces or commas.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || strings.ContainsFunc(tag, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTag, tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// HasTag reports whether the subscription carries tag, in any letter case.
+func (s Subscription) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Token is the metadata of an ERC-20 contract as read from its name(),
+// symbol() and decimals() functions. Contracts that do not implement one of
+// them leave the field empty; Decimals is nil when it is unknown, in which
+// case amounts cannot be formatted.
+type Token struct {
+	Address    string    `json:"address"`
+	Name       string    `json:"name,omitempty"`
+	Symbol     string    `json:"symbol,omitempty"`
+	Decimals   *uint8    `json:"decimals,omitempty"`
+	ResolvedAt time.Time `json:"resolvedAt"`
+}
+
+// TokenTransfer is an ERC-20 transfer made by a call to transfer or
+// transferFrom. Amount is the raw integer amount in decimal; FormattedAmount
+// is the same amount in whole tokens, set when the decimals of the token are
+// known.
+type TokenTransfer struct {
+	Token           string `json:"token"`
+	Symbol          string `json:"symbol,omitempty"`
+	Decimals        *uint8 `json:"decimals,omitempty"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+	Amount          string `json:"amount"`
+	FormattedAmount string `json:"formattedAmount,omitempty"`
+}
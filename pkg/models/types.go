@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type JSONRPCRequest struct {
 	JsonRPC string        `json:"jsonrpc"`
 	Method  string        `json:"method"`
@@ -14,27 +16,53 @@ type JSONRPCResponse struct {
 	ID      int         `json:"id"`
 }
 
+// Header holds the block fields returned by eth_getBlockByNumber regardless of
+// whether full transaction objects were requested.
+type Header struct {
+	Difficulty       string   `json:"difficulty"`
+	ExtraData        string   `json:"extraData"`
+	GasLimit         string   `json:"gasLimit"`
+	GasUsed          string   `json:"gasUsed"`
+	Hash             string   `json:"hash"`
+	LogsBloom        string   `json:"logsBloom"`
+	Miner            string   `json:"miner"`
+	MixHash          string   `json:"mixHash"`
+	Nonce            string   `json:"nonce"`
+	Number           string   `json:"number"`
+	ParentHash       string   `json:"parentHash"`
+	ReceiptsRoot     string   `json:"receiptsRoot"`
+	Sha3Uncles       string   `json:"sha3Uncles"`
+	Size             string   `json:"size"`
+	StateRoot        string   `json:"stateRoot"`
+	Timestamp        string   `json:"timestamp"`
+	TotalDifficulty  string   `json:"totalDifficulty"`
+	TransactionsRoot string   `json:"transactionsRoot"`
+	Uncles           []string `json:"uncles"`
+
+	// Fields added by later forks, absent on older blocks.
+	BaseFeePerGas         string `json:"baseFeePerGas,omitempty"`         // London
+	WithdrawalsRoot       string `json:"withdrawalsRoot,omitempty"`       // Shanghai
+	BlobGasUsed           string `json:"blobGasUsed,omitempty"`           // Cancun
+	ExcessBlobGas         string `json:"excessBlobGas,omitempty"`         // Cancun
+	ParentBeaconBlockRoot string `json:"parentBeaconBlockRoot,omitempty"` // Cancun
+	RequestsHash          string `json:"requestsHash,omitempty"`          // Prague
+
+	// Withdrawals are not part of the header, but returned with and without
+	// full transaction objects; only their root is hashed into the header.
+	Withdrawals []Withdrawal `json:"withdrawals,omitempty"` // Shanghai
+}
+
+// Block is a block fetched with full transaction objects.
 type Block struct {
-	Difficulty       string        `json:"difficulty"`
-	ExtraData        string        `json:"extraData"`
-	GasLimit         string        `json:"gasLimit"`
-	GasUsed          string        `json:"gasUsed"`
-	Hash             string        `json:"hash"`
-	LogsBloom        string        `json:"logsBloom"`
-	Miner            string        `json:"miner"`
-	MixHash          string        `json:"mixHash"`
-	Nonce            string        `json:"nonce"`
-	Number           string        `json:"number"`
-	ParentHash       string        `json:"parentHash"`
-	ReceiptsRoot     string        `json:"receiptsRoot"`
-	Sha3Uncles       string        `json:"sha3Uncles"`
-	Size             string        `json:"size"`
-	StateRoot        string        `json:"stateRoot"`
-	Timestamp        string        `json:"timestamp"`
-	TotalDifficulty  string        `json:"totalDifficulty"`
-	Transactions     []Transaction `json:"transactions"`
-	TransactionsRoot string        `json:"transactionsRoot"`
-	Uncles           []string      `json:"uncles"`
+	Header
+	Transactions []Transaction `json:"transactions"`
+}
+
+// BlockHeader is a block fetched without transaction bodies; only the
+// transaction hashes are included.
+type BlockHeader struct {
+	Header
+	TransactionHashes []string `json:"transactions"`
 }
 
 type Transaction struct {
@@ -58,9 +86,153 @@ type Transaction struct {
 	R                    string            `json:"r"`
 	S                    string            `json:"s"`
 	YParity              string            `json:"yParity"`
+	// Blob transactions (type 0x3, EIP-4844).
+	MaxFeePerBlobGas    string   `json:"maxFeePerBlobGas,omitempty"`
+	BlobVersionedHashes []string `json:"blobVersionedHashes,omitempty"`
+	// Set code transactions (type 0x4, EIP-7702).
+	AuthorizationList []Authorization `json:"authorizationList,omitempty"`
+	// OP Stack deposits (type 0x7e).
+	SourceHash string `json:"sourceHash,omitempty"`
+	Mint       string `json:"mint,omitempty"`
+	IsSystemTx bool   `json:"isSystemTx,omitempty"`
+	// Arbitrum system transactions (types 0x64-0x6a).
+	RequestID           string `json:"requestId,omitempty"`
+	TicketID            string `json:"ticketId,omitempty"`
+	RefundTo            string `json:"refundTo,omitempty"`
+	MaxRefund           string `json:"maxRefund,omitempty"`
+	SubmissionFeeRefund string `json:"submissionFeeRefund,omitempty"`
+	L1BaseFee           string `json:"l1BaseFee,omitempty"`
+	DepositValue        string `json:"depositValue,omitempty"`
+	RetryTo             string `json:"retryTo,omitempty"`
+	RetryValue          string `json:"retryValue,omitempty"`
+	RetryData           string `json:"retryData,omitempty"`
+	Beneficiary         string `json:"beneficiary,omitempty"`
+	MaxSubmissionFee    string `json:"maxSubmissionFee,omitempty"`
+
+	// Fees is computed by the parser once the transaction is mined; it is
+	// not part of the node's transaction object.
+	Fees *Fees `json:"fees,omitempty"`
+	// DecodedInput is set by the parser for calls to contracts with a
+	// registered ABI.
+	DecodedInput *DecodedInput `json:"decodedInput,omitempty"`
+	// Method is the text signature of the called function when its selector
+	// is in the signature database, e.g. "transfer(address,uint256)".
+	Method string `json:"method,omitempty"`
+	// TokenTransfer is set by the parser for calls that move ERC-20 tokens.
+	TokenTransfer *TokenTransfer `json:"tokenTransfer,omitempty"`
+	// RecoveredFrom is set by the parser, when verifying senders, to the
+	// sender recovered from the signature if the node reported another one.
+	RecoveredFrom string `json:"recoveredFrom,omitempty"`
+}
+
+// DecodedInput is call data decoded with the ABI of the called contract.
+type DecodedInput struct {
+	Method    string         `json:"method"`
+	Signature string         `json:"signature"`
+	Selector  string         `json:"selector"`
+	Params    []DecodedParam `json:"params"`
+}
+
+// DecodedParam is one argument of a call. Value holds integers as decimal
+// strings, addresses checksummed, bytes as hex, arrays as lists and tuples
+// as lists of DecodedParam.
+type DecodedParam struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// Fees is what a mined transaction paid, as hex quantities in wei. Blob
+// fields are only set for blob transactions.
+type Fees struct {
+	GasUsed           string `json:"gasUsed"`
+	EffectiveGasPrice string `json:"effectiveGasPrice"`
+	ExecutionFee      string `json:"executionFee"`
+	BlobGasUsed       string `json:"blobGasUsed,omitempty"`
+	BlobBaseFee       string `json:"blobBaseFee,omitempty"`
+	BlobFee           string `json:"blobFee,omitempty"`
+	TotalFee          string `json:"totalFee"`
+}
+
+// Receipt is the subset of eth_getTransactionReceipt the parser uses.
+type Receipt struct {
+	TransactionHash   string `json:"transactionHash"`
+	BlockHash         string `json:"blockHash"`
+	BlockNumber       string `json:"blockNumber"`
+	Status            string `json:"status"`
+	GasUsed           string `json:"gasUsed"`
+	EffectiveGasPrice string `json:"effectiveGasPrice"`
+	BlobGasUsed       string `json:"blobGasUsed,omitempty"`
+	BlobGasPrice      string `json:"blobGasPrice,omitempty"`
+	// L1Fee is the data fee OP Stack chains charge on top of L2 gas.
+	L1Fee string `json:"l1Fee,omitempty"`
+	Logs  []Log  `json:"logs"`
+}
+
+type Log struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+	LogIndex         string   `json:"logIndex"`
+	Removed          bool     `json:"removed"`
+	// Event is the text signature of the event when its first topic is in
+	// the signature database; set by the parser.
+	Event string `json:"event,omitempty"`
 }
 
 type AccessListEntry struct {
 	Address     string   `json:"address"`
 	StorageKeys []string `json:"storageKeys"`
 }
+
+// Authorization is an EIP-7702 authorization tuple of a set code transaction.
+type Authorization struct {
+	ChainID string `json:"chainId"`
+	Address string `json:"address"`
+	Nonce   string `json:"nonce"`
+	YParity string `json:"yParity"`
+	R       string `json:"r"`
+	S       string `json:"s"`
+}
+
+// Withdrawal is a validator withdrawal (EIP-4895) credited to Address at the
+// end of a block. Amount is in gwei.
+type Withdrawal struct {
+	Index          string `json:"index"`
+	ValidatorIndex string `json:"validatorIndex"`
+	Address        string `json:"address"`
+	Amount         string `json:"amount"`
+}
+
+// CallFrame is a call as reported by the callTracer of debug_traceBlock*.
+// Calls holds the calls it made; Error is set when the call reverted, which
+// undoes everything it and its subcalls did.
+type CallFrame struct {
+	Type  string      `json:"type"`
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Value string      `json:"value,omitempty"`
+	Error string      `json:"error,omitempty"`
+	Calls []CallFrame `json:"calls,omitempty"`
+}
+
+// TransactionTrace is the call tree of one transaction of a traced block.
+type TransactionTrace struct {
+	TxHash string    `json:"txHash"`
+	Result CallFrame `json:"result"`
+}
+
+// RejectedBlock records a block that failed verification against the node
+// that served it.
+type RejectedBlock struct {
+	ChainID    uint64    `json:"chainId"`
+	Number     int64     `json:"number"`
+	Hash       string    `json:"hash"`
+	Endpoint   string    `json:"endpoint"`
+	Reason     string    `json:"reason"`
+	RejectedAt time.Time `json:"rejectedAt"`
+}
@@ -0,0 +1,69 @@
+// Package rlp implements the Recursive Length Prefix encoding used to
+// serialise Ethereum headers and transactions for hashing.
+package rlp
+
+import (
+	"encoding/binary"
+	"math/big"
+	"math/bits"
+)
+
+var (
+	// EmptyString is the encoding of the empty byte string.
+	EmptyString = []byte{0x80}
+	// EmptyList is the encoding of the empty list.
+	EmptyList = []byte{0xc0}
+)
+
+// EncodeBytes encodes b as a byte string.
+func EncodeBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return []byte{b[0]}
+	}
+	return append(header(0x80, len(b)), b...)
+}
+
+// EncodeUint64 encodes v as a big-endian byte string without leading zeros;
+// zero is the empty string.
+func EncodeUint64(v uint64) []byte {
+	if v == 0 {
+		return EmptyString
+	}
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return EncodeBytes(buf[bits.LeadingZeros64(v)/8:])
+}
+
+// EncodeBig encodes a non-negative integer like EncodeUint64. A nil value
+// encodes as zero.
+func EncodeBig(v *big.Int) []byte {
+	if v == nil || v.Sign() == 0 {
+		return EmptyString
+	}
+	return EncodeBytes(v.Bytes())
+}
+
+// EncodeList wraps already encoded items in a list.
+func EncodeList(items ...[]byte) []byte {
+	size := 0
+	for _, item := range items {
+		size += len(item)
+	}
+	out := header(0xc0, size)
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// header returns the prefix for a payload of size bytes; offset is 0x80 for
+// strings and 0xc0 for lists.
+func header(offset byte, size int) []byte {
+	if size < 56 {
+		return []byte{offset + byte(size)}
+	}
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(size))
+	sizeBytes := buf[bits.LeadingZeros64(uint64(size))/8:]
+	return append([]byte{offset + 55 + byte(len(sizeBytes))}, sizeBytes...)
+}
@@ -0,0 +1,49 @@
+package rlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	longString := []byte(strings.Repeat("a", 56))
+
+	testCases := []struct {
+		name     string
+		encoded  []byte
+		expected string
+	}{
+		{"empty string", EncodeBytes(nil), "80"},
+		{"single low byte", EncodeBytes([]byte{0x7f}), "7f"},
+		{"single high byte", EncodeBytes([]byte{0x80}), "8180"},
+		{"dog", EncodeBytes([]byte("dog")), "83646f67"},
+		{"56 byte string", EncodeBytes(longString), "b838" + hex.EncodeToString(longString)},
+		{"zero", EncodeUint64(0), "80"},
+		{"small int", EncodeUint64(15), "0f"},
+		{"1024", EncodeUint64(1024), "820400"},
+		{"big", EncodeBig(new(big.Int).Lsh(big.NewInt(1), 64)), "89010000000000000000"},
+		{"nil big", EncodeBig(nil), "80"},
+		{"empty list", EncodeList(), "c0"},
+		{"cat dog", EncodeList(EncodeBytes([]byte("cat")), EncodeBytes([]byte("dog"))), "c88363617483646f67"},
+		{"set theory", EncodeList(EncodeList(), EncodeList(EncodeList()), EncodeList(EncodeList(), EncodeList(EncodeList()))), "c7c0c1c0c3c0c1c0"},
+	}
+
+	for _, tc := range testCases {
+		if got := hex.EncodeToString(tc.encoded); got != tc.expected {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.expected)
+		}
+	}
+
+	// A list whose payload is 56 bytes or more uses the long form.
+	items := make([][]byte, 20)
+	for i := range items {
+		items[i] = EncodeBytes([]byte("dog"))
+	}
+	encoded := EncodeList(items...)
+	if !bytes.HasPrefix(encoded, []byte{0xf8, 80}) {
+		t.Errorf("long list prefix = %x", encoded[:2])
+	}
+}
@@ -0,0 +1,232 @@
+// Package secp256k1 implements the arithmetic of the secp256k1 curve needed to
+// recover the public key behind an Ethereum signature. It works on math/big
+// and is not constant time, which is fine for public data such as signatures
+// but makes it unsuitable for handling private keys.
+package secp256k1
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	// P is the order of the underlying field.
+	P, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	// N is the order of the base point.
+	N, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	// HalfN is N/2, the upper bound of s in a canonical (low s) signature.
+	HalfN = new(big.Int).Rsh(N, 1)
+
+	gx, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	gy, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+
+	curveB = big.NewInt(7)
+	// sqrtExp is (P+1)/4; since P = 3 mod 4, a^sqrtExp is a square root of a.
+	sqrtExp = new(big.Int).Rsh(new(big.Int).Add(P, big.NewInt(1)), 2)
+)
+
+var (
+	ErrInvalidSignature  = errors.New("invalid signature values")
+	ErrInvalidRecoveryID = errors.New("invalid recovery id")
+	ErrNoPublicKey       = errors.New("signature does not recover to a public key")
+)
+
+// RecoverPublicKey returns the public key that produced the signature (r, s)
+// over hash. The recovery id selects the curve point behind r: bit 0 is the
+// parity of its y coordinate and bit 1 is set when its x coordinate is r+N.
+func RecoverPublicKey(hash []byte, r, s *big.Int, recoveryID byte) (x, y *big.Int, err error) {
+	if r.Sign() <= 0 || r.Cmp(N) >= 0 || s.Sign() <= 0 || s.Cmp(N) >= 0 {
+		return nil, nil, ErrInvalidSignature
+	}
+	if recoveryID > 3 {
+		return nil, nil, ErrInvalidRecoveryID
+	}
+
+	rx := new(big.Int).Set(r)
+	if recoveryID&2 != 0 {
+		rx.Add(rx, N)
+		if rx.Cmp(P) >= 0 {
+			return nil, nil, ErrInvalidRecoveryID
+		}
+	}
+	ry, ok := decompress(rx, recoveryID&1 == 1)
+	if !ok {
+		return nil, nil, ErrNoPublicKey
+	}
+
+	// Q = r^-1 (s*R - e*G) = (-e/r)*G + (s/r)*R
+	rInv := new(big.Int).ModInverse(r, N)
+	e := new(big.Int).SetBytes(hash)
+	u1 := new(big.Int).Mul(e, rInv)
+	u1.Neg(u1).Mod(u1, N)
+	u2 := new(big.Int).Mul(s, rInv)
+	u2.Mod(u2, N)
+
+	q := doubleScalarMult(u1, newPoint(gx, gy), u2, newPoint(rx, ry))
+	if q.isInfinity() {
+		return nil, nil, ErrNoPublicKey
+	}
+	x, y = q.affine()
+	return x, y, nil
+}
+
+// ScalarBaseMult returns k*G.
+func ScalarBaseMult(k *big.Int) (x, y *big.Int) {
+	return doubleScalarMult(k, newPoint(gx, gy), new(big.Int), infinity()).affine()
+}
+
+// IsOnCurve reports whether (x, y) satisfies y² = x³ + 7 over the field.
+func IsOnCurve(x, y *big.Int) bool {
+	if x.Sign() < 0 || x.Cmp(P) >= 0 || y.Sign() < 0 || y.Cmp(P) >= 0 {
+		return false
+	}
+	lhs := new(big.Int).Mul(y, y)
+	lhs.Mod(lhs, P)
+	return lhs.Cmp(curveRHS(x)) == 0
+}
+
+// curveRHS returns x³ + 7 mod P.
+func curveRHS(x *big.Int) *big.Int {
+	rhs := new(big.Int).Mul(x, x)
+	rhs.Mul(rhs, x)
+	rhs.Add(rhs, curveB)
+	return rhs.Mod(rhs, P)
+}
+
+// decompress returns the y coordinate of the point with the given x and y
+// parity, if there is one.
+func decompress(x *big.Int, odd bool) (*big.Int, bool) {
+	rhs := curveRHS(x)
+	y := new(big.Int).Exp(rhs, sqrtExp, P)
+	check := new(big.Int).Mul(y, y)
+	if check.Mod(check, P).Cmp(rhs) != 0 {
+		return nil, false
+	}
+	if (y.Bit(0) == 1) != odd {
+		y.Sub(P, y)
+	}
+	return y, true
+}
+
+// point is a curve point in Jacobian coordinates (X/Z², Y/Z³); Z = 0 is the
+// point at infinity.
+type point struct {
+	x, y, z *big.Int
+}
+
+func newPoint(x, y *big.Int) point {
+	return point{new(big.Int).Set(x), new(big.Int).Set(y), big.NewInt(1)}
+}
+
+func infinity() point {
+	return point{new(big.Int), new(big.Int), new(big.Int)}
+}
+
+func (p point) isInfinity() bool {
+	return p.z.Sign() == 0
+}
+
+func (p point) affine() (x, y *big.Int) {
+	if p.isInfinity() {
+		return new(big.Int), new(big.Int)
+	}
+	zInv := new(big.Int).ModInverse(p.z, P)
+	zInv2 := mulMod(zInv, zInv)
+	return mulMod(p.x, zInv2), mulMod(p.y, mulMod(zInv2, zInv))
+}
+
+func mulMod(a, b *big.Int) *big.Int {
+	r := new(big.Int).Mul(a, b)
+	return r.Mod(r, P)
+}
+
+func subMod(a, b *big.Int) *big.Int {
+	r := new(big.Int).Sub(a, b)
+	return r.Mod(r, P)
+}
+
+func addMod(a, b *big.Int) *big.Int {
+	r := new(big.Int).Add(a, b)
+	return r.Mod(r, P)
+}
+
+// double uses the dbl-2009-l formulas for a = 0.
+func (p point) double() point {
+	if p.isInfinity() || p.y.Sign() == 0 {
+		return infinity()
+	}
+	a := mulMod(p.x, p.x)
+	b := mulMod(p.y, p.y)
+	c := mulMod(b, b)
+	d := subMod(subMod(mulMod(addMod(p.x, b), addMod(p.x, b)), a), c)
+	d = addMod(d, d)
+	e := addMod(addMod(a, a), a)
+	f := mulMod(e, e)
+
+	x3 := subMod(f, addMod(d, d))
+	c8 := new(big.Int).Lsh(c, 3)
+	y3 := subMod(mulMod(e, subMod(d, x3)), c8)
+	z3 := mulMod(p.y, p.z)
+	z3 = addMod(z3, z3)
+	return point{x3, y3, z3}
+}
+
+// add uses the add-2007-bl formulas.
+func (p point) add(q point) point {
+	if p.isInfinity() {
+		return q
+	}
+	if q.isInfinity() {
+		return p
+	}
+	z1z1 := mulMod(p.z, p.z)
+	z2z2 := mulMod(q.z, q.z)
+	u1 := mulMod(p.x, z2z2)
+	u2 := mulMod(q.x, z1z1)
+	s1 := mulMod(mulMod(p.y, q.z), z2z2)
+	s2 := mulMod(mulMod(q.y, p.z), z1z1)
+	if u1.Cmp(u2) == 0 {
+		if s1.Cmp(s2) == 0 {
+			return p.double()
+		}
+		return infinity()
+	}
+
+	h := subMod(u2, u1)
+	i := addMod(h, h)
+	i = mulMod(i, i)
+	j := mulMod(h, i)
+	r := subMod(s2, s1)
+	r = addMod(r, r)
+	v := mulMod(u1, i)
+
+	x3 := subMod(subMod(mulMod(r, r), j), addMod(v, v))
+	s1j := mulMod(s1, j)
+	y3 := subMod(mulMod(r, subMod(v, x3)), addMod(s1j, s1j))
+	zs := addMod(p.z, q.z)
+	z3 := mulMod(subMod(subMod(mulMod(zs, zs), z1z1), z2z2), h)
+	return point{x3, y3, z3}
+}
+
+// doubleScalarMult returns k1*p1 + k2*p2 with a single double-and-add pass
+// (Shamir's trick).
+func doubleScalarMult(k1 *big.Int, p1 point, k2 *big.Int, p2 point) point {
+	sum := p1.add(p2)
+	result := infinity()
+	bits := k1.BitLen()
+	if k2.BitLen() > bits {
+		bits = k2.BitLen()
+	}
+	for i := bits - 1; i >= 0; i-- {
+		result = result.double()
+		switch b1, b2 := k1.Bit(i), k2.Bit(i); {
+		case b1 == 1 && b2 == 1:
+			result = result.add(sum)
+		case b1 == 1:
+			result = result.add(p1)
+		case b2 == 1:
+			result = result.add(p2)
+		}
+	}
+	return result
+}
@@ -0,0 +1,119 @@
+package secp256k1
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func hexInt(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("bad hex " + s)
+	}
+	return v
+}
+
+// sign produces a low-s signature with the given nonce. Tests only: a fixed
+// nonce leaks the private key.
+func sign(hash []byte, priv, k *big.Int) (r, s *big.Int, recoveryID byte) {
+	rx, ry := ScalarBaseMult(k)
+	r = new(big.Int).Mod(rx, N)
+	s = new(big.Int).Mul(r, priv)
+	s.Add(s, new(big.Int).SetBytes(hash))
+	s.Mul(s, new(big.Int).ModInverse(k, N))
+	s.Mod(s, N)
+	recoveryID = byte(ry.Bit(0))
+	if rx.Cmp(N) >= 0 {
+		recoveryID |= 2
+	}
+	if s.Cmp(HalfN) > 0 {
+		s.Sub(N, s)
+		recoveryID ^= 1
+	}
+	return r, s, recoveryID
+}
+
+func TestScalarBaseMult(t *testing.T) {
+	x, y := ScalarBaseMult(big.NewInt(1))
+	if x.Cmp(gx) != 0 || y.Cmp(gy) != 0 {
+		t.Errorf("1*G = (%x, %x)", x, y)
+	}
+
+	x, y = ScalarBaseMult(big.NewInt(2))
+	if x.Cmp(hexInt("c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5")) != 0 ||
+		y.Cmp(hexInt("1ae168fea63dc339a3c58419466ceaeef7f632653266d0e1236431a950cfe52a")) != 0 {
+		t.Errorf("2*G = (%x, %x)", x, y)
+	}
+	if !IsOnCurve(x, y) {
+		t.Error("2*G is not on the curve")
+	}
+
+	x, y = ScalarBaseMult(N)
+	if x.Sign() != 0 || y.Sign() != 0 {
+		t.Errorf("N*G = (%x, %x), want infinity", x, y)
+	}
+}
+
+func TestRecoverPublicKey(t *testing.T) {
+	hash := hexInt("daf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53").Bytes()
+	for _, priv := range []*big.Int{
+		big.NewInt(1),
+		hexInt("4646464646464646464646464646464646464646464646464646464646464646"),
+		new(big.Int).Sub(N, big.NewInt(1)),
+	} {
+		wantX, wantY := ScalarBaseMult(priv)
+		for _, k := range []int64{3, 1234567, 987654321987} {
+			r, s, id := sign(hash, priv, big.NewInt(k))
+			x, y, err := RecoverPublicKey(hash, r, s, id)
+			if err != nil {
+				t.Fatalf("priv %x, k %d: %v", priv, k, err)
+			}
+			if x.Cmp(wantX) != 0 || y.Cmp(wantY) != 0 {
+				t.Errorf("priv %x, k %d: recovered the wrong key", priv, k)
+			}
+
+			// The other parity recovers a different, valid key.
+			x, y, err = RecoverPublicKey(hash, r, s, id^1)
+			if err != nil || x.Cmp(wantX) == 0 || !IsOnCurve(x, y) {
+				t.Errorf("priv %x, k %d: flipped recovery id: %v", priv, k, err)
+			}
+		}
+	}
+}
+
+func TestRecoverPublicKeyInvalid(t *testing.T) {
+	hash := make([]byte, 32)
+	one := big.NewInt(1)
+	for _, tc := range []struct {
+		name string
+		r, s *big.Int
+		id   byte
+		want error
+	}{
+		{"zero r", new(big.Int), one, 0, ErrInvalidSignature},
+		{"zero s", one, new(big.Int), 0, ErrInvalidSignature},
+		{"r = N", N, one, 0, ErrInvalidSignature},
+		{"s = N", one, N, 0, ErrInvalidSignature},
+		{"recovery id", one, one, 4, ErrInvalidRecoveryID},
+		// r + N exceeds the field unless r < P - N (about 2^128).
+		{"r + N overflow", new(big.Int).Lsh(one, 200), one, 2, ErrInvalidRecoveryID},
+		// x = 5 has no point: 5³ + 7 = 132 is not a square mod P.
+		{"not on curve", big.NewInt(5), one, 0, ErrNoPublicKey},
+	} {
+		if _, _, err := RecoverPublicKey(hash, tc.r, tc.s, tc.id); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func BenchmarkRecoverPublicKey(b *testing.B) {
+	hash := hexInt("daf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53").Bytes()
+	r, s, id := sign(hash, big.NewInt(0xdeadbeef), big.NewInt(0x1337))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := RecoverPublicKey(hash, r, s, id); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Package trie computes Merkle-Patricia trie roots, such as the transactions
+// root committed to in a block header. The trie lives in memory only and is
+// built for one-shot root computation rather than lookups or proofs.
+package trie
+
+import (
+	"eth-parser/pkg/rlp"
+	"eth-parser/pkg/utils"
+)
+
+// EmptyRoot is the root of a trie without entries, keccak256(rlp("")).
+var EmptyRoot = utils.Keccak256(rlp.EmptyString)
+
+// terminator marks the end of a key in nibble form; a key ending with it
+// leads to a value rather than to another node.
+const terminator = 16
+
+type (
+	node interface{}
+
+	// fullNode is a branch with one child per nibble and an optional value
+	// in slot 16.
+	fullNode struct {
+		children [17]node
+	}
+
+	// shortNode is an extension (val is a node) or a leaf (key ends with the
+	// terminator and val is a valueNode).
+	shortNode struct {
+		key []byte
+		val node
+	}
+
+	valueNode []byte
+)
+
+type Trie struct {
+	root node
+}
+
+// Update stores value under key. Keys must not be prefixes of one another,
+// which holds for the RLP-encoded indices used by the header tries.
+func (t *Trie) Update(key, value []byte) {
+	t.root = insert(t.root, keyToNibbles(key), valueNode(value))
+}
+
+// Hash returns the root hash of the trie.
+func (t *Trie) Hash() [32]byte {
+	if t.root == nil {
+		return EmptyRoot
+	}
+	return utils.Keccak256(encodeNode(t.root))
+}
+
+// DeriveRoot computes the root of the trie mapping rlp(i) to items[i], the
+// layout of the transactions, receipts and withdrawals tries.
+func DeriveRoot(items [][]byte) [32]byte {
+	var t Trie
+	for i, item := range items {
+		t.Update(rlp.EncodeUint64(uint64(i)), item)
+	}
+	return t.Hash()
+}
+
+func insert(n node, key []byte, value node) node {
+	if len(key) == 0 {
+		return value
+	}
+	switch n := n.(type) {
+	case nil:
+		return &shortNode{key: key, val: value}
+	case *shortNode:
+		match := prefixLen(key, n.key)
+		if match == len(n.key) {
+			n.val = insert(n.val, key[match:], value)
+			return n
+		}
+		// Split at the first differing nibble.
+		branch := &fullNode{}
+		branch.children[n.key[match]] = insert(nil, n.key[match+1:], n.val)
+		branch.children[key[match]] = insert(nil, key[match+1:], value)
+		if match == 0 {
+			return branch
+		}
+		return &shortNode{key: key[:match], val: branch}
+	case *fullNode:
+		n.children[key[0]] = insert(n.children[key[0]], key[1:], value)
+		return n
+	default:
+		// A valueNode with key left over: the old key was a prefix of the
+		// new one. Not possible with the key layouts this package targets.
+		panic("trie: key is a prefix of an existing key")
+	}
+}
+
+// encodeNode returns the RLP encoding of n.
+func encodeNode(n node) []byte {
+	switch n := n.(type) {
+	case *shortNode:
+		return rlp.EncodeList(rlp.EncodeBytes(compactKey(n.key)), childRef(n.val))
+	case *fullNode:
+		items := make([][]byte, 17)
+		for i, child := range n.children {
+			items[i] = childRef(child)
+		}
+		return rlp.EncodeList(items...)
+	case valueNode:
+		return rlp.EncodeBytes(n)
+	}
+	return rlp.EmptyString
+}
+
+// childRef returns how a parent refers to n: nodes that encode to fewer than
+// 32 bytes are embedded, larger ones are referenced by hash.
+func childRef(n node) []byte {
+	if n == nil {
+		return rlp.EmptyString
+	}
+	enc := encodeNode(n)
+	if _, isValue := n.(valueNode); isValue || len(enc) < 32 {
+		return enc
+	}
+	hash := utils.Keccak256(enc)
+	return rlp.EncodeBytes(hash[:])
+}
+
+func keyToNibbles(key []byte) []byte {
+	nibbles := make([]byte, len(key)*2+1)
+	for i, b := range key {
+		nibbles[i*2] = b >> 4
+		nibbles[i*2+1] = b & 0x0f
+	}
+	nibbles[len(nibbles)-1] = terminator
+	return nibbles
+}
+
+// compactKey applies hex-prefix encoding: the first nibble carries the leaf
+// flag (2) and the odd-length flag (1).
+func compactKey(nibbles []byte) []byte {
+	var flag byte
+	if len(nibbles) > 0 && nibbles[len(nibbles)-1] == terminator {
+		flag = 2
+		nibbles = nibbles[:len(nibbles)-1]
+	}
+	out := make([]byte, len(nibbles)/2+1)
+	out[0] = flag << 4
+	if len(nibbles)%2 == 1 {
+		out[0] |= (1 << 4) | nibbles[0]
+		nibbles = nibbles[1:]
+	}
+	for i := 0; i < len(nibbles); i += 2 {
+		out[i/2+1] = nibbles[i]<<4 | nibbles[i+1]
+	}
+	return out
+}
+
+func prefixLen(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
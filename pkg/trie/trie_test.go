@@ -0,0 +1,78 @@
+package trie
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestTrieHash(t *testing.T) {
+	// Vectors from the ethereum/tests trie suite.
+	testCases := []struct {
+		name     string
+		entries  [][2]string
+		expected string
+	}{
+		{"empty", nil, "56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"},
+		{
+			"singleItem",
+			[][2]string{{"A", strings.Repeat("a", 50)}},
+			"d23786fb4a010da3ce639d66d5e904a11dbc02746d1ce25029e53290cabf28ab",
+		},
+		{
+			"dogs",
+			[][2]string{{"doe", "reindeer"}, {"dog", "puppy"}, {"dogglesworth", "cat"}},
+			"8aad789dff2f538bca5d8ea56e8abe10f4c7ba3a5dea95fea4cd6e7c3a1168d3",
+		},
+		{
+			"puppy",
+			[][2]string{{"do", "verb"}, {"horse", "stallion"}, {"doge", "coin"}, {"dog", "puppy"}},
+			"5991bb8c6514148a29db676a14ac506cd2cd5775ace63c30a4fe457715e9ac84",
+		},
+	}
+
+	for _, tc := range testCases {
+		var tr Trie
+		for _, entry := range tc.entries {
+			tr.Update([]byte(entry[0]), []byte(entry[1]))
+		}
+		root := tr.Hash()
+		if got := hex.EncodeToString(root[:]); got != tc.expected {
+			t.Errorf("%s: root %s, want %s", tc.name, got, tc.expected)
+		}
+	}
+}
+
+func TestDeriveRootOrderIndependent(t *testing.T) {
+	items := make([][]byte, 300)
+	for i := range items {
+		items[i] = []byte(strings.Repeat("x", i%70))
+	}
+	want := DeriveRoot(items)
+
+	// Insertion order must not matter; 300 items cross the one and two byte
+	// RLP index boundaries (0x7f, 0x80).
+	var tr Trie
+	for i := len(items) - 1; i >= 0; i-- {
+		tr.Update(encodeIndex(i), items[i])
+	}
+	if got := tr.Hash(); got != want {
+		t.Errorf("reverse insertion root %x, want %x", got, want)
+	}
+	if DeriveRoot(nil) != EmptyRoot {
+		t.Error("DeriveRoot(nil) should be the empty root")
+	}
+}
+
+func encodeIndex(i int) []byte {
+	switch {
+	case i == 0:
+		return []byte{0x80}
+	case i < 0x80:
+		return []byte{byte(i)}
+	case i < 0x100:
+		return []byte{0x81, byte(i)}
+	default:
+		return []byte{0x82, byte(i >> 8), byte(i)}
+	}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"encoding/hex"
+	"errors"
+)
+
+const addressLength = 20
+
+var (
+	ErrInvalidAddress  = errors.New("address must be 0x followed by 40 hex digits")
+	ErrAddressChecksum = errors.New("address has an invalid EIP-55 checksum")
+)
+
+// ParseAddress strictly parses a 20 byte address. All-lowercase and
+// all-uppercase inputs are accepted as is; mixed-case inputs must carry a
+// valid EIP-55 checksum, since a wrong one usually means a typo.
+func ParseAddress(s string) ([addressLength]byte, error) {
+	var a [addressLength]byte
+	if err := DecodeFixedBytes(s, a[:]); err != nil {
+		return a, ErrInvalidAddress
+	}
+
+	digits := s[2:]
+	hasLower, hasUpper := false, false
+	for i := 0; i < len(digits); i++ {
+		switch c := digits[i]; {
+		case 'a' <= c && c <= 'f':
+			hasLower = true
+		case 'A' <= c && c <= 'F':
+			hasUpper = true
+		}
+	}
+	if hasLower && hasUpper && ChecksumAddress(a)[2:] != digits {
+		return [addressLength]byte{}, ErrAddressChecksum
+	}
+	return a, nil
+}
+
+// ChecksumAddress returns the EIP-55 mixed-case encoding of a: a hex letter is
+// uppercased when the matching nibble of keccak256(lowercase hex) is >= 8.
+func ChecksumAddress(a [addressLength]byte) string {
+	var buf [2 + 2*addressLength]byte
+	copy(buf[:2], "0x")
+	hex.Encode(buf[2:], a[:])
+
+	hash := Keccak256(buf[2:])
+	for i := 2; i < len(buf); i++ {
+		nibble := hash[(i-2)/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		} else {
+			nibble &= 0x0f
+		}
+		if buf[i] > '9' && nibble >= 8 {
+			buf[i] -= 'a' - 'A'
+		}
+	}
+	return string(buf[:])
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestChecksumAddress(t *testing.T) {
+	// Test vectors from EIP-55.
+	vectors := []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+		"0x52908400098527886E0F7030069857D2E4169EE7",
+		"0x8617E340B3D01FA5F11F306F4090FD50E238070D",
+		"0xde709f2102306220921060314715629080e2fb77",
+		"0x27b1fdb04752bbc536007a920d24acb045561c26",
+	}
+
+	for _, vector := range vectors {
+		a, err := ParseAddress(vector)
+		if err != nil {
+			t.Errorf("ParseAddress(%s) error = %v", vector, err)
+			continue
+		}
+		if got := ChecksumAddress(a); got != vector {
+			t.Errorf("ChecksumAddress(%s) = %s", vector, got)
+		}
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	testCases := []struct {
+		input string
+		err   error
+	}{
+		{"0xdac17f958d2ee523a2206206994597c13d831ec7", nil},
+		{"0xDAC17F958D2EE523A2206206994597C13D831EC7", nil},
+		{"0xdAC17F958D2ee523a2206206994597C13D831ec7", nil},
+		// One letter flipped from the valid checksum above.
+		{"0xDAC17F958D2ee523a2206206994597C13D831ec7", ErrAddressChecksum},
+		{"dac17f958d2ee523a2206206994597c13d831ec7", ErrInvalidAddress},
+		{"0xdac17f958d2ee523a2206206994597c13d831e", ErrInvalidAddress},
+		{"0xdac17f958d2ee523a2206206994597c13d831ec7aa", ErrInvalidAddress},
+		{"0xzac17f958d2ee523a2206206994597c13d831ec7", ErrInvalidAddress},
+		{"", ErrInvalidAddress},
+		{"not-an-address", ErrInvalidAddress},
+	}
+
+	for _, tc := range testCases {
+		a, err := ParseAddress(tc.input)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("ParseAddress(%q) error = %v, want %v", tc.input, err, tc.err)
+			continue
+		}
+		if err == nil && !strings.EqualFold(ChecksumAddress(a), tc.input) {
+			t.Errorf("ParseAddress(%q) = %x", tc.input, a)
+		}
+	}
+}
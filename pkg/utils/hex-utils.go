@@ -1,17 +1,165 @@
 package utils
 
 import (
+	"encoding/hex"
 	"errors"
+	"math"
+	"math/big"
 	"strconv"
 )
 
+// Hex encoding as specified for the Ethereum JSON-RPC API:
+//
+//   - QUANTITY: 0x-prefixed, most compact form with no leading zero digits,
+//     zero is "0x0".
+//   - DATA: 0x-prefixed, two hex digits per byte, empty data is "0x".
+//
+// Decoders accept both "0x" and "0X" prefixes and any letter case in the
+// digits. Encoders always produce lowercase.
+
+var (
+	ErrEmptyString   = errors.New("empty hex string")
+	ErrMissingPrefix = errors.New("hex string without 0x prefix")
+	ErrSyntax        = errors.New("invalid hex string")
+	ErrOddLength     = errors.New("hex string of odd length")
+	ErrEmptyNumber   = errors.New("hex string \"0x\" is not a quantity")
+	ErrLeadingZero   = errors.New("hex quantity with leading zero digits")
+	ErrUint64Range   = errors.New("hex quantity larger than 64 bits")
+	ErrInt64Range    = errors.New("hex quantity larger than int64")
+	ErrBig256Range   = errors.New("hex quantity larger than 256 bits")
+	ErrWrongLength   = errors.New("hex data of wrong length")
+)
+
+// maxBigBits bounds DecodeBig to 256 bit values, the EVM word size.
+const maxBigBits = 256
+
 func HexToInt(hex string) (int64, error) {
-	if len(hex) > 2 && hex[:2] == "0x" {
-		parsed, err := strconv.ParseInt(hex[2:], 16, 64)
-		if err != nil {
-			return 0, err
+	v, err := DecodeUint64(hex)
+	if err != nil {
+		return 0, err
+	}
+	if v > math.MaxInt64 {
+		return 0, ErrInt64Range
+	}
+	return int64(v), nil
+}
+
+// DecodeUint64 decodes a QUANTITY into a uint64.
+func DecodeUint64(s string) (uint64, error) {
+	digits, err := checkQuantity(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(digits) > 16 {
+		return 0, ErrUint64Range
+	}
+	v, err := strconv.ParseUint(digits, 16, 64)
+	if err != nil {
+		return 0, ErrSyntax
+	}
+	return v, nil
+}
+
+// EncodeUint64 encodes v as a QUANTITY.
+func EncodeUint64(v uint64) string {
+	return "0x" + strconv.FormatUint(v, 16)
+}
+
+// DecodeBig decodes a QUANTITY of at most 256 bits.
+func DecodeBig(s string) (*big.Int, error) {
+	digits, err := checkQuantity(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(digits) > maxBigBits/4 {
+		return nil, ErrBig256Range
+	}
+	v, ok := new(big.Int).SetString(digits, 16)
+	if !ok {
+		return nil, ErrSyntax
+	}
+	return v, nil
+}
+
+// EncodeBig encodes v as a QUANTITY. Negative values, which have no QUANTITY
+// form, are written with a leading minus sign.
+func EncodeBig(v *big.Int) string {
+	switch v.Sign() {
+	case 0:
+		return "0x0"
+	case -1:
+		return "-0x" + new(big.Int).Neg(v).Text(16)
+	default:
+		return "0x" + v.Text(16)
+	}
+}
+
+// DecodeBytes decodes DATA of any length.
+func DecodeBytes(s string) ([]byte, error) {
+	digits, err := trimPrefix(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(digits)%2 != 0 {
+		return nil, ErrOddLength
+	}
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, ErrSyntax
+	}
+	return b, nil
+}
+
+// EncodeBytes encodes b as DATA.
+func EncodeBytes(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
+
+// DecodeFixedBytes decodes DATA that must be exactly len(out) bytes long, such
+// as a 32 byte hash or a 20 byte address, into out.
+func DecodeFixedBytes(s string, out []byte) error {
+	digits, err := trimPrefix(s)
+	if err != nil {
+		return err
+	}
+	if len(digits) != 2*len(out) {
+		if len(digits)%2 != 0 {
+			return ErrOddLength
+		}
+		return ErrWrongLength
+	}
+	if _, err := hex.Decode(out, []byte(digits)); err != nil {
+		return ErrSyntax
+	}
+	return nil
+}
+
+func trimPrefix(s string) (string, error) {
+	if s == "" {
+		return "", ErrEmptyString
+	}
+	if len(s) < 2 || s[0] != '0' || (s[1] != 'x' && s[1] != 'X') {
+		return "", ErrMissingPrefix
+	}
+	return s[2:], nil
+}
+
+func checkQuantity(s string) (string, error) {
+	digits, err := trimPrefix(s)
+	if err != nil {
+		return "", err
+	}
+	if digits == "" {
+		return "", ErrEmptyNumber
+	}
+	if len(digits) > 1 && digits[0] == '0' {
+		return "", ErrLeadingZero
+	}
+	for i := 0; i < len(digits); i++ {
+		c := digits[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return "", ErrSyntax
 		}
-		return parsed, nil
 	}
-	return 0, errors.New("invalid hex string")
+	return digits, nil
 }
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestDecodeUint64(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected uint64
+		err      error
+	}{
+		{"0x0", 0, nil},
+		{"0x1", 1, nil},
+		{"0X1a", 26, nil},
+		{"0xffffffffffffffff", 1<<64 - 1, nil},
+		{"0xABCdef", 0xabcdef, nil},
+		{"", 0, ErrEmptyString},
+		{"1a", 0, ErrMissingPrefix},
+		{"0x", 0, ErrEmptyNumber},
+		{"0x01", 0, ErrLeadingZero},
+		{"0x00", 0, ErrLeadingZero},
+		{"0x10000000000000000", 0, ErrUint64Range},
+		{"0xzz", 0, ErrSyntax},
+		{"0x-1", 0, ErrSyntax},
+	}
+
+	for _, tc := range testCases {
+		v, err := DecodeUint64(tc.input)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("DecodeUint64(%q) error = %v, want %v", tc.input, err, tc.err)
+			continue
+		}
+		if v != tc.expected {
+			t.Errorf("DecodeUint64(%q) = %d, want %d", tc.input, v, tc.expected)
+		}
+	}
+}
+
+func TestHexToInt(t *testing.T) {
+	if v, err := HexToInt("0x1312d00"); err != nil || v != 20000000 {
+		t.Errorf("HexToInt = %d, %v", v, err)
+	}
+	if _, err := HexToInt("0x8000000000000000"); !errors.Is(err, ErrInt64Range) {
+		t.Errorf("HexToInt above int64 error = %v", err)
+	}
+}
+
+func TestDecodeBig(t *testing.T) {
+	v, err := DecodeBig("0x152d02c7e14af6800000")
+	if err != nil || v.String() != "100000000000000000000000" {
+		t.Errorf("DecodeBig = %v, %v", v, err)
+	}
+	max256 := "0x" + strings.Repeat("f", 64)
+	if _, err := DecodeBig(max256); err != nil {
+		t.Errorf("DecodeBig(2^256-1) error = %v", err)
+	}
+	if _, err := DecodeBig("0x1" + strings.Repeat("0", 64)); !errors.Is(err, ErrBig256Range) {
+		t.Errorf("DecodeBig(2^256) error = %v", err)
+	}
+	if _, err := DecodeBig("0x0001"); !errors.Is(err, ErrLeadingZero) {
+		t.Errorf("DecodeBig with leading zeros error = %v", err)
+	}
+
+	if got := EncodeBig(big.NewInt(0)); got != "0x0" {
+		t.Errorf("EncodeBig(0) = %s", got)
+	}
+	if got := EncodeBig(big.NewInt(-255)); got != "-0xff" {
+		t.Errorf("EncodeBig(-255) = %s", got)
+	}
+}
+
+func TestDecodeBytes(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []byte
+		err      error
+	}{
+		{"0x", []byte{}, nil},
+		{"0x00", []byte{0}, nil},
+		{"0X0a0B", []byte{0x0a, 0x0b}, nil},
+		{"0x0", nil, ErrOddLength},
+		{"0xgg", nil, ErrSyntax},
+		{"00", nil, ErrMissingPrefix},
+	}
+
+	for _, tc := range testCases {
+		b, err := DecodeBytes(tc.input)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("DecodeBytes(%q) error = %v, want %v", tc.input, err, tc.err)
+			continue
+		}
+		if !bytes.Equal(b, tc.expected) {
+			t.Errorf("DecodeBytes(%q) = %x, want %x", tc.input, b, tc.expected)
+		}
+	}
+
+	var hash [32]byte
+	if err := DecodeFixedBytes("0x"+strings.Repeat("ab", 32), hash[:]); err != nil || hash[31] != 0xab {
+		t.Errorf("DecodeFixedBytes = %x, %v", hash, err)
+	}
+	if err := DecodeFixedBytes("0x"+strings.Repeat("ab", 31), hash[:]); !errors.Is(err, ErrWrongLength) {
+		t.Errorf("DecodeFixedBytes short input error = %v", err)
+	}
+}
+
+func FuzzUint64RoundTrip(f *testing.F) {
+	f.Add(uint64(0))
+	f.Add(uint64(1))
+	f.Add(uint64(1<<64 - 1))
+	f.Fuzz(func(t *testing.T, v uint64) {
+		decoded, err := DecodeUint64(EncodeUint64(v))
+		if err != nil || decoded != v {
+			t.Fatalf("round trip of %d: %d, %v", v, decoded, err)
+		}
+	})
+}
+
+// FuzzDecodeUint64 checks that every accepted input is canonical: encoding the
+// decoded value reproduces it up to prefix and letter case.
+func FuzzDecodeUint64(f *testing.F) {
+	for _, seed := range []string{"0x0", "0x01", "0X1A", "0x", "0xffffffffffffffff1", "1"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		v, err := DecodeUint64(s)
+		if err != nil {
+			return
+		}
+		if got := EncodeUint64(v); got != "0x"+strings.ToLower(s[2:]) {
+			t.Fatalf("DecodeUint64(%q) accepted a non-canonical quantity, re-encodes to %s", s, got)
+		}
+	})
+}
+
+func FuzzBigRoundTrip(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0, 0, 1})
+	f.Add(bytes.Repeat([]byte{0xff}, 32))
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		if len(raw) > 32 {
+			raw = raw[:32]
+		}
+		v := new(big.Int).SetBytes(raw)
+		decoded, err := DecodeBig(EncodeBig(v))
+		if err != nil || decoded.Cmp(v) != 0 {
+			t.Fatalf("round trip of %s: %v, %v", v, decoded, err)
+		}
+	})
+}
+
+func FuzzBytesRoundTrip(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0})
+	f.Add([]byte("eth-parser"))
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		decoded, err := DecodeBytes(EncodeBytes(raw))
+		if err != nil || !bytes.Equal(decoded, raw) {
+			t.Fatalf("round trip of %x: %x, %v", raw, decoded, err)
+		}
+	})
+}
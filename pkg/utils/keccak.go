@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// Keccak-256 as used by Ethereum. This is the original Keccak submission with
+// 0x01 domain padding, not the NIST SHA3-256 variant (0x06 padding).
+
+const (
+	keccak256Rate = 136
+	keccak256Size = 32
+)
+
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+var keccakRotations = [25]int{
+	0, 1, 62, 28, 27,
+	36, 44, 6, 55, 20,
+	3, 10, 43, 25, 39,
+	41, 45, 15, 21, 8,
+	18, 2, 61, 56, 14,
+}
+
+type keccakState struct {
+	a   [25]uint64
+	buf [keccak256Rate]byte
+	n   int
+}
+
+// NewKeccak256 returns a streaming Keccak-256 hash.
+func NewKeccak256() hash.Hash {
+	return &keccakState{}
+}
+
+// Keccak256 returns the Keccak-256 digest of the concatenation of data.
+func Keccak256(data ...[]byte) [32]byte {
+	var k keccakState
+	for _, d := range data {
+		k.Write(d)
+	}
+	var out [32]byte
+	k.sum(out[:0])
+	return out
+}
+
+func (k *keccakState) Size() int      { return keccak256Size }
+func (k *keccakState) BlockSize() int { return keccak256Rate }
+
+func (k *keccakState) Reset() {
+	*k = keccakState{}
+}
+
+func (k *keccakState) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		c := copy(k.buf[k.n:], p)
+		k.n += c
+		p = p[c:]
+		if k.n == keccak256Rate {
+			k.absorb()
+		}
+	}
+	return written, nil
+}
+
+func (k *keccakState) Sum(b []byte) []byte {
+	// Work on a copy so the caller can keep writing.
+	dup := *k
+	return dup.sum(b)
+}
+
+func (k *keccakState) sum(b []byte) []byte {
+	for i := k.n; i < keccak256Rate; i++ {
+		k.buf[i] = 0
+	}
+	k.buf[k.n] ^= 0x01
+	k.buf[keccak256Rate-1] ^= 0x80
+	k.absorb()
+
+	var out [keccak256Size]byte
+	for i := 0; i < keccak256Size/8; i++ {
+		binary.LittleEndian.PutUint64(out[i*8:], k.a[i])
+	}
+	return append(b, out[:]...)
+}
+
+func (k *keccakState) absorb() {
+	for i := 0; i < keccak256Rate/8; i++ {
+		k.a[i] ^= binary.LittleEndian.Uint64(k.buf[i*8:])
+	}
+	keccakF1600(&k.a)
+	k.n = 0
+}
+
+func keccakF1600(a *[25]uint64) {
+	var c [5]uint64
+	var b [25]uint64
+	for round := 0; round < 24; round++ {
+		// θ
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				a[y+x] ^= d
+			}
+		}
+		// ρ and π
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(a[x+5*y], keccakRotations[x+5*y])
+			}
+		}
+		// χ
+		for y := 0; y < 25; y += 5 {
+			for x := 0; x < 5; x++ {
+				a[y+x] = b[y+x] ^ (^b[y+(x+1)%5] & b[y+(x+2)%5])
+			}
+		}
+		// ι
+		a[0] ^= keccakRoundConstants[round]
+	}
+}
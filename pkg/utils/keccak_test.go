@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestKeccak256(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		{"Transfer(address,address,uint256)", "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"},
+	}
+
+	for _, tc := range testCases {
+		sum := Keccak256([]byte(tc.input))
+		if got := hex.EncodeToString(sum[:]); got != tc.expected {
+			t.Errorf("Keccak256(%q) = %s, want %s", tc.input, got, tc.expected)
+		}
+	}
+}
+
+func TestKeccak256Streaming(t *testing.T) {
+	// Cross the 136 byte rate boundary in uneven chunks.
+	input := []byte(strings.Repeat("eth-parser", 50))
+	want := Keccak256(input)
+
+	h := NewKeccak256()
+	for i := 0; i < len(input); i += 7 {
+		end := i + 7
+		if end > len(input) {
+			end = len(input)
+		}
+		h.Write(input[i:end])
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, want[:]) {
+		t.Errorf("streaming digest %x, want %x", got, want)
+	}
+
+	if got := Keccak256(input[:100], input[100:]); got != want {
+		t.Errorf("multi-slice digest %x, want %x", got, want)
+	}
+}
+
+func BenchmarkKeccak256(b *testing.B) {
+	data := make([]byte, 32)
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		Keccak256(data)
+	}
+}
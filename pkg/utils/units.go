@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"math/big"
+	"strings"
+)
+
+// FormatUnits formats an integer amount of a token's smallest unit as a
+// decimal number of whole tokens, e.g. 1500000 with 6 decimals as "1.5".
+// Trailing zeros of the fraction are dropped.
+func FormatUnits(amount *big.Int, decimals uint8) string {
+	digits := new(big.Int).Abs(amount).String()
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+	if decimals == 0 {
+		return sign + digits
+	}
+	if pad := int(decimals) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	point := len(digits) - int(decimals)
+	fraction := strings.TrimRight(digits[point:], "0")
+	if fraction == "" {
+		return sign + digits[:point]
+	}
+	return sign + digits[:point] + "." + fraction
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals uint8
+		want     string
+	}{
+		{"0", 18, "0"},
+		{"1", 18, "0.000000000000000001"},
+		{"1500000", 6, "1.5"},
+		{"1000000000000000000", 18, "1"},
+		{"123456789", 0, "123456789"},
+		{"120", 2, "1.2"},
+		{"-250", 2, "-2.5"},
+		{"115792089237316195423570985008687907853269984665640564039457584007913129639935", 18,
+			"115792089237316195423570985008687907853269984665640564039457.584007913129639935"},
+	}
+	for _, tt := range tests {
+		amount, _ := new(big.Int).SetString(tt.amount, 10)
+		if got := FormatUnits(amount, tt.decimals); got != tt.want {
+			t.Errorf("FormatUnits(%s, %d) = %s, want %s", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}